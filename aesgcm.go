@@ -0,0 +1,153 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// ContentEncoding is the content coding used to encrypt the push message
+type ContentEncoding string
+
+const (
+	// ContentEncodingAES128GCM is the encoding of RFC 8188 / RFC 8291 used by all current browsers
+	ContentEncodingAES128GCM ContentEncoding = "aes128gcm"
+	// ContentEncodingAESGCM is the legacy encoding of draft-ietf-webpush-encryption-04, needed by very
+	// old Firefox subscriptions. It is sent with the pre RFC 8292 "WebPush" Authorization scheme and
+	// the VAPID public key in the Crypto-Key header.
+	ContentEncodingAESGCM ContentEncoding = "aesgcm"
+)
+
+// aesgcmDefaultRecordSize is the record size assumed by receivers when the Encryption header has no rs
+const aesgcmDefaultRecordSize = 4096
+
+// Key derivation info of aesgcm, followed by the key derivation context
+var (
+	aesgcmIKMInfo   = []byte("Content-Encoding: auth\x00")
+	aesgcmKeyInfo   = []byte("Content-Encoding: aesgcm\x00")
+	aesgcmNonceInfo = []byte("Content-Encoding: nonce\x00")
+)
+
+// encryptAESGCM encrypts message as a single aesgcm record (draft-ietf-webpush-encryption-04), sealed
+// in place in a record allocated at its final size
+func encryptAESGCM(message, authSecret, dh, salt, localPublicKey, sharedECDHSecret []byte, recordSize uint32) ([]byte, error) {
+	// The derived keys share one allocation
+	derived := make([]byte, 32+16+12)
+	ikm, contentEncryptionKey, nonce := derived[:32], derived[32:48], derived[48:]
+
+	if err := newHKDF(authSecret, sharedECDHSecret).expand(ikm, aesgcmIKMInfo); err != nil {
+		return nil, err
+	}
+
+	// Key derivation info: the info of the key and of the nonce, each followed by the context of both public keys
+	buf := getHeaderBuffer()
+	defer putHeaderBuffer(buf)
+	info := append((*buf)[:0], aesgcmKeyInfo...)
+	info = appendAESGCMContext(info, dh, localPublicKey)
+	keyInfoLength := len(info)
+	info = append(info, aesgcmNonceInfo...)
+	info = appendAESGCMContext(info, dh, localPublicKey)
+	*buf = info
+
+	// Derive the Content Encryption Key and the Nonce
+	keys := newHKDF(salt, ikm)
+	if err := keys.expand(contentEncryptionKey, info[:keyInfoLength]); err != nil {
+		return nil, err
+	}
+	if err := keys.expand(nonce, info[keyInfoLength:]); err != nil {
+		return nil, err
+	}
+
+	// Cipher
+	block, err := aes.NewCipher(contentEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The record starts with the padding length, the padding precedes the data
+	recordLength := int(recordSize) - 16
+	padLen := recordLength - 2 - len(message)
+	if padLen < 0 || padLen > 0xffff {
+		return nil, ErrMaxPadExceeded
+	}
+
+	record := make([]byte, recordLength+gcm.Overhead())
+	data := record[:recordLength]
+	binary.BigEndian.PutUint16(data, uint16(padLen))
+	copy(data[2+padLen:], message)
+
+	// Compose the ciphertext
+	gcm.Seal(data[:0], nonce, data, nil)
+
+	return record, nil
+}
+
+// appendAESGCMContext appends the aesgcm key derivation context of the public keys to b
+func appendAESGCMContext(b, dh, localPublicKey []byte) []byte {
+	b = append(b, "P-256\x00"...)
+	b = append(b, byte(len(dh)>>8), byte(len(dh)))
+	b = append(b, dh...)
+	b = append(b, byte(len(localPublicKey)>>8), byte(len(localPublicKey)))
+	return append(b, localPublicKey...)
+}
+
+// legacyVAPIDHeaders converts a "vapid t=..., k=..." header into the pre RFC 8292
+// "WebPush <jwt>" Authorization header and the p256ecdsa Crypto-Key parameter
+func legacyVAPIDHeaders(vapidHeader string) (authorization, p256ecdsa string) {
+	params := strings.TrimPrefix(vapidHeader, "vapid ")
+	for params != "" {
+		param := params
+		if i := strings.Index(params, ", "); i >= 0 {
+			param, params = params[:i], params[i+2:]
+		} else {
+			params = ""
+		}
+
+		switch {
+		case strings.HasPrefix(param, "t="):
+			authorization = "WebPush " + param[2:]
+		case strings.HasPrefix(param, "k="):
+			p256ecdsa = param[2:]
+		}
+	}
+
+	return authorization, p256ecdsa
+}
+
+// aesgcmEncryptionHeader returns the Encryption header of an aesgcm record, "salt=<salt>[;rs=<size>]"
+// with the plaintext record size unless it is the default
+func aesgcmEncryptionHeader(salt []byte, recordSize uint32) string {
+	buf := getHeaderBuffer()
+	defer putHeaderBuffer(buf)
+
+	b := append(*buf, "salt="...)
+	b = appendBase64(b, salt)
+	if plaintextSize := int(recordSize) - 16; plaintextSize > aesgcmDefaultRecordSize {
+		b = append(b, ";rs="...)
+		b = strconv.AppendInt(b, int64(plaintextSize), 10)
+	}
+	*buf = b
+
+	return string(b)
+}
+
+// aesgcmCryptoKeyHeader returns the Crypto-Key header of an aesgcm record, "dh=<key>;p256ecdsa=<VAPID key>"
+func aesgcmCryptoKeyHeader(localPublicKey []byte, p256ecdsa string) string {
+	buf := getHeaderBuffer()
+	defer putHeaderBuffer(buf)
+
+	b := append(*buf, "dh="...)
+	b = appendBase64(b, localPublicKey)
+	b = append(b, ";p256ecdsa="...)
+	b = append(b, p256ecdsa...)
+	*buf = b
+
+	return string(b)
+}
@@ -0,0 +1,128 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestSendAESGCMNotification(t *testing.T) {
+	// User agent key pair and auth secret
+	uaKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authSecret := make([]byte, 16)
+	if _, err := rand.Read(authSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := base64.RawURLEncoding
+	s := &Subscription{
+		Endpoint: "https://updates.push.services.mozilla.com/wpush/v1/gAAAAA",
+		Keys: Keys{
+			P256dh: b64.EncodeToString(uaKey.PublicKey().Bytes()),
+			Auth:   b64.EncodeToString(authSecret),
+		},
+	}
+
+	keys := getTestVAPIDKeys(t)
+	httpClient := &recordingHTTPClient{}
+	_, err = SendNotification([]byte("Legacy"), s, &Options{
+		HTTPClient:      httpClient,
+		ContentEncoding: ContentEncodingAESGCM,
+		Subscriber:      "test@test.com",
+		VAPIDPublicKey:  keys.PublicKey,
+		VAPIDPrivateKey: keys.PrivateKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httpClient.req
+	if req.Header.Get("Content-Encoding") != "aesgcm" {
+		t.Fatalf("Incorrect Content-Encoding, got %s", req.Header.Get("Content-Encoding"))
+	}
+
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "WebPush ey") {
+		t.Fatalf("Incorrect Authorization scheme, got %s", req.Header.Get("Authorization"))
+	}
+
+	// Crypto-Key: dh=<server key>;p256ecdsa=<VAPID key>
+	cryptoKey := strings.Split(req.Header.Get("Crypto-Key"), ";")
+	if len(cryptoKey) != 2 || cryptoKey[1] != "p256ecdsa="+keys.PublicKey {
+		t.Fatalf("Incorrect Crypto-Key, got %s", req.Header.Get("Crypto-Key"))
+	}
+
+	serverKey, _ := b64.DecodeString(strings.TrimPrefix(cryptoKey[0], "dh="))
+	salt, _ := b64.DecodeString(strings.TrimPrefix(req.Header.Get("Encryption"), "salt="))
+	body, _ := io.ReadAll(req.Body)
+
+	plaintext := decryptAESGCM(t, uaKey, serverKey, authSecret, salt, body)
+	if string(plaintext) != "Legacy" {
+		t.Fatalf("Incorrect plaintext, expected=Legacy, got=%q", plaintext)
+	}
+}
+
+// decryptAESGCM decrypts an aesgcm record the way a user agent does
+func decryptAESGCM(t *testing.T, uaKey *ecdh.PrivateKey, serverKey, authSecret, salt, record []byte) []byte {
+	serverPublicKey, err := ecdh.P256().NewPublicKey(serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := uaKey.ECDH(serverPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ikm, _ := getHKDFKey(hkdf.New(sha256.New, secret, authSecret, []byte("Content-Encoding: auth\x00")), 32)
+
+	context := []byte("P-256\x00\x00\x41")
+	context = append(context, uaKey.PublicKey().Bytes()...)
+	context = append(context, 0, 0x41)
+	context = append(context, serverKey...)
+
+	cek, _ := getHKDFKey(hkdf.New(sha256.New, ikm, salt, append([]byte("Content-Encoding: aesgcm\x00"), context...)), 16)
+	nonce, _ := getHKDFKey(hkdf.New(sha256.New, ikm, salt, append([]byte("Content-Encoding: nonce\x00"), context...)), 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	data, err := gcm.Open(nil, nonce, record, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	padLen := int(binary.BigEndian.Uint16(data))
+	if !bytes.Equal(data[2:2+padLen], make([]byte, padLen)) {
+		t.Fatal("Padding is not zeroed")
+	}
+
+	return data[2+padLen:]
+}
+
+func TestLegacyVAPIDHeaders(t *testing.T) {
+	authorization, p256ecdsa := legacyVAPIDHeaders("vapid t=a.b.c, k=BKEY, kid=1")
+	if authorization != "WebPush a.b.c" || p256ecdsa != "BKEY" {
+		t.Fatalf("Incorrect legacy headers, got %q and %q", authorization, p256ecdsa)
+	}
+}
+
+// getHKDFKey returns a key of length from the hkdf reader, the reference the key derivation of
+// the encryption is checked against
+func getHKDFKey(hkdf io.Reader, length int) ([]byte, error) {
+	key := make([]byte, length)
+	_, err := io.ReadFull(hkdf, key)
+	return key, err
+}
@@ -0,0 +1,51 @@
+package webpush
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AuditRecord is what an AuditSink receives for every send. It identifies the subscription by a hash
+// of its endpoint, so audit logs prove what was pushed to whom without storing push credentials.
+type AuditRecord struct {
+	Time          time.Time // When the push service answered or the send failed, by the Client clock
+	EndpointHash  string    // EndpointHash of the subscription endpoint
+	CorrelationID string
+	MessageID     string // Push message resource from the Location header of the response
+	StatusCode    int    // Zero when the send failed without a response
+	TTL           int
+	Urgency       Urgency
+	TenantID      string
+	Err           error // Why the send failed
+}
+
+// AuditSink receives an AuditRecord for every notification a Client sends, e.g. to write a compliance log.
+// Audit is called synchronously on the sending goroutine, sinks must buffer slow writes themselves.
+type AuditSink interface {
+	Audit(ctx context.Context, record AuditRecord)
+}
+
+// AuditSinkFunc adapts a function to AuditSink
+type AuditSinkFunc func(ctx context.Context, record AuditRecord)
+
+// Audit implements AuditSink
+func (f AuditSinkFunc) Audit(ctx context.Context, record AuditRecord) {
+	f(ctx, record)
+}
+
+// WithAuditSink sends an AuditRecord of every send to sink
+func WithAuditSink(sink AuditSink) ClientOption {
+	return func(c *Client) error {
+		c.audit = sink
+		return nil
+	}
+}
+
+// EndpointHash returns the hex encoded SHA-256 hash of endpoint used by AuditRecord,
+// to look up the audit records of a subscription
+func EndpointHash(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientAuditSink(t *testing.T) {
+	now := time.Now()
+	var records []AuditRecord
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithClock(func() time.Time { return now }),
+		WithAuditSink(AuditSinkFunc(func(ctx context.Context, record AuditRecord) {
+			records = append(records, record)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{"Location": {"https://updates.push.services.mozilla.com/m/message-id"}},
+		}, nil
+	})
+	s := getStandardEncodedTestSubscription()
+	options := &Options{HTTPClient: httpClient, TTL: 60, Urgency: UrgencyHigh, CorrelationID: "campaign-42"}
+	if _, err := client.Send(context.Background(), []byte("Test"), s, options); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := AuditRecord{
+		Time:          now,
+		EndpointHash:  EndpointHash(s.Endpoint),
+		CorrelationID: "campaign-42",
+		MessageID:     "https://updates.push.services.mozilla.com/m/message-id",
+		StatusCode:    http.StatusCreated,
+		TTL:           60,
+		Urgency:       UrgencyHigh,
+	}
+	if len(records) != 1 || records[0] != expected {
+		t.Fatalf("Incorrect audit records, expected=%+v, got=%+v", expected, records)
+	}
+
+	if len(expected.EndpointHash) != 64 || expected.EndpointHash == EndpointHash(s.Endpoint+"x") {
+		t.Fatalf("Incorrect endpoint hash, got %s", expected.EndpointHash)
+	}
+}
@@ -0,0 +1,113 @@
+// Package awskms signs VAPID JWTs with an AWS KMS asymmetric ECC_NIST_P256 key,
+// so the VAPID private key never leaves KMS.
+//
+// The Signer implements crypto.Signer and is passed as webpush.Options.VAPIDSigner.
+// KMS returns ASN.1 DER signatures, which webpush converts to the JOSE r || s form,
+// and signed Authorization headers are cached per audience so KMS is only called
+// once per push service origin and token lifetime.
+package awskms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"errors"
+	"io"
+	"time"
+)
+
+// DefaultTimeout bounds a single KMS Sign call
+const DefaultTimeout = 10 * time.Second
+
+// ErrUnsupportedKey is returned when the KMS key is not an ECC_NIST_P256 key
+var ErrUnsupportedKey = errors.New("awskms: key must be an ECC_NIST_P256 key")
+
+// API is the subset of AWS KMS used by Signer.
+// It is satisfied by a thin wrapper around the AWS SDK, e.g. with aws-sdk-go-v2:
+//
+//	type kmsAPI struct{ client *kms.Client }
+//
+//	func (a kmsAPI) Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+//		out, err := a.client.Sign(ctx, &kms.SignInput{
+//			KeyId:            aws.String(keyID),
+//			Message:          digest,
+//			MessageType:      types.MessageTypeDigest,
+//			SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256,
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Signature, nil
+//	}
+//
+//	func (a kmsAPI) GetPublicKey(ctx context.Context, keyID string) ([]byte, error) {
+//		out, err := a.client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(keyID)})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.PublicKey, nil
+//	}
+type API interface {
+	// Sign signs a SHA-256 digest with ECDSA_SHA_256 and returns the DER encoded signature
+	Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error)
+	// GetPublicKey returns the DER encoded SubjectPublicKeyInfo of the key
+	GetPublicKey(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// Signer is a crypto.Signer backed by an AWS KMS key
+type Signer struct {
+	api       API
+	keyID     string
+	publicKey *ecdsa.PublicKey
+
+	// Timeout bounds each Sign call, defaults to DefaultTimeout
+	Timeout time.Duration
+}
+
+// New fetches the public key of keyID and returns a Signer for it
+func New(ctx context.Context, api API, keyID string) (*Signer, error) {
+	der, err := api.GetPublicKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok || publicKey.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedKey
+	}
+
+	return &Signer{
+		api:       api,
+		keyID:     keyID,
+		publicKey: publicKey,
+		Timeout:   DefaultTimeout,
+	}, nil
+}
+
+// Public returns the public key of the KMS key
+func (s *Signer) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs digest in KMS; rand is unused as KMS supplies its own entropy
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.SHA256 {
+		return nil, errors.New("awskms: only SHA-256 digests are supported")
+	}
+
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	return s.api.Sign(ctx, s.keyID, digest)
+}
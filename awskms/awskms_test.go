@@ -0,0 +1,95 @@
+package awskms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type fakeKMS struct {
+	key   *ecdsa.PrivateKey
+	signs int
+}
+
+func (f *fakeKMS) Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	f.signs++
+	return ecdsa.SignASN1(rand.Reader, f.key, digest)
+}
+
+func (f *fakeKMS) GetPublicKey(ctx context.Context, keyID string) ([]byte, error) {
+	return x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+}
+
+type recordingClient struct {
+	authorization string
+}
+
+func (c *recordingClient) Do(req *http.Request) (*http.Response, error) {
+	c.authorization = req.Header.Get("Authorization")
+	return &http.Response{StatusCode: 201}, nil
+}
+
+func TestSignerSendsVerifiableVAPIDToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeKMS{key: key}
+	signer, err := New(context.Background(), api, "alias/webpush")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &recordingClient{}
+	sub := &webpush.Subscription{
+		Endpoint: "https://updates.push.services.mozilla.com/wpush/v2/awskms",
+		Keys: webpush.Keys{
+			P256dh: "BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk",
+			Auth:   "zqbxT6JKstKSY9JKibZLSQ",
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := webpush.SendNotification([]byte("Test"), sub, &webpush.Options{
+			HTTPClient:  client,
+			Subscriber:  "test@example.com",
+			VAPIDSigner: signer,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second send to the same audience must reuse the signed token
+	if api.signs != 1 {
+		t.Fatalf("Incorrect number of KMS calls, expected=1, got=%d", api.signs)
+	}
+
+	tokenString := strings.TrimSuffix(strings.SplitN(client.authorization, "t=", 2)[1], ",")
+	tokenString = strings.SplitN(tokenString, ",", 2)[0]
+	token, err := jwt.Parse(tokenString, func(*jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	if err != nil || !token.Valid {
+		t.Fatalf("KMS signed token did not verify: %v", err)
+	}
+}
+
+func TestNewRejectsNonP256Keys(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(context.Background(), &fakeKMS{key: key}, "alias/webpush"); err != ErrUnsupportedKey {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrUnsupportedKey, err)
+	}
+}
@@ -0,0 +1,154 @@
+package webpush
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned without sending while the circuit breaker of an origin is open
+	ErrCircuitOpen = errors.New("webpush: circuit open")
+
+	// ErrInvalidCircuitBreaker is returned by WithCircuitBreaker for a negative threshold or cooldown
+	ErrInvalidCircuitBreaker = errors.New("webpush: circuit breaker threshold and cooldown must not be negative")
+)
+
+// circuitBreaker fails sends fast to origins that keep failing
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu      sync.Mutex
+	origins map[string]*circuit
+}
+
+// circuit is the state of the breaker of one origin
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool // a request is testing the origin after the cooldown
+}
+
+// WithCircuitBreaker stops sending to a push service origin for cooldown after threshold consecutive
+// failures, transport errors or 5xx responses, returning ErrCircuitOpen instead. Sends canceled by their
+// caller are not counted. After the cooldown a single request probes the origin: its success closes the
+// circuit, its failure opens it again. A zero threshold disables the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) error {
+		if threshold < 0 || cooldown < 0 {
+			return ErrInvalidCircuitBreaker
+		}
+
+		c.breaker = nil
+		if threshold > 0 {
+			c.breaker = &circuitBreaker{threshold: threshold, cooldown: cooldown, origins: make(map[string]*circuit)}
+		}
+
+		return nil
+	}
+}
+
+// wrap returns client failing fast while the circuit of an origin is open, calling onOpen when it opens
+func (b *circuitBreaker) wrap(client HTTPClient, now func() time.Time, onOpen func(req *http.Request, failures int, until time.Time)) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		origin := requestOrigin(req)
+		allowed, probe := b.allow(origin, now())
+		if !allowed {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, origin)
+		}
+
+		resp, err := client.Do(req)
+		if abandonedSend(req, err) {
+			b.abandon(origin, probe)
+			return resp, err
+		}
+
+		if failures, until, opened := b.record(origin, failedSend(resp, err), now()); opened {
+			onOpen(req, failures, until)
+		}
+
+		return resp, err
+	})
+}
+
+// allow reports whether a request to origin may be sent at now, and whether it is the probe of the origin
+func (b *circuitBreaker) allow(origin string, now time.Time) (allowed, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.origins[origin]
+	if !ok || state.failures < b.threshold {
+		return true, false
+	}
+
+	if now.Before(state.openUntil) || state.probing {
+		return false, false
+	}
+
+	state.probing = true
+	return true, true
+}
+
+// abandon forgets a request to origin its caller gave up on, another request may probe in its place
+func (b *circuitBreaker) abandon(origin string, probe bool) {
+	if !probe {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if state, ok := b.origins[origin]; ok {
+		state.probing = false
+	}
+}
+
+// record counts the outcome of a request to origin, reporting whether it opened the circuit
+func (b *circuitBreaker) record(origin string, failed bool, now time.Time) (int, time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.origins[origin]
+	if !failed {
+		if ok {
+			delete(b.origins, origin)
+		}
+		return 0, time.Time{}, false
+	}
+
+	if !ok {
+		state = &circuit{}
+		b.origins[origin] = state
+	}
+
+	state.failures++
+	if state.failures < b.threshold || (!state.probing && state.failures > b.threshold) {
+		return 0, time.Time{}, false
+	}
+
+	state.probing = false
+	state.openUntil = now.Add(b.cooldown)
+	return state.failures, state.openUntil, true
+}
+
+// states returns the state of the origins with failures at now
+func (b *circuitBreaker) states(now time.Time) map[string]circuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make(map[string]circuitState, len(b.origins))
+	for origin, state := range b.origins {
+		open := state.failures >= b.threshold && (now.Before(state.openUntil) || state.probing)
+		states[origin] = circuitState{
+			Failures:  state.failures,
+			Open:      open,
+			OpenUntil: state.openUntil,
+			Probing:   state.probing,
+		}
+	}
+
+	return states
+}
@@ -0,0 +1,140 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	events := &recordingEvents{}
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithClock(func() time.Time { return now }),
+		WithCircuitBreaker(2, time.Minute),
+		WithEvents(events),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int
+	statusCode := http.StatusServiceUnavailable
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: statusCode}, nil
+	})
+	send := func() error {
+		_, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient})
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if events.opened.Failures != 2 || !events.opened.Until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Incorrect CircuitOpened event, got %+v", events.opened)
+	}
+
+	// Open: sends fail without reaching the push service
+	if err := send(); !errors.Is(err, ErrCircuitOpen) || requests != 2 {
+		t.Fatalf("Expected %v without a request, got err=%v requests=%d", ErrCircuitOpen, err, requests)
+	}
+
+	// A failed probe after the cooldown opens the circuit again
+	now = now.Add(time.Minute)
+	if err := send(); err != nil || requests != 3 {
+		t.Fatalf("Expected a probe, got err=%v requests=%d", err, requests)
+	}
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrCircuitOpen, err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	statusCode = http.StatusCreated
+	for i := 0; i < 2; i++ {
+		if err := send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests != 5 {
+		t.Fatalf("Incorrect requests, expected=5, got=%d", requests)
+	}
+
+	if _, err := NewClient(WithCircuitBreaker(-1, time.Minute)); err != ErrInvalidCircuitBreaker {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidCircuitBreaker, err)
+	}
+}
+
+func TestCircuitBreakerIgnoresCanceledSends(t *testing.T) {
+	now := time.Now()
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithClock(func() time.Time { return now }),
+		WithCircuitBreaker(2, time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int
+	statusCode := http.StatusServiceUnavailable
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: statusCode}, nil
+	})
+	send := func(ctx context.Context) error {
+		_, err := client.Send(ctx, []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient})
+		return err
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Sends canceled by the caller leave the circuit of a healthy push service closed
+	for i := 0; i < 3; i++ {
+		if err := send(canceled); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected %v, got %v", context.Canceled, err)
+		}
+	}
+	if state := client.breaker.states(now); len(state) != 0 || requests != 3 {
+		t.Fatalf("Expected 3 requests without failures, got %d requests and %+v", requests, state)
+	}
+
+	// Nor do they reset the failures of a failing one
+	if err := send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+	if err := send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected %v, got %v", ErrCircuitOpen, err)
+	}
+
+	// A canceled probe lets the next send probe
+	now = now.Add(time.Minute)
+	if err := send(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+	statusCode = http.StatusCreated
+	if err := send(context.Background()); err != nil {
+		t.Fatalf("Expected a probe, got %v", err)
+	}
+	if state := client.breaker.states(now); len(state) != 0 {
+		t.Fatalf("Expected the probe to close the circuit, got %+v", state)
+	}
+}
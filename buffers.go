@@ -0,0 +1,41 @@
+package webpush
+
+import (
+	"encoding/base64"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which a buffer isn't returned to its pool, so one large
+// header doesn't pin its memory
+const maxPooledBuffer = 4096
+
+// headerBuffers are the scratch buffers header values and cache keys are assembled in before they
+// are converted to a string, the only allocation of their assembly
+var headerBuffers = sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, 512)
+	return &b
+}}
+
+// getHeaderBuffer returns an empty buffer of headerBuffers
+func getHeaderBuffer() *[]byte {
+	return headerBuffers.Get().(*[]byte)
+}
+
+// putHeaderBuffer returns b to headerBuffers, b must not be used afterwards
+func putHeaderBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+
+	*b = (*b)[:0]
+	headerBuffers.Put(b)
+}
+
+// appendBase64 appends the raw URL base64 encoding of src to b
+func appendBase64(b, src []byte) []byte {
+	n := len(b)
+	b = append(b, make([]byte, base64.RawURLEncoding.EncodedLen(len(src)))...)
+	base64.RawURLEncoding.Encode(b[n:], src)
+
+	return b
+}
@@ -0,0 +1,698 @@
+package webpush
+
+import (
+	"container/list"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultVAPIDCacheMargin is how long before expiration a cached header is re-signed unless configured otherwise
+const DefaultVAPIDCacheMargin = 30 * time.Minute
+
+// ErrInvalidVAPIDCacheMargin is returned for negative cache margins and ratios outside (0, 1)
+var ErrInvalidVAPIDCacheMargin = errors.New("webpush: VAPID cache margin must not be negative and its ratio must be between 0 and 1")
+
+// minHeaderValidity is the validity a cached header inside the margin must have left
+// to be served while it is refreshed in the background
+const minHeaderValidity = time.Minute
+
+// VAPIDCacheStats are the counters of the VAPID Authorization header cache
+type VAPIDCacheStats struct {
+	Hits      uint64 // Headers served from the cache
+	Misses    uint64 // Headers that had to be signed
+	Evictions uint64 // Entries removed because they expired, were invalidated or exceeded the capacity
+	Size      int64  // Entries currently cached
+	Refreshes uint64 // Headers re-signed in the background as they neared expiration
+
+	CapacityEvictions uint64 // Least recently used entries evicted to stay within the maximum entries
+
+	PrivateKeyHits   uint64 // Private keys served already parsed
+	PrivateKeyMisses uint64 // Private keys that had to be parsed
+	PrivateKeys      int64  // Parsed private keys currently cached
+
+	PrivateKeyEvictions uint64 // Least recently used private keys evicted to stay within the maximum entries
+}
+
+// VAPIDHeaderCache stores signed VAPID Authorization headers, e.g. in Redis or memcached so a fleet
+// of senders shares signed tokens instead of each signing per audience. Implementations are best
+// effort: backend errors should be reported as misses by Get and ignored by Set and Delete.
+//
+// Keys start with the VAPID public key and the audience, followed by a digest of the other inputs
+// of the token, they never contain private key material.
+type VAPIDHeaderCache interface {
+	// Get returns the header of key and how long it remains valid
+	Get(key string) (header string, ttl time.Duration, ok bool)
+
+	// Set stores header under key for ttl
+	Set(key, header string, ttl time.Duration)
+
+	// Delete removes the header of key
+	Delete(key string)
+}
+
+// VAPIDHeaderCachePurger is implemented by VAPIDHeaderCache backends able to delete entries
+// in bulk. The invalidation functions of the Client only reach backends implementing it.
+type VAPIDHeaderCachePurger interface {
+	// DeleteMatching removes every header whose key matches
+	DeleteMatching(matches func(key string) bool)
+}
+
+// WithVAPIDHeaderCache stores signed headers in cache instead of the in-memory cache of the Client.
+// Size, eviction and maximum entries only apply to the in-memory cache.
+func WithVAPIDHeaderCache(cache VAPIDHeaderCache) ClientOption {
+	return func(c *Client) error {
+		c.cache.external = cache
+		return nil
+	}
+}
+
+// vapidCacheCounters are the live counters behind VAPIDCacheStats, updated atomically
+type vapidCacheCounters struct {
+	hits              uint64
+	misses            uint64
+	evictions         uint64
+	capacityEvictions uint64
+	refreshes         uint64
+	size              int64
+
+	privateKeyHits   uint64
+	privateKeyMisses uint64
+	privateKeys      int64
+
+	privateKeyEvictions uint64
+}
+
+func (s *vapidCacheCounters) snapshot() VAPIDCacheStats {
+	return VAPIDCacheStats{
+		Hits:      atomic.LoadUint64(&s.hits),
+		Misses:    atomic.LoadUint64(&s.misses),
+		Evictions: atomic.LoadUint64(&s.evictions),
+		Size:      atomic.LoadInt64(&s.size),
+		Refreshes: atomic.LoadUint64(&s.refreshes),
+
+		CapacityEvictions: atomic.LoadUint64(&s.capacityEvictions),
+
+		PrivateKeyHits:   atomic.LoadUint64(&s.privateKeyHits),
+		PrivateKeyMisses: atomic.LoadUint64(&s.privateKeyMisses),
+		PrivateKeys:      atomic.LoadInt64(&s.privateKeys),
+
+		PrivateKeyEvictions: atomic.LoadUint64(&s.privateKeyEvictions),
+	}
+}
+
+// reset zeroes every counter but the sizes, which track the entries actually cached
+func (s *vapidCacheCounters) reset() {
+	atomic.StoreUint64(&s.hits, 0)
+	atomic.StoreUint64(&s.misses, 0)
+	atomic.StoreUint64(&s.evictions, 0)
+	atomic.StoreUint64(&s.capacityEvictions, 0)
+	atomic.StoreUint64(&s.refreshes, 0)
+	atomic.StoreUint64(&s.privateKeyHits, 0)
+	atomic.StoreUint64(&s.privateKeyMisses, 0)
+	atomic.StoreUint64(&s.privateKeyEvictions, 0)
+}
+
+// vapidCache holds the caches of a Client: signed Authorization headers, parsed private keys
+// and imported pregenerated headers
+type vapidCache struct {
+	stats        vapidCacheCounters
+	headers      *shardedHeaderCache
+	external     VAPIDHeaderCache // used instead of headers when set
+	privateKeys  *privateKeyCache
+	pregenerated pregeneratedStore
+	flights      flightGroup // signings of missing headers keyed by cache key
+}
+
+func newVAPIDCache() *vapidCache {
+	cache := &vapidCache{}
+	cache.headers = newShardedHeaderCache(1, 0, &cache.stats)
+	cache.privateKeys = newPrivateKeyCache(0, &cache.stats)
+	cache.pregenerated.entries = make(map[string][]vapidCacheEntry)
+
+	return cache
+}
+
+// load returns the cached header of key, external TTLs are converted to an expiration after now
+func (c *vapidCache) load(key string, now time.Time) (vapidCacheEntry, bool) {
+	if c.external == nil {
+		return c.headers.load(key)
+	}
+
+	header, ttl, ok := c.external.Get(key)
+	if !ok {
+		return vapidCacheEntry{}, false
+	}
+
+	return vapidCacheEntry{header: header, expiration: now.Add(ttl)}, true
+}
+
+// store caches entry under key, external entries are kept for at most maxTTL unless it is zero
+func (c *vapidCache) store(key string, entry vapidCacheEntry, now time.Time, maxTTL time.Duration) {
+	if c.external == nil {
+		c.headers.store(key, entry)
+		return
+	}
+
+	ttl := entry.expiration.Sub(now)
+	if maxTTL > 0 && maxTTL < ttl {
+		ttl = maxTTL
+	}
+
+	c.external.Set(key, entry.header, ttl)
+}
+
+// evict removes the cached header of key
+func (c *vapidCache) evict(key string) {
+	if c.external == nil {
+		c.headers.evict(key)
+		return
+	}
+
+	c.external.Delete(key)
+}
+
+// evictMatching removes the cached headers whose key matches
+func (c *vapidCache) evictMatching(matches func(key string) bool) {
+	c.headers.evictMatching(matches)
+
+	if purger, ok := c.external.(VAPIDHeaderCachePurger); ok {
+		purger.DeleteMatching(matches)
+	}
+}
+
+// headerCacheKey returns the header cache key publicKey|audience|subscriber|digest. The digest covers
+// the private key and the other inputs of the signed token, so keys carry no private key material.
+func headerCacheKey(publicKey, audience, subscriber string, inputs ...string) string {
+	buf := getHeaderBuffer()
+	defer putHeaderBuffer(buf)
+
+	b := *buf
+	for _, input := range inputs {
+		b = append(b, input...)
+		b = append(b, 0)
+	}
+	digest := sha256.Sum256(b)
+
+	// The inputs include the private key, it doesn't stay behind in the pool
+	for i := range b {
+		b[i] = 0
+	}
+
+	b = append(b[:0], publicKey...)
+	b = append(b, '|')
+	b = append(b, audience...)
+	b = append(b, '|')
+	b = append(b, subscriber...)
+	b = append(b, '|')
+	n := len(b)
+	b = append(b, make([]byte, hex.EncodedLen(len(digest)))...)
+	hex.Encode(b[n:], digest[:])
+	*buf = b
+
+	return string(b)
+}
+
+// headerCacheKeyAudience returns the audience of a header cache key
+func headerCacheKeyAudience(key string) string {
+	fields := strings.SplitN(key, "|", 3)
+	if len(fields) < 2 {
+		return ""
+	}
+
+	return fields[1]
+}
+
+// invalidateKeys drops the cached headers and parsed private key of a key pair
+func (c *vapidCache) invalidateKeys(keys VAPIDKeys) {
+	c.invalidatePublicKey(keys.PublicKey)
+	c.privateKeys.forget(keys.PrivateKey)
+}
+
+// invalidatePublicKey drops the cached and imported headers of a VAPID public key
+func (c *vapidCache) invalidatePublicKey(publicKey string) {
+	decoded, err := decodeVapidKey(publicKey)
+	if err != nil {
+		return
+	}
+	prefix := base64.RawURLEncoding.EncodeToString(decoded) + "|"
+
+	c.evictMatching(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+	c.pregenerated.forgetMatching(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// invalidateAudience drops the cached and imported headers of a normalized audience
+func (c *vapidCache) invalidateAudience(audience string) {
+	matches := func(key string) bool {
+		return headerCacheKeyAudience(key) == audience
+	}
+
+	c.evictMatching(matches)
+	c.pregenerated.forgetMatching(matches)
+}
+
+// clear drops every cached header, parsed private key and imported header
+func (c *vapidCache) clear() {
+	c.evictMatching(func(string) bool { return true })
+	c.pregenerated.forgetMatching(func(string) bool { return true })
+
+	c.privateKeys.clear()
+}
+
+// privateKey parses the private key, through the private key cache unless noCache is set
+func (c *vapidCache) privateKey(vapidPrivateKey string, noCache bool) (*ecdsa.PrivateKey, error) {
+	if noCache {
+		return VAPIDKeys{PrivateKey: vapidPrivateKey}.ecdsaPrivateKey()
+	}
+
+	// Check cache
+	if cached, ok := c.privateKeys.load(vapidPrivateKey); ok {
+		atomic.AddUint64(&c.stats.privateKeyHits, 1)
+		return cached, nil
+	}
+
+	atomic.AddUint64(&c.stats.privateKeyMisses, 1)
+
+	// Decode and parse the private key
+	decodedVapidPrivateKey, err := decodeVapidKey(vapidPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	privKey, err := parseVAPIDPrivateKey(decodedVapidPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the parsed key
+	c.privateKeys.store(vapidPrivateKey, privKey)
+
+	return privKey, nil
+}
+
+// sweep removes the headers that would no longer be served at now
+func (c *vapidCache) sweep(now time.Time) {
+	c.headers.sweep(now)
+	c.pregenerated.sweep(now)
+}
+
+// vapidCacheEntry stores cached VAPID header with expiration
+type vapidCacheEntry struct {
+	header     string
+	expiration time.Time
+	signedAt   time.Time
+}
+
+// headerCache is the VAPID Authorization header cache, bounded to maxEntries
+// by evicting the least recently used header
+type headerCache struct {
+	mu         sync.Mutex
+	maxEntries int // 0 means unbounded
+	entries    map[string]*list.Element
+	order      *list.List // front is the most recently used
+	stats      *vapidCacheCounters
+}
+
+// headerCacheItem is the value of a headerCache list element
+type headerCacheItem struct {
+	key   string
+	entry vapidCacheEntry
+}
+
+func newHeaderCache(maxEntries int, stats *vapidCacheCounters) *headerCache {
+	return &headerCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		stats:      stats,
+	}
+}
+
+// load returns the entry of key and marks it as recently used
+func (c *headerCache) load(key string) (vapidCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return vapidCacheEntry{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*headerCacheItem).entry, true
+}
+
+// store caches entry under key, evicting the least recently used entries above maxEntries
+func (c *headerCache) store(key string, entry vapidCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*headerCacheItem).entry = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&headerCacheItem{key: key, entry: entry})
+	atomic.AddInt64(&c.stats.size, 1)
+
+	c.trim()
+}
+
+// trim evicts the least recently used entries until the cache fits maxEntries, c.mu must be held
+func (c *headerCache) trim() {
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+		atomic.AddUint64(&c.stats.capacityEvictions, 1)
+	}
+}
+
+// evict removes the entry of key
+func (c *headerCache) evict(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// evictMatching removes every entry whose key matches
+func (c *headerCache) evictMatching(matches func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if matches(key) {
+			c.remove(element)
+		}
+	}
+}
+
+// sweep removes the entries that would no longer be served at now, even while being refreshed
+func (c *headerCache) sweep(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, element := range c.entries {
+		if !now.Add(minHeaderValidity).Before(element.Value.(*headerCacheItem).entry.expiration) {
+			c.remove(element)
+		}
+	}
+}
+
+// remove drops element from the cache and counts the eviction, c.mu must be held
+func (c *headerCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*headerCacheItem).key)
+
+	atomic.AddInt64(&c.stats.size, -1)
+	atomic.AddUint64(&c.stats.evictions, 1)
+}
+
+// setMaxEntries changes the bound of the cache, evicting entries above it right away
+func (c *headerCache) setMaxEntries(maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxEntries = maxEntries
+	c.trim()
+}
+
+// items returns a copy of the cached items, most recently used first
+func (c *headerCache) items() []headerCacheItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	items := make([]headerCacheItem, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		items = append(items, *element.Value.(*headerCacheItem))
+	}
+
+	return items
+}
+
+// keys returns the cached keys, most recently used first
+func (c *headerCache) keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		keys = append(keys, element.Value.(*headerCacheItem).key)
+	}
+
+	return keys
+}
+
+// VAPIDCacheEntryInfo describes a cached VAPID Authorization header without revealing it
+type VAPIDCacheEntryInfo struct {
+	Audience       string        `json:"audience"`
+	Subscriber     string        `json:"subscriber"`
+	KeyFingerprint string        `json:"keyFingerprint"` // VAPIDKeyFingerprint of the public key
+	Expiration     time.Time     `json:"expiration"`
+	Age            time.Duration `json:"age"` // time since the token was signed
+}
+
+// VAPIDCacheEntries returns the metadata of the headers in the in-memory cache of the Client,
+// most recently used first, e.g. for an admin endpoint. The headers themselves are never returned.
+func (c *Client) VAPIDCacheEntries() []VAPIDCacheEntryInfo {
+	now := c.now()
+
+	items := c.cache.headers.items()
+	entries := make([]VAPIDCacheEntryInfo, 0, len(items))
+	for _, item := range items {
+		// Keys are publicKey|audience|subscriber|digest, the subscriber may contain |
+		fields := strings.Split(item.key, "|")
+		if len(fields) < 4 {
+			continue
+		}
+
+		fingerprint, _ := VAPIDKeyFingerprint(fields[0])
+		entries = append(entries, VAPIDCacheEntryInfo{
+			Audience:       fields[1],
+			Subscriber:     strings.Join(fields[2:len(fields)-1], "|"),
+			KeyFingerprint: fingerprint,
+			Expiration:     item.entry.expiration,
+			Age:            now.Sub(item.entry.signedAt),
+		})
+	}
+
+	return entries
+}
+
+// WithVAPIDCacheMargin re-signs cached headers margin before they expire, instead of DefaultVAPIDCacheMargin
+func WithVAPIDCacheMargin(margin time.Duration) ClientOption {
+	return func(c *Client) error {
+		if margin < 0 {
+			return ErrInvalidVAPIDCacheMargin
+		}
+
+		c.cacheMargin = margin
+		c.cacheMarginRatio = 0
+		return nil
+	}
+}
+
+// WithVAPIDCacheMarginRatio re-signs cached headers once the given fraction of the token lifetime
+// is left, e.g. 0.1 re-signs 12 hour tokens 72 minutes and 1 hour tokens 6 minutes before they expire
+func WithVAPIDCacheMarginRatio(ratio float64) ClientOption {
+	return func(c *Client) error {
+		if ratio <= 0 || ratio >= 1 {
+			return ErrInvalidVAPIDCacheMargin
+		}
+
+		c.cacheMarginRatio = ratio
+		return nil
+	}
+}
+
+// vapidCacheMargin returns the cache margin of tokens valid for lifetime
+func (c *Client) vapidCacheMargin(lifetime time.Duration) time.Duration {
+	if c.cacheMarginRatio > 0 {
+		return time.Duration(float64(lifetime) * c.cacheMarginRatio)
+	}
+
+	return c.cacheMargin
+}
+
+// WithVAPIDCacheMaxEntries bounds the VAPID header cache of the Client to maxEntries headers,
+// evicting the least recently used (key, audience) pair first. Zero, the default, means unbounded.
+func WithVAPIDCacheMaxEntries(maxEntries int) ClientOption {
+	return func(c *Client) error {
+		c.SetVAPIDCacheMaxEntries(maxEntries)
+		return nil
+	}
+}
+
+// SetVAPIDCacheMaxEntries changes the bound of the VAPID header cache, see WithVAPIDCacheMaxEntries
+func (c *Client) SetVAPIDCacheMaxEntries(maxEntries int) {
+	if maxEntries < 0 {
+		maxEntries = 0
+	}
+
+	c.cache.headers.setMaxEntries(maxEntries)
+}
+
+// VAPIDCacheStats returns the counters of the Client VAPID header cache
+func (c *Client) VAPIDCacheStats() VAPIDCacheStats {
+	return c.cache.stats.snapshot()
+}
+
+// ResetVAPIDCacheStats zeroes the hit, miss and eviction counters of the Client VAPID header cache
+func (c *Client) ResetVAPIDCacheStats() {
+	c.cache.stats.reset()
+}
+
+// GetVAPIDCacheStats returns cache hit/miss stats for monitoring
+func GetVAPIDCacheStats() (hits, misses uint64) {
+	stats := defaultClient.VAPIDCacheStats()
+	return stats.Hits, stats.Misses
+}
+
+// GetVAPIDCacheCounters returns every counter of the shared VAPID header cache,
+// including evictions and size
+func GetVAPIDCacheCounters() VAPIDCacheStats {
+	return defaultClient.VAPIDCacheStats()
+}
+
+// ResetVAPIDCacheStats zeroes the hit, miss and eviction counters of the shared cache. The size is
+// left as is, since it tracks the entries actually cached.
+func ResetVAPIDCacheStats() {
+	defaultClient.ResetVAPIDCacheStats()
+}
+
+// SetVAPIDCacheMaxEntries bounds the shared VAPID header cache used by the package level
+// functions, see WithVAPIDCacheMaxEntries
+func SetVAPIDCacheMaxEntries(maxEntries int) {
+	defaultClient.SetVAPIDCacheMaxEntries(maxEntries)
+}
+
+// ClearVAPIDCache drops every cached Authorization header, parsed private key and imported header of the Client
+func (c *Client) ClearVAPIDCache() {
+	c.cache.clear()
+}
+
+// InvalidateVAPIDCacheForKey drops the cached and imported Authorization headers of a VAPID public key,
+// e.g. after a key was revoked
+func (c *Client) InvalidateVAPIDCacheForKey(publicKey string) {
+	c.cache.invalidatePublicKey(publicKey)
+}
+
+// InvalidateAudience drops the cached and imported Authorization headers of the push service origin,
+// e.g. https://fcm.googleapis.com
+func (c *Client) InvalidateAudience(origin string) error {
+	audience, err := normalizeAudience(origin, true)
+	if err != nil {
+		return err
+	}
+
+	c.cache.invalidateAudience(audience)
+	return nil
+}
+
+// ClearVAPIDCache drops everything cached by the package level functions, see Client.ClearVAPIDCache
+func ClearVAPIDCache() {
+	defaultClient.ClearVAPIDCache()
+}
+
+// InvalidateVAPIDCacheForKey drops the shared cached headers of a VAPID public key, see Client.InvalidateVAPIDCacheForKey
+func InvalidateVAPIDCacheForKey(publicKey string) {
+	defaultClient.InvalidateVAPIDCacheForKey(publicKey)
+}
+
+// InvalidateAudience drops the shared cached headers of a push service origin, see Client.InvalidateAudience
+func InvalidateAudience(origin string) error {
+	return defaultClient.InvalidateAudience(origin)
+}
+
+// VAPIDCacheJanitor periodically removes expired headers from the VAPID caches
+type VAPIDCacheJanitor struct {
+	stop chan struct{}
+	once sync.Once
+	done chan struct{}
+}
+
+// StartVAPIDCacheJanitor sweeps the expired entries of the Client VAPID header cache every interval,
+// so memory from one-off audiences is reclaimed without looking them up again.
+// Call Stop to end the background goroutine.
+func (c *Client) StartVAPIDCacheJanitor(interval time.Duration) *VAPIDCacheJanitor {
+	j := &VAPIDCacheJanitor{stop: make(chan struct{}), done: make(chan struct{})}
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.cache.sweep(c.now())
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+
+	return j
+}
+
+// StartVAPIDCacheJanitor sweeps the shared VAPID header cache every interval, see Client.StartVAPIDCacheJanitor
+func StartVAPIDCacheJanitor(interval time.Duration) *VAPIDCacheJanitor {
+	return defaultClient.StartVAPIDCacheJanitor(interval)
+}
+
+// Stop ends the janitor and waits for a running sweep to finish, it is safe to call more than once
+func (j *VAPIDCacheJanitor) Stop() {
+	j.once.Do(func() {
+		close(j.stop)
+	})
+	<-j.done
+}
+
+// ErrInvalidAudienceCacheTTL is returned by WithAudienceCacheTTL for invalid patterns or non-positive TTLs
+var ErrInvalidAudienceCacheTTL = errors.New("webpush: audience cache TTL needs a valid pattern and a positive TTL")
+
+// audienceTTL is a cache TTL override for the audiences matching pattern
+type audienceTTL struct {
+	pattern string
+	ttl     time.Duration
+}
+
+// WithAudienceCacheTTL re-signs the cached headers of audiences matching pattern once they are ttl old,
+// for gateways rejecting tokens older than a few minutes even if they are not expired. Patterns are
+// matched against the normalized origin with path.Match, e.g. "https://*.push.example.com", and the
+// first matching override applies. Other audiences, e.g. FCM or Mozilla, keep the default behavior.
+func WithAudienceCacheTTL(pattern string, ttl time.Duration) ClientOption {
+	return func(c *Client) error {
+		if _, err := path.Match(pattern, ""); err != nil || ttl <= 0 {
+			return ErrInvalidAudienceCacheTTL
+		}
+
+		c.audienceTTLs = append(c.audienceTTLs, audienceTTL{pattern: pattern, ttl: ttl})
+		return nil
+	}
+}
+
+// audienceCacheTTL returns the cache TTL override of audience, zero if there is none
+func audienceCacheTTL(overrides []audienceTTL, audience string) time.Duration {
+	for _, override := range overrides {
+		if matched, _ := path.Match(override.pattern, audience); matched {
+			return override.ttl
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,137 @@
+package webpush
+
+import (
+	"container/list"
+	"crypto/ecdsa"
+	"sync"
+	"sync/atomic"
+)
+
+// privateKeyCache holds parsed private keys, bounded to maxEntries by evicting the least recently used key
+type privateKeyCache struct {
+	mu         sync.Mutex
+	maxEntries int // 0 means unbounded
+	entries    map[string]*list.Element
+	order      *list.List // front is the most recently used
+	stats      *vapidCacheCounters
+}
+
+// privateKeyCacheItem is the value of a privateKeyCache list element
+type privateKeyCacheItem struct {
+	key     string
+	privKey *ecdsa.PrivateKey
+}
+
+func newPrivateKeyCache(maxEntries int, stats *vapidCacheCounters) *privateKeyCache {
+	return &privateKeyCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		stats:      stats,
+	}
+}
+
+// load returns the parsed key of vapidPrivateKey and marks it as recently used
+func (c *privateKeyCache) load(vapidPrivateKey string) (*ecdsa.PrivateKey, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[vapidPrivateKey]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*privateKeyCacheItem).privKey, true
+}
+
+// store caches privKey, evicting the least recently used keys above maxEntries
+func (c *privateKeyCache) store(vapidPrivateKey string, privKey *ecdsa.PrivateKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[vapidPrivateKey]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[vapidPrivateKey] = c.order.PushFront(&privateKeyCacheItem{key: vapidPrivateKey, privKey: privKey})
+	atomic.AddInt64(&c.stats.privateKeys, 1)
+
+	c.trim()
+}
+
+// trim evicts the least recently used keys until the cache fits maxEntries, c.mu must be held
+func (c *privateKeyCache) trim() {
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+		atomic.AddUint64(&c.stats.privateKeyEvictions, 1)
+	}
+}
+
+// forget drops the parsed key of vapidPrivateKey
+func (c *privateKeyCache) forget(vapidPrivateKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[vapidPrivateKey]; ok {
+		c.remove(element)
+	}
+}
+
+// clear drops every parsed key
+func (c *privateKeyCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, element := range c.entries {
+		c.remove(element)
+	}
+}
+
+// remove drops element from the cache, c.mu must be held
+func (c *privateKeyCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*privateKeyCacheItem).key)
+
+	atomic.AddInt64(&c.stats.privateKeys, -1)
+}
+
+// setMaxEntries changes the bound of the cache, evicting keys above it right away
+func (c *privateKeyCache) setMaxEntries(maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxEntries = maxEntries
+	c.trim()
+}
+
+// WithPrivateKeyCacheMaxEntries bounds the parsed private key cache of the Client to maxEntries keys,
+// evicting the least recently used key first, for platforms signing with many tenant keys.
+// Zero, the default, means unbounded.
+func WithPrivateKeyCacheMaxEntries(maxEntries int) ClientOption {
+	return func(c *Client) error {
+		if maxEntries < 0 {
+			maxEntries = 0
+		}
+
+		c.cache.privateKeys.setMaxEntries(maxEntries)
+		return nil
+	}
+}
+
+// ForgetPrivateKey drops the parsed private key of vapidPrivateKey and the headers signed with it,
+// e.g. when offboarding a tenant
+func (c *Client) ForgetPrivateKey(vapidPrivateKey string) {
+	c.cache.privateKeys.forget(vapidPrivateKey)
+
+	if publicKey, err := VAPIDPublicKeyFromPrivate(vapidPrivateKey); err == nil {
+		c.cache.invalidatePublicKey(publicKey)
+	}
+}
+
+// ForgetPrivateKey drops a private key from the shared cache of the package level functions,
+// see Client.ForgetPrivateKey
+func ForgetPrivateKey(vapidPrivateKey string) {
+	defaultClient.ForgetPrivateKey(vapidPrivateKey)
+}
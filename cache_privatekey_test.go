@@ -0,0 +1,58 @@
+package webpush
+
+import (
+	"testing"
+)
+
+func TestPrivateKeyCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	client, err := NewClient(WithPrivateKeyCacheMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second, third := getTestVAPIDKeys(t), getTestVAPIDKeys(t), getTestVAPIDKeys(t)
+	for _, keys := range []VAPIDKeys{first, second, first, third} {
+		if _, err := client.cache.privateKey(keys.PrivateKey, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// first was used after second, so second is the one evicted
+	if _, ok := client.cache.privateKeys.load(second.PrivateKey); ok {
+		t.Fatal("Least recently used private key is still cached")
+	}
+
+	for _, keys := range []VAPIDKeys{first, third} {
+		if _, ok := client.cache.privateKeys.load(keys.PrivateKey); !ok {
+			t.Fatal("Recently used private key was evicted")
+		}
+	}
+
+	stats := client.VAPIDCacheStats()
+	if stats.PrivateKeys != 2 || stats.PrivateKeyEvictions != 1 {
+		t.Fatalf("Incorrect private key stats, got %+v", stats)
+	}
+}
+
+func TestClientForgetPrivateKey(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	client, err := NewClient(WithVAPIDKeys(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.WarmVAPIDCache([]string{"https://fcm.googleapis.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	client.ForgetPrivateKey(keys.PrivateKey)
+
+	if _, ok := client.cache.privateKeys.load(keys.PrivateKey); ok {
+		t.Fatal("Forgotten private key is still cached")
+	}
+
+	stats := client.VAPIDCacheStats()
+	if stats.PrivateKeys != 0 || stats.Size != 0 {
+		t.Fatalf("Expected no cached keys or headers, got %+v", stats)
+	}
+}
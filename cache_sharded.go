@@ -0,0 +1,111 @@
+package webpush
+
+import (
+	"errors"
+	"hash/fnv"
+	"time"
+)
+
+// ErrInvalidVAPIDCacheShards is returned by WithVAPIDCacheShards for shard counts below 1
+var ErrInvalidVAPIDCacheShards = errors.New("webpush: VAPID cache needs at least one shard")
+
+// shardedHeaderCache spreads headers over independently locked headerCaches by key hash,
+// so concurrent senders don't contend on a single lock
+type shardedHeaderCache struct {
+	shards     []*headerCache
+	maxEntries int // in total, 0 means unbounded
+}
+
+// newShardedHeaderCache returns a cache of count shards holding at most maxEntries headers in total
+func newShardedHeaderCache(count, maxEntries int, stats *vapidCacheCounters) *shardedHeaderCache {
+	c := &shardedHeaderCache{shards: make([]*headerCache, count), maxEntries: maxEntries}
+	for i := range c.shards {
+		c.shards[i] = newHeaderCache(shardMaxEntries(maxEntries, count), stats)
+	}
+
+	return c
+}
+
+// shardMaxEntries splits maxEntries over count shards, rounding up
+func shardMaxEntries(maxEntries, count int) int {
+	return (maxEntries + count - 1) / count
+}
+
+// shard returns the shard of key
+func (c *shardedHeaderCache) shard(key string) *headerCache {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+func (c *shardedHeaderCache) load(key string) (vapidCacheEntry, bool) {
+	return c.shard(key).load(key)
+}
+
+func (c *shardedHeaderCache) store(key string, entry vapidCacheEntry) {
+	c.shard(key).store(key, entry)
+}
+
+func (c *shardedHeaderCache) evict(key string) {
+	c.shard(key).evict(key)
+}
+
+func (c *shardedHeaderCache) evictMatching(matches func(key string) bool) {
+	for _, shard := range c.shards {
+		shard.evictMatching(matches)
+	}
+}
+
+func (c *shardedHeaderCache) sweep(now time.Time) {
+	for _, shard := range c.shards {
+		shard.sweep(now)
+	}
+}
+
+// setMaxEntries bounds the cache to maxEntries headers in total
+func (c *shardedHeaderCache) setMaxEntries(maxEntries int) {
+	c.maxEntries = maxEntries
+	for _, shard := range c.shards {
+		shard.setMaxEntries(shardMaxEntries(maxEntries, len(c.shards)))
+	}
+}
+
+// items returns a copy of the cached items, most recently used first within each shard
+func (c *shardedHeaderCache) items() []headerCacheItem {
+	var items []headerCacheItem
+	for _, shard := range c.shards {
+		items = append(items, shard.items()...)
+	}
+
+	return items
+}
+
+// keys returns the cached keys, most recently used first within each shard
+func (c *shardedHeaderCache) keys() []string {
+	var keys []string
+	for _, shard := range c.shards {
+		keys = append(keys, shard.keys()...)
+	}
+
+	return keys
+}
+
+// WithVAPIDCacheShards splits the in-memory VAPID header cache into count independently locked shards,
+// for senders whose profiles show contention on the cache. The maximum entries, if any, are split
+// evenly between the shards. Headers cached before the option is applied are dropped.
+func WithVAPIDCacheShards(count int) ClientOption {
+	return func(c *Client) error {
+		if count < 1 {
+			return ErrInvalidVAPIDCacheShards
+		}
+
+		c.cache.headers.evictMatching(func(string) bool { return true })
+		c.cache.headers = newShardedHeaderCache(count, c.cache.headers.maxEntries, &c.cache.stats)
+		return nil
+	}
+}
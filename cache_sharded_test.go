@@ -0,0 +1,55 @@
+package webpush
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestShardedHeaderCache(t *testing.T) {
+	var stats vapidCacheCounters
+	cache := newShardedHeaderCache(4, 8, &stats)
+
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		cache.store(key, vapidCacheEntry{header: key})
+	}
+
+	// Every shard holds at most its share of the bound
+	for _, shard := range cache.shards {
+		if n := len(shard.keys()); n > 2 {
+			t.Fatalf("Shard exceeds its bound, got %d entries", n)
+		}
+	}
+
+	if size := stats.snapshot().Size; size != int64(len(cache.keys())) {
+		t.Fatalf("Incorrect size, expected=%d, got=%d", len(cache.keys()), size)
+	}
+
+	key := cache.keys()[0]
+	if entry, ok := cache.load(key); !ok || entry.header != key {
+		t.Fatalf("Incorrect entry for %s", key)
+	}
+}
+
+func TestClientWithVAPIDCacheShards(t *testing.T) {
+	client, err := NewClient(WithVAPIDCacheMaxEntries(16), WithVAPIDCacheShards(8), WithVAPIDKeys(getTestVAPIDKeys(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.cache.headers.shards) != 8 || client.cache.headers.shards[0].maxEntries != 2 {
+		t.Fatal("Cache was not sharded with the configured bound")
+	}
+
+	if err := client.WarmVAPIDCache([]string{"https://fcm.googleapis.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries := client.VAPIDCacheEntries(); len(entries) != 1 {
+		t.Fatalf("Incorrect number of entries, expected=1, got=%d", len(entries))
+	}
+
+	if _, err := NewClient(WithVAPIDCacheShards(0)); err != ErrInvalidVAPIDCacheShards {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidVAPIDCacheShards, err)
+	}
+}
@@ -0,0 +1,113 @@
+package webpush
+
+import (
+	"bytes"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// RedundantEndpointParams are query parameters some gateways and tracking tools append to endpoints.
+// Push services ignore them, so CanonicalEndpoint drops them.
+var RedundantEndpointParams = []string{"utm_source", "utm_medium", "utm_campaign", "utm_content", "utm_term"}
+
+// CanonicalEndpoint returns endpoint with a lower case scheme and host, without a default port,
+// fragment or RedundantEndpointParams, and with the remaining query parameters sorted, so two
+// spellings of the same push subscription endpoint compare equal
+func CanonicalEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	u.Fragment = ""
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for _, param := range RedundantEndpointParams {
+			query.Del(param)
+		}
+
+		// Encode sorts the parameters by key
+		u.RawQuery = query.Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// DeduplicateSubscriptions returns subscriptions without the duplicates of an earlier subscription:
+// those with the same canonical endpoint, or the same keys, which a browser only reuses for the same
+// push subscription. Fan-out sends to the result don't notify a browser twice.
+func DeduplicateSubscriptions(subscriptions []*Subscription) []*Subscription {
+	unique := make([]*Subscription, 0, len(subscriptions))
+	seen := newDeduplicator()
+	for _, s := range subscriptions {
+		if seen.add(s) {
+			unique = append(unique, s)
+		}
+	}
+
+	return unique
+}
+
+// deduplicator remembers the canonical endpoints and keys of the subscriptions it has seen
+type deduplicator struct {
+	endpoints map[string]bool
+	keys      map[string]bool
+}
+
+func newDeduplicator() *deduplicator {
+	return &deduplicator{endpoints: make(map[string]bool), keys: make(map[string]bool)}
+}
+
+// add reports whether s is not a duplicate of a subscription seen before and remembers it
+func (d *deduplicator) add(s *Subscription) bool {
+	endpoint, err := CanonicalEndpoint(s.Endpoint)
+	if err != nil {
+		endpoint = s.Endpoint
+	}
+
+	key, hasKey := subscriptionKeyID(s)
+	if d.endpoints[endpoint] || (hasKey && d.keys[key]) {
+		return false
+	}
+
+	d.endpoints[endpoint] = true
+	if hasKey {
+		d.keys[key] = true
+	}
+
+	return true
+}
+
+// IsDuplicateSubscription reports whether a and b are the same push subscription, see DeduplicateSubscriptions
+func IsDuplicateSubscription(a, b *Subscription) bool {
+	return len(DeduplicateSubscriptions([]*Subscription{a, b})) == 1
+}
+
+// subscriptionKeyID returns the decoded keys of s, whatever their base64 encoding
+func subscriptionKeyID(s *Subscription) (string, bool) {
+	p256dh, err := decodeSubscriptionKey(s.Keys.P256dh)
+	if err != nil || len(p256dh) == 0 {
+		return "", false
+	}
+
+	auth, err := decodeSubscriptionKey(s.Keys.Auth)
+	if err != nil || len(auth) == 0 {
+		return "", false
+	}
+
+	return string(bytes.Join([][]byte{p256dh, auth}, []byte{0})), true
+}
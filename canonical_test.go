@@ -0,0 +1,51 @@
+package webpush
+
+import (
+	"testing"
+)
+
+func TestCanonicalEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"https://fcm.googleapis.com/fcm/send/abc", "https://fcm.googleapis.com/fcm/send/abc"},
+		{"HTTPS://FCM.GoogleAPIs.com:443/fcm/send/abc", "https://fcm.googleapis.com/fcm/send/abc"},
+		{" https://fcm.googleapis.com/fcm/send/abc? ", "https://fcm.googleapis.com/fcm/send/abc"},
+		{"https://gateway.example.com:8443/push/abc#fragment", "https://gateway.example.com:8443/push/abc"},
+		{"https://wns2-par02p.notify.windows.com/w/?token=AQE&utm_source=app", "https://wns2-par02p.notify.windows.com/w/?token=AQE"},
+		{"https://gateway.example.com/push?b=2&a=1", "https://gateway.example.com/push?a=1&b=2"},
+	}
+
+	for _, test := range tests {
+		got, err := CanonicalEndpoint(test.endpoint)
+		if err != nil || got != test.want {
+			t.Errorf("CanonicalEndpoint(%q) = %q (%v), want %q", test.endpoint, got, err, test.want)
+		}
+	}
+}
+
+func TestDeduplicateSubscriptions(t *testing.T) {
+	first := getURLEncodedTestSubscription()
+
+	respelled := getURLEncodedTestSubscription()
+	respelled.Endpoint = "HTTPS://Updates.Push.Services.Mozilla.com:443" + first.Endpoint[len("https://updates.push.services.mozilla.com"):]
+	respelled.Keys.Auth = "aaaaaaaaaaaaaaaaaaaaaa"
+
+	// The standard encoding of the keys of first, on another endpoint
+	sameKeys := getStandardEncodedTestSubscription()
+	sameKeys.Endpoint = "https://fcm.googleapis.com/fcm/send/other"
+
+	other := getURLEncodedTestSubscription()
+	other.Endpoint = "https://fcm.googleapis.com/fcm/send/third"
+	other.Keys.Auth = "bbbbbbbbbbbbbbbbbbbbbb"
+
+	unique := DeduplicateSubscriptions([]*Subscription{first, respelled, sameKeys, other})
+	if len(unique) != 2 || unique[0] != first || unique[1] != other {
+		t.Fatalf("Incorrect unique subscriptions, got %v", unique)
+	}
+
+	if !IsDuplicateSubscription(first, respelled) || IsDuplicateSubscription(first, other) {
+		t.Fatal("Incorrect duplicate detection")
+	}
+}
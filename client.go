@@ -0,0 +1,417 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultVAPIDLifetime is the lifetime of VAPID JWT tokens unless configured otherwise
+	DefaultVAPIDLifetime = 12 * time.Hour
+
+	// MaxVAPIDLifetime is the longest VAPID JWT lifetime allowed by RFC 8292
+	MaxVAPIDLifetime = 24 * time.Hour
+)
+
+var (
+	// ErrNoVAPIDKeys is returned by WithVAPIDKeys when no key pair is given
+	ErrNoVAPIDKeys = errors.New("webpush: at least one VAPID key pair is required")
+
+	// ErrInvalidVAPIDLifetime is returned by WithVAPIDLifetime for lifetimes outside (0, MaxVAPIDLifetime]
+	ErrInvalidVAPIDLifetime = errors.New("webpush: VAPID JWT lifetime must be positive and at most 24 hours")
+)
+
+// defaultClient is used by the package level send functions
+var defaultClient = &Client{
+	vapidLifetime: DefaultVAPIDLifetime,
+	now:           time.Now,
+	cacheMargin:   DefaultVAPIDCacheMargin,
+	cache:         newVAPIDCache(),
+}
+
+// Client sends push notifications with a shared configuration.
+// Values set in the Options passed to Send take precedence over the Client configuration.
+type Client struct {
+	transportStats    transportCounters // first for the alignment of its 64-bit atomics
+	subscriber        string
+	audience          string
+	keys              vapidKeyRing
+	vapidLifetime     time.Duration
+	now               func() time.Time
+	clockSkew         time.Duration
+	claims            ClaimsFunc
+	jwtEncoder        JWTEncoder
+	strictSubscriber  bool
+	subscriberPolicy  *SubscriberPolicy
+	tenants           *tenantKeyCache
+	headerParams      string
+	noCache           bool
+	allowInsecure     bool
+	cacheMargin       time.Duration
+	cacheMarginRatio  float64
+	audienceTTLs      []audienceTTL
+	cache             *vapidCache
+	httpClient        HTTPClient
+	requestTimings    bool
+	interceptors      []Interceptor
+	limiter           *concurrencyLimiter
+	recycler          *connectionRecycler
+	timeouts          *timeoutBudgets
+	retrier           *transportRetrier
+	retryBudget       *RetryBudget
+	redirects         *RedirectPolicy
+	traceContext      TraceContextFunc
+	tracer            Tracer
+	logger            Logger
+	events            Events
+	breaker           *circuitBreaker
+	rolling           rollingStats
+	audit             AuditSink
+	correlationHeader string
+	pruner            *subscriptionPruner
+	store             SubscriptionStore
+	groups            map[string]Group
+	frequency         *frequencyLimiter
+	duplicates        *duplicateSuppressor
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client) error
+
+// NewClient returns a Client configured with options
+func NewClient(options ...ClientOption) (*Client, error) {
+	c := &Client{
+		vapidLifetime: DefaultVAPIDLifetime,
+		now:           time.Now,
+		cacheMargin:   DefaultVAPIDCacheMargin,
+		cache:         newVAPIDCache(),
+	}
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// WithSubscriber sets the default subscriber (sub) of the VAPID JWT token
+func WithSubscriber(subscriber string) ClientOption {
+	return func(c *Client) error {
+		c.subscriber = subscriber
+		return nil
+	}
+}
+
+// WithStrictSubscriber rejects subscribers that are not a valid mailto: address, e-mail address
+// or https URL, instead of prefixing anything but https URLs with mailto:
+func WithStrictSubscriber() ClientOption {
+	return func(c *Client) error {
+		c.strictSubscriber = true
+		return nil
+	}
+}
+
+// WithVAPIDHeaderParams appends params to the vapid Authorization header after t and k
+// (e.g. a key identifier), for gateways that require additional scheme parameters
+func WithVAPIDHeaderParams(params map[string]string) ClientOption {
+	return func(c *Client) error {
+		encoded, err := encodeHeaderParams(params)
+		if err != nil {
+			return err
+		}
+
+		c.headerParams = encoded
+		return nil
+	}
+}
+
+// WithoutVAPIDCache signs a new VAPID JWT token for every notification, bypassing both the
+// Authorization header cache and the parsed private key cache
+func WithoutVAPIDCache() ClientOption {
+	return func(c *Client) error {
+		c.noCache = true
+		return nil
+	}
+}
+
+// WithInsecureEndpoints accepts plain http endpoints, e.g. for a local mock push service
+func WithInsecureEndpoints() ClientOption {
+	return func(c *Client) error {
+		c.allowInsecure = true
+		return nil
+	}
+}
+
+// WithAudience sets the aud claim of every VAPID JWT token instead of deriving it from the endpoint,
+// for private push gateways fronting the real push service behind a proxy hostname
+func WithAudience(audience string) ClientOption {
+	return func(c *Client) error {
+		c.audience = audience
+		return nil
+	}
+}
+
+// WithVAPIDKeys registers the active VAPID key pairs.
+// Each notification is signed with the pair matching the subscription's ApplicationServerKey,
+// so subscriptions created with an older key keep working while keys are rotated.
+// The first pair is the primary key, used for subscriptions without an ApplicationServerKey.
+// Every pair is validated, so transposed or mismatched keys fail here instead of with a 403.
+func WithVAPIDKeys(keys ...VAPIDKeys) ClientOption {
+	return func(c *Client) error {
+		if len(keys) == 0 {
+			return ErrNoVAPIDKeys
+		}
+
+		for _, pair := range keys {
+			if err := pair.Validate(); err != nil {
+				return err
+			}
+		}
+
+		c.keys.replace(keys)
+		return nil
+	}
+}
+
+// WithVAPIDLifetime sets how long VAPID JWT tokens are valid for.
+// RFC 8292 forbids tokens valid for more than 24 hours.
+func WithVAPIDLifetime(lifetime time.Duration) ClientOption {
+	return func(c *Client) error {
+		if lifetime <= 0 || lifetime > MaxVAPIDLifetime {
+			return ErrInvalidVAPIDLifetime
+		}
+
+		c.vapidLifetime = lifetime
+		return nil
+	}
+}
+
+// WithClock replaces time.Now as the source of the current time for JWT claims and cache expiry
+func WithClock(now func() time.Time) ClientOption {
+	return func(c *Client) error {
+		c.now = now
+		return nil
+	}
+}
+
+// WithClockSkew adds an iat claim backdated by skew to VAPID JWT tokens.
+// Push services running on a clock ahead of ours otherwise reject fresh tokens as not yet valid.
+func WithClockSkew(skew time.Duration) ClientOption {
+	return func(c *Client) error {
+		if skew < 0 {
+			return errors.New("webpush: clock skew must not be negative")
+		}
+
+		c.clockSkew = skew
+		return nil
+	}
+}
+
+// WithVAPIDClaims adds the claims returned by claims to every VAPID JWT token,
+// for push gateways that require claims beyond aud, exp and sub
+func WithVAPIDClaims(claims ClaimsFunc) ClientOption {
+	return func(c *Client) error {
+		c.claims = claims
+		return nil
+	}
+}
+
+// WithJWTEncoder replaces the golang-jwt based encoder of VAPID JWT tokens
+func WithJWTEncoder(encoder JWTEncoder) ClientOption {
+	return func(c *Client) error {
+		c.jwtEncoder = encoder
+		return nil
+	}
+}
+
+// SetVAPIDKeys atomically makes keys the primary VAPID key pair.
+// The previous key pairs stay active for grace, so in-flight sends and subscriptions created
+// with them keep working during the rotation; with a zero grace they are dropped right away.
+// Cached headers and parsed private keys of dropped key pairs are invalidated.
+func (c *Client) SetVAPIDKeys(keys VAPIDKeys, grace time.Duration) error {
+	if err := keys.Validate(); err != nil {
+		return err
+	}
+
+	now := c.now()
+	var retireAt time.Time
+	if grace > 0 {
+		retireAt = now.Add(grace)
+	}
+
+	for _, removed := range c.keys.rotate(keys, retireAt, now) {
+		c.cache.invalidateKeys(removed)
+	}
+
+	if grace > 0 {
+		previous := c.keys.active(now)[1:]
+		time.AfterFunc(grace, func() {
+			for _, old := range previous {
+				if c.keys.retire(old, c.now()) {
+					c.cache.invalidateKeys(old)
+				}
+			}
+		})
+	}
+
+	return nil
+}
+
+// Send encrypts message and sends it to the subscription's endpoint
+func (c *Client) Send(ctx context.Context, message []byte, s *Subscription, options *Options) (*http.Response, error) {
+	result, err := c.Deliver(ctx, message, s, options)
+	if result == nil {
+		return nil, err
+	}
+
+	return result.Response, err
+}
+
+// Deliver sends message like Send and reports the details of the delivery in a SendResult.
+// When the request fails, the SendResult still holds the timings of the attempt.
+func (c *Client) Deliver(ctx context.Context, message []byte, s *Subscription, options *Options) (*SendResult, error) {
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+
+	// Resolve the tenant's keys unless the caller supplied one
+	if opts.TenantID != "" && opts.VAPIDPrivateKey == "" && opts.VAPIDSigner == nil {
+		if c.tenants == nil {
+			return nil, ErrNoVAPIDProvider
+		}
+
+		keys, subscriber, err := c.tenants.keysFor(ctx, opts.TenantID, c.now())
+		if err != nil {
+			c.log().Error("webpush: VAPID key provider failed", "tenant", opts.TenantID, "error", loggableError(err))
+			return nil, err
+		}
+
+		opts.VAPIDPublicKey = keys.PublicKey
+		opts.VAPIDPrivateKey = keys.PrivateKey
+		if opts.Subscriber == "" {
+			opts.Subscriber = subscriber
+		}
+	}
+
+	if opts.Subscriber == "" {
+		opts.Subscriber = c.subscriber
+	}
+
+	if opts.Audience == "" {
+		opts.Audience = c.audience
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = c.httpClient
+	}
+
+	// Pick the VAPID key pair unless the caller supplied one
+	if opts.VAPIDPrivateKey == "" && opts.VAPIDSigner == nil {
+		if keys, ok := c.keys.forSubscription(s, c.now()); ok {
+			opts.VAPIDPublicKey = keys.PublicKey
+			opts.VAPIDPrivateKey = keys.PrivateKey
+		}
+	}
+
+	var duplicate string
+	if c.duplicates != nil {
+		duplicate = duplicateKey(s.Endpoint, opts.DedupeKey, message)
+		if err := c.duplicates.claim(ctx, duplicate, c.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.frequency != nil {
+		if err := c.frequency.allow(ctx, s.Endpoint, opts.Urgency, c.now()); err != nil {
+			if duplicate != "" {
+				c.releaseDuplicate(ctx, duplicate)
+			}
+			return nil, err
+		}
+	}
+
+	result, err := c.sendNotification(ctx, message, s, &opts)
+	if duplicate != "" && (err != nil || result == nil || result.Response == nil ||
+		result.Response.StatusCode < 200 || result.Response.StatusCode >= 300) {
+		c.releaseDuplicate(ctx, duplicate)
+	}
+
+	return result, err
+}
+
+// WarmVAPIDCache signs and caches the Authorization headers of every active key pair
+// for the given push service origins (e.g. https://fcm.googleapis.com), so the first
+// requests of a burst don't all pay the signing cost.
+func (c *Client) WarmVAPIDCache(audiences []string) error {
+	for _, audience := range audiences {
+		for _, keys := range c.keys.active(c.now()) {
+			_, err := getVAPIDHeader(c.vapidHeaderParams(audience, &Options{
+				Subscriber:      c.subscriber,
+				Audience:        c.audience,
+				VAPIDPublicKey:  keys.PublicKey,
+				VAPIDPrivateKey: keys.PrivateKey,
+			}))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// vapidHeaderParams returns the VAPID header inputs for a notification to endpoint
+func (c *Client) vapidHeaderParams(endpoint string, options *Options) *vapidHeaderParams {
+	now := c.now()
+	expiration := c.vapidExpiration(now, options.VapidExpiration)
+
+	return &vapidHeaderParams{
+		endpoint:         endpoint,
+		audience:         options.Audience,
+		subscriber:       options.Subscriber,
+		vapidPublicKey:   options.VAPIDPublicKey,
+		vapidPrivateKey:  options.VAPIDPrivateKey,
+		signer:           options.VAPIDSigner,
+		expiration:       expiration,
+		now:              now,
+		issuedAt:         c.issuedAt(now),
+		claims:           c.claims,
+		strictSubscriber: c.strictSubscriber,
+		subscriberPolicy: c.subscriberPolicy,
+		headerParams:     c.headerParams,
+		noCache:          c.noCache,
+		allowInsecure:    c.allowInsecure,
+		encoder:          c.jwtEncoder,
+		cacheMargin:      c.vapidCacheMargin(expiration.Sub(now)),
+		audienceTTLs:     c.audienceTTLs,
+		cache:            c.cache,
+		logger:           c.log(),
+	}
+}
+
+// vapidExpiration returns the exp claim for a token, an explicit expiration
+// beyond the 24 hour maximum of RFC 8292 is clamped to it
+func (c *Client) vapidExpiration(now, expiration time.Time) time.Time {
+	if expiration.IsZero() {
+		return now.Add(c.vapidLifetime)
+	}
+
+	if latest := now.Add(MaxVAPIDLifetime); expiration.After(latest) {
+		return latest
+	}
+
+	return expiration
+}
+
+// issuedAt returns the iat claim for a token, zero when no clock skew is configured
+func (c *Client) issuedAt(now time.Time) time.Time {
+	if c.clockSkew == 0 {
+		return time.Time{}
+	}
+
+	return now.Add(-c.clockSkew)
+}
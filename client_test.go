@@ -0,0 +1,264 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// recordingHTTPClient keeps the last request it was asked to send
+type recordingHTTPClient struct {
+	req *http.Request
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.req = req
+	return &http.Response{StatusCode: 201}, nil
+}
+
+func TestClientSelectsKeyBySubscription(t *testing.T) {
+	primary := getTestVAPIDKeys(t)
+	previous := getTestVAPIDKeys(t)
+
+	client, err := NewClient(
+		WithSubscriber("test@example.com"),
+		WithVAPIDKeys(primary, previous),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                 string
+		applicationServerKey string
+		expectedKey          string
+	}{
+		{"no application server key", "", primary.PublicKey},
+		{"previous key", previous.PublicKey, previous.PublicKey},
+	}
+
+	for _, test := range tests {
+		httpClient := &recordingHTTPClient{}
+		s := getURLEncodedTestSubscription()
+		s.ApplicationServerKey = test.applicationServerKey
+
+		_, err := client.Send(context.Background(), []byte("Test"), s, &Options{HTTPClient: httpClient})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		auth := httpClient.req.Header.Get("Authorization")
+		if !strings.HasSuffix(auth, "k="+test.expectedKey) {
+			t.Fatalf("%s: signed with the wrong key, got header %s", test.name, auth)
+		}
+	}
+}
+
+func TestClientRejectsUnknownApplicationServerKey(t *testing.T) {
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getURLEncodedTestSubscription()
+	s.ApplicationServerKey = getTestVAPIDKeys(t).PublicKey
+
+	_, err = client.Send(context.Background(), []byte("Test"), s, &Options{HTTPClient: &testHTTPClient{}})
+	if !errors.Is(err, ErrVAPIDKeyMismatch) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrVAPIDKeyMismatch, err)
+	}
+}
+
+func TestWithVAPIDKeysRequiresKeys(t *testing.T) {
+	if _, err := NewClient(WithVAPIDKeys()); err != ErrNoVAPIDKeys {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrNoVAPIDKeys, err)
+	}
+}
+
+func TestClientVAPIDLifetime(t *testing.T) {
+	if _, err := NewClient(WithVAPIDLifetime(25 * time.Hour)); err != ErrInvalidVAPIDLifetime {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidVAPIDLifetime, err)
+	}
+
+	client, err := NewClient(WithVAPIDLifetime(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := client.vapidExpiration(time.Now(), time.Time{}); exp.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("Default expiration ignores the configured lifetime, got %v", exp)
+	}
+
+	// Explicit expirations may not exceed the RFC 8292 maximum
+	if exp := client.vapidExpiration(time.Now(), time.Now().Add(48*time.Hour)); exp.After(time.Now().Add(MaxVAPIDLifetime)) {
+		t.Fatalf("Expiration was not clamped to 24 hours, got %v", exp)
+	}
+}
+
+func TestClientClockAndSkew(t *testing.T) {
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	keys := getTestVAPIDKeys(t)
+
+	client, err := NewClient(
+		WithVAPIDKeys(keys),
+		WithClock(func() time.Time { return now }),
+		WithClockSkew(5*time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := &recordingHTTPClient{}
+	_, err = client.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), &Options{HTTPClient: httpClient})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenString := getTokenFromAuthorizationHeader(httpClient.req.Header.Get("Authorization"), t)
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		t.Fatal(err)
+	}
+
+	if claims["iat"] != float64(now.Add(-5*time.Minute).Unix()) {
+		t.Fatalf("Incorrect iat, expected=%d, got=%v", now.Add(-5*time.Minute).Unix(), claims["iat"])
+	}
+
+	if claims["exp"] != float64(now.Add(DefaultVAPIDLifetime).Unix()) {
+		t.Fatalf("Incorrect exp, expected=%d, got=%v", now.Add(DefaultVAPIDLifetime).Unix(), claims["exp"])
+	}
+}
+
+func TestClientAudienceOverride(t *testing.T) {
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithAudience("https://push.internal.example.com"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		options  *Options
+		expected string
+	}{
+		{&Options{}, "https://push.internal.example.com"},
+		{&Options{Audience: "https://gateway.example.com"}, "https://gateway.example.com"},
+	}
+
+	for _, test := range tests {
+		httpClient := &recordingHTTPClient{}
+		test.options.HTTPClient = httpClient
+		if _, err := client.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), test.options); err != nil {
+			t.Fatal(err)
+		}
+
+		claims := jwt.MapClaims{}
+		tokenString := getTokenFromAuthorizationHeader(httpClient.req.Header.Get("Authorization"), t)
+		if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+			t.Fatal(err)
+		}
+
+		if claims["aud"] != test.expected {
+			t.Fatalf("Incorrect aud, expected=%s, got=%v", test.expected, claims["aud"])
+		}
+	}
+}
+
+func TestClientWarmVAPIDCache(t *testing.T) {
+	encoder := &countingJWTEncoder{}
+	client, err := NewClient(
+		WithSubscriber("test@example.com"),
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithJWTEncoder(encoder),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.WarmVAPIDCache([]string{
+		"https://fcm.googleapis.com",
+		"https://updates.push.services.mozilla.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if encoder.encoded != 2 {
+		t.Fatalf("Incorrect number of signed tokens, expected=2, got=%d", encoder.encoded)
+	}
+
+	// Sends to a warmed origin are served from the cache
+	_, err = client.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), &Options{HTTPClient: &testHTTPClient{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if encoder.encoded != 2 {
+		t.Fatalf("Send signed a new token for a warmed origin, %d tokens signed", encoder.encoded)
+	}
+}
+
+func TestClientVAPIDHeaderParams(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	if _, err := NewClient(WithVAPIDHeaderParams(map[string]string{"k": "x"})); !errors.Is(err, ErrInvalidHeaderParam) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidHeaderParam, err)
+	}
+
+	plain, err := NewClient(WithVAPIDKeys(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(
+		WithVAPIDKeys(keys),
+		WithVAPIDHeaderParams(map[string]string{"kid": "2024-01", "region": "eu west"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Send without params first so a cached header would be visible
+	for _, c := range []*Client{plain, client} {
+		httpClient := &recordingHTTPClient{}
+		if _, err := c.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != nil {
+			t.Fatal(err)
+		}
+
+		auth := httpClient.req.Header.Get("Authorization")
+		hasParams := strings.HasSuffix(auth, `k=`+keys.PublicKey+`, kid=2024-01, region="eu west"`)
+		if hasParams != (c == client) {
+			t.Fatalf("Incorrect Authorization header params, got %s", auth)
+		}
+	}
+}
+
+func TestClientWithoutVAPIDCache(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	encoder := &countingJWTEncoder{}
+	client, err := NewClient(WithVAPIDKeys(keys), WithJWTEncoder(encoder), WithoutVAPIDCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), &Options{HTTPClient: &testHTTPClient{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if encoder.encoded != 3 {
+		t.Fatalf("Incorrect number of signed tokens, expected=3, got=%d", encoder.encoded)
+	}
+
+	if _, ok := client.cache.privateKeys.load(keys.PrivateKey); ok {
+		t.Fatal("Private key was cached")
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+
+	"github.com/SherClockHolmes/webpush-go/webpushtest"
+)
+
+// benchmark compares the cost of a send with each encoding, with and without the VAPID header cache
+func benchmark(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	size := flags.Int("size", 128, "payload size in bytes")
+	duration := flags.Duration("duration", webpushtest.DefaultBenchmarkDuration, "time spent measuring each configuration")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := webpushtest.RunBenchmark(context.Background(), webpushtest.BenchmarkOptions{PayloadSize: *size, Duration: *duration})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
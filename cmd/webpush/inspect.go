@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// inspect reports the fingerprint and consistency of a key pair and the details of a subscription
+func inspect(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	keysFile := flags.String("keys", "", "JSON file with the VAPID privateKey and/or publicKey")
+	subscriptionFile := flags.String("subscription", "", "file with the PushSubscription JSON, - for stdin")
+	allowedHosts := flags.String("allowed-hosts", "", "comma separated hosts of private push gateways to accept")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *keysFile == "" && *subscriptionFile == "" {
+		return errors.New("-keys or -subscription is required")
+	}
+
+	var keys webpush.VAPIDKeys
+	if *keysFile != "" {
+		var err error
+		if keys, err = loadKeys(*keysFile); err != nil {
+			return err
+		}
+	}
+
+	var subscription *webpush.Subscription
+	if *subscriptionFile != "" {
+		data, err := readInput(*subscriptionFile, stdin)
+		if err != nil {
+			return err
+		}
+
+		if subscription, err = webpush.ParseSubscription(data); err != nil {
+			return err
+		}
+	}
+
+	var hosts []string
+	if *allowedHosts != "" {
+		hosts = strings.Split(*allowedHosts, ",")
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(webpush.Inspect(keys, subscription, hosts...))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushtest"
+)
+
+// loadtest sends notifications to synthetic subscriptions of an in-process push service
+func loadtest(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	subscriptions := flags.Int("n", 1000, "number of synthetic subscriptions")
+	qps := flags.Float64("qps", 0, "target notifications per second, unlimited when 0")
+	size := flags.Int("size", 128, "payload size in bytes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return err
+	}
+
+	client, err := webpush.NewClient(
+		webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		webpush.WithSubscriber("loadtest@example.com"),
+	)
+	if err != nil {
+		return err
+	}
+
+	report, err := webpushtest.RunLoad(context.Background(), client, webpushtest.LoadOptions{
+		Subscriptions: *subscriptions,
+		QPS:           *qps,
+		Payload:       make([]byte, *size),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, report)
+	return nil
+}
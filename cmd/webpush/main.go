@@ -0,0 +1,138 @@
+// Command webpush provides VAPID tooling and sends notifications without writing Go.
+//
+// generate-vapid writes a new VAPID key pair as JSON, the -keys file of the other commands:
+//
+//	webpush generate-vapid > keys.json
+//
+// send sends a notification to a subscription, read as PushSubscription.toJSON() from -subscription,
+// with the payload of -message or read from -payload; either file can be - to read stdin:
+//
+//	echo '{"title":"Hello"}' | webpush send -keys keys.json -subscriber ops@example.com \
+//		-subscription subscription.json -payload - -ttl 3600 -urgency high
+//
+// batch-send sends the payload to every subscription of a JSONL file, one subscription per line:
+//
+//	webpush batch-send -keys keys.json -subscriber ops@example.com -subscriptions campaign.jsonl -message Hello
+//
+// inspect reports the fingerprint and consistency of a key pair and the push service, accepted encodings,
+// expiration and problems of a subscription, as JSON:
+//
+//	webpush inspect -keys keys.json -subscription subscription.json
+//
+// loadtest sends notifications to synthetic subscriptions of an in-process push service at a target
+// rate and reports throughput, allocations and latency:
+//
+//	webpush loadtest -n 10000 -qps 2000
+//
+// benchmark measures the cost of a send with each content encoding, with and without the VAPID
+// header cache, on this machine and writes a JSON report:
+//
+//	webpush benchmark -size 1024 -duration 2s
+//
+// pregenerate signs vapid Authorization headers ahead of time, e.g. on an air-gapped
+// signing host, and writes them as JSON for webpush.LoadVAPIDHeaders:
+//
+//	webpush pregenerate -keys keys.json -subscriber ops@example.com \
+//		-audiences https://fcm.googleapis.com,https://updates.push.services.mozilla.com \
+//		-windows 14 > headers.json
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "webpush:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: webpush generate-vapid | send | batch-send | inspect | loadtest | benchmark | pregenerate [flags]")
+	}
+
+	switch args[0] {
+	case "generate-vapid":
+		return generateVAPID(args[1:], stdout)
+	case "send":
+		return send(args[1:], os.Stdin, stdout)
+	case "batch-send":
+		return batchSend(args[1:], stdout)
+	case "inspect":
+		return inspect(args[1:], os.Stdin, stdout)
+	case "loadtest":
+		return loadtest(args[1:], stdout)
+	case "benchmark":
+		return benchmark(args[1:], stdout)
+	case "pregenerate":
+		return pregenerate(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func pregenerate(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("pregenerate", flag.ContinueOnError)
+	keysFile := flags.String("keys", "", "JSON file with the VAPID privateKey and publicKey")
+	subscriber := flags.String("subscriber", "", "sub claim of the VAPID JWT tokens")
+	audiences := flags.String("audiences", "", "comma separated push service origins")
+	windows := flags.Int("windows", 1, "number of headers per audience, expiring every -interval")
+	interval := flags.Duration("interval", webpush.DefaultVAPIDLifetime, "time between the expirations of consecutive headers")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *keysFile == "" || *audiences == "" {
+		return errors.New("-keys and -audiences are required")
+	}
+
+	keys, err := loadKeys(*keysFile)
+	if err != nil {
+		return err
+	}
+
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(keys), webpush.WithSubscriber(*subscriber))
+	if err != nil {
+		return err
+	}
+
+	// The first header is usable right away, the following ones take over as it expires
+	now := time.Now()
+	expirations := make([]time.Time, *windows)
+	for i := range expirations {
+		expirations[i] = now.Add(webpush.MaxVAPIDLifetime + time.Duration(i)*(*interval))
+	}
+
+	headers, err := client.PregenerateVAPIDHeaders(strings.Split(*audiences, ","), expirations)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(headers)
+}
+
+// loadKeys reads the VAPID key pair of the JSON file name
+func loadKeys(name string) (webpush.VAPIDKeys, error) {
+	var keys webpush.VAPIDKeys
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return keys, err
+	}
+
+	err = json.Unmarshal(data, &keys)
+	return keys, err
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// generateVAPID writes a new VAPID key pair as JSON
+func generateVAPID(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("generate-vapid", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey})
+}
+
+// sendFlags are the flags shared by send and batch-send
+type sendFlags struct {
+	keys       *string
+	subscriber *string
+	message    *string
+	payload    *string
+	ttl        *int
+	urgency    *string
+	topic      *string
+}
+
+func newSendFlags(flags *flag.FlagSet) *sendFlags {
+	return &sendFlags{
+		keys:       flags.String("keys", "", "JSON file with the VAPID privateKey and publicKey"),
+		subscriber: flags.String("subscriber", "", "sub claim of the VAPID JWT tokens"),
+		message:    flags.String("message", "", "payload of the notification"),
+		payload:    flags.String("payload", "", "file with the payload of the notification, - for stdin"),
+		ttl:        flags.Int("ttl", 0, "TTL of the notification in seconds"),
+		urgency:    flags.String("urgency", "", "very-low, low, normal or high"),
+		topic:      flags.String("topic", "", "topic replacing pending notifications with the same topic"),
+	}
+}
+
+// client returns the Client and Options of the flags, with the payload read from stdin if requested
+func (f *sendFlags) client(stdin io.Reader) (*webpush.Client, []byte, *webpush.Options, error) {
+	if *f.keys == "" {
+		return nil, nil, nil, errors.New("-keys is required")
+	}
+
+	keys, err := loadKeys(*f.keys)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(keys), webpush.WithSubscriber(*f.subscriber))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	message := []byte(*f.message)
+	if *f.payload != "" {
+		if message, err = readInput(*f.payload, stdin); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	options := &webpush.Options{TTL: *f.ttl, Urgency: webpush.Urgency(*f.urgency), Topic: *f.topic}
+	return client, message, options, nil
+}
+
+// send sends a notification to one subscription
+func send(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	common := newSendFlags(flags)
+	subscriptionFile := flags.String("subscription", "", "file with the PushSubscription JSON, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *subscriptionFile == "" {
+		return errors.New("-subscription is required")
+	}
+	if *subscriptionFile == "-" && *common.payload == "-" {
+		return errors.New("only one of -subscription and -payload can be read from stdin")
+	}
+
+	data, err := readInput(*subscriptionFile, stdin)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := webpush.ParseSubscription(data)
+	if err != nil {
+		return err
+	}
+
+	client, message, options, err := common.client(stdin)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Send(context.Background(), message, subscription, options)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	fmt.Fprintln(stdout, resp.Status)
+	if location := resp.Header.Get("Location"); location != "" {
+		fmt.Fprintln(stdout, "Location:", location)
+	}
+	if len(body) > 0 {
+		fmt.Fprintln(stdout, string(body))
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push service responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// batchSend sends a notification to every subscription of a JSONL file
+func batchSend(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("batch-send", flag.ContinueOnError)
+	common := newSendFlags(flags)
+	subscriptionsFile := flags.String("subscriptions", "", "JSONL file with one PushSubscription per line")
+	allowedHosts := flags.String("allowed-hosts", "", "comma separated hosts of private push gateways to accept")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *subscriptionsFile == "" {
+		return errors.New("-subscriptions is required")
+	}
+	if *common.payload == "-" {
+		return errors.New("-payload can't be read from stdin by batch-send")
+	}
+
+	client, message, options, err := common.client(nil)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*subscriptionsFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	store := webpush.NewMemorySubscriptionStore()
+	var importOptions webpush.ImportOptions
+	if *allowedHosts != "" {
+		importOptions.AllowedHosts = strings.Split(*allowedHosts, ",")
+	}
+
+	imported, err := webpush.ImportJSONL(ctx, store, f, importOptions)
+	if err != nil {
+		return err
+	}
+	for _, err := range imported.Errors {
+		fmt.Fprintln(stdout, "skipped", err)
+	}
+
+	results, err := client.SendToSegment(ctx, webpush.NewSegment(store), func(*webpush.StoredSubscription) ([]byte, *webpush.Options, error) {
+		return message, options, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(stdout, "failed %s: %v\n", result.Subscription.Endpoint, result.Err)
+		case result.Result.Response.StatusCode >= 300:
+			fmt.Fprintf(stdout, "failed %s: %s\n", result.Subscription.Endpoint, result.Result.Response.Status)
+		}
+	}
+
+	report := webpush.SummarizeFanOut(results)
+	origins := make([]string, 0, len(report.Origins))
+	for origin := range report.Origins {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		counts := report.Origins[origin]
+		fmt.Fprintf(stdout, "%s: sent %d, failed %d, reused connections %d\n", origin, counts.Delivered, counts.Failed, counts.ReusedConnections)
+	}
+
+	fmt.Fprintf(stdout, "sent %d, failed %d, skipped %d\n", report.Delivered, report.Failed, len(imported.Errors))
+	if report.Failed > 0 {
+		return fmt.Errorf("%d notifications failed", report.Failed)
+	}
+
+	return nil
+}
+
+// readInput returns the contents of the file name, or of stdin for -
+func readInput(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+
+	return ioutil.ReadFile(name)
+}
@@ -0,0 +1,54 @@
+package webpush
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying the correlation ID id, used by the
+// notifications sent with it unless their Options set a CorrelationID
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID of ctx. The request context seen by
+// interceptors and HTTP clients carries the ID of its notification.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// WithCorrelationHeader sends the correlation ID of every notification in the request header name,
+// e.g. X-Correlation-ID, so gateway logs can be matched with the logs of the application
+func WithCorrelationHeader(name string) ClientOption {
+	return func(c *Client) error {
+		c.correlationHeader = name
+		return nil
+	}
+}
+
+// correlationID returns the correlation ID of a notification: the one of its Options,
+// else the one of ctx, else a new random one
+func correlationID(ctx context.Context, options *Options) (string, error) {
+	if options.CorrelationID != "" {
+		return options.CorrelationID, nil
+	}
+
+	if ctx != nil {
+		if id := CorrelationIDFromContext(ctx); id != "" {
+			return id, nil
+		}
+	}
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+
+	var encoded [32]byte
+	hex.Encode(encoded[:], id[:])
+	return string(encoded[:]), nil
+}
@@ -0,0 +1,51 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestClientCorrelationID(t *testing.T) {
+	events := &recordingEvents{}
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithCorrelationHeader("X-Correlation-ID"), WithEvents(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var header, fromContext string
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header.Get("X-Correlation-ID")
+		fromContext = CorrelationIDFromContext(req.Context())
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	deliver := func(ctx context.Context, options *Options) *SendResult {
+		options.HTTPClient = httpClient
+		result, err := client.Deliver(ctx, []byte("Test"), getStandardEncodedTestSubscription(), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// Generated when not given
+	first := deliver(context.Background(), &Options{})
+	second := deliver(context.Background(), &Options{})
+	if len(first.CorrelationID) != 32 || first.CorrelationID == second.CorrelationID {
+		t.Fatalf("Expected distinct random correlation IDs, got %q and %q", first.CorrelationID, second.CorrelationID)
+	}
+
+	if header != second.CorrelationID || fromContext != second.CorrelationID || events.completed.CorrelationID != second.CorrelationID {
+		t.Fatalf("Correlation ID not propagated, header=%q context=%q event=%q", header, fromContext, events.completed.CorrelationID)
+	}
+
+	// Options take precedence over the context
+	ctx := ContextWithCorrelationID(context.Background(), "from-context")
+	if result := deliver(ctx, &Options{}); result.CorrelationID != "from-context" || header != "from-context" {
+		t.Fatalf("Expected the correlation ID of the context, got %q", result.CorrelationID)
+	}
+
+	if result := deliver(ctx, &Options{CorrelationID: "from-options"}); result.CorrelationID != "from-options" {
+		t.Fatalf("Expected the correlation ID of the options, got %q", result.CorrelationID)
+	}
+}
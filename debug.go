@@ -0,0 +1,93 @@
+package webpush
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+)
+
+// DebugDumpBodyLimit is the number of response body bytes included in a DebugDump
+const DebugDumpBodyLimit = 1024
+
+// DebugDump is a request and its response as seen by WithDebugDump
+type DebugDump struct {
+	Request  []byte // Request line and headers with secrets redacted, followed by the payload size
+	Response []byte // Status line, headers and up to DebugDumpBodyLimit bytes of the body, nil when the request failed
+	Err      error  // Error of the request
+}
+
+var (
+	// jwtSignature matches the signature segment of a JWT
+	jwtSignature = regexp.MustCompile(`([A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)\.[A-Za-z0-9_-]+`)
+
+	// encryptionParams matches the key material of the Crypto-Key and Encryption headers
+	encryptionParams = regexp.MustCompile(`(dh|salt)=[^;,]+`)
+)
+
+// WithDebugDump calls dump with every request and response of the Client, for troubleshooting.
+// The signature of the VAPID JWT token and the encryption key material are redacted, and the
+// encrypted payload is reduced to its size.
+func WithDebugDump(dump func(DebugDump)) ClientOption {
+	return WithInterceptors(func(next HTTPClient) HTTPClient {
+		return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+			d := DebugDump{Request: dumpRequest(req)}
+
+			resp, err := next.Do(req)
+			d.Err = err
+			if resp != nil {
+				d.Response = dumpResponse(resp)
+			}
+
+			dump(d)
+			return resp, err
+		})
+	})
+}
+
+// dumpRequest dumps the headers of req with the secrets redacted
+func dumpRequest(req *http.Request) []byte {
+	redacted := req.Clone(req.Context())
+	redacted.Body = nil
+	redacted.Header = req.Header.Clone()
+
+	for _, name := range []string{"Authorization", "Crypto-Key", "Encryption"} {
+		if value := redacted.Header.Get(name); value != "" {
+			value = jwtSignature.ReplaceAllString(value, "$1.REDACTED")
+			value = encryptionParams.ReplaceAllString(value, "$1=REDACTED")
+			redacted.Header.Set(name, value)
+		}
+	}
+
+	dump, err := httputil.DumpRequest(redacted, false)
+	if err != nil {
+		return []byte(err.Error())
+	}
+
+	return append(dump, fmt.Sprintf("[%d bytes encrypted payload]\n", req.ContentLength)...)
+}
+
+// dumpResponse dumps resp with the start of its body, leaving the body intact for the caller
+func dumpResponse(resp *http.Response) []byte {
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return []byte(err.Error())
+	}
+
+	if resp.Body == nil {
+		return dump
+	}
+
+	head := make([]byte, DebugDumpBodyLimit)
+	n, _ := io.ReadFull(resp.Body, head)
+	head = head[:n]
+
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+
+	return append(dump, head...)
+}
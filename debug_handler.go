@@ -0,0 +1,131 @@
+package webpush
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// debugState is the client state rendered by DebugHandler
+type debugState struct {
+	Config       debugConfig             `json:"config"`
+	Cache        VAPIDCacheStats         `json:"cache"`
+	CacheEntries []VAPIDCacheEntryInfo   `json:"cacheEntries"`
+	Circuits     map[string]circuitState `json:"circuits,omitempty"`
+	Concurrency  *limiterLevels          `json:"concurrency,omitempty"`
+	Transport    TransportStats          `json:"transport"`
+	Stats        Stats                   `json:"stats"`
+}
+
+// debugConfig is the configuration of a Client without its secrets, keys appear as fingerprints
+type debugConfig struct {
+	Subscriber        string        `json:"subscriber"`
+	Audience          string        `json:"audience,omitempty"`
+	KeyFingerprints   []string      `json:"keyFingerprints"`
+	VAPIDLifetime     time.Duration `json:"vapidLifetime"`
+	ClockSkew         time.Duration `json:"clockSkew"`
+	StrictSubscriber  bool          `json:"strictSubscriber"`
+	HeaderParams      string        `json:"headerParams,omitempty"`
+	NoCache           bool          `json:"noCache"`
+	AllowInsecure     bool          `json:"allowInsecure"`
+	CacheMargin       time.Duration `json:"cacheMargin"`
+	TenantProvider    bool          `json:"tenantProvider"`
+	RequestTimings    bool          `json:"requestTimings"`
+	Interceptors      int           `json:"interceptors"`
+	TransportRetries  int           `json:"transportRetries"`
+	Redirects         int           `json:"redirectHops"`
+	Timeouts          *Timeouts     `json:"timeouts,omitempty"`
+	CircuitThreshold  int           `json:"circuitThreshold,omitempty"`
+	CircuitCooldown   time.Duration `json:"circuitCooldown,omitempty"`
+	CorrelationHeader string        `json:"correlationHeader,omitempty"`
+}
+
+// circuitState is the circuit breaker state of an origin
+type circuitState struct {
+	Failures  int       `json:"failures"`
+	Open      bool      `json:"open"`
+	OpenUntil time.Time `json:"openUntil,omitempty"`
+	Probing   bool      `json:"probing"`
+}
+
+// limiterLevels are the slots in use of a concurrencyLimiter
+type limiterLevels struct {
+	Global         int            `json:"global"`
+	GlobalLimit    int            `json:"globalLimit"`
+	PerOriginLimit int            `json:"perOriginLimit"`
+	Origins        map[string]int `json:"origins"`
+}
+
+// DebugHandler returns an http.Handler rendering the state of the Client as JSON for live troubleshooting:
+// its configuration with keys reduced to fingerprints, the VAPID cache stats and entry metadata, the
+// circuit breaker states, the concurrency limiter levels and the transport and origin stats.
+// Mount it under an access controlled path, e.g. /debug/webpush.
+func (c *Client) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := debugState{
+			Config:       c.debugConfig(),
+			Cache:        c.VAPIDCacheStats(),
+			CacheEntries: c.VAPIDCacheEntries(),
+			Transport:    c.TransportStats(),
+			Stats:        c.Stats(),
+		}
+		if c.breaker != nil {
+			state.Circuits = c.breaker.states(c.now())
+		}
+		if c.limiter != nil {
+			state.Concurrency = c.limiter.levels()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(state)
+	})
+}
+
+// debugConfig returns the configuration of the Client without secrets
+func (c *Client) debugConfig() debugConfig {
+	config := debugConfig{
+		Subscriber:        c.subscriber,
+		Audience:          c.audience,
+		KeyFingerprints:   []string{},
+		VAPIDLifetime:     c.vapidLifetime,
+		ClockSkew:         c.clockSkew,
+		StrictSubscriber:  c.strictSubscriber,
+		HeaderParams:      c.headerParams,
+		NoCache:           c.noCache,
+		AllowInsecure:     c.allowInsecure,
+		CacheMargin:       c.cacheMargin,
+		TenantProvider:    c.tenants != nil,
+		RequestTimings:    c.requestTimings,
+		Interceptors:      len(c.interceptors),
+		CorrelationHeader: c.correlationHeader,
+	}
+
+	for _, keys := range c.keys.active(c.now()) {
+		if fingerprint, err := VAPIDKeyFingerprint(keys.PublicKey); err == nil {
+			config.KeyFingerprints = append(config.KeyFingerprints, fingerprint)
+		}
+	}
+
+	if c.retrier != nil {
+		config.TransportRetries = c.retrier.retries
+	}
+
+	if c.redirects != nil {
+		config.Redirects = c.redirects.MaxHops
+	}
+
+	if c.timeouts != nil {
+		timeouts := c.timeouts.defaults
+		config.Timeouts = &timeouts
+	}
+
+	if c.breaker != nil {
+		config.CircuitThreshold = c.breaker.threshold
+		config.CircuitCooldown = c.breaker.cooldown
+	}
+
+	return config
+}
@@ -0,0 +1,61 @@
+package webpush
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientDebugHandler(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	client, err := NewClient(
+		WithVAPIDKeys(keys),
+		WithSubscriber("ops@example.com"),
+		WithCircuitBreaker(1, time.Minute),
+		WithConcurrencyLimits(10, 2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusBadGateway}, nil
+	})
+	if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	client.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/webpush", nil))
+
+	body := recorder.Body.String()
+	if strings.Contains(body, keys.PrivateKey) || strings.Contains(body, keys.PublicKey) {
+		t.Fatal("Debug output leaks a VAPID key")
+	}
+
+	var state debugState
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint, _ := VAPIDKeyFingerprint(keys.PublicKey)
+	if state.Config.Subscriber != "ops@example.com" || len(state.Config.KeyFingerprints) != 1 || state.Config.KeyFingerprints[0] != fingerprint {
+		t.Fatalf("Incorrect config, got %+v", state.Config)
+	}
+
+	if circuit := state.Circuits["https://updates.push.services.mozilla.com"]; !circuit.Open || circuit.Failures != 1 {
+		t.Fatalf("Expected an open circuit, got %+v", state.Circuits)
+	}
+
+	if state.Concurrency == nil || state.Concurrency.GlobalLimit != 10 || state.Concurrency.PerOriginLimit != 2 {
+		t.Fatalf("Incorrect concurrency levels, got %+v", state.Concurrency)
+	}
+
+	if len(state.CacheEntries) != 1 || state.Cache.Misses != 1 {
+		t.Fatalf("Incorrect cache state, got %+v %+v", state.Cache, state.CacheEntries)
+	}
+}
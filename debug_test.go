@@ -0,0 +1,75 @@
+package webpush
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClientDebugDump(t *testing.T) {
+	var dumps []DebugDump
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": []string{"text/plain"}},
+			Body:       ioutil.NopCloser(strings.NewReader("invalid subscription")),
+		}, nil
+	})
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithDebugDump(func(dump DebugDump) { dumps = append(dumps, dump) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range []ContentEncoding{ContentEncodingAES128GCM, ContentEncodingAESGCM} {
+		resp, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{ContentEncoding: encoding})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The caller still reads the whole body
+		if body, _ := ioutil.ReadAll(resp.Body); string(body) != "invalid subscription" {
+			t.Fatalf("Incorrect body after dumping, got %q", body)
+		}
+	}
+
+	if len(dumps) != 2 {
+		t.Fatalf("Expected 2 dumps, got %d", len(dumps))
+	}
+
+	for _, dump := range dumps {
+		request := string(dump.Request)
+		if !strings.Contains(request, ".REDACTED") || !strings.Contains(request, "bytes encrypted payload]") {
+			t.Fatalf("Expected a redacted request dump, got %s", request)
+		}
+
+		// The JWT in the Authorization header has its signature redacted
+		for _, line := range strings.Split(request, "\r\n") {
+			if !strings.HasPrefix(line, "Authorization: ") {
+				continue
+			}
+
+			for _, match := range jwtSignature.FindAllString(line, -1) {
+				if !strings.HasSuffix(match, ".REDACTED") {
+					t.Fatalf("Unredacted JWT in the dump: %s", match)
+				}
+			}
+		}
+
+		if !strings.Contains(string(dump.Response), "400 Bad Request") || !strings.HasSuffix(string(dump.Response), "invalid subscription") {
+			t.Fatalf("Incorrect response dump, got %s", dump.Response)
+		}
+	}
+
+	if legacy := string(dumps[1].Request); !strings.Contains(legacy, "dh=REDACTED") || !strings.Contains(legacy, "salt=REDACTED") {
+		t.Fatalf("Expected the encryption parameters to be redacted, got %s", legacy)
+	}
+}
@@ -0,0 +1,148 @@
+package webpush
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDNSCacheTTL is how long resolved push service addresses are cached unless configured otherwise
+	DefaultDNSCacheTTL = 30 * time.Second
+
+	// DefaultDNSCacheNegativeTTL is how long failed lookups are cached unless configured otherwise
+	DefaultDNSCacheNegativeTTL = 5 * time.Second
+)
+
+// DNSCache caches the host lookups of a transport, so a high request volume to a few push service
+// origins doesn't hit the local resolver for every new connection.
+// The Go resolver doesn't expose the TTL of DNS records, addresses are cached for a fixed TTL that
+// should stay below the record TTLs of the push services (FCM and Mozilla use a few minutes).
+type DNSCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	dialer      *net.Dialer
+
+	// lookupHost and now are replaced in tests
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]dnsCacheEntry
+	flights flightGroup
+}
+
+// dnsCacheEntry is a cached lookup, err is set for a failed lookup
+type dnsCacheEntry struct {
+	addrs      []string
+	err        error
+	expiration time.Time
+}
+
+// NewDNSCache returns a DNSCache resolving with resolver (net.DefaultResolver when nil), keeping
+// addresses for ttl and failed lookups for negativeTTL. Zero durations use the defaults,
+// a negative negativeTTL disables negative caching.
+func NewDNSCache(resolver *net.Resolver, ttl, negativeTTL time.Duration) *DNSCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	if ttl == 0 {
+		ttl = DefaultDNSCacheTTL
+	}
+
+	if negativeTTL == 0 {
+		negativeTTL = DefaultDNSCacheNegativeTTL
+	}
+
+	return &DNSCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		dialer:      newDialer(),
+		lookupHost:  resolver.LookupHost,
+		now:         time.Now,
+		entries:     make(map[string]dnsCacheEntry),
+	}
+}
+
+// LookupHost returns the addresses of host, from the cache while they are fresh
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(host)
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expiration) {
+		return entry.addrs, entry.err
+	}
+
+	// Concurrent lookups of the same host share a single query
+	joined, err := c.flights.do(host, func() (string, error) {
+		addrs, err := c.lookupHost(ctx, host)
+		c.store(host, addrs, err)
+
+		return strings.Join(addrs, ","), err
+	})
+	if err != nil || joined == "" {
+		return nil, err
+	}
+
+	return strings.Split(joined, ","), nil
+}
+
+// store caches the result of a lookup of host
+func (c *DNSCache) store(host string, addrs []string, err error) {
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.negativeTTL
+	}
+
+	// Lookups canceled by the caller say nothing about the host
+	if ttl < 0 || err == context.Canceled || err == context.DeadlineExceeded {
+		return
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsCacheEntry{addrs: addrs, err: err, expiration: c.now().Add(ttl)}
+	c.mu.Unlock()
+}
+
+// DialContext connects to address through the cache, trying each resolved address in turn.
+// It can be used as the DialContext of an http.Transport.
+func (c *DNSCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return c.dial(ctx, c.dialer, network, address)
+}
+
+// dial connects to address with dialer through the cache
+func (c *DNSCache) dial(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := c.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	for _, addr := range addrs {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
@@ -0,0 +1,128 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDNSCache(t *testing.T) {
+	now := time.Now()
+	lookups := map[string]int{}
+	cache := NewDNSCache(nil, time.Minute, 10*time.Second)
+	cache.now = func() time.Time { return now }
+	cache.lookupHost = func(_ context.Context, host string) ([]string, error) {
+		lookups[host]++
+		if host == "missing.example.com" {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []string{"192.0.2.1", "192.0.2.2"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		addrs, err := cache.LookupHost(context.Background(), "FCM.googleapis.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(addrs) != 2 || addrs[1] != "192.0.2.2" {
+			t.Fatalf("Incorrect addresses, got %v", addrs)
+		}
+
+		if _, err := cache.LookupHost(context.Background(), "missing.example.com"); err == nil {
+			t.Fatal("Expected a lookup error")
+		}
+	}
+
+	if lookups["fcm.googleapis.com"] != 1 || lookups["missing.example.com"] != 1 {
+		t.Fatalf("Expected cached lookups, got %v", lookups)
+	}
+
+	// Failed lookups expire first
+	now = now.Add(30 * time.Second)
+	cache.LookupHost(context.Background(), "fcm.googleapis.com")
+	cache.LookupHost(context.Background(), "missing.example.com")
+	if lookups["fcm.googleapis.com"] != 1 || lookups["missing.example.com"] != 2 {
+		t.Fatalf("Incorrect negative caching, got %v", lookups)
+	}
+
+	now = now.Add(time.Minute)
+	cache.LookupHost(context.Background(), "fcm.googleapis.com")
+	if lookups["fcm.googleapis.com"] != 2 {
+		t.Fatalf("Expected the addresses to expire, got %v", lookups)
+	}
+}
+
+func TestDNSCacheSharesConcurrentLookups(t *testing.T) {
+	var mu sync.Mutex
+	lookups := 0
+	release := make(chan struct{})
+	cache := NewDNSCache(nil, 0, 0)
+	cache.lookupHost = func(context.Context, string) ([]string, error) {
+		mu.Lock()
+		lookups++
+		mu.Unlock()
+		<-release
+		return []string{"192.0.2.1"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.LookupHost(context.Background(), "fcm.googleapis.com")
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if lookups != 1 {
+		t.Fatalf("Expected a single lookup, got %d", lookups)
+	}
+}
+
+func TestDNSCacheDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	cache := NewDNSCache(nil, 0, 0)
+	cache.lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if host != "push.example.com" {
+			return nil, errors.New("unexpected host " + host)
+		}
+		return []string{"127.0.0.1"}, nil
+	}
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTransportOptions(TransportOptions{DNSCache: cache}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = strings.Replace(server.URL, "127.0.0.1", "push.example.com", 1) + "/push/abc"
+
+	resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Incorrect status, expected=%d, got=%d", http.StatusCreated, resp.StatusCode)
+	}
+}
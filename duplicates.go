@@ -0,0 +1,157 @@
+package webpush
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateSuppressed is returned by Client.Send instead of sending a notification already sent
+	// to the subscription within the duplicate suppression window
+	ErrDuplicateSuppressed = errors.New("webpush: duplicate notification suppressed")
+
+	// ErrInvalidDuplicateWindow is returned by WithDuplicateSuppression for a window that isn't positive
+	ErrInvalidDuplicateWindow = errors.New("webpush: duplicate suppression needs a positive window")
+)
+
+// DuplicateTracker remembers the notifications recently sent to every subscription. Implementations
+// backed by a shared store, e.g. webpushd.RedisDuplicateTracker, suppress duplicates across processes;
+// they must be safe for concurrent use.
+type DuplicateTracker interface {
+	// Remember records key until expires and reports whether it was already recorded and hasn't expired,
+	// atomically so only one of concurrent sends of the same key goes out
+	Remember(ctx context.Context, key string, now, expires time.Time) (bool, error)
+
+	// Forget removes key, so a notification that wasn't delivered can be sent again
+	Forget(ctx context.Context, key string) error
+}
+
+// duplicateSuppressor enforces the duplicate suppression window of a Client
+type duplicateSuppressor struct {
+	window  time.Duration
+	tracker DuplicateTracker
+}
+
+// WithDuplicateSuppression refuses with ErrDuplicateSuppressed to send a notification sent to the same
+// subscription within window, e.g. by an overlapping campaign or a replayed queue. Notifications are the
+// same when they have the same Options.DedupeKey, or the same payload when they have none. Only delivered
+// notifications are suppressed, one that failed or was rejected by the push service can be resent.
+// Notifications are tracked in memory unless tracker is set.
+func WithDuplicateSuppression(tracker DuplicateTracker, window time.Duration) ClientOption {
+	return func(c *Client) error {
+		if window <= 0 {
+			return ErrInvalidDuplicateWindow
+		}
+
+		if tracker == nil {
+			tracker = NewMemoryDuplicateTracker()
+		}
+
+		c.duplicates = &duplicateSuppressor{window: window, tracker: tracker}
+		return nil
+	}
+}
+
+// duplicateKey returns the DuplicateTracker key of a notification to endpoint
+func duplicateKey(endpoint, dedupeKey string, message []byte) string {
+	if dedupeKey == "" {
+		sum := sha256.Sum256(message)
+		dedupeKey = "sha256:" + hex.EncodeToString(sum[:])
+	} else {
+		dedupeKey = "key:" + dedupeKey
+	}
+
+	return EndpointHash(endpoint) + ":" + dedupeKey
+}
+
+// claim remembers the notification of key at now, or returns ErrDuplicateSuppressed when it was sent within the window
+func (d *duplicateSuppressor) claim(ctx context.Context, key string, now time.Time) error {
+	seen, err := d.tracker.Remember(ctx, key, now, now.Add(d.window))
+	if err != nil {
+		return err
+	}
+
+	if seen {
+		return ErrDuplicateSuppressed
+	}
+
+	return nil
+}
+
+// releaseDuplicate forgets the notification of key when it wasn't delivered, logging failures since the send already failed
+func (c *Client) releaseDuplicate(ctx context.Context, key string) {
+	if err := c.duplicates.tracker.Forget(ctx, key); err != nil {
+		c.log().Error("webpush: duplicate tracker failed", "error", loggableError(err))
+	}
+}
+
+// MemoryDuplicateTracker is an in-memory DuplicateTracker, suppressing duplicates within one process.
+// Keys are dropped in the order they were remembered once expired, so a tracker is best used with one window.
+type MemoryDuplicateTracker struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+	order   []duplicateEntry // Remembered keys, oldest first from head
+	head    int
+}
+
+// duplicateEntry is a key remembered by a MemoryDuplicateTracker
+type duplicateEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryDuplicateTracker returns an empty MemoryDuplicateTracker
+func NewMemoryDuplicateTracker() *MemoryDuplicateTracker {
+	return &MemoryDuplicateTracker{expires: make(map[string]time.Time)}
+}
+
+// Remember implements DuplicateTracker
+func (m *MemoryDuplicateTracker) Remember(ctx context.Context, key string, now, expires time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dropExpired(now)
+	if until, ok := m.expires[key]; ok && until.After(now) {
+		return true, nil
+	}
+
+	m.expires[key] = expires
+	m.order = append(m.order, duplicateEntry{key: key, expires: expires})
+	return false, nil
+}
+
+// dropExpired removes the oldest keys while they're expired at now, keys remembered again or forgotten
+// since are left alone
+func (m *MemoryDuplicateTracker) dropExpired(now time.Time) {
+	for m.head < len(m.order) && !m.order[m.head].expires.After(now) {
+		entry := m.order[m.head]
+		if until, ok := m.expires[entry.key]; ok && until.Equal(entry.expires) {
+			delete(m.expires, entry.key)
+		}
+		m.order[m.head] = duplicateEntry{}
+		m.head++
+	}
+
+	// Copy the remaining keys down once half the slice is dropped, so it doesn't grow forever
+	if m.head > 0 && m.head >= len(m.order)/2 {
+		n := copy(m.order, m.order[m.head:])
+		for i := n; i < len(m.order); i++ {
+			m.order[i] = duplicateEntry{}
+		}
+		m.order = m.order[:n]
+		m.head = 0
+	}
+}
+
+// Forget implements DuplicateTracker
+func (m *MemoryDuplicateTracker) Forget(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.expires, key)
+	return nil
+}
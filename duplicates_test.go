@@ -0,0 +1,133 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWithDuplicateSuppression(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	status := http.StatusCreated
+	requests := 0
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	})
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithClock(func() time.Time { return now }),
+		WithDuplicateSuppression(nil, time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(message, dedupeKey string) error {
+		_, err := client.Send(context.Background(), []byte(message), getStandardEncodedTestSubscription(), &Options{DedupeKey: dedupeKey})
+		return err
+	}
+
+	if err := send("Sale", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("Sale", ""); !errors.Is(err, ErrDuplicateSuppressed) {
+		t.Fatalf("Expected ErrDuplicateSuppressed, got %v", err)
+	}
+	if err := send("Other", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dedupe key replaces the payload
+	if err := send("Sale, 10% off", "sale"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("Sale, 20% off", "sale"); !errors.Is(err, ErrDuplicateSuppressed) {
+		t.Fatalf("Expected ErrDuplicateSuppressed for the same dedupe key, got %v", err)
+	}
+
+	// A notification that wasn't delivered can be resent
+	status = http.StatusServiceUnavailable
+	if err := send("Retried", ""); err != nil {
+		t.Fatal(err)
+	}
+	status = http.StatusCreated
+	if err := send("Retried", ""); err != nil {
+		t.Fatalf("Expected the failed notification to be resent, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := send("Sale", ""); err != nil {
+		t.Fatalf("Expected the window to expire, got %v", err)
+	}
+
+	if requests != 6 {
+		t.Fatalf("Expected 6 requests, got %d", requests)
+	}
+
+	if _, err := NewClient(WithDuplicateSuppression(nil, 0)); !errors.Is(err, ErrInvalidDuplicateWindow) {
+		t.Fatalf("Expected ErrInvalidDuplicateWindow, got %v", err)
+	}
+}
+
+func TestFanOutCountsSuppressed(t *testing.T) {
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+		})),
+		WithDuplicateSuppression(nil, time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := func(*StoredSubscription) ([]byte, *Options, error) { return []byte("Sale"), nil, nil }
+
+	// The second campaign overlaps the first
+	for i, expected := range []FanOutCounts{{Notifications: 1, Delivered: 1}, {Notifications: 1, Suppressed: 1}} {
+		counts, err := client.SendToIterator(context.Background(), &failingIterator{n: 1, err: io.EOF}, payload, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *counts != expected {
+			t.Fatalf("Campaign %d: expected %+v, got %+v", i, expected, *counts)
+		}
+	}
+}
+
+func TestMemoryDuplicateTrackerDropsExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewMemoryDuplicateTracker()
+	start := time.Unix(0, 0)
+
+	// With a window of 100 keys, at most 100 are kept however many are remembered
+	for i := 0; i < 10000; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		if seen, _ := tracker.Remember(ctx, strconv.Itoa(i), now, now.Add(100*time.Second)); seen {
+			t.Fatalf("Key %d was seen before", i)
+		}
+	}
+
+	if len(tracker.expires) != 100 || len(tracker.order) > 200 {
+		t.Fatalf("Expired keys were kept, keys=%d order=%d", len(tracker.expires), len(tracker.order))
+	}
+
+	// A key forgotten and remembered again outlives its first expiry
+	now := start.Add(20000 * time.Second)
+	tracker.Remember(ctx, "key", now, now.Add(10*time.Second))
+	tracker.Forget(ctx, "key")
+	tracker.Remember(ctx, "key", now.Add(5*time.Second), now.Add(15*time.Second))
+	if seen, _ := tracker.Remember(ctx, "key", now.Add(12*time.Second), now.Add(22*time.Second)); !seen {
+		t.Fatal("Key remembered again was dropped at its first expiry")
+	}
+
+	if seen, _ := tracker.Remember(ctx, "key", now.Add(15*time.Second), now.Add(25*time.Second)); seen {
+		t.Fatal("Expired key was still seen")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build go1.20
+// +build go1.20
+
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+)
+
+// ephemeralECDH generates a single use P-256 key pair and derives its shared secret with the
+// subscription key dh, returning the uncompressed public key of the pair and the secret
+func ephemeralECDH(dh []byte) (localPublicKey, sharedECDHSecret []byte, err error) {
+	curve := ecdh.P256()
+
+	remote, err := curve.NewPublicKey(dh)
+	if err != nil {
+		return nil, nil, errors.New("Unmarshal Error: Public key is not a valid point on the curve")
+	}
+
+	local, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sharedECDHSecret, err = local.ECDH(remote)
+	if err != nil {
+		return nil, nil, errors.New("Encryption error: ECDH shared secret isn't on curve")
+	}
+
+	return local.PublicKey().Bytes(), sharedECDHSecret, nil
+}
@@ -0,0 +1,40 @@
+//go:build !go1.20
+// +build !go1.20
+
+package webpush
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+)
+
+// ephemeralECDH generates a single use P-256 key pair and derives its shared secret with the
+// subscription key dh, returning the uncompressed public key of the pair and the secret
+func ephemeralECDH(dh []byte) (localPublicKey, sharedECDHSecret []byte, err error) {
+	curve := elliptic.P256()
+
+	// Application server key pairs (single use)
+	localPrivateKey, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	localPublicKey = elliptic.Marshal(curve, x, y)
+
+	// Combine application keys with receiver's EC public key
+	sharedX, sharedY := elliptic.Unmarshal(curve, dh)
+	if sharedX == nil {
+		return nil, nil, errors.New("Unmarshal Error: Public key is not a valid point on the curve")
+	}
+
+	// Derive ECDH shared secret
+	sx, sy := curve.ScalarMult(sharedX, sharedY, localPrivateKey)
+	if !curve.IsOnCurve(sx, sy) {
+		return nil, nil, errors.New("Encryption error: ECDH shared secret isn't on curve")
+	}
+	sharedECDHSecret = make([]byte, curve.Params().BitSize/8)
+	sx.FillBytes(sharedECDHSecret)
+
+	return localPublicKey, sharedECDHSecret, nil
+}
@@ -0,0 +1,100 @@
+package webpush
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PushService identifies the push service behind an endpoint
+type PushService string
+
+// Push services recognized by DetectPushService
+const (
+	PushServiceFCM     PushService = "fcm"     // Chrome, Edge and other Chromium browsers
+	PushServiceMozilla PushService = "mozilla" // Firefox
+	PushServiceApple   PushService = "apple"   // Safari
+	PushServiceWNS     PushService = "wns"     // Windows Push Notification Services
+	PushServiceOther   PushService = "other"
+)
+
+// pushServiceHosts maps host suffixes to their push service
+var pushServiceHosts = []struct {
+	suffix  string
+	service PushService
+}{
+	{"fcm.googleapis.com", PushServiceFCM},
+	{"android.googleapis.com", PushServiceFCM},
+	{"push.services.mozilla.com", PushServiceMozilla},
+	{"push.apple.com", PushServiceApple},
+	{"notify.windows.com", PushServiceWNS},
+}
+
+// DetectPushService returns the push service of endpoint from its host, PushServiceOther when unknown
+func DetectPushService(endpoint string) PushService {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return PushServiceOther
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, known := range pushServiceHosts {
+		if host == known.suffix || strings.HasSuffix(host, "."+known.suffix) {
+			return known.service
+		}
+	}
+
+	return PushServiceOther
+}
+
+// ErrorClass is the canonical class of a failed send
+type ErrorClass string
+
+// Error classes returned by ClassifySend
+const (
+	ErrorClassGone            ErrorClass = "gone"              // 404 or 410, the subscription expired or was unsubscribed
+	ErrorClassUnauthorized    ErrorClass = "unauthorized"      // 401 or 403, usually a VAPID key mismatch
+	ErrorClassPayloadTooLarge ErrorClass = "payload_too_large" // 413
+	ErrorClassThrottled       ErrorClass = "throttled"         // 429
+	ErrorClassClient          ErrorClass = "client_error"      // Other 4xx responses
+	ErrorClassServer          ErrorClass = "server_error"      // 5xx responses
+	ErrorClassTimeout         ErrorClass = "timeout"           // ErrTimeout or a network timeout
+	ErrorClassCircuitOpen     ErrorClass = "circuit_open"      // ErrCircuitOpen
+	ErrorClassDuplicate       ErrorClass = "duplicate"         // ErrDuplicateSuppressed
+	ErrorClassTransport       ErrorClass = "transport"         // Other errors without a response
+)
+
+// ClassifySend returns the class of a send that got resp and err, empty for a successful one
+func ClassifySend(resp *http.Response, err error) ErrorClass {
+	if err != nil || resp == nil {
+		var timeout interface{ Timeout() bool }
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			return ErrorClassCircuitOpen
+		case errors.Is(err, ErrDuplicateSuppressed):
+			return ErrorClassDuplicate
+		case errors.Is(err, ErrTimeout), errors.As(err, &timeout) && timeout.Timeout():
+			return ErrorClassTimeout
+		default:
+			return ErrorClassTransport
+		}
+	}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusNotFound || code == http.StatusGone:
+		return ErrorClassGone
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrorClassUnauthorized
+	case code == http.StatusRequestEntityTooLarge:
+		return ErrorClassPayloadTooLarge
+	case code == http.StatusTooManyRequests:
+		return ErrorClassThrottled
+	case code >= 500:
+		return ErrorClassServer
+	case code >= 400:
+		return ErrorClassClient
+	default:
+		return ""
+	}
+}
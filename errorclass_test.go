@@ -0,0 +1,74 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestDetectPushService(t *testing.T) {
+	for endpoint, service := range map[string]PushService{
+		"https://fcm.googleapis.com/fcm/send/abc":                PushServiceFCM,
+		"https://updates.push.services.mozilla.com/wpush/v2/abc": PushServiceMozilla,
+		"https://web.push.apple.com/QGxvbmc":                     PushServiceApple,
+		"https://wns2-par02p.notify.windows.com/w/?token=abc":    PushServiceWNS,
+		"https://push.example.com/fcm.googleapis.com":            PushServiceOther,
+		"https://notfcm.googleapis.com.example/send":             PushServiceOther,
+		"://invalid": PushServiceOther,
+	} {
+		if got := DetectPushService(endpoint); got != service {
+			t.Errorf("DetectPushService(%q) = %q, expected %q", endpoint, got, service)
+		}
+	}
+}
+
+func TestClassifySend(t *testing.T) {
+	response := func(code int) *http.Response { return &http.Response{StatusCode: code} }
+
+	for _, test := range []struct {
+		resp  *http.Response
+		err   error
+		class ErrorClass
+	}{
+		{response(http.StatusCreated), nil, ""},
+		{response(http.StatusGone), nil, ErrorClassGone},
+		{response(http.StatusNotFound), nil, ErrorClassGone},
+		{response(http.StatusForbidden), nil, ErrorClassUnauthorized},
+		{response(http.StatusRequestEntityTooLarge), nil, ErrorClassPayloadTooLarge},
+		{response(http.StatusTooManyRequests), nil, ErrorClassThrottled},
+		{response(http.StatusBadRequest), nil, ErrorClassClient},
+		{response(http.StatusBadGateway), nil, ErrorClassServer},
+		{nil, fmt.Errorf("%w: https://a.example", ErrCircuitOpen), ErrorClassCircuitOpen},
+		{nil, ErrDuplicateSuppressed, ErrorClassDuplicate},
+		{nil, &TimeoutError{Origin: "https://a.example", Stage: "dial"}, ErrorClassTimeout},
+		{nil, &net.DNSError{Err: "timeout", IsTimeout: true}, ErrorClassTimeout},
+		{nil, errors.New("connection refused"), ErrorClassTransport},
+	} {
+		if class := ClassifySend(test.resp, test.err); class != test.class {
+			t.Errorf("ClassifySend(%v, %v) = %q, expected %q", test.resp, test.err, class, test.class)
+		}
+	}
+}
+
+func TestClientStatsErrors(t *testing.T) {
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusGone}, nil
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if gone := client.Stats().Errors[PushServiceMozilla][ErrorClassGone]; gone != 2 {
+		t.Fatalf("Incorrect gone count, expected=2, got=%d", gone)
+	}
+}
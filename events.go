@@ -0,0 +1,120 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Events receives the lifecycle events of the notifications of a Client, e.g. to feed metrics,
+// logs or audit systems. Methods are called synchronously on the sending goroutine and must not block.
+// Embed NopEvents to implement only some of them.
+type Events interface {
+	SendStarted(ctx context.Context, event SendStartedEvent)
+	SendCompleted(ctx context.Context, event SendCompletedEvent)
+	RetryScheduled(ctx context.Context, event RetryScheduledEvent)
+	CircuitOpened(ctx context.Context, event CircuitOpenedEvent)
+	SubscriptionGone(ctx context.Context, event SubscriptionGoneEvent)
+}
+
+// SendStartedEvent is emitted before a notification is sent to the push service
+type SendStartedEvent struct {
+	Origin        string // Push service origin, e.g. https://fcm.googleapis.com
+	CorrelationID string // Correlation ID of the notification
+	PayloadSize   int    // Size of the encrypted payload
+}
+
+// SendCompletedEvent is emitted when the push service answered a notification or sending it failed
+type SendCompletedEvent struct {
+	Origin        string
+	CorrelationID string        // Correlation ID of the notification
+	StatusCode    int           // Status code of the response, zero without a response
+	Duration      time.Duration // Duration of the send, including retries
+	Err           error
+}
+
+// RetryScheduledEvent is emitted when a request that failed before reaching the push service is retried
+type RetryScheduledEvent struct {
+	Origin        string
+	CorrelationID string        // Correlation ID of the notification
+	Attempt       int           // Number of the retry, starting at 1
+	Backoff       time.Duration // Wait before the retry
+	Err           error         // Error of the failed attempt
+}
+
+// CircuitOpenedEvent is emitted when the circuit breaker of an origin opens, see WithCircuitBreaker
+type CircuitOpenedEvent struct {
+	Origin        string
+	CorrelationID string    // Correlation ID of the notification whose failure opened the circuit
+	Failures      int       // Consecutive failures that opened the circuit
+	Until         time.Time // End of the cooldown
+}
+
+// SubscriptionGoneEvent is emitted when the push service reports a subscription as expired
+// or unsubscribed with a 404 or 410 response, so it can be removed
+type SubscriptionGoneEvent struct {
+	Origin        string
+	CorrelationID string // Correlation ID of the notification
+	StatusCode    int
+	Subscription  *Subscription
+}
+
+// NopEvents ignores every event
+type NopEvents struct{}
+
+// SendStarted implements Events
+func (NopEvents) SendStarted(context.Context, SendStartedEvent) {}
+
+// SendCompleted implements Events
+func (NopEvents) SendCompleted(context.Context, SendCompletedEvent) {}
+
+// RetryScheduled implements Events
+func (NopEvents) RetryScheduled(context.Context, RetryScheduledEvent) {}
+
+// CircuitOpened implements Events
+func (NopEvents) CircuitOpened(context.Context, CircuitOpenedEvent) {}
+
+// SubscriptionGone implements Events
+func (NopEvents) SubscriptionGone(context.Context, SubscriptionGoneEvent) {}
+
+// WithEvents emits the lifecycle events of every notification to events
+func WithEvents(events Events) ClientOption {
+	return func(c *Client) error {
+		c.events = events
+		return nil
+	}
+}
+
+// emit returns the Events of the Client
+func (c *Client) emit() Events {
+	if c.events == nil {
+		return NopEvents{}
+	}
+
+	return c.events
+}
+
+// retryScheduled reports a transport retry of req
+func (c *Client) retryScheduled(req *http.Request, attempt int, backoff time.Duration, err error) {
+	c.log().Info("webpush: retrying request that failed before reaching the push service",
+		"host", req.URL.Host, "attempt", attempt, "error", loggableError(err),
+		"correlation_id", CorrelationIDFromContext(req.Context()))
+	c.emit().RetryScheduled(req.Context(), RetryScheduledEvent{
+		Origin:        requestOrigin(req),
+		CorrelationID: CorrelationIDFromContext(req.Context()),
+		Attempt:       attempt,
+		Backoff:       backoff,
+		Err:           err,
+	})
+}
+
+// circuitOpened reports the circuit of the origin of req opening
+func (c *Client) circuitOpened(req *http.Request, failures int, until time.Time) {
+	c.log().Warn("webpush: circuit opened", "host", req.URL.Host, "failures", failures, "until", until)
+	c.emit().CircuitOpened(req.Context(), CircuitOpenedEvent{
+		Origin:        requestOrigin(req),
+		CorrelationID: CorrelationIDFromContext(req.Context()),
+		Failures:      failures,
+		Until:         until,
+	})
+}
@@ -0,0 +1,69 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// recordingEvents records the names of the emitted events
+type recordingEvents struct {
+	NopEvents
+	names     []string
+	completed SendCompletedEvent
+	gone      SubscriptionGoneEvent
+	opened    CircuitOpenedEvent
+	retries   []RetryScheduledEvent
+}
+
+func (e *recordingEvents) SendStarted(ctx context.Context, event SendStartedEvent) {
+	e.names = append(e.names, "SendStarted")
+}
+
+func (e *recordingEvents) SendCompleted(ctx context.Context, event SendCompletedEvent) {
+	e.names = append(e.names, "SendCompleted")
+	e.completed = event
+}
+
+func (e *recordingEvents) RetryScheduled(ctx context.Context, event RetryScheduledEvent) {
+	e.retries = append(e.retries, event)
+}
+
+func (e *recordingEvents) CircuitOpened(ctx context.Context, event CircuitOpenedEvent) {
+	e.names = append(e.names, "CircuitOpened")
+	e.opened = event
+}
+
+func (e *recordingEvents) SubscriptionGone(ctx context.Context, event SubscriptionGoneEvent) {
+	e.names = append(e.names, "SubscriptionGone")
+	e.gone = event
+}
+
+func TestClientEvents(t *testing.T) {
+	events := &recordingEvents{}
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithEvents(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusGone}, nil
+	})
+	s := getStandardEncodedTestSubscription()
+	if _, err := client.Send(context.Background(), []byte("Test"), s, &Options{HTTPClient: httpClient}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(events.names, []string{"SendStarted", "SendCompleted", "SubscriptionGone"}) {
+		t.Fatalf("Incorrect events, got %v", events.names)
+	}
+
+	if events.completed.StatusCode != http.StatusGone || events.completed.Origin != "https://updates.push.services.mozilla.com" {
+		t.Fatalf("Incorrect SendCompleted event, got %+v", events.completed)
+	}
+
+	if events.gone.Subscription != s {
+		t.Fatal("Expected the gone subscription in the SubscriptionGone event")
+	}
+}
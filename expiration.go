@@ -0,0 +1,73 @@
+package webpush
+
+import (
+	"context"
+	"time"
+)
+
+// NudgedTag is the tag of subscriptions NudgeExpiring has sent a re-subscription nudge to
+const NudgedTag = "webpush:nudged"
+
+// ExpiringSubscriptionStore is a SubscriptionStore that can query subscriptions by expiration time
+// itself, instead of IterateExpiring reading every subscription
+type ExpiringSubscriptionStore interface {
+	SubscriptionStore
+
+	// IterateExpiring calls fn for every subscription expiring before before, see IterateByTag
+	IterateExpiring(ctx context.Context, before time.Time, fn func(*StoredSubscription) error) error
+}
+
+// IterateExpiring calls fn for every subscription of store with an expiration time before before,
+// including those already expired
+func IterateExpiring(ctx context.Context, store SubscriptionStore, before time.Time, fn func(*StoredSubscription) error) error {
+	if expiring, ok := store.(ExpiringSubscriptionStore); ok {
+		return expiring.IterateExpiring(ctx, before, fn)
+	}
+
+	return store.IterateByTag(ctx, "", func(s *StoredSubscription) error {
+		if expiration, ok := s.Expiration(); ok && expiration.Before(before) {
+			return fn(s)
+		}
+
+		return nil
+	})
+}
+
+// NudgeExpiring sends message, e.g. a "please refresh" notification to which the service worker
+// responds by subscribing again, to the stored subscriptions expiring within the next within.
+// Expired subscriptions and those nudged before are skipped; nudged subscriptions are tagged with NudgedTag.
+func (c *Client) NudgeExpiring(ctx context.Context, within time.Duration, message []byte, options *Options) ([]FanOutResult, error) {
+	if c.store == nil {
+		return nil, ErrNoSubscriptionStore
+	}
+
+	now := c.now()
+	var subscriptions []*StoredSubscription
+	err := IterateExpiring(ctx, c.store, now.Add(within), func(s *StoredSubscription) error {
+		if expiration, _ := s.Expiration(); expiration.After(now) && !s.HasTag(NudgedTag) {
+			subscriptions = append(subscriptions, s)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := c.fanOut(ctx, subscriptions, func(s *StoredSubscription) ([]byte, *Options, error) {
+		return message, options, nil
+	})
+
+	for _, result := range results {
+		if result.Err != nil || result.Result.Response.StatusCode >= 300 {
+			continue
+		}
+
+		result.Subscription.Tags = append(result.Subscription.Tags, NudgedTag)
+		if err := c.store.Save(ctx, result.Subscription); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
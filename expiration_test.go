@@ -0,0 +1,79 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNudgeExpiring(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemorySubscriptionStore()
+	for endpoint, expiresIn := range map[string]time.Duration{
+		"expired":  -time.Hour,
+		"soon":     time.Hour,
+		"later":    72 * time.Hour,
+		"never":    0,
+		"nudged":   2 * time.Hour,
+		"tomorrow": 20 * time.Hour,
+	} {
+		s := &StoredSubscription{Subscription: *getURLEncodedTestSubscription()}
+		s.Endpoint = "https://fcm.googleapis.com/fcm/send/" + endpoint
+		if expiresIn != 0 {
+			expiration := now.Add(expiresIn).UnixNano() / int64(time.Millisecond)
+			s.ExpirationTime = &expiration
+		}
+		if endpoint == "nudged" {
+			s.Tags = []string{NudgedTag}
+		}
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var expiring []string
+	err := IterateExpiring(ctx, store, now.Add(24*time.Hour), func(s *StoredSubscription) error {
+		expiring = append(expiring, s.Endpoint[len("https://fcm.googleapis.com/fcm/send/"):])
+		return nil
+	})
+	if err != nil || len(expiring) != 4 || expiring[0] != "expired" || expiring[1] != "nudged" || expiring[2] != "soon" || expiring[3] != "tomorrow" {
+		t.Fatalf("Incorrect expiring subscriptions, got %v (%v)", expiring, err)
+	}
+
+	var mu sync.Mutex
+	var sent []string
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, req.URL.Path)
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithSubscriptionStore(store),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.NudgeExpiring(ctx, 24*time.Hour, []byte(`{"type":"resubscribe"}`), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second run skips the subscriptions nudged by the first
+	if len(sent) != 2 {
+		t.Fatalf("Incorrect nudges, got %v", sent)
+	}
+
+	stored, err := store.Get(ctx, "https://fcm.googleapis.com/fcm/send/soon")
+	if err != nil || !stored.HasTag(NudgedTag) {
+		t.Fatalf("Expected the subscription tagged as nudged, got %+v (%v)", stored, err)
+	}
+}
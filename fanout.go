@@ -0,0 +1,191 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+)
+
+// FanOutConcurrency is the number of notifications SendToTags sends at once
+const FanOutConcurrency = 32
+
+// ErrNoSubscriptionStore is returned by SendToTags when the Client has no SubscriptionStore
+var ErrNoSubscriptionStore = errors.New("webpush: no subscription store configured")
+
+// PayloadFunc returns the message and Options of the notification to s, e.g. in the locale of s.
+// A nil Options sends with the Client configuration; an error skips s.
+type PayloadFunc func(s *StoredSubscription) ([]byte, *Options, error)
+
+// FanOutResult is the outcome of the notification to one subscription of a fan-out send.
+// The response body is already closed.
+type FanOutResult struct {
+	Subscription     *StoredSubscription
+	Result           *SendResult
+	Err              error
+	ReusedConnection bool // The request went over an already established connection
+}
+
+// FanOutReport summarizes the results of a fan-out send, in total and per push service origin
+type FanOutReport struct {
+	FanOutCounts
+	Origins map[string]*FanOutCounts
+}
+
+// FanOutCounts count the notifications of a fan-out send
+type FanOutCounts struct {
+	Notifications     int // Subscriptions of the send
+	Delivered         int // Notifications answered with a 2xx status
+	Failed            int // Notifications skipped, failed or rejected by the push service
+	Suppressed        int // Notifications not sent as duplicates of a recent one, not counted as Failed
+	ReusedConnections int // Requests sent over an already established connection
+}
+
+// add counts result into c
+func (c *FanOutCounts) add(result *FanOutResult) {
+	c.Notifications++
+	if result.Err == nil && result.Result != nil && result.Result.Response != nil &&
+		result.Result.Response.StatusCode >= 200 && result.Result.Response.StatusCode < 300 {
+		c.Delivered++
+	} else if errors.Is(result.Err, ErrDuplicateSuppressed) {
+		c.Suppressed++
+	} else {
+		c.Failed++
+	}
+
+	if result.ReusedConnection {
+		c.ReusedConnections++
+	}
+}
+
+// SummarizeFanOut counts the delivered and failed notifications of results and how many of their
+// requests reused a connection, per origin: a low reuse rate for an origin means its connections are
+// closed between sends, e.g. by an idle timeout shorter than the gaps of the batch
+func SummarizeFanOut(results []FanOutResult) *FanOutReport {
+	report := &FanOutReport{Origins: make(map[string]*FanOutCounts)}
+	for i := range results {
+		origin := EndpointOrigin(results[i].Subscription.Endpoint)
+		counts, ok := report.Origins[origin]
+		if !ok {
+			counts = &FanOutCounts{}
+			report.Origins[origin] = counts
+		}
+
+		counts.add(&results[i])
+		report.add(&results[i])
+	}
+
+	return report
+}
+
+// WithSubscriptionStore sets the SubscriptionStore SendToTags resolves subscriptions from
+func WithSubscriptionStore(store SubscriptionStore) ClientOption {
+	return func(c *Client) error {
+		c.store = store
+		return nil
+	}
+}
+
+// SendToTags sends a notification built by payload to every subscription stored under any of tags,
+// deduplicated with DeduplicateSubscriptions, FanOutConcurrency at a time. The results are in the order
+// the store returned the subscriptions; the returned error is only set when the store can't be read.
+func (c *Client) SendToTags(ctx context.Context, tags []string, payload PayloadFunc) ([]FanOutResult, error) {
+	if c.store == nil {
+		return nil, ErrNoSubscriptionStore
+	}
+
+	subscriptions, err := resolveTags(ctx, c.store, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.fanOut(ctx, subscriptions, payload), nil
+}
+
+// fanOut sends the notifications built by payload to subscriptions, FanOutConcurrency at a time and
+// in the interleaved origin bursts of originOrder
+func (c *Client) fanOut(ctx context.Context, subscriptions []*StoredSubscription, payload PayloadFunc) []FanOutResult {
+	job := newFanOutJob(len(subscriptions))
+	c.runFanOut(ctx, job, subscriptions, payload)
+
+	return job.results
+}
+
+// runFanOut sends the notifications of job like fanOut and closes its Done channel once they completed
+func (c *Client) runFanOut(ctx context.Context, job *FanOutJob, subscriptions []*StoredSubscription, payload PayloadFunc) {
+	defer close(job.done)
+
+	results := job.results
+	slots := make(chan struct{}, FanOutConcurrency)
+	var wg sync.WaitGroup
+	for _, i := range originOrder(subscriptions) {
+		s := subscriptions[i]
+		results[i].Subscription = s
+
+		if err := job.dispatch(ctx); err != nil {
+			results[i].Err = err
+			job.complete(&results[i])
+			continue
+		}
+
+		message, options, err := payload(s)
+		if err != nil {
+			results[i].Err = err
+			job.complete(&results[i])
+			continue
+		}
+
+		if err := acquire(ctx, slots); err != nil {
+			results[i].Err = err
+			job.complete(&results[i])
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *FanOutResult, message []byte, options *Options) {
+			defer wg.Done()
+			defer release(slots)
+			defer job.complete(result)
+
+			c.fanOutSend(ctx, result, message, options)
+		}(&results[i], message, options)
+	}
+	wg.Wait()
+}
+
+// fanOutSend sends message to the subscription of result, noting whether the request reused a
+// connection, and closes the response body
+func (c *Client) fanOutSend(ctx context.Context, result *FanOutResult, message []byte, options *Options) {
+	var reused int32
+	traced := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+		if info.Reused {
+			atomic.StoreInt32(&reused, 1)
+		}
+	}})
+
+	result.Result, result.Err = c.Deliver(traced, message, &result.Subscription.Subscription, options)
+	result.ReusedConnection = atomic.LoadInt32(&reused) == 1
+	if result.Result != nil && result.Result.Response != nil && result.Result.Response.Body != nil {
+		result.Result.Response.Body.Close()
+	}
+}
+
+// resolveTags returns the unique subscriptions of store stored under any of tags
+func resolveTags(ctx context.Context, store SubscriptionStore, tags []string) ([]*StoredSubscription, error) {
+	var resolved []*StoredSubscription
+	seen := newDeduplicator()
+	for _, tag := range tags {
+		err := store.IterateByTag(ctx, tag, func(s *StoredSubscription) error {
+			if seen.add(&s.Subscription) {
+				resolved = append(resolved, s)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,198 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrFanOutCanceled is the error of the notifications a canceled FanOutJob didn't send
+var ErrFanOutCanceled = errors.New("webpush: fan-out job canceled")
+
+// FanOutProgress is a snapshot of the progress of a FanOutJob
+type FanOutProgress struct {
+	FanOutCounts
+	Remaining int           // Notifications neither delivered nor failed yet
+	Elapsed   time.Duration // Time spent sending, excluding pauses
+	ETA       time.Duration // Estimated time left at the rate so far, zero until a notification completed
+	Paused    bool
+	Canceled  bool
+	Done      bool // Every notification completed, the results are available
+}
+
+// FanOutJob is a fan-out send running in the background, e.g. a campaign controlled from an admin UI.
+// Pausing or canceling it stops the dispatch of further notifications; the notifications in flight
+// complete either way, so a canceled job drains cleanly.
+type FanOutJob struct {
+	results  []FanOutResult
+	done     chan struct{}
+	canceled chan struct{}
+
+	mu        sync.Mutex
+	counts    FanOutCounts
+	started   time.Time
+	pausedAt  time.Time     // Zero unless paused
+	pausedFor time.Duration // Sum of the completed pauses
+	resume    chan struct{} // Closed by Resume, nil unless paused
+}
+
+// newFanOutJob returns a FanOutJob of n notifications
+func newFanOutJob(n int) *FanOutJob {
+	return &FanOutJob{
+		results:  make([]FanOutResult, n),
+		done:     make(chan struct{}),
+		canceled: make(chan struct{}),
+		started:  time.Now(),
+	}
+}
+
+// StartSendToTags starts SendToTags in the background and returns its FanOutJob. The subscriptions are
+// resolved before StartSendToTags returns; ctx bounds the whole job.
+func (c *Client) StartSendToTags(ctx context.Context, tags []string, payload PayloadFunc) (*FanOutJob, error) {
+	if c.store == nil {
+		return nil, ErrNoSubscriptionStore
+	}
+
+	subscriptions, err := resolveTags(ctx, c.store, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	job := newFanOutJob(len(subscriptions))
+	go c.runFanOut(ctx, job, subscriptions, payload)
+
+	return job, nil
+}
+
+// StartSendToSegment starts SendToSegment in the background and returns its FanOutJob, like StartSendToTags
+func (c *Client) StartSendToSegment(ctx context.Context, segment *Segment, payload PayloadFunc) (*FanOutJob, error) {
+	subscriptions, err := segment.Subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	job := newFanOutJob(len(subscriptions))
+	go c.runFanOut(ctx, job, subscriptions, payload)
+
+	return job, nil
+}
+
+// Progress returns the progress of j so far
+func (j *FanOutJob) Progress() FanOutProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	progress := FanOutProgress{
+		FanOutCounts: j.counts,
+		Remaining:    len(j.results) - j.counts.Delivered - j.counts.Failed - j.counts.Suppressed,
+		Elapsed:      now.Sub(j.started) - j.pausedFor,
+		Paused:       j.resume != nil,
+	}
+	progress.Notifications = len(j.results)
+	if progress.Paused {
+		progress.Elapsed -= now.Sub(j.pausedAt)
+	}
+
+	select {
+	case <-j.canceled:
+		progress.Canceled = true
+	default:
+	}
+
+	select {
+	case <-j.done:
+		progress.Done = true
+	default:
+	}
+
+	if completed := j.counts.Delivered + j.counts.Failed + j.counts.Suppressed; completed > 0 && progress.Remaining > 0 {
+		progress.ETA = progress.Elapsed * time.Duration(progress.Remaining) / time.Duration(completed)
+	}
+
+	return progress
+}
+
+// Pause stops the dispatch of notifications until Resume
+func (j *FanOutJob) Pause() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.resume == nil {
+		j.resume = make(chan struct{})
+		j.pausedAt = time.Now()
+	}
+}
+
+// Resume continues the dispatch of notifications after Pause
+func (j *FanOutJob) Resume() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.resume != nil {
+		close(j.resume)
+		j.resume = nil
+		j.pausedFor += time.Since(j.pausedAt)
+	}
+}
+
+// Cancel stops the dispatch of notifications for good, the notifications not sent yet fail with
+// ErrFanOutCanceled. Wait for Done to know when the notifications in flight completed.
+func (j *FanOutJob) Cancel() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	select {
+	case <-j.canceled:
+	default:
+		close(j.canceled)
+	}
+}
+
+// Done is closed once every notification of j completed
+func (j *FanOutJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for j to complete and returns its results, in the order of its subscriptions
+func (j *FanOutJob) Wait() []FanOutResult {
+	<-j.done
+	return j.results
+}
+
+// dispatch waits while j is paused and returns ErrFanOutCanceled once j is canceled or the error of
+// ctx once it is done
+func (j *FanOutJob) dispatch(ctx context.Context) error {
+	for {
+		j.mu.Lock()
+		resume := j.resume
+		j.mu.Unlock()
+
+		select {
+		case <-j.canceled:
+			return ErrFanOutCanceled
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if resume == nil {
+			return nil
+		}
+
+		select {
+		case <-resume:
+		case <-j.canceled:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// complete counts a completed notification
+func (j *FanOutJob) complete(result *FanOutResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.counts.add(result)
+}
@@ -0,0 +1,85 @@
+package webpush
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFanOutJob(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	for i := 0; i < 40; i++ {
+		s := &StoredSubscription{Subscription: *getURLEncodedTestSubscription()}
+		s.Endpoint = "https://fcm.googleapis.com/fcm/send/" + strconv.Itoa(i)
+		s.Keys.Auth = base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%016d", i)))
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The payload of the 10th and 20th notifications waits for the test to pause or cancel the job
+	hold, proceed := make(chan struct{}), make(chan struct{})
+	built := 0
+	job, err := client.StartSendToSegment(ctx, NewSegment(store), func(*StoredSubscription) ([]byte, *Options, error) {
+		built++
+		if built == 10 || built == 20 {
+			hold <- struct{}{}
+			<-proceed
+		}
+		return []byte("Hello"), &Options{TTL: 60}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-hold
+	job.Pause()
+	proceed <- struct{}{}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Progress().Delivered < 10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Paused job didn't deliver the dispatched notifications, got %+v", job.Progress())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	progress := job.Progress()
+	if progress.Notifications != 40 || progress.Delivered != 10 || progress.Remaining != 30 || !progress.Paused || progress.Done || progress.ETA <= 0 {
+		t.Fatalf("Incorrect progress of a paused job, got %+v", progress)
+	}
+
+	job.Resume()
+	<-hold
+	job.Cancel()
+	proceed <- struct{}{}
+
+	results := job.Wait()
+	canceled := 0
+	for _, result := range results {
+		if result.Err == ErrFanOutCanceled {
+			canceled++
+		}
+	}
+	if len(results) != 40 || canceled != 20 {
+		t.Fatalf("Expected 20 canceled notifications, got %d of %d", canceled, len(results))
+	}
+
+	progress = job.Progress()
+	if progress.Delivered != 20 || progress.Failed != 20 || progress.Remaining != 0 || !progress.Canceled || !progress.Done || progress.ETA != 0 {
+		t.Fatalf("Incorrect progress of a canceled job, got %+v", progress)
+	}
+}
@@ -0,0 +1,142 @@
+package webpush
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSendToTags(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+
+	subscription := func(endpoint, auth, locale string, tags ...string) *StoredSubscription {
+		s := &StoredSubscription{Subscription: *getURLEncodedTestSubscription(), Tags: tags, Locale: locale}
+		s.Endpoint = "https://fcm.googleapis.com/fcm/send/" + endpoint
+		s.Keys.Auth = auth
+		return s
+	}
+	for _, s := range []*StoredSubscription{
+		subscription("a", "aaaaaaaaaaaaaaaaaaaaaa", "en", "news"),
+		subscription("b", "bbbbbbbbbbbbbbbbbbbbbb", "de", "news", "sports"),
+		subscription("c", "cccccccccccccccccccccc", "de", "sports"),
+		subscription("d", "dddddddddddddddddddddd", "fr", "weather"),
+		subscription("e", "cccccccccccccccccccccc", "de", "sports"), // Same browser as c
+		subscription("f", "ffffffffffffffffffffff", "", "sports"),
+	} {
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	sent := make(map[string]bool)
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent[req.URL.Path] = true
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient), WithSubscriptionStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errNoLocale := errors.New("no locale")
+	results, err := client.SendToTags(ctx, []string{"news", "sports"}, func(s *StoredSubscription) ([]byte, *Options, error) {
+		if s.Locale == "" {
+			return nil, nil, errNoLocale
+		}
+		return []byte("Hello " + s.Locale), &Options{TTL: 60}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var endpoints []string
+	for _, result := range results {
+		endpoints = append(endpoints, result.Subscription.Endpoint[len("https://fcm.googleapis.com/fcm/send/"):])
+		if result.Subscription.Endpoint[len(result.Subscription.Endpoint)-1] == 'f' {
+			if result.Err != errNoLocale {
+				t.Fatalf("Expected the payload error, got %v", result.Err)
+			}
+		} else if result.Err != nil || result.Result.Response.StatusCode != http.StatusCreated {
+			t.Fatalf("Incorrect result, got %+v", result)
+		}
+	}
+
+	if len(endpoints) != 4 || endpoints[0] != "a" || endpoints[1] != "b" || endpoints[2] != "c" || endpoints[3] != "f" {
+		t.Fatalf("Incorrect subscriptions, got %v", endpoints)
+	}
+
+	if len(sent) != 3 || !sent["/fcm/send/a"] || !sent["/fcm/send/b"] || !sent["/fcm/send/c"] {
+		t.Fatalf("Incorrect requests, got %v", sent)
+	}
+}
+
+func TestSendToTagsWithoutStore(t *testing.T) {
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.SendToTags(context.Background(), []string{"news"}, nil); err != ErrNoSubscriptionStore {
+		t.Fatalf("Expected ErrNoSubscriptionStore, got %v", err)
+	}
+}
+
+func TestSummarizeFanOut(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/gone") {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	for i := 0; i < 2*FanOutConcurrency; i++ {
+		s := &StoredSubscription{Subscription: *getURLEncodedTestSubscription(), Tags: []string{"news"}}
+		s.Endpoint = server.URL + "/push/" + strconv.Itoa(i)
+		if i == 0 {
+			s.Endpoint = server.URL + "/push/gone"
+		}
+		s.Keys.Auth = base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("auth-%011d", i)))
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(server.Client()), WithSubscriptionStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := client.SendToTags(ctx, []string{"news"}, func(*StoredSubscription) ([]byte, *Options, error) {
+		return []byte("Hello"), &Options{TTL: 60}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := SummarizeFanOut(results)
+	origin := report.Origins[EndpointOrigin(server.URL)]
+	if report.Notifications != 2*FanOutConcurrency || report.Delivered != 2*FanOutConcurrency-1 || report.Failed != 1 ||
+		origin == nil || *origin != report.FanOutCounts {
+		t.Fatalf("Incorrect report, got %+v %+v", report, origin)
+	}
+
+	// The sends beyond FanOutConcurrency go over the connections of earlier ones
+	if report.ReusedConnections == 0 {
+		t.Fatalf("Expected connection reuse, got %d of %d", report.ReusedConnections, report.Notifications)
+	}
+}
@@ -0,0 +1,210 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFrequencyCapped is matched by errors.Is for every FrequencyCapError
+	ErrFrequencyCapped = errors.New("webpush: subscription frequency cap reached")
+
+	// ErrInvalidFrequencyCap is returned by WithFrequencyCaps for caps without a positive limit and window
+	// or with an invalid Urgency
+	ErrInvalidFrequencyCap = errors.New("webpush: frequency caps need a positive limit and window")
+)
+
+// FrequencyCap limits the notifications sent to one subscription, e.g. 5 low urgency notifications a day
+type FrequencyCap struct {
+	Urgency Urgency       // Only sends of this urgency count, every send when empty; sends without an urgency are normal
+	Limit   int           // Sends allowed within Window
+	Window  time.Duration // Sliding window of the limit
+}
+
+// FrequencyCapError is returned by Client.Send instead of sending a notification over a FrequencyCap
+type FrequencyCapError struct {
+	Cap     FrequencyCap
+	RetryAt time.Time // When the cap allows the next send
+}
+
+func (e *FrequencyCapError) Error() string {
+	return fmt.Sprintf("%s: %d sends in %s, retry at %s", ErrFrequencyCapped.Error(), e.Cap.Limit, e.Cap.Window,
+		e.RetryAt.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrFrequencyCapped
+func (e *FrequencyCapError) Is(target error) bool {
+	return target == ErrFrequencyCapped
+}
+
+// FrequencySend is a send recorded by a FrequencyTracker
+type FrequencySend struct {
+	Time    time.Time
+	Urgency Urgency
+}
+
+// FrequencyTracker records the recent sends to every subscription. Implementations backed by the
+// database of the application share the caps between several senders; they must be safe for concurrent use.
+type FrequencyTracker interface {
+	// Sends returns the sends to endpoint at or after since, older sends can be forgotten
+	Sends(ctx context.Context, endpoint string, since time.Time) ([]FrequencySend, error)
+
+	// Record adds a send to endpoint
+	Record(ctx context.Context, endpoint string, send FrequencySend) error
+}
+
+// frequencyLimiter enforces the frequency caps of a Client
+type frequencyLimiter struct {
+	caps    []FrequencyCap
+	window  time.Duration // Longest window of the caps
+	tracker FrequencyTracker
+}
+
+// WithFrequencyCaps refuses to send a notification over any of caps with a FrequencyCapError.
+// Sends are counted when they are attempted, whatever their outcome, and tracked in memory unless
+// tracker is set. Concurrent sends to the same subscription can exceed a cap slightly.
+func WithFrequencyCaps(tracker FrequencyTracker, caps ...FrequencyCap) ClientOption {
+	return func(c *Client) error {
+		if len(caps) == 0 {
+			c.frequency = nil
+			return nil
+		}
+
+		limiter := &frequencyLimiter{caps: caps, tracker: tracker}
+		for _, rule := range caps {
+			if rule.Limit <= 0 || rule.Window <= 0 || (rule.Urgency != "" && !isValidUrgency(rule.Urgency)) {
+				return ErrInvalidFrequencyCap
+			}
+
+			if rule.Window > limiter.window {
+				limiter.window = rule.Window
+			}
+		}
+
+		if limiter.tracker == nil {
+			limiter.tracker = NewMemoryFrequencyTracker()
+		}
+
+		if memory, ok := limiter.tracker.(*MemoryFrequencyTracker); ok {
+			memory.keep(limiter.window)
+		}
+
+		c.frequency = limiter
+		return nil
+	}
+}
+
+// allow records a send to endpoint with urgency at now, or returns a FrequencyCapError when a cap is reached
+func (l *frequencyLimiter) allow(ctx context.Context, endpoint string, urgency Urgency, now time.Time) error {
+	if urgency == "" {
+		urgency = UrgencyNormal
+	}
+
+	sends, err := l.tracker.Sends(ctx, endpoint, now.Add(-l.window))
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range l.caps {
+		if rule.Urgency != "" && rule.Urgency != urgency {
+			continue
+		}
+
+		// Sends within the window of the cap, oldest first
+		var counted []time.Time
+		since := now.Add(-rule.Window)
+		for _, send := range sends {
+			if (rule.Urgency == "" || send.Urgency == rule.Urgency) && send.Time.After(since) {
+				counted = append(counted, send.Time)
+			}
+		}
+
+		if len(counted) >= rule.Limit {
+			return &FrequencyCapError{Cap: rule, RetryAt: counted[len(counted)-rule.Limit].Add(rule.Window)}
+		}
+	}
+
+	return l.tracker.Record(ctx, endpoint, FrequencySend{Time: now, Urgency: urgency})
+}
+
+// MemoryFrequencyTracker is an in-memory FrequencyTracker. It forgets the sends to a subscription
+// when they are queried after the longest window, and the sends to every subscription once per longest
+// window of the Clients using it.
+type MemoryFrequencyTracker struct {
+	mu     sync.Mutex
+	sends  map[string][]FrequencySend
+	window time.Duration // Longest window of the caps using the tracker, sends are only forgotten when queried when zero
+	swept  time.Time     // Time of the send that last forgot the old sends of every subscription
+}
+
+// NewMemoryFrequencyTracker returns an empty MemoryFrequencyTracker
+func NewMemoryFrequencyTracker() *MemoryFrequencyTracker {
+	return &MemoryFrequencyTracker{sends: make(map[string][]FrequencySend)}
+}
+
+// keep makes the tracker keep the sends within window at least
+func (m *MemoryFrequencyTracker) keep(window time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if window > m.window {
+		m.window = window
+	}
+}
+
+// Sends implements FrequencyTracker
+func (m *MemoryFrequencyTracker) Sends(ctx context.Context, endpoint string, since time.Time) ([]FrequencySend, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sends := trimFrequencySends(m.sends[endpoint], since)
+	if len(sends) == 0 {
+		delete(m.sends, endpoint)
+		return nil, nil
+	}
+
+	m.sends[endpoint] = sends
+	return append([]FrequencySend(nil), sends...), nil
+}
+
+// Record implements FrequencyTracker
+func (m *MemoryFrequencyTracker) Record(ctx context.Context, endpoint string, send FrequencySend) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sends[endpoint] = append(m.sends[endpoint], send)
+
+	// Forget the sends to subscriptions that aren't queried anymore
+	if m.window > 0 && send.Time.Sub(m.swept) >= m.window {
+		since := send.Time.Add(-m.window)
+		for endpoint, sends := range m.sends {
+			if sends = trimFrequencySends(sends, since); len(sends) == 0 {
+				delete(m.sends, endpoint)
+			} else {
+				m.sends[endpoint] = sends
+			}
+		}
+		m.swept = send.Time
+	}
+
+	return nil
+}
+
+// trimFrequencySends removes the sends before since, oldest first, copying the rest down so the
+// backing array doesn't keep them
+func trimFrequencySends(sends []FrequencySend, since time.Time) []FrequencySend {
+	i := 0
+	for i < len(sends) && sends[i].Time.Before(since) {
+		i++
+	}
+
+	if i == 0 {
+		return sends
+	}
+
+	n := copy(sends, sends[i:])
+	return sends[:n]
+}
@@ -0,0 +1,105 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWithFrequencyCaps(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	requests := 0
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithClock(func() time.Time { return now }),
+		WithFrequencyCaps(nil,
+			FrequencyCap{Urgency: UrgencyLow, Limit: 2, Window: 24 * time.Hour},
+			FrequencyCap{Limit: 3, Window: time.Hour},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(urgency Urgency) error {
+		_, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{Urgency: urgency})
+		return err
+	}
+
+	for _, urgency := range []Urgency{UrgencyLow, UrgencyLow} {
+		if err := send(urgency); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	var capped *FrequencyCapError
+	if err := send(UrgencyLow); !errors.Is(err, ErrFrequencyCapped) || !errors.As(err, &capped) ||
+		!capped.RetryAt.Equal(time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the low urgency cap, got %v", err)
+	}
+
+	// Other urgencies only count towards the cap of every send
+	if err := send(UrgencyHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(""); !errors.Is(err, ErrFrequencyCapped) {
+		t.Fatalf("Expected the hourly cap, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := send(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 4 {
+		t.Fatalf("Incorrect requests, got %d", requests)
+	}
+}
+
+func TestWithFrequencyCapsInvalid(t *testing.T) {
+	for _, rule := range []FrequencyCap{{Window: time.Hour}, {Limit: 1}, {Limit: 1, Window: time.Hour, Urgency: "urgent"}} {
+		if _, err := NewClient(WithFrequencyCaps(nil, rule)); err != ErrInvalidFrequencyCap {
+			t.Errorf("Expected ErrInvalidFrequencyCap for %+v, got %v", rule, err)
+		}
+	}
+}
+
+func TestMemoryFrequencyTrackerForgetsOldSends(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewMemoryFrequencyTracker()
+	if _, err := NewClient(WithFrequencyCaps(tracker, FrequencyCap{Limit: 1, Window: time.Minute}, FrequencyCap{Limit: 5, Window: time.Hour})); err != nil {
+		t.Fatal(err)
+	}
+
+	if tracker.window != time.Hour {
+		t.Fatalf("Incorrect window, expected=%s, got=%s", time.Hour, tracker.window)
+	}
+
+	// The sends to subscriptions never queried again are forgotten after the longest window
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		tracker.Record(ctx, "https://push.example.com/"+strconv.Itoa(i), FrequencySend{Time: now})
+	}
+	tracker.Record(ctx, "https://push.example.com/active", FrequencySend{Time: now.Add(30 * time.Minute)})
+	tracker.Record(ctx, "https://push.example.com/active", FrequencySend{Time: now.Add(90 * time.Minute)})
+
+	if len(tracker.sends) != 1 || len(tracker.sends["https://push.example.com/active"]) != 2 {
+		t.Fatalf("Old sends were kept, subscriptions=%d", len(tracker.sends))
+	}
+
+	// Trimming copies the remaining sends down
+	sends, _ := tracker.Sends(ctx, "https://push.example.com/active", now.Add(time.Hour))
+	kept := tracker.sends["https://push.example.com/active"]
+	if len(sends) != 1 || len(kept) != 1 || cap(kept) < 2 || !kept[0].Time.Equal(now.Add(90*time.Minute)) {
+		t.Fatalf("Incorrect sends, got %+v kept %+v", sends, kept)
+	}
+}
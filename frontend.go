@@ -0,0 +1,51 @@
+package webpush
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// ApplicationServerKey decodes a VAPID public key into the 65 byte uncompressed point
+// expected as applicationServerKey by PushManager.subscribe()
+func ApplicationServerKey(publicKey string) ([]byte, error) {
+	decoded, err := decodeVapidKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateVAPIDPublicKey(decoded); err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// ApplicationServerKeyBase64 returns the VAPID public key in the unpadded base64 URL form
+// that PushManager.subscribe() accepts as a string
+func ApplicationServerKeyBase64(publicKey string) (string, error) {
+	key, err := ApplicationServerKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// ApplicationServerKeyJS returns a JavaScript statement declaring the VAPID public key as a
+// Uint8Array, to be rendered into the page or service worker calling PushManager.subscribe():
+//
+//	const applicationServerKey = new Uint8Array([4, 23, ...]);
+func ApplicationServerKeyJS(publicKey string) (string, error) {
+	key, err := ApplicationServerKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]string, len(key))
+	for i, b := range key {
+		values[i] = strconv.Itoa(int(b))
+	}
+
+	return "const applicationServerKey = new Uint8Array([" + strings.Join(values, ", ") + "]);", nil
+}
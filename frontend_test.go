@@ -0,0 +1,41 @@
+package webpush
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplicationServerKey(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	key, err := ApplicationServerKey(keys.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(key) != 65 || key[0] != 4 {
+		t.Fatalf("Incorrect applicationServerKey, got %d bytes", len(key))
+	}
+
+	encoded, err := ApplicationServerKeyBase64(keys.PublicKey + "=")
+	if err != nil || encoded != keys.PublicKey {
+		t.Fatalf("Incorrect base64 form, expected=%s, got=%s (%v)", keys.PublicKey, encoded, err)
+	}
+
+	js, err := ApplicationServerKeyJS(keys.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(js, "const applicationServerKey = new Uint8Array([4, ") || !strings.HasSuffix(js, "]);") {
+		t.Fatalf("Incorrect JS snippet, got %s", js)
+	}
+
+	if strings.Count(js, ",") != 64 {
+		t.Fatalf("JS snippet does not contain 65 values, got %s", js)
+	}
+
+	if _, err := ApplicationServerKey(keys.PrivateKey); err != ErrInvalidVAPIDPublicKey {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidVAPIDPublicKey, err)
+	}
+}
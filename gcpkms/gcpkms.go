@@ -0,0 +1,122 @@
+// Package gcpkms signs VAPID JWTs with a Google Cloud KMS EC_SIGN_P256_SHA256 key,
+// so the VAPID private key never has to be distributed to the senders.
+//
+// The Signer implements crypto.Signer and is passed as webpush.Options.VAPIDSigner.
+// Cloud KMS returns ASN.1 DER signatures, which webpush converts to the JOSE r || s form,
+// and signed Authorization headers are cached per audience.
+package gcpkms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"time"
+)
+
+// DefaultTimeout bounds a single Cloud KMS AsymmetricSign call
+const DefaultTimeout = 10 * time.Second
+
+var (
+	// ErrUnsupportedKey is returned when the key version is not an EC_SIGN_P256_SHA256 key
+	ErrUnsupportedKey = errors.New("gcpkms: key must be an EC_SIGN_P256_SHA256 key")
+
+	// ErrInvalidPEM is returned when the public key returned by Cloud KMS is not PEM encoded
+	ErrInvalidPEM = errors.New("gcpkms: public key is not a PEM encoded PUBLIC KEY")
+)
+
+// API is the subset of Cloud KMS used by Signer.
+// It is satisfied by a thin wrapper around cloud.google.com/go/kms/apiv1:
+//
+//	type kmsAPI struct{ client *kms.KeyManagementClient }
+//
+//	func (a kmsAPI) AsymmetricSign(ctx context.Context, name string, digest []byte) ([]byte, error) {
+//		resp, err := a.client.AsymmetricSign(ctx, &kmspb.AsymmetricSignRequest{
+//			Name:   name,
+//			Digest: &kmspb.Digest{Digest: &kmspb.Digest_Sha256{Sha256: digest}},
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return resp.Signature, nil
+//	}
+//
+//	func (a kmsAPI) GetPublicKey(ctx context.Context, name string) (string, error) {
+//		resp, err := a.client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: name})
+//		if err != nil {
+//			return "", err
+//		}
+//		return resp.Pem, nil
+//	}
+type API interface {
+	// AsymmetricSign signs a SHA-256 digest and returns the DER encoded signature
+	AsymmetricSign(ctx context.Context, name string, digest []byte) ([]byte, error)
+	// GetPublicKey returns the PEM encoded public key of the key version
+	GetPublicKey(ctx context.Context, name string) (string, error)
+}
+
+// Signer is a crypto.Signer backed by a Cloud KMS key version
+type Signer struct {
+	api       API
+	name      string
+	publicKey *ecdsa.PublicKey
+
+	// Timeout bounds each Sign call, defaults to DefaultTimeout
+	Timeout time.Duration
+}
+
+// New fetches the public key of the key version name
+// (projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*) and returns a Signer for it
+func New(ctx context.Context, api API, name string) (*Signer, error) {
+	publicKeyPEM, err := api.GetPublicKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrInvalidPEM
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok || publicKey.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedKey
+	}
+
+	return &Signer{
+		api:       api,
+		name:      name,
+		publicKey: publicKey,
+		Timeout:   DefaultTimeout,
+	}, nil
+}
+
+// Public returns the public key of the key version
+func (s *Signer) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs digest in Cloud KMS; rand is unused as Cloud KMS supplies its own entropy
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.SHA256 {
+		return nil, errors.New("gcpkms: only SHA-256 digests are supported")
+	}
+
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	return s.api.AsymmetricSign(ctx, s.name, digest)
+}
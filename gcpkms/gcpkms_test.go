@@ -0,0 +1,77 @@
+package gcpkms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+const testKeyName = "projects/p/locations/global/keyRings/r/cryptoKeys/vapid/cryptoKeyVersions/1"
+
+type fakeKMS struct {
+	key *ecdsa.PrivateKey
+	pem string
+}
+
+func (f *fakeKMS) AsymmetricSign(ctx context.Context, name string, digest []byte) ([]byte, error) {
+	return ecdsa.SignASN1(rand.Reader, f.key, digest)
+}
+
+func (f *fakeKMS) GetPublicKey(ctx context.Context, name string) (string, error) {
+	if f.pem != "" {
+		return f.pem, nil
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func TestSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := New(context.Background(), &fakeKMS{key: key}, testKeyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !key.PublicKey.Equal(signer.Public()) {
+		t.Fatal("Signer public key does not match the KMS key")
+	}
+
+	digest := sha256.Sum256([]byte("test"))
+	signature, err := signer.Sign(nil, digest[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], signature) {
+		t.Fatal("Signature did not verify")
+	}
+}
+
+func TestNewRejectsInvalidPublicKeys(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(context.Background(), &fakeKMS{key: key}, testKeyName); err != ErrUnsupportedKey {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrUnsupportedKey, err)
+	}
+
+	if _, err := New(context.Background(), &fakeKMS{key: key, pem: "garbage"}, testKeyName); err != ErrInvalidPEM {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidPEM, err)
+	}
+}
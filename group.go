@@ -0,0 +1,114 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+)
+
+// GroupTagPrefix prefixes the tags of the subscriptions of a group
+const GroupTagPrefix = "group:"
+
+// ErrInvalidGroup is returned by WithGroups for groups without a name or with an invalid Urgency
+var ErrInvalidGroup = errors.New("webpush: groups need a name and a valid urgency")
+
+// Group is a named group of subscriptions, e.g. "news" or "alerts", with the defaults of its broadcasts
+type Group struct {
+	Name    string
+	TTL     int     // TTL of broadcasts that don't set one
+	Urgency Urgency // Urgency of broadcasts that don't set one (Optional)
+	Topic   string  // Topic of broadcasts that don't set one (Optional)
+}
+
+// GroupTag returns the tag of the subscriptions of group name in a SubscriptionStore
+func GroupTag(name string) string {
+	return GroupTagPrefix + name
+}
+
+// WithGroups configures the defaults of the broadcasts to groups
+func WithGroups(groups ...Group) ClientOption {
+	return func(c *Client) error {
+		configured := make(map[string]Group, len(groups))
+		for _, group := range groups {
+			if group.Name == "" || (group.Urgency != "" && !isValidUrgency(group.Urgency)) {
+				return ErrInvalidGroup
+			}
+			configured[group.Name] = group
+		}
+
+		c.groups = configured
+		return nil
+	}
+}
+
+// JoinGroup adds the stored subscription of endpoint to group name. The subscription is read and
+// saved again, so concurrent changes to the same subscription can be lost.
+func (c *Client) JoinGroup(ctx context.Context, endpoint, name string) error {
+	return c.updateGroups(ctx, endpoint, func(s *StoredSubscription) bool {
+		if s.HasTag(GroupTag(name)) {
+			return false
+		}
+
+		s.Tags = append(s.Tags, GroupTag(name))
+		return true
+	})
+}
+
+// LeaveGroup removes the stored subscription of endpoint from group name, see JoinGroup
+func (c *Client) LeaveGroup(ctx context.Context, endpoint, name string) error {
+	return c.updateGroups(ctx, endpoint, func(s *StoredSubscription) bool {
+		tags := s.Tags[:0]
+		for _, tag := range s.Tags {
+			if tag != GroupTag(name) {
+				tags = append(tags, tag)
+			}
+		}
+
+		changed := len(tags) != len(s.Tags)
+		s.Tags = tags
+		return changed
+	})
+}
+
+// updateGroups saves the stored subscription of endpoint when update changed it
+func (c *Client) updateGroups(ctx context.Context, endpoint string, update func(*StoredSubscription) bool) error {
+	if c.store == nil {
+		return ErrNoSubscriptionStore
+	}
+
+	s, err := c.store.Get(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	if !update(s) {
+		return nil
+	}
+
+	return c.store.Save(ctx, s)
+}
+
+// Broadcast sends message to every subscription of group name with SendToTags. The TTL, Urgency and
+// Topic left unset in options default to those of the group configured with WithGroups.
+func (c *Client) Broadcast(ctx context.Context, name string, message []byte, options *Options) ([]FanOutResult, error) {
+	opts := Options{}
+	if options != nil {
+		opts = *options
+	}
+
+	if group, ok := c.groups[name]; ok {
+		if opts.TTL == 0 {
+			opts.TTL = group.TTL
+		}
+		if opts.Urgency == "" {
+			opts.Urgency = group.Urgency
+		}
+		if opts.Topic == "" {
+			opts.Topic = group.Topic
+		}
+	}
+
+	return c.SendToTags(ctx, []string{GroupTag(name)}, func(s *StoredSubscription) ([]byte, *Options, error) {
+		perSubscription := opts
+		return message, &perSubscription, nil
+	})
+}
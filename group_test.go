@@ -0,0 +1,74 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestBroadcast(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	for _, endpoint := range []string{"a", "b"} {
+		s := &StoredSubscription{Subscription: *getURLEncodedTestSubscription()}
+		s.Endpoint = "https://fcm.googleapis.com/fcm/send/" + endpoint
+		s.Keys.Auth = endpoint + "aaaaaaaaaaaaaaaaaaaaa"
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	headers := make(map[string]http.Header)
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		headers[req.URL.Path] = req.Header
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithSubscriptionStore(store),
+		WithGroups(Group{Name: "alerts", TTL: 300, Urgency: UrgencyHigh, Topic: "alert"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"alerts", "news"} {
+		if err := client.JoinGroup(ctx, "https://fcm.googleapis.com/fcm/send/a", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.JoinGroup(ctx, "https://fcm.googleapis.com/fcm/send/b", "alerts"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.LeaveGroup(ctx, "https://fcm.googleapis.com/fcm/send/b", "alerts"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := client.Broadcast(ctx, "alerts", []byte("Test"), &Options{Topic: "override"})
+	if err != nil || len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Incorrect results, got %+v (%v)", results, err)
+	}
+
+	header := headers["/fcm/send/a"]
+	if len(headers) != 1 || header.Get("TTL") != "300" || header.Get("Urgency") != "high" || header.Get("Topic") != "override" {
+		t.Fatalf("Incorrect requests, got %v", headers)
+	}
+
+	stored, err := store.Get(ctx, "https://fcm.googleapis.com/fcm/send/a")
+	if err != nil || !stored.HasTag(GroupTag("news")) {
+		t.Fatalf("Expected the subscription in the news group, got %+v (%v)", stored, err)
+	}
+}
+
+func TestWithGroupsInvalid(t *testing.T) {
+	for _, group := range []Group{{}, {Name: "alerts", Urgency: "urgent"}} {
+		if _, err := NewClient(WithGroups(group)); err != ErrInvalidGroup {
+			t.Errorf("Expected ErrInvalidGroup for %+v, got %v", group, err)
+		}
+	}
+}
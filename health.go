@@ -0,0 +1,81 @@
+package webpush
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// OriginHealth is the result of probing a push service origin with CheckOrigins
+type OriginHealth struct {
+	Origin     string         // Normalized origin, e.g. https://fcm.googleapis.com
+	Healthy    bool           // The origin answered without a 5xx status
+	StatusCode int            // Status of the probe response, zero without a response
+	Latency    time.Duration  // Duration of the probe, from getting a connection to the response headers
+	Timings    RequestTimings // DNS, connect, TLS handshake and first byte timings of the probe
+	Err        error          // Why the probe failed
+}
+
+// CheckOrigins probes every push service origin with a HEAD request over the transport of the Client,
+// for readiness checks before a campaign and alerting. Origins are probed concurrently and the results
+// are returned in the order of origins. Any response but a 5xx one counts as healthy, push services
+// answer HEAD / with various client errors. Cancel ctx to bound the duration of the probes.
+func (c *Client) CheckOrigins(ctx context.Context, origins []string) []OriginHealth {
+	client := c.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	results := make([]OriginHealth, len(origins))
+	var wg sync.WaitGroup
+	for i, origin := range origins {
+		normalized, err := normalizeAudience(origin, c.allowInsecure)
+		if err != nil {
+			results[i] = OriginHealth{Origin: origin, Err: err}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, origin string) {
+			defer wg.Done()
+			results[i] = checkOrigin(ctx, client, origin)
+		}(i, normalized)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// checkOrigin probes origin with a HEAD request sent with client
+func checkOrigin(ctx context.Context, client HTTPClient, origin string) OriginHealth {
+	health := OriginHealth{Origin: origin}
+
+	req, err := http.NewRequest(http.MethodHead, origin+"/", nil)
+	if err != nil {
+		health.Err = err
+		return health
+	}
+
+	tracer := &requestTracer{}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, tracer.clientTrace()))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	health.Latency = time.Since(start)
+	health.Timings = *tracer.timings()
+	if err != nil {
+		health.Err = err
+		return health
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	health.StatusCode = resp.StatusCode
+	health.Healthy = resp.StatusCode < 500
+	return health
+}
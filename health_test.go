@@ -0,0 +1,42 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCheckOrigins(t *testing.T) {
+	healthy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer healthy.Close()
+
+	failing := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	client, err := NewClient(WithHTTPClient(healthy.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := client.CheckOrigins(context.Background(), []string{healthy.URL, failing.URL, "http://insecure.example"})
+	if len(results) != 3 {
+		t.Fatalf("Incorrect results, got %+v", results)
+	}
+
+	if !results[0].Healthy || results[0].StatusCode != http.StatusNotFound || results[0].Timings.TLSHandshake <= 0 || results[0].Latency <= 0 {
+		t.Fatalf("Expected a healthy origin with TLS timings, got %+v", results[0])
+	}
+
+	if results[1].Healthy || results[1].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected an unhealthy origin, got %+v", results[1])
+	}
+
+	if results[2].Healthy || results[2].Err == nil {
+		t.Fatalf("Expected an insecure origin error, got %+v", results[2])
+	}
+}
@@ -0,0 +1,25 @@
+package webpush
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// hkdfSHA256 derives keys with HKDF-SHA256 (RFC 5869) from a pseudorandom key extracted once, instead
+// of extracting it again for every key
+type hkdfSHA256 struct {
+	prk []byte // Pseudorandom key
+}
+
+// newHKDF extracts the pseudorandom key of secret with salt
+func newHKDF(salt, secret []byte) hkdfSHA256 {
+	return hkdfSHA256{prk: hkdf.Extract(sha256.New, secret, salt)}
+}
+
+// expand fills key with the output of HKDF-Expand with info
+func (h hkdfSHA256) expand(key, info []byte) error {
+	_, err := io.ReadFull(hkdf.Expand(sha256.New, h.prk, info), key)
+	return err
+}
@@ -0,0 +1,50 @@
+package webpush
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHKDF(t *testing.T) {
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// The SHA-256 test cases of RFC 5869 appendix A
+	for i, test := range []struct {
+		ikm, salt, info, prk, okm string
+	}{
+		{
+			ikm:  "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+			salt: "000102030405060708090a0b0c",
+			info: "f0f1f2f3f4f5f6f7f8f9",
+			prk:  "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
+			okm:  "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+		},
+		{
+			ikm:  "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+			salt: "",
+			info: "",
+			prk:  "19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
+			okm:  "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8",
+		},
+	} {
+		keys := newHKDF(decode(test.salt), decode(test.ikm))
+		if !bytes.Equal(keys.prk, decode(test.prk)) {
+			t.Errorf("Case %d: incorrect PRK, got %x", i+1, keys.prk)
+		}
+
+		okm := make([]byte, len(test.okm)/2)
+		if err := keys.expand(okm, decode(test.info)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(okm, decode(test.okm)) {
+			t.Errorf("Case %d: incorrect OKM, got %x", i+1, okm)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package webpush
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KeyInspection describes a VAPID key pair
+type KeyInspection struct {
+	PublicKey     string   `json:"publicKey"`   // Unpadded base64 URL, the applicationServerKey of the browser
+	Fingerprint   string   `json:"fingerprint"` // VAPIDKeyFingerprint of the public key
+	HasPrivateKey bool     `json:"hasPrivateKey"`
+	PairValid     bool     `json:"pairValid"` // The private key derives the public key
+	Problems      []string `json:"problems,omitempty"`
+}
+
+// SubscriptionInspection describes a subscription, without its full endpoint or keys
+type SubscriptionInspection struct {
+	EndpointHost   string            `json:"endpointHost"`
+	EndpointHash   string            `json:"endpointHash"` // EndpointHash of the endpoint, to find it in logs and stores
+	PushService    PushService       `json:"pushService"`
+	Encodings      []ContentEncoding `json:"encodings"`      // Content encodings the push service accepts
+	ExpirationTime *time.Time        `json:"expirationTime"` // Nil when the subscription doesn't expire
+	Expired        bool              `json:"expired"`
+
+	// ApplicationServerKeyFingerprint is the VAPIDKeyFingerprint of the key the subscription was created with,
+	// and KeyMatches whether it is the inspected public key; both are empty when the subscription doesn't say
+	ApplicationServerKeyFingerprint string `json:"applicationServerKeyFingerprint,omitempty"`
+	KeyMatches                      *bool  `json:"keyMatches,omitempty"`
+
+	Problems []string `json:"problems,omitempty"` // Reported by Subscription.Validate
+}
+
+// Inspection is the report of Inspect
+type Inspection struct {
+	Keys         *KeyInspection          `json:"keys,omitempty"`
+	Subscription *SubscriptionInspection `json:"subscription,omitempty"`
+}
+
+// Inspect reports what support needs to know when notifications fail: the fingerprint and
+// consistency of keys, and the push service, accepted encodings, expiration and problems of s.
+// Keys without a public key or a nil s are left out of the report.
+func Inspect(keys VAPIDKeys, s *Subscription, allowedHosts ...string) Inspection {
+	var inspection Inspection
+	if keys.PublicKey != "" || keys.PrivateKey != "" {
+		inspection.Keys = inspectKeys(keys)
+	}
+
+	if s != nil {
+		inspection.Subscription = inspectSubscription(s, allowedHosts, time.Now())
+		if inspection.Keys != nil && inspection.Keys.Fingerprint != "" && s.ApplicationServerKey != "" {
+			matches := inspection.Subscription.ApplicationServerKeyFingerprint == inspection.Keys.Fingerprint
+			inspection.Subscription.KeyMatches = &matches
+		}
+	}
+
+	return inspection
+}
+
+// inspectKeys describes keys, deriving a missing public key from the private key
+func inspectKeys(keys VAPIDKeys) *KeyInspection {
+	inspection := &KeyInspection{HasPrivateKey: keys.PrivateKey != ""}
+	if keys.PublicKey == "" {
+		derived, err := VAPIDPublicKeyFromPrivate(keys.PrivateKey)
+		if err != nil {
+			inspection.Problems = append(inspection.Problems, err.Error())
+			return inspection
+		}
+		keys.PublicKey = derived
+	}
+
+	publicKey, err := ApplicationServerKeyBase64(keys.PublicKey)
+	if err != nil {
+		inspection.Problems = append(inspection.Problems, err.Error())
+		return inspection
+	}
+	inspection.PublicKey = publicKey
+	inspection.Fingerprint = keys.Fingerprint()
+
+	if inspection.HasPrivateKey {
+		if err := keys.Validate(); err != nil {
+			inspection.Problems = append(inspection.Problems, err.Error())
+		} else {
+			inspection.PairValid = true
+		}
+	}
+
+	return inspection
+}
+
+// inspectSubscription describes s as of now
+func inspectSubscription(s *Subscription, allowedHosts []string, now time.Time) *SubscriptionInspection {
+	inspection := &SubscriptionInspection{
+		EndpointHash: EndpointHash(s.Endpoint),
+		PushService:  DetectPushService(s.Endpoint),
+	}
+	if u, err := url.Parse(s.Endpoint); err == nil {
+		inspection.EndpointHost = strings.ToLower(u.Hostname())
+	}
+
+	switch inspection.PushService {
+	case PushServiceFCM, PushServiceMozilla:
+		inspection.Encodings = []ContentEncoding{ContentEncodingAES128GCM, ContentEncodingAESGCM}
+	default:
+		inspection.Encodings = []ContentEncoding{ContentEncodingAES128GCM}
+	}
+
+	if expiration, ok := s.Expiration(); ok {
+		inspection.ExpirationTime = &expiration
+		inspection.Expired = !now.Before(expiration)
+	}
+
+	if s.ApplicationServerKey != "" {
+		inspection.ApplicationServerKeyFingerprint, _ = VAPIDKeyFingerprint(s.ApplicationServerKey)
+	}
+
+	if problems, ok := s.Validate(allowedHosts...).(SubscriptionErrors); ok {
+		for _, problem := range problems {
+			inspection.Problems = append(inspection.Problems, problem.Error())
+		}
+	}
+
+	return inspection
+}
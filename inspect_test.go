@@ -0,0 +1,46 @@
+package webpush
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	privateKey, publicKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPublicKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getURLEncodedTestSubscription()
+	s.ApplicationServerKey = publicKey
+	expiration := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	s.ExpirationTime = &expiration
+
+	inspection := Inspect(VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}, s)
+	keys := inspection.Keys
+	if keys == nil || !keys.PairValid || keys.Fingerprint != (VAPIDKeys{PublicKey: publicKey}).Fingerprint() || len(keys.Problems) != 0 {
+		t.Fatalf("Incorrect key inspection, got %+v", keys)
+	}
+
+	sub := inspection.Subscription
+	if sub == nil || sub.PushService != PushServiceMozilla || sub.EndpointHost != "updates.push.services.mozilla.com" ||
+		len(sub.Encodings) != 2 || !sub.Expired || sub.KeyMatches == nil || !*sub.KeyMatches || len(sub.Problems) != 1 {
+		t.Fatalf("Incorrect subscription inspection, got %+v", sub)
+	}
+
+	// A transposed pair and a subscription of another key
+	inspection = Inspect(VAPIDKeys{PrivateKey: privateKey, PublicKey: otherPublicKey}, s)
+	if inspection.Keys.PairValid || len(inspection.Keys.Problems) != 1 || *inspection.Subscription.KeyMatches {
+		t.Fatalf("Expected a mismatched pair and subscription, got %+v %+v", inspection.Keys, inspection.Subscription)
+	}
+
+	// The public key is derived from a lone private key
+	inspection = Inspect(VAPIDKeys{PrivateKey: privateKey}, nil)
+	if inspection.Keys.PublicKey != publicKey || !inspection.Keys.PairValid || inspection.Subscription != nil {
+		t.Fatalf("Incorrect inspection of a private key, got %+v", inspection)
+	}
+}
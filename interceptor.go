@@ -0,0 +1,35 @@
+package webpush
+
+import (
+	"net/http"
+)
+
+// HTTPClientFunc adapts a function to HTTPClient
+type HTTPClientFunc func(*http.Request) (*http.Response, error)
+
+// Do implements HTTPClient
+func (f HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Interceptor wraps the HTTPClient sending notifications. It can change the request before calling
+// next, e.g. to authenticate with a private gateway, answer without calling next, or record the exchange.
+type Interceptor func(next HTTPClient) HTTPClient
+
+// WithInterceptors wraps the HTTPClient of every notification with interceptors.
+// The first interceptor sees the request first; interceptors of repeated calls are appended.
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(c *Client) error {
+		c.interceptors = append(c.interceptors, interceptors...)
+		return nil
+	}
+}
+
+// intercept wraps client with the interceptors of the Client
+func (c *Client) intercept(client HTTPClient) HTTPClient {
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		client = c.interceptors[i](client)
+	}
+
+	return client
+}
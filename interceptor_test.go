@@ -0,0 +1,69 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClientInterceptors(t *testing.T) {
+	var order []string
+	record := func(name string) Interceptor {
+		return func(next HTTPClient) HTTPClient {
+			return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				req.Header.Set("X-Gateway-Token", name)
+				return next.Do(req)
+			})
+		}
+	}
+
+	httpClient := &recordingHTTPClient{}
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithInterceptors(record("outer"), record("inner")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Fatalf("Incorrect interceptor order, got %v", order)
+	}
+
+	// The request reaching the HTTPClient carries the changes of the interceptors
+	if token := httpClient.req.Header.Get("X-Gateway-Token"); token != "inner" {
+		t.Fatalf("Incorrect header, expected=inner, got=%s", token)
+	}
+}
+
+func TestClientInterceptorShortCircuits(t *testing.T) {
+	httpClient := &recordingHTTPClient{}
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithInterceptors(func(HTTPClient) HTTPClient {
+			return HTTPClientFunc(func(*http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusTooManyRequests, Body: http.NoBody}, nil
+			})
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests || httpClient.req != nil {
+		t.Fatalf("Expected the interceptor to answer, got status=%d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,202 @@
+package webpush
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// iteratorWindow is the number of subscriptions SendToIterator reads ahead and interleaves by origin
+const iteratorWindow = 1024
+
+// SubscriptionIterator yields subscriptions one at a time, e.g. from a file or a database cursor, so a
+// campaign doesn't hold every subscription in memory
+type SubscriptionIterator interface {
+	// Next returns the next subscription, or io.EOF after the last one
+	Next(ctx context.Context) (*StoredSubscription, error)
+}
+
+// ErrLineTooLong is returned by JSONLIterator.Next for a line longer than its MaxLineSize
+var ErrLineTooLong = errors.New("webpush: JSONL line exceeds the maximum line size")
+
+const (
+	// DefaultMaxJSONLLine is the longest line a JSONLIterator reads unless configured otherwise
+	DefaultMaxJSONLLine = 64 << 10
+
+	// MaxJSONLIteratorErrors is the number of invalid lines a JSONLIterator keeps in Errors
+	MaxJSONLIteratorErrors = 100
+)
+
+// JSONLIterator is a SubscriptionIterator of the lines of a JSONL file, in the format of ImportJSONL.
+// Lines that don't decode or fail Subscription.Validate are skipped and counted in Invalid, the first
+// MaxJSONLIteratorErrors of them are kept in Errors so a file of bad lines doesn't grow the memory.
+// OnError and MaxLineSize are read by the first Next.
+type JSONLIterator struct {
+	Errors  []*ImportError
+	Invalid int // Skipped lines, including those past Errors
+
+	OnError     func(*ImportError) // Called for every skipped line (Optional)
+	MaxLineSize int                // Longest line read before Next fails with ErrLineTooLong, DefaultMaxJSONLLine when zero
+
+	reader       io.Reader
+	scanner      *bufio.Scanner
+	line         int
+	allowedHosts []string
+}
+
+// NewJSONLIterator returns a JSONLIterator of r, validating the subscriptions with allowedHosts
+func NewJSONLIterator(r io.Reader, allowedHosts ...string) *JSONLIterator {
+	return &JSONLIterator{reader: r, allowedHosts: allowedHosts}
+}
+
+// Next implements SubscriptionIterator
+func (it *JSONLIterator) Next(ctx context.Context) (*StoredSubscription, error) {
+	if it.scanner == nil {
+		maxLine := it.MaxLineSize
+		if maxLine <= 0 {
+			maxLine = DefaultMaxJSONLLine
+		}
+
+		// The capacity of the initial buffer is a maximum too
+		size := 4096
+		if maxLine < size {
+			size = maxLine
+		}
+
+		it.scanner = bufio.NewScanner(it.reader)
+		it.scanner.Buffer(make([]byte, 0, size), maxLine)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if !it.scanner.Scan() {
+			err := it.scanner.Err()
+			if err == bufio.ErrTooLong {
+				return nil, &ImportError{Line: it.line + 1, Err: ErrLineTooLong}
+			}
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		it.line++
+
+		data := bytes.TrimSpace(it.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		s := &StoredSubscription{}
+		if decodeErr := json.Unmarshal(data, s); decodeErr != nil {
+			it.skip(&ImportError{Line: it.line, Err: fmt.Errorf("%w: %v", ErrInvalidSubscription, decodeErr)})
+		} else if validateErr := s.Validate(it.allowedHosts...); validateErr != nil {
+			it.skip(&ImportError{Line: it.line, Err: validateErr})
+		} else {
+			return s, nil
+		}
+	}
+}
+
+// skip counts an invalid line, keeping it while Errors holds less than MaxJSONLIteratorErrors
+func (it *JSONLIterator) skip(importErr *ImportError) {
+	it.Invalid++
+	if len(it.Errors) < MaxJSONLIteratorErrors {
+		it.Errors = append(it.Errors, importErr)
+	}
+	if it.OnError != nil {
+		it.OnError(importErr)
+	}
+}
+
+// SendToIterator sends a notification built by payload to every subscription of it, FanOutConcurrency
+// at a time, in constant memory: subscriptions are read a window at a time and interleaved by origin
+// within it, and each result is written to sink, when not nil, as soon as it completes instead of
+// being kept. sink is never written concurrently and flushed once every result was written; when it
+// fails, no further notifications are sent. Unlike SendToTags, duplicates are not removed. The
+// returned counts cover every result; the error is only set when it, sink or ctx fails.
+func (c *Client) SendToIterator(ctx context.Context, it SubscriptionIterator, payload PayloadFunc, sink ResultSink) (*FanOutCounts, error) {
+	counts := &FanOutCounts{}
+	var mu sync.Mutex
+	var sinkErr error
+	report := func(result *FanOutResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		counts.add(result)
+		if sink != nil && sinkErr == nil {
+			sinkErr = sink.Write(ctx, result)
+		}
+	}
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return sinkErr
+	}
+
+	slots := make(chan struct{}, FanOutConcurrency)
+	var wg sync.WaitGroup
+
+	window := make([]*StoredSubscription, 0, iteratorWindow)
+	for {
+		window = window[:0]
+		var err error
+		for len(window) < iteratorWindow {
+			var s *StoredSubscription
+			if s, err = it.Next(ctx); err != nil {
+				break
+			}
+			window = append(window, s)
+		}
+
+		for _, i := range originOrder(window) {
+			if err := failed(); err != nil {
+				wg.Wait()
+				return counts, err
+			}
+
+			result := &FanOutResult{Subscription: window[i]}
+
+			message, options, payloadErr := payload(result.Subscription)
+			if payloadErr != nil {
+				result.Err = payloadErr
+				report(result)
+				continue
+			}
+
+			if acquireErr := acquire(ctx, slots); acquireErr != nil {
+				result.Err = acquireErr
+				report(result)
+				continue
+			}
+
+			wg.Add(1)
+			go func(result *FanOutResult, message []byte, options *Options) {
+				defer wg.Done()
+				defer release(slots)
+				defer report(result)
+
+				c.fanOutSend(ctx, result, message, options)
+			}(result, message, options)
+		}
+
+		if err != nil {
+			wg.Wait()
+			if err == io.EOF {
+				err = failed()
+			}
+			if err == nil && sink != nil {
+				err = sink.Flush(ctx)
+			}
+			return counts, err
+		}
+	}
+}
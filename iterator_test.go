@@ -0,0 +1,114 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// failingIterator yields n subscriptions, then fails
+type failingIterator struct {
+	n   int
+	err error
+}
+
+func (it *failingIterator) Next(context.Context) (*StoredSubscription, error) {
+	if it.n == 0 {
+		return nil, it.err
+	}
+	it.n--
+
+	return &StoredSubscription{Subscription: *getURLEncodedTestSubscription()}, nil
+}
+
+func TestSendToIterator(t *testing.T) {
+	var sent int32
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&sent, 1)
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getURLEncodedTestSubscription()
+	line := `{"endpoint":"` + s.Endpoint + `","keys":{"p256dh":"` + s.Keys.P256dh + `","auth":"` + s.Keys.Auth + `"},"locale":"%s"}`
+	input := strings.Join([]string{
+		strings.Replace(line, "%s", "en", 1),
+		strings.Replace(line, "%s", "de", 1),
+		`{"endpoint": 42}`,
+		"",
+		strings.Replace(line, "%s", "", 1),
+	}, "\n")
+
+	it := NewJSONLIterator(strings.NewReader(input))
+	var locales []string
+	counts, err := client.SendToIterator(context.Background(), it, func(s *StoredSubscription) ([]byte, *Options, error) {
+		if s.Locale == "" {
+			return nil, nil, errors.New("no locale")
+		}
+		return []byte("Hello " + s.Locale), &Options{TTL: 60}, nil
+	}, ResultSinkFunc(func(_ context.Context, result *FanOutResult) error {
+		locales = append(locales, result.Subscription.Locale)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counts.Notifications != 3 || counts.Delivered != 2 || counts.Failed != 1 || len(locales) != 3 || atomic.LoadInt32(&sent) != 2 {
+		t.Fatalf("Incorrect sends, got %+v for %v", counts, locales)
+	}
+	if len(it.Errors) != 1 || it.Errors[0].Line != 3 || !errors.Is(it.Errors[0], ErrInvalidSubscription) {
+		t.Fatalf("Expected the invalid third line, got %v", it.Errors)
+	}
+
+	errCursor := errors.New("cursor closed")
+	counts, err = client.SendToIterator(context.Background(), &failingIterator{n: 2, err: errCursor}, func(*StoredSubscription) ([]byte, *Options, error) {
+		return []byte("Hello"), nil, nil
+	}, nil)
+	if err != errCursor || counts.Delivered != 2 {
+		t.Fatalf("Expected the sends before the iterator failed, got %+v (%v)", counts, err)
+	}
+
+	if _, err := client.SendToIterator(context.Background(), &failingIterator{err: io.EOF}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONLIteratorLimits(t *testing.T) {
+	s := getStandardEncodedTestSubscription()
+	valid := `{"endpoint":"` + s.Endpoint + `","keys":{"p256dh":"` + s.Keys.P256dh + `","auth":"` + s.Keys.Auth + `"}}`
+	lines := []string{valid}
+	for i := 0; i < MaxJSONLIteratorErrors+10; i++ {
+		lines = append(lines, `{"endpoint": 42}`)
+	}
+	lines = append(lines, valid, `{"endpoint":"`+strings.Repeat("a", len(valid))+`"}`, valid)
+
+	var reported int
+	it := NewJSONLIterator(strings.NewReader(strings.Join(lines, "\n")))
+	it.OnError = func(*ImportError) { reported++ }
+	it.MaxLineSize = len(valid) + 1
+
+	for i := 0; i < 2; i++ {
+		if _, err := it.Next(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every invalid line is reported, only the first are kept
+	if it.Invalid != MaxJSONLIteratorErrors+10 || reported != it.Invalid || len(it.Errors) != MaxJSONLIteratorErrors {
+		t.Fatalf("Incorrect invalid lines, got invalid=%d reported=%d errors=%d", it.Invalid, reported, len(it.Errors))
+	}
+
+	_, err := it.Next(context.Background())
+	var importErr *ImportError
+	if !errors.Is(err, ErrLineTooLong) || !errors.As(err, &importErr) || importErr.Line != len(lines)-1 {
+		t.Fatalf("Expected %v on line %d, got %v", ErrLineTooLong, len(lines)-1, err)
+	}
+}
@@ -0,0 +1,42 @@
+package webpush
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// JWTEncoder creates the ES256 signed JWT tokens of the VAPID Authorization header
+type JWTEncoder interface {
+	// Encode returns the JWS compact serialization of claims signed with ES256 by key
+	Encode(claims map[string]interface{}, key crypto.Signer) (string, error)
+}
+
+// jwtHeader is the JOSE header of every VAPID token
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","typ":"JWT"}`))
+
+// nativeJWTEncoder encodes tokens with the standard library only
+type nativeJWTEncoder struct{}
+
+// WithNativeJWTEncoder encodes VAPID JWT tokens without github.com/golang-jwt/jwt.
+// Build with the webpush_nojwt tag to make it the default and leave golang-jwt out of the binary.
+func WithNativeJWTEncoder() ClientOption {
+	return WithJWTEncoder(nativeJWTEncoder{})
+}
+
+// Encode implements JWTEncoder
+func (nativeJWTEncoder) Encode(claims map[string]interface{}, key crypto.Signer) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingString := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	signature, err := signES256(key, signingString)
+	if err != nil {
+		return "", err
+	}
+
+	return signingString + "." + signature, nil
+}
@@ -0,0 +1,40 @@
+//go:build !webpush_nojwt
+// +build !webpush_nojwt
+
+package webpush
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultJWTEncoder is used unless a Client is configured with WithJWTEncoder
+var defaultJWTEncoder JWTEncoder = golangJWTEncoder{}
+
+// golangJWTEncoder encodes tokens with github.com/golang-jwt/jwt
+type golangJWTEncoder struct{}
+
+// Encode implements JWTEncoder
+func (golangJWTEncoder) Encode(claims map[string]interface{}, key crypto.Signer) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims(claims))
+
+	// Sign token with private key
+	if privKey, ok := key.(*ecdsa.PrivateKey); ok {
+		return token.SignedString(privKey)
+	}
+
+	// Sign token through the external signer
+	signingString, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signES256(key, signingString)
+	if err != nil {
+		return "", err
+	}
+
+	return signingString + "." + signature, nil
+}
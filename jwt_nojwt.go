@@ -0,0 +1,7 @@
+//go:build webpush_nojwt
+// +build webpush_nojwt
+
+package webpush
+
+// defaultJWTEncoder is used unless a Client is configured with WithJWTEncoder
+var defaultJWTEncoder JWTEncoder = nativeJWTEncoder{}
@@ -0,0 +1,76 @@
+package webpush
+
+import (
+	"context"
+	"crypto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// countingJWTEncoder wraps the default encoder and counts encoded tokens
+type countingJWTEncoder struct {
+	encoded int
+}
+
+func (e *countingJWTEncoder) Encode(claims map[string]interface{}, key crypto.Signer) (string, error) {
+	e.encoded++
+	return defaultJWTEncoder.Encode(claims, key)
+}
+
+func TestClientUsesJWTEncoder(t *testing.T) {
+	encoder := &countingJWTEncoder{}
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithJWTEncoder(encoder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := &recordingHTTPClient{}
+	_, err = client.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), &Options{HTTPClient: httpClient})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if encoder.encoded != 1 {
+		t.Fatalf("Incorrect number of encoded tokens, expected=1, got=%d", encoder.encoded)
+	}
+
+	if !strings.HasPrefix(httpClient.req.Header.Get("Authorization"), "vapid t=") {
+		t.Fatal("Authorization header was not built from the encoded token")
+	}
+}
+
+func TestNativeJWTEncoder(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	privKey, err := keys.ecdsaPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := map[string]interface{}{
+		"aud": "https://updates.push.services.mozilla.com",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"sub": "mailto:test@test.com",
+	}
+
+	tokenString, err := nativeJWTEncoder{}.Encode(claims, privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodES256 {
+			t.Fatalf("Incorrect signing method, expected=ES256, got=%v", token.Method.Alg())
+		}
+		return &privKey.PublicKey, nil
+	})
+	if err != nil || !token.Valid {
+		t.Fatalf("Native token did not verify: %v", err)
+	}
+
+	if sub, _ := token.Claims.GetSubject(); sub != claims["sub"] {
+		t.Fatalf("Incorrect sub, expected=%v, got=%s", claims["sub"], sub)
+	}
+}
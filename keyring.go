@@ -0,0 +1,126 @@
+package webpush
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// vapidKeyRing is the set of active VAPID key pairs of a Client
+type vapidKeyRing struct {
+	mu   sync.RWMutex
+	keys []ringKey // keys[0] is the primary key pair
+}
+
+// ringKey is an active key pair, retired after retireAt unless it is zero
+type ringKey struct {
+	keys     VAPIDKeys
+	retireAt time.Time
+}
+
+// active returns the key pairs that are not retired at now, the primary key pair first
+func (r *vapidKeyRing) active(now time.Time) []VAPIDKeys {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	active := make([]VAPIDKeys, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.retireAt.IsZero() || now.Before(key.retireAt) {
+			active = append(active, key.keys)
+		}
+	}
+
+	return active
+}
+
+// first returns the first active key pair, without copying the active ones
+func (r *vapidKeyRing) first(now time.Time) (VAPIDKeys, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.retireAt.IsZero() || now.Before(key.retireAt) {
+			return key.keys, true
+		}
+	}
+
+	return VAPIDKeys{}, false
+}
+
+// forSubscription returns the key pair the subscription was created with, or the primary key pair
+func (r *vapidKeyRing) forSubscription(s *Subscription, now time.Time) (VAPIDKeys, bool) {
+	if s.ApplicationServerKey == "" {
+		return r.first(now)
+	}
+
+	active := r.active(now)
+	if len(active) == 0 {
+		return VAPIDKeys{}, false
+	}
+
+	if serverKey, err := decodeSubscriptionKey(s.ApplicationServerKey); err == nil {
+		for _, keys := range active {
+			publicKey, err := decodeVapidKey(keys.PublicKey)
+			if err == nil && bytes.Equal(publicKey, serverKey) {
+				return keys, true
+			}
+		}
+	}
+
+	return active[0], true
+}
+
+// replace makes keys the only active key pairs
+func (r *vapidKeyRing) replace(keys []VAPIDKeys) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys = make([]ringKey, len(keys))
+	for i, pair := range keys {
+		r.keys[i] = ringKey{keys: pair}
+	}
+}
+
+// rotate makes keys the primary key pair. The previous key pairs stay active until
+// retireAt, or are removed right away when retireAt is zero. The removed key pairs are returned.
+func (r *vapidKeyRing) rotate(keys VAPIDKeys, retireAt, now time.Time) (removed []VAPIDKeys) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rotated := []ringKey{{keys: keys}}
+	for _, key := range r.keys {
+		if key.keys == keys {
+			continue
+		}
+
+		expired := !key.retireAt.IsZero() && !now.Before(key.retireAt)
+		if retireAt.IsZero() || expired {
+			removed = append(removed, key.keys)
+			continue
+		}
+
+		// Never extend the grace period of a key pair already retiring
+		if key.retireAt.IsZero() || retireAt.Before(key.retireAt) {
+			key.retireAt = retireAt
+		}
+		rotated = append(rotated, key)
+	}
+
+	r.keys = rotated
+	return removed
+}
+
+// retire removes keys if its grace period is over
+func (r *vapidKeyRing) retire(keys VAPIDKeys, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, key := range r.keys {
+		if key.keys == keys && !key.retireAt.IsZero() && !now.Before(key.retireAt) {
+			r.keys = append(r.keys[:i:i], r.keys[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientSetVAPIDKeys(t *testing.T) {
+	now := time.Now()
+	oldKeys := getTestVAPIDKeys(t)
+	newKeys := getTestVAPIDKeys(t)
+
+	client, err := NewClient(
+		WithVAPIDKeys(oldKeys),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(applicationServerKey string) (string, error) {
+		httpClient := &recordingHTTPClient{}
+		s := getURLEncodedTestSubscription()
+		s.ApplicationServerKey = applicationServerKey
+		_, err := client.Send(context.Background(), []byte("Test"), s, &Options{HTTPClient: httpClient})
+		if err != nil {
+			return "", err
+		}
+		return httpClient.req.Header.Get("Authorization"), nil
+	}
+
+	if _, err := send(oldKeys.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SetVAPIDKeys(newKeys, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// New subscriptions use the new key, old ones keep working during the grace period
+	if auth, err := send(""); err != nil || !strings.HasSuffix(auth, "k="+newKeys.PublicKey) {
+		t.Fatalf("Expected the new primary key, got %s (%v)", auth, err)
+	}
+
+	if auth, err := send(oldKeys.PublicKey); err != nil || !strings.HasSuffix(auth, "k="+oldKeys.PublicKey) {
+		t.Fatalf("Expected the old key during the grace period, got %s (%v)", auth, err)
+	}
+
+	// After the grace period the old key is gone
+	now = now.Add(2 * time.Hour)
+	if _, err := send(oldKeys.PublicKey); !errors.Is(err, ErrVAPIDKeyMismatch) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrVAPIDKeyMismatch, err)
+	}
+}
+
+func TestClientSetVAPIDKeysInvalidatesCache(t *testing.T) {
+	oldKeys := getTestVAPIDKeys(t)
+	client, err := NewClient(WithVAPIDKeys(oldKeys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.WarmVAPIDCache([]string{"https://fcm.googleapis.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SetVAPIDKeys(getTestVAPIDKeys(t), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cacheKey := range client.cache.headers.keys() {
+		if strings.HasPrefix(cacheKey, oldKeys.PublicKey+"|") {
+			t.Fatal("Header of the replaced key pair is still cached")
+		}
+	}
+
+	if _, ok := client.cache.privateKeys.load(oldKeys.PrivateKey); ok {
+		t.Fatal("Private key of the replaced key pair is still cached")
+	}
+
+	if err := client.SetVAPIDKeys(VAPIDKeys{PrivateKey: oldKeys.PrivateKey, PublicKey: getTestVAPIDKeys(t).PublicKey}, 0); err != ErrVAPIDKeyPairMismatch {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrVAPIDKeyPairMismatch, err)
+	}
+}
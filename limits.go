@@ -0,0 +1,124 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrInvalidConcurrencyLimit is returned by WithConcurrencyLimits for negative limits
+var ErrInvalidConcurrencyLimit = errors.New("webpush: concurrency limits must not be negative")
+
+// concurrencyLimiter caps the requests in flight, in total and per push service origin
+type concurrencyLimiter struct {
+	global    chan struct{}
+	perOrigin int
+
+	mu      sync.Mutex
+	origins map[string]chan struct{}
+}
+
+// WithConcurrencyLimits caps the requests of the Client waiting for a push service response at
+// global in total and at perOrigin per push service origin, so the backlog of a slow origin can't
+// use up the goroutines and sockets of healthy ones. Sends over a limit wait for a free slot or
+// for their context to end. Zero means unlimited.
+func WithConcurrencyLimits(global, perOrigin int) ClientOption {
+	return func(c *Client) error {
+		if global < 0 || perOrigin < 0 {
+			return ErrInvalidConcurrencyLimit
+		}
+
+		if global == 0 && perOrigin == 0 {
+			c.limiter = nil
+			return nil
+		}
+
+		c.limiter = &concurrencyLimiter{perOrigin: perOrigin, origins: make(map[string]chan struct{})}
+		if global > 0 {
+			c.limiter.global = make(chan struct{}, global)
+		}
+
+		return nil
+	}
+}
+
+// wrap returns client limited to the slots of l
+func (l *concurrencyLimiter) wrap(client HTTPClient) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		origin := l.origin(req)
+
+		// The origin slot is taken first, so requests queued for a slow origin don't hold global slots
+		if err := acquire(req.Context(), origin); err != nil {
+			return nil, err
+		}
+		defer release(origin)
+
+		if err := acquire(req.Context(), l.global); err != nil {
+			return nil, err
+		}
+		defer release(l.global)
+
+		return client.Do(req)
+	})
+}
+
+// origin returns the semaphore of the origin of req, nil without a per origin limit
+func (l *concurrencyLimiter) origin(req *http.Request) chan struct{} {
+	if l.perOrigin == 0 {
+		return nil
+	}
+
+	key := requestOrigin(req)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	semaphore, ok := l.origins[key]
+	if !ok {
+		semaphore = make(chan struct{}, l.perOrigin)
+		l.origins[key] = semaphore
+	}
+
+	return semaphore
+}
+
+// acquire takes a slot of semaphore, waiting until one is free or ctx ends
+func acquire(ctx context.Context, semaphore chan struct{}) error {
+	if semaphore == nil {
+		return nil
+	}
+
+	select {
+	case semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot of semaphore
+func release(semaphore chan struct{}) {
+	if semaphore != nil {
+		<-semaphore
+	}
+}
+
+// levels returns the slots of l in use
+func (l *concurrencyLimiter) levels() *limiterLevels {
+	levels := &limiterLevels{
+		Global:         len(l.global),
+		GlobalLimit:    cap(l.global),
+		PerOriginLimit: l.perOrigin,
+		Origins:        make(map[string]int),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for origin, semaphore := range l.origins {
+		levels.Origins[origin] = len(semaphore)
+	}
+
+	return levels
+}
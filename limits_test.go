@@ -0,0 +1,80 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientConcurrencyLimits(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string, 10)
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		started <- req.URL.Host
+		if req.URL.Host == "slow.example.com" {
+			<-release
+		}
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	})
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(httpClient),
+		WithConcurrencyLimits(2, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(ctx context.Context, host string) error {
+		s := getStandardEncodedTestSubscription()
+		s.Endpoint = "https://" + host + "/push/abc"
+		_, err := client.Send(ctx, []byte("Test"), s, nil)
+		return err
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- send(context.Background(), "slow.example.com") }()
+	}
+
+	if host := <-started; host != "slow.example.com" {
+		t.Fatalf("Incorrect first request, got %s", host)
+	}
+
+	// The second request to the slow origin waits, other origins are still served
+	if err := send(context.Background(), "fast.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if host := <-started; host != "fast.example.com" {
+		t.Fatalf("Expected the healthy origin to be served, got %s", host)
+	}
+
+	select {
+	case host := <-started:
+		t.Fatalf("Per origin limit exceeded by a request to %s", host)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Waiting for a slot ends with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := send(ctx, "slow.example.com"); err != context.DeadlineExceeded {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWithConcurrencyLimitsValidates(t *testing.T) {
+	if _, err := NewClient(WithConcurrencyLimits(-1, 0)); err != ErrInvalidConcurrencyLimit {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidConcurrencyLimit, err)
+	}
+}
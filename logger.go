@@ -0,0 +1,52 @@
+package webpush
+
+import (
+	"errors"
+	"net/url"
+)
+
+// Logger receives the debug, info, warn and error events of a Client as a message with alternating key
+// and value arguments. A *slog.Logger satisfies it. Events carry endpoint hosts, never full endpoints or keys.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// WithLogger logs VAPID header cache misses, transport retries, throttled requests and
+// VAPID key provider errors to logger
+func WithLogger(logger Logger) ClientOption {
+	return func(c *Client) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// noopLogger is the Logger of a Client without one
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+
+// log returns the Logger of the Client
+func (c *Client) log() Logger {
+	if c.logger == nil {
+		return noopLogger{}
+	}
+
+	return c.logger
+}
+
+// loggableError returns the message of err without the request URL of a *url.Error,
+// which holds the full endpoint
+func loggableError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err.Error()
+	}
+
+	return err.Error()
+}
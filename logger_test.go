@@ -0,0 +1,60 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// recordingLogger records the logged events as "level msg args"
+type recordingLogger struct {
+	events []string
+}
+
+func (l *recordingLogger) record(level, msg string, args []interface{}) {
+	l.events = append(l.events, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args) }
+
+func TestClientLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	keys := getTestVAPIDKeys(t)
+	client, err := NewClient(WithVAPIDKeys(keys), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}, nil
+	})
+	if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logger.events) != 2 ||
+		!strings.HasPrefix(logger.events[0], "DEBUG webpush: VAPID header cache miss") ||
+		!strings.HasPrefix(logger.events[1], "WARN webpush: push service is throttling [host updates.push.services.mozilla.com retry_after 30 correlation_id ") {
+		t.Fatalf("Incorrect events, got %q", logger.events)
+	}
+
+	for _, event := range logger.events {
+		if strings.Contains(event, "/wpush/") || strings.Contains(event, keys.PrivateKey) {
+			t.Fatalf("Event leaks the endpoint or a key: %s", event)
+		}
+	}
+}
+
+func TestLoggableError(t *testing.T) {
+	err := &url.Error{Op: "Post", URL: "https://fcm.googleapis.com/fcm/send/secret", Err: errors.New("connection refused")}
+	if message := loggableError(fmt.Errorf("send: %w", err)); message != "connection refused" {
+		t.Fatalf("Incorrect message, got %q", message)
+	}
+}
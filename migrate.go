@@ -0,0 +1,131 @@
+package webpush
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// ResubscribeTag is the tag of subscriptions MigrateEndpoints found the browser has to replace
+const ResubscribeTag = "webpush:resubscribe"
+
+// MigrationAction is what MigrateEndpoints did with a legacy subscription
+type MigrationAction string
+
+const (
+	// MigrationRewritten subscriptions were saved under their current endpoint
+	MigrationRewritten MigrationAction = "rewritten"
+	// MigrationResubscribe subscriptions can't be sent to and are tagged with ResubscribeTag
+	MigrationResubscribe MigrationAction = "resubscribe"
+)
+
+// MigrationOptions configure MigrateEndpoints
+type MigrationOptions struct {
+	// DryRun only reports the subscriptions that would be migrated, leaving the store untouched
+	DryRun bool
+}
+
+// MigrationEntry reports the migration of one subscription
+type MigrationEntry struct {
+	Endpoint    string
+	NewEndpoint string // The rewritten endpoint, empty unless the Action is MigrationRewritten
+	Action      MigrationAction
+	Reason      string
+}
+
+// MigrationReport is the outcome of MigrateEndpoints
+type MigrationReport struct {
+	Scanned     int
+	Rewritten   int
+	Resubscribe int
+	Entries     []MigrationEntry
+}
+
+// MigrateEndpoints scans store for legacy subscriptions: GCM endpoints are rewritten to their FCM
+// equivalent and other endpoints to CanonicalEndpoint, while subscriptions without encryption keys or
+// with plain http or pre Web Push Mozilla endpoints are tagged with ResubscribeTag, for the application
+// to ask the browser for a new subscription. The store is only written after the scan.
+func MigrateEndpoints(ctx context.Context, store SubscriptionStore, options MigrationOptions) (*MigrationReport, error) {
+	report := &MigrationReport{}
+	var migrated []*StoredSubscription
+	err := store.IterateByTag(ctx, "", func(s *StoredSubscription) error {
+		report.Scanned++
+
+		entry, ok := migrateEndpoint(s)
+		if !ok {
+			return nil
+		}
+
+		switch entry.Action {
+		case MigrationRewritten:
+			report.Rewritten++
+		case MigrationResubscribe:
+			report.Resubscribe++
+		}
+		report.Entries = append(report.Entries, entry)
+		migrated = append(migrated, s)
+		return nil
+	})
+	if err != nil || options.DryRun {
+		return report, err
+	}
+
+	for i, s := range migrated {
+		if err := applyMigration(ctx, store, s, report.Entries[i]); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// applyMigration saves s migrated as described by entry
+func applyMigration(ctx context.Context, store SubscriptionStore, s *StoredSubscription, entry MigrationEntry) error {
+	if entry.Action == MigrationResubscribe {
+		if s.HasTag(ResubscribeTag) {
+			return nil
+		}
+
+		s.Tags = append(s.Tags, ResubscribeTag)
+		return store.Save(ctx, s)
+	}
+
+	s.Endpoint = entry.NewEndpoint
+	if err := store.Save(ctx, s); err != nil {
+		return err
+	}
+
+	return store.Delete(ctx, entry.Endpoint)
+}
+
+// migrateEndpoint returns the migration of s, false when s is current
+func migrateEndpoint(s *StoredSubscription) (MigrationEntry, bool) {
+	entry := MigrationEntry{Endpoint: s.Endpoint, Action: MigrationResubscribe}
+
+	u, err := url.Parse(s.Endpoint)
+	switch {
+	case err != nil || u.Host == "":
+		entry.Reason = "endpoint is not a URL"
+	case strings.ToLower(u.Scheme) != "https":
+		entry.Reason = "endpoint is not an https URL"
+	case s.Keys.P256dh == "" || s.Keys.Auth == "":
+		entry.Reason = "subscription has no encryption keys"
+	case DetectPushService(s.Endpoint) == PushServiceMozilla && !strings.HasPrefix(u.Path, "/wpush/"):
+		entry.Reason = "Mozilla endpoint predates Web Push"
+	case strings.EqualFold(u.Hostname(), "android.googleapis.com") && strings.HasPrefix(u.Path, "/gcm/send/"):
+		entry.Action = MigrationRewritten
+		entry.NewEndpoint = "https://fcm.googleapis.com/fcm/send/" + strings.TrimPrefix(u.EscapedPath(), "/gcm/send/")
+		entry.Reason = "GCM endpoint"
+	default:
+		canonical, err := CanonicalEndpoint(s.Endpoint)
+		if err != nil || canonical == s.Endpoint {
+			return MigrationEntry{}, false
+		}
+
+		entry.Action = MigrationRewritten
+		entry.NewEndpoint = canonical
+		entry.Reason = "endpoint is not canonical"
+	}
+
+	return entry, true
+}
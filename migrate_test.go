@@ -0,0 +1,65 @@
+package webpush
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrateEndpoints(t *testing.T) {
+	ctx := context.Background()
+	keys := getURLEncodedTestSubscription().Keys
+	subscriptions := map[string]Keys{
+		"https://android.googleapis.com/gcm/send/token-1":            keys,
+		"https://FCM.googleapis.com:443/fcm/send/token-2":            keys,
+		"https://fcm.googleapis.com/fcm/send/token-3":                keys,
+		"http://push.example.com/token-4":                            keys,
+		"https://fcm.googleapis.com/fcm/send/token-5":                {},
+		"https://updates.push.services.mozilla.com/push/v1/token-6":  keys,
+		"https://updates.push.services.mozilla.com/wpush/v2/token-7": keys,
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		store := NewMemorySubscriptionStore()
+		for endpoint, keys := range subscriptions {
+			s := &StoredSubscription{Subscription: Subscription{Endpoint: endpoint, Keys: keys}, Tags: []string{"news"}}
+			if err := store.Save(ctx, s); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		report, err := MigrateEndpoints(ctx, store, MigrationOptions{DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Scanned != 7 || report.Rewritten != 2 || report.Resubscribe != 3 || len(report.Entries) != 5 {
+			t.Fatalf("Incorrect report, got %+v", report)
+		}
+
+		_, err = store.Get(ctx, "https://fcm.googleapis.com/fcm/send/token-1")
+		if migrated := err == nil; migrated == dryRun {
+			t.Fatalf("Expected the GCM endpoint rewritten %t, got %v", !dryRun, err)
+		}
+
+		if dryRun {
+			continue
+		}
+
+		if _, err := store.Get(ctx, "https://android.googleapis.com/gcm/send/token-1"); err != ErrSubscriptionNotFound {
+			t.Fatalf("Expected the GCM endpoint removed, got %v", err)
+		}
+
+		if s, err := store.Get(ctx, "https://fcm.googleapis.com/fcm/send/token-2"); err != nil || !s.HasTag("news") {
+			t.Fatalf("Expected the canonical endpoint, got %+v (%v)", s, err)
+		}
+
+		var flagged []string
+		err = store.IterateByTag(ctx, ResubscribeTag, func(s *StoredSubscription) error {
+			flagged = append(flagged, s.Endpoint)
+			return nil
+		})
+		if err != nil || len(flagged) != 3 {
+			t.Fatalf("Incorrect subscriptions to resubscribe, got %v (%v)", flagged, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package webpush
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// EndpointOrigin returns the lower case scheme://host of endpoint, the push service origin the
+// notifications to it are sent to, or "" when endpoint is not an absolute URL
+func EndpointOrigin(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// GroupByOrigin buckets subscriptions by EndpointOrigin, keeping their order within a bucket, e.g. to
+// estimate the load of a campaign per push service. Subscriptions without a valid endpoint are under "".
+func GroupByOrigin(subscriptions []*Subscription) map[string][]*Subscription {
+	groups := make(map[string][]*Subscription)
+	for _, s := range subscriptions {
+		origin := EndpointOrigin(s.Endpoint)
+		groups[origin] = append(groups[origin], s)
+	}
+
+	return groups
+}
+
+// originBurst is the number of consecutive fan-out sends to an origin before the next origin's turn:
+// enough to multiplex HTTP/2 streams over one connection, few enough to keep the connections of the
+// other origins busy instead of idling out while one origin is drained
+const originBurst = FanOutConcurrency / 4
+
+// originOrder returns the indexes of subscriptions grouped by origin and interleaved in bursts of
+// originBurst, so the sends of a batch reuse the connection of each push service
+func originOrder(subscriptions []*StoredSubscription) []int {
+	groups := make(map[string][]int)
+	var origins []string
+	for i, s := range subscriptions {
+		origin := EndpointOrigin(s.Endpoint)
+		if _, ok := groups[origin]; !ok {
+			origins = append(origins, origin)
+		}
+		groups[origin] = append(groups[origin], i)
+	}
+	sort.Strings(origins)
+
+	order := make([]int, 0, len(subscriptions))
+	for len(order) < len(subscriptions) {
+		for _, origin := range origins {
+			group := groups[origin]
+			n := originBurst
+			if n > len(group) {
+				n = len(group)
+			}
+			order = append(order, group[:n]...)
+			groups[origin] = group[n:]
+		}
+	}
+
+	return order
+}
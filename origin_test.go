@@ -0,0 +1,50 @@
+package webpush
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByOrigin(t *testing.T) {
+	fcm1 := &Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/a"}
+	mozilla := &Subscription{Endpoint: "https://updates.push.services.mozilla.com/wpush/v2/b"}
+	fcm2 := &Subscription{Endpoint: "https://FCM.googleapis.com/fcm/send/c"}
+	invalid := &Subscription{Endpoint: "not a url"}
+
+	groups := GroupByOrigin([]*Subscription{fcm1, mozilla, fcm2, invalid})
+	want := map[string][]*Subscription{
+		"https://fcm.googleapis.com":                {fcm1, fcm2},
+		"https://updates.push.services.mozilla.com": {mozilla},
+		"": {invalid},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("Incorrect groups, got %v", groups)
+	}
+}
+
+func TestOriginOrder(t *testing.T) {
+	subscriptions := []*StoredSubscription{
+		{Subscription: Subscription{Endpoint: "https://updates.push.services.mozilla.com/wpush/v2/a"}},
+		{Subscription: Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/b"}},
+		{Subscription: Subscription{Endpoint: "https://updates.push.services.mozilla.com/wpush/v2/c"}},
+		{Subscription: Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/d"}},
+	}
+
+	if order := originOrder(subscriptions); !reflect.DeepEqual(order, []int{1, 3, 0, 2}) {
+		t.Fatalf("Incorrect order, got %v", order)
+	}
+}
+
+func TestOriginOrderInterleaves(t *testing.T) {
+	var subscriptions []*StoredSubscription
+	for i := 0; i < 2*originBurst; i++ {
+		subscriptions = append(subscriptions, &StoredSubscription{Subscription: Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/" + string(rune('a'+i))}})
+	}
+	subscriptions = append(subscriptions, &StoredSubscription{Subscription: Subscription{Endpoint: "https://updates.push.services.mozilla.com/wpush/v2/z"}})
+
+	// A burst of FCM, the Mozilla subscription, then the rest of FCM
+	order := originOrder(subscriptions)
+	if len(order) != len(subscriptions) || order[originBurst] != 2*originBurst || order[originBurst+1] != originBurst {
+		t.Fatalf("Incorrect order, got %v", order)
+	}
+}
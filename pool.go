@@ -0,0 +1,233 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrPoolClosed is returned by Pool.Submit after Shutdown
+	ErrPoolClosed = errors.New("webpush: pool is shut down")
+
+	// ErrPoolFull is returned by Pool.TrySubmit when the queue of the Pool is full
+	ErrPoolFull = errors.New("webpush: pool queue is full")
+
+	// ErrInvalidPoolOptions is returned by NewPool for negative options
+	ErrInvalidPoolOptions = errors.New("webpush: pool options must not be negative")
+)
+
+// PoolOptions configure a Pool
+type PoolOptions struct {
+	Workers   int // Notifications sent at once, FanOutConcurrency when zero
+	QueueSize int // Notifications waiting for a worker, Workers when zero
+	PerOrigin int // Notifications sent at once to a push service origin, unlimited when zero
+}
+
+// PoolJob is a notification sent by a Pool
+type PoolJob struct {
+	Message      []byte
+	Subscription *Subscription
+	Options      *Options
+
+	// Done is called by the worker with the outcome of the send, the response body already closed (Optional)
+	Done func(result *SendResult, err error)
+}
+
+// Pool sends notifications with a fixed number of workers from a bounded queue. With PerOrigin set,
+// notifications to an origin at its limit wait in the queue while workers send to other origins, so a
+// slow push service doesn't hold every worker.
+type Pool struct {
+	client   *Client
+	options  PoolOptions
+	slots    chan struct{} // Free places of the queue
+	ctx      context.Context
+	cancel   context.CancelFunc
+	finished chan struct{}
+
+	mu      sync.Mutex
+	ready   *sync.Cond
+	queues  map[string][]*PoolJob // Jobs waiting per origin
+	origins []string              // Origins with waiting jobs, in round-robin order
+	active  map[string]int        // Jobs being sent per origin
+	closed  bool
+	workers int
+}
+
+// NewPool starts the workers of a Pool sending with client
+func NewPool(client *Client, options PoolOptions) (*Pool, error) {
+	if options.Workers < 0 || options.QueueSize < 0 || options.PerOrigin < 0 {
+		return nil, ErrInvalidPoolOptions
+	}
+
+	if options.Workers == 0 {
+		options.Workers = FanOutConcurrency
+	}
+
+	if options.QueueSize == 0 {
+		options.QueueSize = options.Workers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		client:   client,
+		options:  options,
+		slots:    make(chan struct{}, options.QueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		finished: make(chan struct{}),
+		queues:   make(map[string][]*PoolJob),
+		active:   make(map[string]int),
+		workers:  options.Workers,
+	}
+	p.ready = sync.NewCond(&p.mu)
+
+	for i := 0; i < options.Workers; i++ {
+		go p.work()
+	}
+
+	return p, nil
+}
+
+// Submit queues job, waiting for a free place in the queue until ctx ends. ctx only bounds the wait:
+// the send itself runs until it completes or Shutdown gives up on it.
+func (p *Pool) Submit(ctx context.Context, job PoolJob) error {
+	if err := acquire(ctx, p.slots); err != nil {
+		return err
+	}
+
+	return p.enqueue(&job)
+}
+
+// TrySubmit queues job, failing with ErrPoolFull instead of waiting when the queue is full
+func (p *Pool) TrySubmit(job PoolJob) error {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		return ErrPoolFull
+	}
+
+	return p.enqueue(&job)
+}
+
+// Pending returns the number of queued notifications no worker picked yet
+func (p *Pool) Pending() int {
+	return len(p.slots)
+}
+
+// enqueue adds job, which holds a slot, to the queue of its origin
+func (p *Pool) enqueue(job *PoolJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		release(p.slots)
+		return ErrPoolClosed
+	}
+
+	origin := EndpointOrigin(job.Subscription.Endpoint)
+	if len(p.queues[origin]) == 0 {
+		p.origins = append(p.origins, origin)
+	}
+	p.queues[origin] = append(p.queues[origin], job)
+	p.ready.Signal()
+
+	return nil
+}
+
+// Shutdown stops accepting notifications and waits for the queued and in-flight ones to be sent.
+// When ctx ends first, the sends still running are canceled and ctx.Err() returned, the remaining
+// queued jobs are dropped without calling Done.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.ready.Broadcast()
+	p.mu.Unlock()
+
+	select {
+	case <-p.finished:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+
+		p.mu.Lock()
+		for _, queue := range p.queues {
+			for range queue {
+				release(p.slots)
+			}
+		}
+		p.queues, p.origins = make(map[string][]*PoolJob), nil
+		p.ready.Broadcast()
+		p.mu.Unlock()
+
+		<-p.finished
+		return ctx.Err()
+	}
+}
+
+// work sends jobs until the Pool is shut down and its queue is empty
+func (p *Pool) work() {
+	for {
+		job, origin, ok := p.next()
+		if !ok {
+			return
+		}
+
+		result, err := p.client.Deliver(p.ctx, job.Message, job.Subscription, job.Options)
+		if result != nil && result.Response != nil && result.Response.Body != nil {
+			result.Response.Body.Close()
+		}
+		if job.Done != nil {
+			job.Done(result, err)
+		}
+
+		p.mu.Lock()
+		p.active[origin]--
+		if p.active[origin] == 0 {
+			delete(p.active, origin)
+		}
+		p.ready.Broadcast()
+		p.mu.Unlock()
+	}
+}
+
+// next waits for a job of an origin below its limit, false once the Pool is shut down and drained
+func (p *Pool) next() (*PoolJob, string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		for i, origin := range p.origins {
+			if p.options.PerOrigin > 0 && p.active[origin] >= p.options.PerOrigin {
+				continue
+			}
+
+			queue := p.queues[origin]
+			job := queue[0]
+			queue[0] = nil
+			if len(queue) == 1 {
+				delete(p.queues, origin)
+				p.origins = append(p.origins[:i], p.origins[i+1:]...)
+			} else {
+				p.queues[origin] = queue[1:]
+				// Move the origin to the back so origins take turns
+				p.origins = append(append(p.origins[:i], p.origins[i+1:]...), origin)
+			}
+
+			p.active[origin]++
+			release(p.slots)
+			return job, origin, true
+		}
+
+		if p.closed && len(p.origins) == 0 {
+			p.workers--
+			if p.workers == 0 {
+				close(p.finished)
+			}
+			return nil, "", false
+		}
+
+		p.ready.Wait()
+	}
+}
@@ -0,0 +1,132 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxSlow := map[string]int{}, 0
+	slow := make(chan struct{})
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		inFlight[req.URL.Host]++
+		if req.URL.Host == "slow.example.com" && inFlight[req.URL.Host] > maxSlow {
+			maxSlow = inFlight[req.URL.Host]
+		}
+		mu.Unlock()
+
+		if req.URL.Host == "slow.example.com" {
+			<-slow
+		}
+
+		mu.Lock()
+		inFlight[req.URL.Host]--
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	})
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := NewPool(client, PoolOptions{Workers: 4, QueueSize: 100, PerOrigin: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent int32
+	fast := make(chan struct{}, 10)
+	submit := func(host string) {
+		s := getURLEncodedTestSubscription()
+		s.Endpoint = "https://" + host + "/push"
+		err := pool.Submit(context.Background(), PoolJob{Message: []byte("Hello"), Subscription: s, Done: func(result *SendResult, err error) {
+			if err != nil || result.Response.StatusCode != http.StatusCreated {
+				t.Errorf("Incorrect send, got %v (%v)", result, err)
+			}
+			atomic.AddInt32(&sent, 1)
+			if host == "fast.example.com" {
+				fast <- struct{}{}
+			}
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The slow origin can't hold more than 2 workers, the others keep sending to the fast origin
+	for i := 0; i < 5; i++ {
+		submit("slow.example.com")
+	}
+	for i := 0; i < 10; i++ {
+		submit("fast.example.com")
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case <-fast:
+		case <-time.After(5 * time.Second):
+			t.Fatal("The fast origin is blocked by the slow one")
+		}
+	}
+
+	// Shutdown drains the queued notifications of the slow origin
+	close(slow)
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 15 || maxSlow != 2 {
+		t.Fatalf("Expected 15 sends with at most 2 to the slow origin, got %d and %d", sent, maxSlow)
+	}
+
+	if err := pool.Submit(context.Background(), PoolJob{Subscription: getURLEncodedTestSubscription()}); err != ErrPoolClosed {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrPoolClosed, err)
+	}
+}
+
+func TestPoolShutdownTimeout(t *testing.T) {
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := NewPool(client, PoolOptions{Workers: 1, QueueSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canceled := make(chan error, 1)
+	if err := pool.TrySubmit(PoolJob{Subscription: getURLEncodedTestSubscription(), Done: func(_ *SendResult, err error) { canceled <- err }}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker takes the first job, the queue holds the second, the third doesn't fit
+	for pool.Pending() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := pool.TrySubmit(PoolJob{Subscription: getURLEncodedTestSubscription()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.TrySubmit(PoolJob{Subscription: getURLEncodedTestSubscription()}); err != ErrPoolFull {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrPoolFull, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", context.DeadlineExceeded, err)
+	}
+	if err := <-canceled; err == nil {
+		t.Fatal("Expected the in-flight send to be canceled")
+	}
+}
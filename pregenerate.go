@@ -0,0 +1,204 @@
+package webpush
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidPregeneratedHeader is returned when importing a header that is not a vapid Authorization header
+var ErrInvalidPregeneratedHeader = errors.New("webpush: invalid pregenerated VAPID header")
+
+// PregeneratedVAPIDHeader is a vapid Authorization header signed ahead of time,
+// e.g. on an air-gapped signing host, for the push service origin Audience
+type PregeneratedVAPIDHeader struct {
+	Audience   string    `json:"audience"`
+	Subscriber string    `json:"subscriber"`
+	PublicKey  string    `json:"publicKey"`
+	Expiration time.Time `json:"expiration"`
+	Header     string    `json:"header"`
+}
+
+// pregeneratedStore holds the imported headers keyed by publicKey + audience + subscriber,
+// each sorted by expiration
+type pregeneratedStore struct {
+	mu      sync.RWMutex
+	entries map[string][]vapidCacheEntry
+}
+
+// PregenerateVAPIDHeaders signs the Authorization headers of every active key pair for each
+// audience and expiration. Expirations may be further away than MaxVAPIDLifetime: a header is
+// only used by the runtime once its expiration is within MaxVAPIDLifetime.
+func (c *Client) PregenerateVAPIDHeaders(audiences []string, expirations []time.Time) ([]PregeneratedVAPIDHeader, error) {
+	now := c.now()
+
+	var headers []PregeneratedVAPIDHeader
+	for _, audience := range audiences {
+		for _, keys := range c.keys.active(now) {
+			params := c.vapidHeaderParams(audience, &Options{
+				Subscriber:      c.subscriber,
+				Audience:        c.audience,
+				VAPIDPublicKey:  keys.PublicKey,
+				VAPIDPrivateKey: keys.PrivateKey,
+			})
+			// Don't fill the signing host's own cache
+			params.noCache = true
+
+			normalizedAudience, _, subscriber, err := params.resolve()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, expiration := range expirations {
+				if !expiration.After(now) {
+					return nil, ErrInvalidVAPIDLifetime
+				}
+
+				params.expiration = expiration
+				header, err := getVAPIDHeader(params)
+				if err != nil {
+					return nil, err
+				}
+
+				headers = append(headers, PregeneratedVAPIDHeader{
+					Audience:   normalizedAudience,
+					Subscriber: subscriber,
+					PublicKey:  keys.PublicKey,
+					Expiration: expiration,
+					Header:     header,
+				})
+			}
+		}
+	}
+
+	return headers, nil
+}
+
+// ImportVAPIDHeaders loads pregenerated headers into the Client cache. They are used for
+// notifications with the same VAPID public key, audience and subscriber, so the runtime
+// host needs no VAPID private key.
+func (c *Client) ImportVAPIDHeaders(headers []PregeneratedVAPIDHeader) error {
+	return c.cache.pregenerated.store(headers)
+}
+
+// LoadVAPIDHeaders imports the JSON encoded pregenerated headers read from r
+func (c *Client) LoadVAPIDHeaders(r io.Reader) error {
+	var headers []PregeneratedVAPIDHeader
+	if err := json.NewDecoder(r).Decode(&headers); err != nil {
+		return err
+	}
+
+	return c.ImportVAPIDHeaders(headers)
+}
+
+// ImportVAPIDHeaders loads pregenerated headers into the shared cache of the package level functions
+func ImportVAPIDHeaders(headers []PregeneratedVAPIDHeader) error {
+	return defaultClient.ImportVAPIDHeaders(headers)
+}
+
+// LoadVAPIDHeaders imports the JSON encoded pregenerated headers read from r into the shared cache
+func LoadVAPIDHeaders(r io.Reader) error {
+	return defaultClient.LoadVAPIDHeaders(r)
+}
+
+// store adds headers to the imported headers
+func (p *pregeneratedStore) store(headers []PregeneratedVAPIDHeader) error {
+	keys := make([]string, len(headers))
+	for i, header := range headers {
+		if !strings.HasPrefix(header.Header, "vapid t=") || header.Expiration.IsZero() {
+			return ErrInvalidPregeneratedHeader
+		}
+
+		key, err := pregeneratedHeaderKey(header.PublicKey, header.Audience, header.Subscriber)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, header := range headers {
+		entries := append(p.entries[keys[i]], vapidCacheEntry{
+			header:     header.Header,
+			expiration: header.Expiration,
+		})
+		sort.Slice(entries, func(a, b int) bool {
+			return entries[a].expiration.Before(entries[b].expiration)
+		})
+		p.entries[keys[i]] = entries
+	}
+
+	return nil
+}
+
+// lookup returns the imported header usable at now with the most time left
+func (p *pregeneratedStore) lookup(vapidPublicKey, audience, subscriber string, now time.Time, margin time.Duration) (string, bool) {
+	key, err := pregeneratedHeaderKey(vapidPublicKey, audience, subscriber)
+	if err != nil {
+		return "", false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entries := p.entries[key]
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		// Push services reject tokens expiring more than 24 hours after the request
+		if !entry.expiration.After(now.Add(MaxVAPIDLifetime)) && now.Add(margin).Before(entry.expiration) {
+			return entry.header, true
+		}
+	}
+
+	return "", false
+}
+
+// sweep drops the imported headers that expire before they could be used at now
+func (p *pregeneratedStore) sweep(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, entries := range p.entries {
+		// Entries are sorted by expiration
+		i := 0
+		for i < len(entries) && !now.Add(minHeaderValidity).Before(entries[i].expiration) {
+			i++
+		}
+
+		if i == len(entries) {
+			delete(p.entries, key)
+		} else {
+			p.entries[key] = entries[i:]
+		}
+	}
+}
+
+// forgetMatching drops the imported headers whose key matches
+func (p *pregeneratedStore) forgetMatching(matches func(key string) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key := range p.entries {
+		if matches(key) {
+			delete(p.entries, key)
+		}
+	}
+}
+
+// pregeneratedHeaderKey returns the key of pregenerated headers, the public key
+// is re-encoded so any base64 form of it matches
+func pregeneratedHeaderKey(vapidPublicKey, audience, subscriber string) (string, error) {
+	decoded, err := decodeVapidKey(vapidPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(decoded) + "|" + audience + "|" + subscriber, nil
+}
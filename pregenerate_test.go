@@ -0,0 +1,85 @@
+package webpush
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPregenerateVAPIDHeaders(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	now := time.Now()
+
+	signer, err := NewClient(WithVAPIDKeys(keys), WithSubscriber("ops@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audience := "https://Updates.Push.Services.Mozilla.com:443"
+	expirations := []time.Time{now.Add(MaxVAPIDLifetime), now.Add(MaxVAPIDLifetime + DefaultVAPIDLifetime)}
+	headers, err := signer.PregenerateVAPIDHeaders([]string{audience}, expirations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(headers) != 2 || headers[0].Audience != "https://updates.push.services.mozilla.com" || headers[0].Subscriber != "mailto:ops@example.com" {
+		t.Fatalf("Incorrect pregenerated headers, got %+v", headers)
+	}
+
+	data, err := json.Marshal(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runtime, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runtime.LoadVAPIDHeaders(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The runtime host only knows the public key
+	lookup := func(now time.Time) (string, error) {
+		return getVAPIDHeader(&vapidHeaderParams{
+			endpoint:       "https://updates.push.services.mozilla.com/wpush/v2/abc",
+			subscriber:     "ops@example.com",
+			vapidPublicKey: keys.PublicKey,
+			expiration:     now.Add(DefaultVAPIDLifetime),
+			now:            now,
+			cache:          runtime.cache,
+		})
+	}
+
+	header, err := lookup(now)
+	if err != nil || header != headers[0].Header {
+		t.Fatalf("Expected the first pregenerated header, got %v", err)
+	}
+
+	// Once the first header nears expiration the second one takes over
+	header, err = lookup(now.Add(MaxVAPIDLifetime - time.Minute))
+	if err != nil || header != headers[1].Header {
+		t.Fatalf("Expected the second pregenerated header, got %v", err)
+	}
+
+	// Without a usable header there is nothing to sign with
+	if _, err := lookup(now.Add(3 * MaxVAPIDLifetime)); err == nil {
+		t.Fatal("Expected an error without a private key")
+	}
+}
+
+func TestImportVAPIDHeadersRejectsInvalidHeaders(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	err := ImportVAPIDHeaders([]PregeneratedVAPIDHeader{{
+		Audience:   "https://fcm.googleapis.com",
+		PublicKey:  keys.PublicKey,
+		Expiration: time.Now().Add(time.Hour),
+		Header:     "Bearer abc",
+	}})
+	if err != ErrInvalidPregeneratedHeader {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidPregeneratedHeader, err)
+	}
+}
@@ -0,0 +1,83 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultVAPIDProviderTTL is how long keys resolved through a VAPIDProvider are cached
+const DefaultVAPIDProviderTTL = 5 * time.Minute
+
+// ErrNoVAPIDProvider is returned when a notification has a TenantID but the Client has no VAPIDProvider
+var ErrNoVAPIDProvider = errors.New("webpush: TenantID set but no VAPIDProvider configured")
+
+// VAPIDProvider resolves the VAPID keys and subscriber of a tenant at send time,
+// for multi-tenant platforms keeping keys in their own store
+type VAPIDProvider interface {
+	KeysFor(ctx context.Context, tenantID string) (keys VAPIDKeys, subscriber string, err error)
+}
+
+// VAPIDProviderFunc adapts a function to the VAPIDProvider interface
+type VAPIDProviderFunc func(ctx context.Context, tenantID string) (VAPIDKeys, string, error)
+
+// KeysFor calls f(ctx, tenantID)
+func (f VAPIDProviderFunc) KeysFor(ctx context.Context, tenantID string) (VAPIDKeys, string, error) {
+	return f(ctx, tenantID)
+}
+
+// tenantKeys is a cached VAPIDProvider result
+type tenantKeys struct {
+	keys       VAPIDKeys
+	subscriber string
+	expiration time.Time
+}
+
+// tenantKeyCache caches VAPIDProvider results per tenant
+type tenantKeyCache struct {
+	provider VAPIDProvider
+	ttl      time.Duration
+	entries  sync.Map
+}
+
+// WithVAPIDProvider resolves the keys of notifications sent with Options.TenantID through provider.
+// Results are validated and cached for ttl, or DefaultVAPIDProviderTTL when ttl is zero.
+func WithVAPIDProvider(provider VAPIDProvider, ttl time.Duration) ClientOption {
+	return func(c *Client) error {
+		if ttl <= 0 {
+			ttl = DefaultVAPIDProviderTTL
+		}
+
+		c.tenants = &tenantKeyCache{provider: provider, ttl: ttl}
+		return nil
+	}
+}
+
+// keysFor returns the cached keys of tenantID, asking the provider when missing or expired
+func (t *tenantKeyCache) keysFor(ctx context.Context, tenantID string, now time.Time) (VAPIDKeys, string, error) {
+	if cached, ok := t.entries.Load(tenantID); ok {
+		entry := cached.(tenantKeys)
+		if now.Before(entry.expiration) {
+			return entry.keys, entry.subscriber, nil
+		}
+		t.entries.Delete(tenantID)
+	}
+
+	keys, subscriber, err := t.provider.KeysFor(ctx, tenantID)
+	if err != nil {
+		return VAPIDKeys{}, "", err
+	}
+
+	if err := keys.Validate(); err != nil {
+		return VAPIDKeys{}, "", err
+	}
+
+	t.entries.Store(tenantID, tenantKeys{
+		keys:       keys,
+		subscriber: subscriber,
+		expiration: now.Add(t.ttl),
+	})
+
+	return keys, subscriber, nil
+}
@@ -0,0 +1,58 @@
+package webpush
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientVAPIDProvider(t *testing.T) {
+	tenantKeys := map[string]VAPIDKeys{
+		"acme":   getTestVAPIDKeys(t),
+		"globex": getTestVAPIDKeys(t),
+	}
+
+	lookups := 0
+	provider := VAPIDProviderFunc(func(ctx context.Context, tenantID string) (VAPIDKeys, string, error) {
+		lookups++
+		return tenantKeys[tenantID], tenantID + "@example.com", nil
+	})
+
+	client, err := NewClient(WithVAPIDProvider(provider, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tenantID := range []string{"acme", "globex", "acme"} {
+		httpClient := &recordingHTTPClient{}
+		_, err := client.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), &Options{
+			HTTPClient: httpClient,
+			TenantID:   tenantID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasSuffix(httpClient.req.Header.Get("Authorization"), "k="+tenantKeys[tenantID].PublicKey) {
+			t.Fatalf("Notification for %s was not signed with the tenant key", tenantID)
+		}
+	}
+
+	// The second acme send is served from the tenant cache
+	if lookups != 2 {
+		t.Fatalf("Incorrect number of provider lookups, expected=2, got=%d", lookups)
+	}
+}
+
+func TestClientTenantIDWithoutProvider(t *testing.T) {
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Send(context.Background(), []byte("Test"), getURLEncodedTestSubscription(), &Options{TenantID: "acme"})
+	if err != ErrNoVAPIDProvider {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrNoVAPIDProvider, err)
+	}
+}
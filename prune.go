@@ -0,0 +1,74 @@
+package webpush
+
+import (
+	"context"
+)
+
+// GoneTag is the tag of subscriptions marked as gone by pruning with PruneOptions.Mark
+const GoneTag = "webpush:gone"
+
+// PruneOptions configure WithStorePruning
+type PruneOptions struct {
+	// Mark tags gone subscriptions with GoneTag instead of deleting them
+	Mark bool
+
+	// DryRun only logs the subscriptions that would be pruned, while operators build confidence
+	DryRun bool
+}
+
+// subscriptionPruner removes gone subscriptions from a SubscriptionStore
+type subscriptionPruner struct {
+	store   SubscriptionStore
+	options PruneOptions
+}
+
+// WithStorePruning deletes a subscription from store, or marks it with options.Mark, when the push
+// service reports it gone with a 404 or 410 response. Store errors are logged, they don't fail the send.
+func WithStorePruning(store SubscriptionStore, options PruneOptions) ClientOption {
+	return func(c *Client) error {
+		c.pruner = nil
+		if store != nil {
+			c.pruner = &subscriptionPruner{store: store, options: options}
+		}
+
+		return nil
+	}
+}
+
+// prune removes the gone subscription s from the store
+func (p *subscriptionPruner) prune(ctx context.Context, s *Subscription, host string, logger Logger, correlation string) {
+	if p.options.DryRun {
+		logger.Info("webpush: dry run, would prune gone subscription", "host", host, "mark", p.options.Mark,
+			"correlation_id", correlation)
+		return
+	}
+
+	var err error
+	if p.options.Mark {
+		err = p.mark(ctx, s.Endpoint)
+	} else {
+		err = p.store.Delete(ctx, s.Endpoint)
+	}
+
+	if err != nil {
+		logger.Warn("webpush: pruning gone subscription failed", "host", host, "error", loggableError(err),
+			"correlation_id", correlation)
+		return
+	}
+
+	logger.Debug("webpush: pruned gone subscription", "host", host, "mark", p.options.Mark, "correlation_id", correlation)
+}
+
+// mark adds GoneTag to the stored subscription of endpoint
+func (p *subscriptionPruner) mark(ctx context.Context, endpoint string) error {
+	stored, err := p.store.Get(ctx, endpoint)
+	if err == ErrSubscriptionNotFound {
+		return nil
+	}
+	if err != nil || stored.HasTag(GoneTag) {
+		return err
+	}
+
+	stored.Tags = append(stored.Tags, GoneTag)
+	return p.store.Save(ctx, stored)
+}
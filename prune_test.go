@@ -0,0 +1,55 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestWithStorePruning(t *testing.T) {
+	tests := []struct {
+		name    string
+		options PruneOptions
+		status  int
+		kept    bool
+		marked  bool
+	}{
+		{name: "delete gone", status: http.StatusGone},
+		{name: "delete not found", status: http.StatusNotFound},
+		{name: "mark", options: PruneOptions{Mark: true}, status: http.StatusGone, kept: true, marked: true},
+		{name: "dry run", options: PruneOptions{DryRun: true}, status: http.StatusGone, kept: true},
+		{name: "delivered", status: http.StatusCreated, kept: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemorySubscriptionStore()
+			stored := &StoredSubscription{Subscription: *getStandardEncodedTestSubscription(), Tags: []string{"news"}}
+			if err := store.Save(ctx, stored); err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithStorePruning(store, test.options))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: test.status}, nil
+			})
+			if _, err := client.Send(ctx, []byte("Test"), &stored.Subscription, &Options{HTTPClient: httpClient}); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.Get(ctx, stored.Endpoint)
+			if kept := err == nil; kept != test.kept {
+				t.Fatalf("Expected the subscription kept %t, got %v", test.kept, err)
+			}
+
+			if test.kept && got.HasTag(GoneTag) != test.marked {
+				t.Fatalf("Expected the subscription marked %t, got tags %v", test.marked, got.Tags)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package webpush
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidConnectionRecycling is returned by WithConnectionRecycling for negative limits
+var ErrInvalidConnectionRecycling = errors.New("webpush: connection recycling limits must not be negative")
+
+// connectionRecycler retires the connection to an origin after a number of requests or an age
+type connectionRecycler struct {
+	maxRequests int
+	maxAge      time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	origins map[string]*recycledOrigin
+}
+
+// recycledOrigin counts the requests to an origin since its connection was last retired
+type recycledOrigin struct {
+	requests int
+	since    time.Time
+}
+
+// WithConnectionRecycling retires the connection to a push service origin after maxRequests requests
+// or maxAge, for push front ends that silently drop very old connections. The request reaching a
+// limit closes its connection once the response is read, requests in flight on it complete and the
+// next request dials a new connection. Limits are counted per origin, which matches the connection
+// with HTTP/2; zero disables a limit.
+func WithConnectionRecycling(maxRequests int, maxAge time.Duration) ClientOption {
+	return func(c *Client) error {
+		if maxRequests < 0 || maxAge < 0 {
+			return ErrInvalidConnectionRecycling
+		}
+
+		if maxRequests == 0 && maxAge == 0 {
+			c.recycler = nil
+			return nil
+		}
+
+		c.recycler = &connectionRecycler{
+			maxRequests: maxRequests,
+			maxAge:      maxAge,
+			now:         func() time.Time { return c.now() },
+			origins:     make(map[string]*recycledOrigin),
+		}
+		return nil
+	}
+}
+
+// wrap returns client retiring connections at the limits of r
+func (r *connectionRecycler) wrap(client HTTPClient) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		if r.retire(strings.ToLower(req.URL.Scheme + "://" + req.URL.Host)) {
+			req.Close = true
+		}
+
+		return client.Do(req)
+	})
+}
+
+// retire counts a request to origin and reports whether it should retire the connection
+func (r *connectionRecycler) retire(origin string) bool {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.origins[origin]
+	if !ok {
+		o = &recycledOrigin{since: now}
+		r.origins[origin] = o
+	}
+
+	o.requests++
+	if (r.maxRequests > 0 && o.requests >= r.maxRequests) || (r.maxAge > 0 && now.Sub(o.since) >= r.maxAge) {
+		o.requests = 0
+		o.since = now
+		return true
+	}
+
+	return false
+}
+
+// CloseIdleConnections closes the idle connections of the HTTPClient of the Client.
+// Without WithHTTPClient or WithTransport that is http.DefaultTransport, shared by the process.
+func (c *Client) CloseIdleConnections() {
+	client := c.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if closer, ok := client.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,109 @@
+package webpush
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newConnCountingServer returns an HTTP/2 TLS test server counting the connections it accepts and closes
+func newConnCountingServer(connections, closed *int32) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			atomic.AddInt32(connections, 1)
+		case http.StateClosed:
+			atomic.AddInt32(closed, 1)
+		}
+	}
+	server.StartTLS()
+
+	return server
+}
+
+func TestClientConnectionRecycling(t *testing.T) {
+	var connections, closed int32
+	server := newConnCountingServer(&connections, &closed)
+	defer server.Close()
+
+	now := time.Now()
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(server.Client()),
+		WithClock(func() time.Time { return now }),
+		WithConnectionRecycling(2, time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	send := func() {
+		resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// Every second request retires its connection
+	for i := 0; i < 5; i++ {
+		send()
+	}
+
+	if got := atomic.LoadInt32(&connections); got != 3 {
+		t.Fatalf("Incorrect connections after recycling by requests, expected=3, got=%d", got)
+	}
+
+	// The fifth request started a new count, an hour later its connection is retired by age
+	now = now.Add(time.Hour)
+	send()
+	send()
+
+	if got := atomic.LoadInt32(&connections); got != 4 {
+		t.Fatalf("Incorrect connections after recycling by age, expected=4, got=%d", got)
+	}
+
+	if _, err := NewClient(WithConnectionRecycling(-1, 0)); err != ErrInvalidConnectionRecycling {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidConnectionRecycling, err)
+	}
+}
+
+func TestClientCloseIdleConnections(t *testing.T) {
+	var connections, closed int32
+	server := newConnCountingServer(&connections, &closed)
+	defer server.Close()
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// The stream of the response is released asynchronously
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&closed) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Idle connection was not closed")
+		}
+
+		client.CloseIdleConnections()
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,111 @@
+package webpush
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidRedirectPolicy is returned by WithRedirectPolicy for a negative number of hops
+var ErrInvalidRedirectPolicy = errors.New("webpush: redirect hops must not be negative")
+
+// RedirectPolicy configures how a Client follows push service redirects, e.g. a private gateway
+// answering 307 with a regional endpoint. Only 307 and 308 redirects are followed, they keep the
+// method and the encrypted payload; other redirects are returned as they are.
+type RedirectPolicy struct {
+	MaxHops int // Redirects followed per notification, zero doesn't follow redirects
+
+	// KeepAuthorization sends the VAPID Authorization header to redirect targets on another origin,
+	// it is always kept on the same origin
+	KeepAuthorization bool
+}
+
+// WithRedirectPolicy follows push service redirects according to policy.
+// Without it redirects are not followed: the 3xx response is returned to the caller.
+// An HTTPClient set with WithHTTPClient or in the Options must not follow redirects itself
+// for the policy to apply.
+func WithRedirectPolicy(policy RedirectPolicy) ClientOption {
+	return func(c *Client) error {
+		if policy.MaxHops < 0 {
+			return ErrInvalidRedirectPolicy
+		}
+
+		c.redirects = nil
+		if policy.MaxHops > 0 {
+			c.redirects = &policy
+		}
+
+		return nil
+	}
+}
+
+// noRedirects is the CheckRedirect of the http.Clients built by the library, redirects are left to RedirectPolicy
+func noRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// wrap returns client following the redirects allowed by p
+func (p *RedirectPolicy) wrap(client HTTPClient, allowInsecure bool) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := client.Do(req)
+
+		for hops := 0; err == nil && hops < p.MaxHops; hops++ {
+			if resp.StatusCode != http.StatusTemporaryRedirect && resp.StatusCode != http.StatusPermanentRedirect {
+				break
+			}
+
+			next, ok := p.follow(req, resp, allowInsecure)
+			if !ok {
+				break
+			}
+
+			if resp.Body != nil {
+				_, _ = io.CopyN(ioutil.Discard, resp.Body, maxResponseDrain)
+				resp.Body.Close()
+			}
+
+			req = next
+			resp, err = client.Do(req)
+		}
+
+		return resp, err
+	})
+}
+
+// follow returns the request following the redirect resp to req, false when it can't be followed
+func (p *RedirectPolicy) follow(req *http.Request, resp *http.Response, allowInsecure bool) (*http.Request, bool) {
+	location, err := req.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Host == "" {
+		return nil, false
+	}
+
+	if location.Scheme != "https" && !(allowInsecure && location.Scheme == "http") {
+		return nil, false
+	}
+
+	next := req.Clone(req.Context())
+	next.URL = location
+	next.Host = ""
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, false
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, false
+		}
+
+		next.Body = body
+	}
+
+	sameOrigin := strings.EqualFold(req.URL.Scheme, location.Scheme) && strings.EqualFold(req.URL.Host, location.Host)
+	if !sameOrigin && !p.KeepAuthorization {
+		next.Header.Del("Authorization")
+	}
+
+	return next, true
+}
@@ -0,0 +1,76 @@
+package webpush
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientRedirectPolicy(t *testing.T) {
+	var regionalAuth string
+	var regionalBody []byte
+	regional := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		regionalAuth = r.Header.Get("Authorization")
+		regionalBody, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer regional.Close()
+
+	var sentBody []byte
+	status := http.StatusTemporaryRedirect
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sentBody, _ = ioutil.ReadAll(r.Body)
+		http.Redirect(w, r, regional.URL+"/push/abc", status)
+	}))
+	defer gateway.Close()
+
+	send := func(options ...ClientOption) *http.Response {
+		regionalAuth, regionalBody = "", nil
+		options = append(options, WithVAPIDKeys(getTestVAPIDKeys(t)), WithInsecureEndpoints())
+		client, err := NewClient(options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := getStandardEncodedTestSubscription()
+		s.Endpoint = gateway.URL + "/push/abc"
+		resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	// Redirects are not followed by default
+	if resp := send(); resp.StatusCode != http.StatusTemporaryRedirect || regionalBody != nil {
+		t.Fatalf("Expected the redirect to be returned, got status=%d", resp.StatusCode)
+	}
+
+	// The method and payload are kept, the Authorization header stays on its origin
+	if resp := send(WithRedirectPolicy(RedirectPolicy{MaxHops: 1})); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected the redirect to be followed, got status=%d", resp.StatusCode)
+	}
+
+	if string(regionalBody) != string(sentBody) || regionalAuth != "" {
+		t.Fatalf("Incorrect redirected request, body kept=%v authorization=%q", string(regionalBody) == string(sentBody), regionalAuth)
+	}
+
+	send(WithRedirectPolicy(RedirectPolicy{MaxHops: 1, KeepAuthorization: true}))
+	if regionalAuth == "" {
+		t.Fatal("Expected the Authorization header to be kept")
+	}
+
+	// A 302 would turn the POST into a GET and lose the payload
+	status = http.StatusFound
+	if resp := send(WithRedirectPolicy(RedirectPolicy{MaxHops: 1})); resp.StatusCode != http.StatusFound || regionalBody != nil {
+		t.Fatalf("Expected the 302 to be returned, got status=%d", resp.StatusCode)
+	}
+
+	if _, err := NewClient(WithRedirectPolicy(RedirectPolicy{MaxHops: -1})); err != ErrInvalidRedirectPolicy {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidRedirectPolicy, err)
+	}
+}
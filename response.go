@@ -0,0 +1,64 @@
+package webpush
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+// maxResponseDrain bounds the unread response bytes discarded on Close to keep a connection reusable.
+// Push service responses are small, a larger body is cheaper to abandon with its connection.
+const maxResponseDrain = 64 << 10
+
+// TransportStats counts the push service requests of a Client and how often they reused a connection
+type TransportStats struct {
+	Requests          uint64 // Requests that got a connection
+	ReusedConnections uint64 // Requests sent over an already established connection
+	InFlight          int64  // Sends waiting for a push service response
+}
+
+// transportCounters backs TransportStats
+type transportCounters struct {
+	requests uint64
+	reused   uint64
+	inFlight int64
+}
+
+// TransportStats returns the connection reuse counters and the sends in flight of the Client.
+// A falling reuse rate means connections are being discarded, e.g. by bodies left unread.
+func (c *Client) TransportStats() TransportStats {
+	return TransportStats{
+		Requests:          atomic.LoadUint64(&c.transportStats.requests),
+		ReusedConnections: atomic.LoadUint64(&c.transportStats.reused),
+		InFlight:          atomic.LoadInt64(&c.transportStats.inFlight),
+	}
+}
+
+// GetTransportStats returns the connection reuse counters of the package level functions
+func GetTransportStats() TransportStats {
+	return defaultClient.TransportStats()
+}
+
+// clientTrace returns the httptrace hooks counting connection reuse into s
+func (s *transportCounters) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			atomic.AddUint64(&s.requests, 1)
+			if info.Reused {
+				atomic.AddUint64(&s.reused, 1)
+			}
+		},
+	}
+}
+
+// drainingBody discards the unread rest of a response body on Close,
+// so the connection goes back to the pool even when the caller doesn't read the body
+type drainingBody struct {
+	io.ReadCloser
+}
+
+func (b *drainingBody) Close() error {
+	_, _ = io.CopyN(ioutil.Discard, b.ReadCloser, maxResponseDrain)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,43 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientDrainsResponseBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(strings.Repeat("invalid subscription ", 2000)))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTransportOptions(TransportOptions{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+
+	// Bodies closed without being read still leave their connection reusable
+	for i := 0; i < 3; i++ {
+		resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	stats := client.TransportStats()
+	if stats.Requests != 3 || stats.ReusedConnections != 2 {
+		t.Fatalf("Incorrect transport stats, got %+v", stats)
+	}
+}
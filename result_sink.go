@@ -0,0 +1,97 @@
+package webpush
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ResultSink receives the outcome of every notification of a bulk send, e.g. to write them to a file
+// or a database instead of keeping them in memory
+type ResultSink interface {
+	// Write receives the result of one notification
+	Write(ctx context.Context, result *FanOutResult) error
+	// Flush is called once the bulk send completed, e.g. to write buffered results
+	Flush(ctx context.Context) error
+}
+
+// ResultSinkFunc adapts a function to a ResultSink with nothing to flush
+type ResultSinkFunc func(ctx context.Context, result *FanOutResult) error
+
+// Write implements ResultSink
+func (f ResultSinkFunc) Write(ctx context.Context, result *FanOutResult) error {
+	return f(ctx, result)
+}
+
+// Flush implements ResultSink
+func (f ResultSinkFunc) Flush(context.Context) error {
+	return nil
+}
+
+// ResultRecord is the outcome of a notification as stored by the ResultSinks of this module
+type ResultRecord struct {
+	Endpoint      string     `json:"endpoint"`
+	StatusCode    int        `json:"statusCode,omitempty"`
+	Class         ErrorClass `json:"class,omitempty"` // Empty when the notification was delivered
+	CorrelationID string     `json:"correlationId,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// NewResultRecord returns the ResultRecord of result
+func NewResultRecord(result *FanOutResult) ResultRecord {
+	record := ResultRecord{Endpoint: result.Subscription.Endpoint}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+	}
+
+	var resp *http.Response
+	if result.Result != nil {
+		resp = result.Result.Response
+		record.CorrelationID = result.Result.CorrelationID
+	}
+	if resp != nil {
+		record.StatusCode = resp.StatusCode
+	}
+	record.Class = ClassifySend(resp, result.Err)
+
+	return record
+}
+
+// JSONLResultSink is a ResultSink writing a ResultRecord per line of JSON
+type JSONLResultSink struct {
+	mu     sync.Mutex
+	writer *bufio.Writer
+}
+
+// NewJSONLResultSink returns a JSONLResultSink writing to w, buffered until Flush
+func NewJSONLResultSink(w io.Writer) *JSONLResultSink {
+	return &JSONLResultSink{writer: bufio.NewWriter(w)}
+}
+
+// Write implements ResultSink
+func (s *JSONLResultSink) Write(_ context.Context, result *FanOutResult) error {
+	line, err := json.Marshal(NewResultRecord(result))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.writer.Write(line); err != nil {
+		return err
+	}
+
+	return s.writer.WriteByte('\n')
+}
+
+// Flush implements ResultSink
+func (s *JSONLResultSink) Flush(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writer.Flush()
+}
@@ -0,0 +1,64 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestJSONLResultSink(t *testing.T) {
+	var sent int32
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&sent, 1) == 2 {
+			return &http.Response{StatusCode: http.StatusGone}, nil
+		}
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := func(*StoredSubscription) ([]byte, *Options, error) { return []byte("Hello"), &Options{TTL: 60}, nil }
+
+	var out bytes.Buffer
+	sink := NewJSONLResultSink(&out)
+	if _, err := client.SendToIterator(context.Background(), &failingIterator{n: 2, err: io.EOF}, payload, sink); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line per result, got %q", out.String())
+	}
+
+	classes := map[ErrorClass]int{}
+	for _, line := range lines {
+		var record ResultRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Endpoint == "" || record.StatusCode == 0 {
+			t.Fatalf("Incomplete record, got %s", line)
+		}
+		classes[record.Class]++
+	}
+	if classes[""] != 1 || classes[ErrorClassGone] != 1 {
+		t.Fatalf("Expected a delivery and a gone subscription, got %v", classes)
+	}
+
+	// A failing sink stops the send
+	errSink := errors.New("disk full")
+	failing := ResultSinkFunc(func(context.Context, *FanOutResult) error { return errSink })
+	atomic.StoreInt32(&sent, 0)
+	counts, err := client.SendToIterator(context.Background(), &failingIterator{n: 100, err: io.EOF}, payload, failing)
+	if err != errSink || counts.Notifications >= 100 {
+		t.Fatalf("Expected the sink error to stop the send, got %+v (%v)", counts, err)
+	}
+}
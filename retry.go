@@ -0,0 +1,142 @@
+package webpush
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// transportRetryBackoff is the wait before the first transport retry, doubled for every further retry
+const transportRetryBackoff = 50 * time.Millisecond
+
+// ErrInvalidTransportRetries is returned by WithTransportRetries for a negative number of retries
+var ErrInvalidTransportRetries = errors.New("webpush: transport retries must not be negative")
+
+// transportRetrier retries requests that failed before reaching the push service
+type transportRetrier struct {
+	retries int
+	backoff time.Duration
+}
+
+// WithTransportRetries retries a request up to retries times when it failed in a way that shows it
+// wasn't sent to the push service: a failed dial, e.g. connection refused, a DNS failure, a failed TLS
+// handshake, or an HTTP/2 GOAWAY or refused stream for a request the push service didn't process.
+// Such a request definitely wasn't delivered, so retrying it can't deliver a notification twice.
+// Certificate verification failures, responses and any other error are never retried.
+func WithTransportRetries(retries int) ClientOption {
+	return func(c *Client) error {
+		if retries < 0 {
+			return ErrInvalidTransportRetries
+		}
+
+		c.retrier = nil
+		if retries > 0 {
+			c.retrier = &transportRetrier{retries: retries, backoff: transportRetryBackoff}
+		}
+
+		return nil
+	}
+}
+
+// wrap returns client retrying the requests that weren't sent while budget, when not nil, allows,
+// calling onRetry before each retry
+func (r *transportRetrier) wrap(client HTTPClient, budget *RetryBudget, onRetry func(req *http.Request, attempt int, backoff time.Duration, err error)) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		attemptReq := req
+		backoff := r.backoff
+
+		for attempt := 0; ; attempt++ {
+			var sent, handshakeFailed int32
+			trace := &httptrace.ClientTrace{
+				WroteHeaders: func() { atomic.StoreInt32(&sent, 1) },
+				TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+					if err != nil {
+						atomic.StoreInt32(&handshakeFailed, 1)
+					}
+				},
+			}
+
+			resp, err := client.Do(attemptReq.WithContext(httptrace.WithClientTrace(ctx, trace)))
+			if err == nil || attempt == r.retries || atomic.LoadInt32(&sent) == 1 || ctx.Err() != nil ||
+				!retryableTransportError(err, atomic.LoadInt32(&handshakeFailed) == 1) {
+				return resp, err
+			}
+
+			// The failed attempt consumed the body, retries need a new one
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return resp, err
+				}
+
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					return resp, err
+				}
+
+				attemptReq = req.Clone(ctx)
+				attemptReq.Body = body
+			}
+
+			if budget != nil && !budget.Retry() {
+				return resp, err
+			}
+
+			recordRetry(requestOrigin(req))
+			onRetry(req, attempt+1, backoff, err)
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return resp, err
+			}
+			backoff *= 2
+		}
+	})
+}
+
+// http2GotGoAway is the message of the error of net/http for a request above the last stream a
+// GOAWAY of the push service accepted, i.e. that it didn't process
+const http2GotGoAway = "http2: Transport received Server's graceful shutdown GOAWAY"
+
+// retryableTransportError reports whether err shows the request wasn't sent, handshakeFailed when the
+// TLS handshake of its connection failed, and a retry may succeed
+func retryableTransportError(err error, handshakeFailed bool) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCertificate x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCertificate) || errors.As(err, &hostname) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &dnsErr), handshakeFailed:
+		return true
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return true
+	}
+
+	return unprocessedHTTP2Error(err)
+}
+
+// unprocessedHTTP2Error reports whether err is a GOAWAY or REFUSED_STREAM error of net/http, whose types
+// aren't exported, for a request the push service didn't process
+func unprocessedHTTP2Error(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		message := err.Error()
+		if message == http2GotGoAway || strings.HasPrefix(message, "stream error: ") && strings.HasSuffix(message, "; REFUSED_STREAM") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,141 @@
+package webpush
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetryBudgetWindow is the period of requests a RetryBudget relates retries to by default
+	DefaultRetryBudgetWindow = 10 * time.Second
+
+	// retryBudgetBuckets is the number of buckets the window of a RetryBudget slides by
+	retryBudgetBuckets = 10
+)
+
+// ErrInvalidRetryBudget is returned by NewRetryBudget for a ratio outside [0, 1] or a negative minimum or window
+var ErrInvalidRetryBudget = errors.New("webpush: retry budget ratio must be between 0 and 1, its minimum and window not negative")
+
+// RetryBudget caps retries at a ratio of the recent request volume, e.g. 10%, so an outage of a push
+// service doesn't turn into a retry storm: once the budget is spent, failed sends fail instead of
+// being retried. Share one RetryBudget between the Clients and workers of a process with WithRetryBudget.
+type RetryBudget struct {
+	ratio  float64
+	min    int
+	bucket time.Duration // Width of a bucket, the window divided by retryBudgetBuckets
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets [retryBudgetBuckets]retryBudgetBucket
+	denied  uint64
+}
+
+// retryBudgetBucket counts the requests and retries of one bucket width
+type retryBudgetBucket struct {
+	index    int64 // Time of the bucket in bucket widths since the Unix epoch
+	requests int
+	retries  int
+}
+
+// RetryBudgetStats is a snapshot of the consumption of a RetryBudget
+type RetryBudgetStats struct {
+	Requests int    // Requests in the window
+	Retries  int    // Retries in the window
+	Limit    int    // Retries the window allows
+	Denied   uint64 // Retries refused since the RetryBudget was created
+}
+
+// NewRetryBudget returns a RetryBudget allowing retries of up to ratio of the requests of the last
+// window, DefaultRetryBudgetWindow when zero, and at least minRetries per window so a quiet process
+// can still retry
+func NewRetryBudget(ratio float64, minRetries int, window time.Duration) (*RetryBudget, error) {
+	if ratio < 0 || ratio > 1 || minRetries < 0 || window < 0 {
+		return nil, ErrInvalidRetryBudget
+	}
+
+	if window == 0 {
+		window = DefaultRetryBudgetWindow
+	}
+
+	bucket := window / retryBudgetBuckets
+	if bucket <= 0 {
+		bucket = 1
+	}
+
+	return &RetryBudget{ratio: ratio, min: minRetries, bucket: bucket, now: time.Now}, nil
+}
+
+// WithRetryBudget counts the sends of the Client in budget and only retries transport failures, see
+// WithTransportRetries, while budget allows
+func WithRetryBudget(budget *RetryBudget) ClientOption {
+	return func(c *Client) error {
+		c.retryBudget = budget
+		return nil
+	}
+}
+
+// Request counts a request, e.g. a send attempt of a worker that doesn't send with a Client of the budget
+func (b *RetryBudget) Request() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current().requests++
+}
+
+// Retry reports whether a retry is within the budget and counts it when it is
+func (b *RetryBudget) Retry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.sum()
+	if stats.Retries >= stats.Limit {
+		b.denied++
+		return false
+	}
+
+	b.current().retries++
+	return true
+}
+
+// Stats returns the requests, retries and limit of the current window and the retries refused so far
+func (b *RetryBudget) Stats() RetryBudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.sum()
+	stats.Denied = b.denied
+
+	return stats
+}
+
+// current returns the bucket of now, emptying it when it held an older bucket
+func (b *RetryBudget) current() *retryBudgetBucket {
+	index := b.now().UnixNano() / int64(b.bucket)
+	bucket := &b.buckets[index%retryBudgetBuckets]
+	if bucket.index != index {
+		*bucket = retryBudgetBucket{index: index}
+	}
+
+	return bucket
+}
+
+// sum returns the requests, retries and limit of the buckets within the window
+func (b *RetryBudget) sum() RetryBudgetStats {
+	index := b.now().UnixNano() / int64(b.bucket)
+
+	var stats RetryBudgetStats
+	for _, bucket := range b.buckets {
+		if bucket.index > index-retryBudgetBuckets && bucket.index <= index {
+			stats.Requests += bucket.requests
+			stats.Retries += bucket.retries
+		}
+	}
+
+	stats.Limit = int(b.ratio * float64(stats.Requests))
+	if stats.Limit < b.min {
+		stats.Limit = b.min
+	}
+
+	return stats
+}
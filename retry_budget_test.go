@@ -0,0 +1,89 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	if _, err := NewRetryBudget(1.5, 0, 0); err != ErrInvalidRetryBudget {
+		t.Fatalf("Expected ErrInvalidRetryBudget, got %v", err)
+	}
+
+	budget, err := NewRetryBudget(0.1, 2, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1000, 0)
+	budget.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		budget.Request()
+	}
+	for i := 0; i < 10; i++ {
+		if !budget.Retry() {
+			t.Fatalf("Expected retry %d within 10%% of 100 requests", i+1)
+		}
+	}
+	if budget.Retry() {
+		t.Fatal("Expected the 11th retry to exceed the budget")
+	}
+
+	stats := budget.Stats()
+	if stats.Requests != 100 || stats.Retries != 10 || stats.Limit != 10 || stats.Denied != 1 {
+		t.Fatalf("Incorrect stats, got %+v", stats)
+	}
+
+	// The requests slide out of the window, the minimum remains
+	now = now.Add(10 * time.Second)
+	stats = budget.Stats()
+	if stats.Requests != 0 || stats.Retries != 0 || stats.Limit != 2 {
+		t.Fatalf("Expected an empty window, got %+v", stats)
+	}
+}
+
+func TestClientRetryBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	budget, err := NewRetryBudget(0, 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dials int32
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTransportOptions(TransportOptions{DialContext: flakyDialer(10, &dials)}),
+		WithTransportRetries(3),
+		WithRetryBudget(budget),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	for i := 0; i < 2; i++ {
+		if _, err := client.Send(context.Background(), []byte("Test"), s, nil); err == nil {
+			t.Fatal("Expected the send to fail")
+		}
+	}
+
+	// The first send retries once, then the budget is spent
+	if dials := atomic.LoadInt32(&dials); dials != 3 {
+		t.Fatalf("Expected 3 dials, got %d", dials)
+	}
+
+	stats := client.Stats().RetryBudget
+	if stats == nil || stats.Requests != 2 || stats.Retries != 1 || stats.Denied != 2 {
+		t.Fatalf("Incorrect retry budget stats, got %+v", stats)
+	}
+}
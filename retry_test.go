@@ -0,0 +1,215 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// flakyDialer fails the first failures dials with connection refused, counting every dial
+func flakyDialer(failures int32, dials *int32) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if atomic.AddInt32(dials, 1) <= failures {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+		}
+
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	}
+}
+
+func TestClientTransportRetries(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	var dials int32
+	events := &recordingEvents{}
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTransportOptions(TransportOptions{DialContext: flakyDialer(2, &dials)}),
+		WithTransportRetries(2),
+		WithEvents(events),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated || atomic.LoadInt32(&dials) != 3 || atomic.LoadInt32(&received) != 1 {
+		t.Fatalf("Expected delivery on the third dial, got status=%d dials=%d received=%d", resp.StatusCode, dials, received)
+	}
+
+	if retries := GetDeliveryStats()[server.URL].Retries; retries != 2 {
+		t.Fatalf("Incorrect retries, expected=2, got=%d", retries)
+	}
+
+	if len(events.retries) != 2 || events.retries[1].Attempt != 2 || events.retries[1].Backoff != 2*transportRetryBackoff {
+		t.Fatalf("Incorrect RetryScheduled events, got %+v", events.retries)
+	}
+
+	// Out of retries the dial error is returned
+	dials = 0
+	client, _ = NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTransportOptions(TransportOptions{DialContext: flakyDialer(5, &dials)}),
+		WithTransportRetries(1),
+	)
+	if _, err := client.Send(context.Background(), []byte("Test"), s, nil); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", syscall.ECONNREFUSED, err)
+	}
+
+	if atomic.LoadInt32(&dials) != 2 {
+		t.Fatalf("Incorrect dials, expected=2, got=%d", dials)
+	}
+}
+
+func TestClientTransportRetriesSkipSentRequests(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+
+		// Drop the connection after the request was received
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTransportOptions(TransportOptions{}),
+		WithTransportRetries(3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	if _, err := client.Send(context.Background(), []byte("Test"), s, nil); err == nil {
+		t.Fatal("Expected an error from the dropped connection")
+	}
+
+	if got := atomic.LoadInt32(&received); got != 1 {
+		t.Fatalf("A request that reached the push service was retried, received=%d", got)
+	}
+}
+
+func TestClientTransportRetriesSkipCertificateErrors(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	var dials int32
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithTransportOptions(TransportOptions{DialContext: flakyDialer(0, &dials)}),
+		WithTransportRetries(3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	if _, err := client.Send(context.Background(), []byte("Test"), s, nil); err == nil {
+		t.Fatal("Expected a certificate error")
+	}
+
+	if got := atomic.LoadInt32(&dials); got != 1 {
+		t.Fatalf("Certificate error was retried, dials=%d", got)
+	}
+}
+
+func TestClientTransportRetriesOnlyPreSendErrors(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"dial", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"dns", &net.DNSError{Err: "no such host", Name: "push.example.com"}, true},
+		{"goaway", errors.New(http2GotGoAway), true},
+		{"refused stream", errors.New("stream error: stream ID 3; REFUSED_STREAM"), true},
+		{"read", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, false},
+		{"canceled stream", errors.New("stream error: stream ID 3; CANCEL"), false},
+		{"unknown", errors.New("custom transport failed"), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// A custom HTTPClient fires no trace hooks, only the error decides
+			var calls int32
+			client, err := NewClient(
+				WithVAPIDKeys(getTestVAPIDKeys(t)),
+				WithHTTPClient(HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+					atomic.AddInt32(&calls, 1)
+					return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: test.err}
+				})),
+				WithTransportRetries(1),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), nil); err == nil {
+				t.Fatal("Expected an error")
+			}
+
+			expected := int32(1)
+			if test.retryable {
+				expected = 2
+			}
+			if got := atomic.LoadInt32(&calls); got != expected {
+				t.Fatalf("Incorrect attempts, expected=%d, got=%d", expected, got)
+			}
+		})
+	}
+}
+
+func TestClientTransportRetriesFailedHandshakes(t *testing.T) {
+	// A plain HTTP server fails the TLS handshakes of an https endpoint
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	var dials int32
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithTransportOptions(TransportOptions{DialContext: flakyDialer(0, &dials)}),
+		WithTransportRetries(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = "https://" + server.Listener.Addr().String() + "/push/abc"
+	if _, err := client.Send(context.Background(), []byte("Test"), s, nil); err == nil {
+		t.Fatal("Expected a handshake error")
+	}
+
+	if got := atomic.LoadInt32(&dials); got != 3 {
+		t.Fatalf("Incorrect dials, expected=3, got=%d", got)
+	}
+}
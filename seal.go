@@ -0,0 +1,163 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sealedPrefix marks a sealed subscription key
+const sealedPrefix = "sealed:"
+
+var (
+	// ErrInvalidSealKey is returned by NewSealer without a key or for keys that are not 16, 24 or 32 bytes
+	ErrInvalidSealKey = errors.New("webpush: seal keys must be 16, 24 or 32 bytes")
+
+	// ErrUnsealFailed is returned when a sealed subscription key can't be decrypted with any key of the Sealer
+	ErrUnsealFailed = errors.New("webpush: unsealing the subscription keys failed")
+)
+
+// Sealer encrypts the p256dh and auth keys of subscriptions with AES-GCM before they are stored,
+// as they are credentials for sending notifications to the browser. The endpoint is authenticated
+// along with the keys, so sealed keys can't be moved to another subscription.
+type Sealer struct {
+	aeads []cipher.AEAD
+}
+
+// NewSealer returns a Sealer sealing with the first of keys and unsealing with any of them,
+// so the seal key can be rotated by prepending a new one while subscriptions are sealed again
+func NewSealer(keys ...[]byte) (*Sealer, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidSealKey
+	}
+
+	sealer := &Sealer{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, ErrInvalidSealKey
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sealer.aeads = append(sealer.aeads, aead)
+	}
+
+	return sealer, nil
+}
+
+// Seal returns a copy of s with sealed keys. Keys already sealed are kept.
+func (sealer *Sealer) Seal(s *StoredSubscription) (*StoredSubscription, error) {
+	sealed := copyStoredSubscription(s)
+	for _, key := range []*string{&sealed.Keys.P256dh, &sealed.Keys.Auth} {
+		if *key == "" || strings.HasPrefix(*key, sealedPrefix) {
+			continue
+		}
+
+		aead := sealer.aeads[0]
+		nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(*key)+aead.Overhead())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+
+		*key = sealedPrefix + base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(*key), []byte(s.Endpoint)))
+	}
+
+	return &sealed, nil
+}
+
+// Unseal returns a copy of s with the keys sealed by Seal decrypted. Keys that are not sealed, e.g.
+// of subscriptions stored before sealing was introduced, are kept.
+func (sealer *Sealer) Unseal(s *StoredSubscription) (*StoredSubscription, error) {
+	unsealed := copyStoredSubscription(s)
+	for _, key := range []*string{&unsealed.Keys.P256dh, &unsealed.Keys.Auth} {
+		if !strings.HasPrefix(*key, sealedPrefix) {
+			continue
+		}
+
+		plaintext, err := sealer.open(strings.TrimPrefix(*key, sealedPrefix), s.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		*key = string(plaintext)
+	}
+
+	return &unsealed, nil
+}
+
+// open decrypts the sealed key encoded with any key of sealer
+func (sealer *Sealer) open(encoded, endpoint string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrUnsealFailed
+	}
+
+	for _, aead := range sealer.aeads {
+		if len(data) < aead.NonceSize() {
+			continue
+		}
+
+		nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(endpoint)); err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, ErrUnsealFailed
+}
+
+// sealedStore seals the subscriptions of a SubscriptionStore
+type sealedStore struct {
+	store  SubscriptionStore
+	sealer *Sealer
+}
+
+// NewSealedStore returns a SubscriptionStore saving the subscriptions to store with sealed keys and
+// returning them unsealed
+func NewSealedStore(store SubscriptionStore, sealer *Sealer) SubscriptionStore {
+	return &sealedStore{store: store, sealer: sealer}
+}
+
+// Save implements SubscriptionStore
+func (s *sealedStore) Save(ctx context.Context, subscription *StoredSubscription) error {
+	sealed, err := s.sealer.Seal(subscription)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Save(ctx, sealed)
+}
+
+// Get implements SubscriptionStore
+func (s *sealedStore) Get(ctx context.Context, endpoint string) (*StoredSubscription, error) {
+	sealed, err := s.store.Get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sealer.Unseal(sealed)
+}
+
+// Delete implements SubscriptionStore
+func (s *sealedStore) Delete(ctx context.Context, endpoint string) error {
+	return s.store.Delete(ctx, endpoint)
+}
+
+// IterateByTag implements SubscriptionStore
+func (s *sealedStore) IterateByTag(ctx context.Context, tag string, fn func(*StoredSubscription) error) error {
+	return s.store.IterateByTag(ctx, tag, func(sealed *StoredSubscription) error {
+		unsealed, err := s.sealer.Unseal(sealed)
+		if err != nil {
+			return err
+		}
+
+		return fn(unsealed)
+	})
+}
@@ -0,0 +1,69 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSealedStore(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	oldSealer, err := NewSealer(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backing := NewMemorySubscriptionStore()
+	s := &StoredSubscription{Subscription: *getURLEncodedTestSubscription(), Tags: []string{"news"}}
+	if err := NewSealedStore(backing, oldSealer).Save(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := backing.Get(ctx, s.Endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw.Keys.P256dh, "sealed:") || !strings.HasPrefix(raw.Keys.Auth, "sealed:") ||
+		strings.Contains(raw.Keys.P256dh, s.Keys.P256dh) {
+		t.Fatalf("Expected sealed keys at rest, got %+v", raw.Keys)
+	}
+
+	// Subscriptions sealed with the old key are still readable after the rotation
+	rotated, err := NewSealer(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewSealedStore(backing, rotated)
+	var got []*StoredSubscription
+	err = store.IterateByTag(ctx, "news", func(s *StoredSubscription) error {
+		got = append(got, s)
+		return nil
+	})
+	if err != nil || len(got) != 1 || got[0].Keys != s.Keys {
+		t.Fatalf("Incorrect unsealed subscriptions, got %+v (%v)", got, err)
+	}
+
+	// Keys moved to another endpoint don't unseal
+	raw.Endpoint = "https://fcm.googleapis.com/fcm/send/other"
+	if _, err := rotated.Unseal(raw); err != ErrUnsealFailed {
+		t.Fatalf("Expected ErrUnsealFailed, got %v", err)
+	}
+
+	// Keys stored before sealing was introduced are returned as they are
+	if err := backing.Save(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := store.Get(ctx, s.Endpoint); err != nil || plain.Keys != s.Keys {
+		t.Fatalf("Incorrect subscription, got %+v (%v)", plain, err)
+	}
+}
+
+func TestNewSealerInvalidKey(t *testing.T) {
+	for _, keys := range [][][]byte{nil, {[]byte("short")}} {
+		if _, err := NewSealer(keys...); err != ErrInvalidSealKey {
+			t.Errorf("Expected ErrInvalidSealKey, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package webpush
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Segment selects stored subscriptions by tags, locale, activity and push service. The filters of a
+// Segment are combined: a subscription has to match every one of them.
+//
+//	segment := webpush.NewSegment(store).Tags("news").Locales("de").ActiveSince(cutoff).PushServices(webpush.PushServiceFCM)
+type Segment struct {
+	store        SubscriptionStore
+	tags         []string
+	excludedTags []string
+	locales      []string
+	activeSince  time.Time
+	services     []PushService
+}
+
+// NewSegment returns a Segment of every subscription of store
+func NewSegment(store SubscriptionStore) *Segment {
+	return &Segment{store: store}
+}
+
+// Tags limits the segment to subscriptions stored under any of tags
+func (s *Segment) Tags(tags ...string) *Segment {
+	s.tags = append(s.tags, tags...)
+	return s
+}
+
+// ExcludeTags drops the subscriptions stored under any of tags from the segment, e.g. ResubscribeTag
+func (s *Segment) ExcludeTags(tags ...string) *Segment {
+	s.excludedTags = append(s.excludedTags, tags...)
+	return s
+}
+
+// Locales limits the segment to subscriptions of any of locales, a language such as "de" matching
+// its regional locales such as "de-AT" too
+func (s *Segment) Locales(locales ...string) *Segment {
+	s.locales = append(s.locales, locales...)
+	return s
+}
+
+// ActiveSince limits the segment to subscriptions last active at or after since
+func (s *Segment) ActiveSince(since time.Time) *Segment {
+	s.activeSince = since
+	return s
+}
+
+// PushServices limits the segment to subscriptions of any of services
+func (s *Segment) PushServices(services ...PushService) *Segment {
+	s.services = append(s.services, services...)
+	return s
+}
+
+// Iterate streams the subscriptions of the segment to fn, once per browser, until fn returns an
+// error, which Iterate returns
+func (s *Segment) Iterate(ctx context.Context, fn func(*StoredSubscription) error) error {
+	tags := s.tags
+	if len(tags) == 0 {
+		tags = []string{""}
+	}
+
+	seen := newDeduplicator()
+	for _, tag := range tags {
+		err := s.store.IterateByTag(ctx, tag, func(subscription *StoredSubscription) error {
+			if !s.matches(subscription) || !seen.add(&subscription.Subscription) {
+				return nil
+			}
+
+			return fn(subscription)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Subscriptions returns the subscriptions of the segment
+func (s *Segment) Subscriptions(ctx context.Context) ([]*StoredSubscription, error) {
+	var subscriptions []*StoredSubscription
+	err := s.Iterate(ctx, func(subscription *StoredSubscription) error {
+		subscriptions = append(subscriptions, subscription)
+		return nil
+	})
+
+	return subscriptions, err
+}
+
+// matches reports whether subscription passes the filters of s other than tags
+func (s *Segment) matches(subscription *StoredSubscription) bool {
+	for _, tag := range s.excludedTags {
+		if subscription.HasTag(tag) {
+			return false
+		}
+	}
+
+	if len(s.locales) > 0 && !matchesLocale(subscription.Locale, s.locales) {
+		return false
+	}
+
+	if !s.activeSince.IsZero() && (subscription.LastActive == nil || subscription.LastActive.Before(s.activeSince)) {
+		return false
+	}
+
+	if len(s.services) > 0 {
+		service := DetectPushService(subscription.Endpoint)
+		for _, want := range s.services {
+			if service == want {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// matchesLocale reports whether locale is one of locales or a regional locale of one of them
+func matchesLocale(locale string, locales []string) bool {
+	for _, want := range locales {
+		if strings.EqualFold(locale, want) ||
+			(len(locale) > len(want) && locale[len(want)] == '-' && strings.EqualFold(locale[:len(want)], want)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SendToSegment sends a notification built by payload to every subscription of segment, like SendToTags
+func (c *Client) SendToSegment(ctx context.Context, segment *Segment, payload PayloadFunc) ([]FanOutResult, error) {
+	subscriptions, err := segment.Subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.fanOut(ctx, subscriptions, payload), nil
+}
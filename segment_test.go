@@ -0,0 +1,77 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSegment(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	recently, longAgo := now.Add(-time.Hour), now.Add(-90*24*time.Hour)
+	store := NewMemorySubscriptionStore()
+	for _, s := range []struct {
+		endpoint   string
+		auth       string
+		locale     string
+		lastActive *time.Time
+		tags       []string
+	}{
+		{"https://fcm.googleapis.com/fcm/send/a", "aaaaaaaaaaaaaaaaaaaaaa", "de-AT", &recently, []string{"news"}},
+		{"https://fcm.googleapis.com/fcm/send/b", "bbbbbbbbbbbbbbbbbbbbbb", "de", &longAgo, []string{"news"}},
+		{"https://fcm.googleapis.com/fcm/send/c", "cccccccccccccccccccccc", "en", &recently, []string{"news"}},
+		{"https://fcm.googleapis.com/fcm/send/d", "dddddddddddddddddddddd", "de", &recently, []string{"sports", ResubscribeTag}},
+		{"https://fcm.googleapis.com/fcm/send/e", "eeeeeeeeeeeeeeeeeeeeee", "de", nil, []string{"sports"}},
+		{"https://updates.push.services.mozilla.com/wpush/v2/f", "ffffffffffffffffffffff", "de", &recently, []string{"sports", "news"}},
+		{"https://fcm.googleapis.com/fcm/send/g", "gggggggggggggggggggggg", "deu", &recently, []string{"news"}},
+	} {
+		stored := &StoredSubscription{Subscription: *getURLEncodedTestSubscription(), Tags: s.tags, Locale: s.locale, LastActive: s.lastActive}
+		stored.Endpoint = s.endpoint
+		stored.Keys.Auth = s.auth
+		if err := store.Save(ctx, stored); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	endpoints := func(segment *Segment) []string {
+		subscriptions, err := segment.Subscriptions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var endpoints []string
+		for _, s := range subscriptions {
+			endpoints = append(endpoints, s.Endpoint[len(s.Endpoint)-1:])
+		}
+		return endpoints
+	}
+
+	if got := endpoints(NewSegment(store).Tags("news", "sports").Locales("de").ActiveSince(now.Add(-24 * time.Hour)).ExcludeTags(ResubscribeTag)); !reflect.DeepEqual(got, []string{"a", "f"}) {
+		t.Fatalf("Incorrect segment, got %v", got)
+	}
+
+	if got := endpoints(NewSegment(store).PushServices(PushServiceMozilla)); !reflect.DeepEqual(got, []string{"f"}) {
+		t.Fatalf("Incorrect Mozilla segment, got %v", got)
+	}
+
+	var requests int32
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&requests, 1)
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := client.SendToSegment(ctx, NewSegment(store).Tags("sports"), func(s *StoredSubscription) ([]byte, *Options, error) {
+		return []byte("Test"), nil, nil
+	})
+	if err != nil || len(results) != 3 || requests != 3 {
+		t.Fatalf("Incorrect results, got %d results and %d requests (%v)", len(results), requests, err)
+	}
+}
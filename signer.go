@@ -0,0 +1,86 @@
+package webpush
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"math/big"
+)
+
+var (
+	// ErrUnsupportedVAPIDSigner is returned when the VAPID signer is not backed by a P-256 ECDSA key
+	ErrUnsupportedVAPIDSigner = errors.New("webpush: VAPID signer must use a P-256 ECDSA key")
+
+	// ErrInvalidSignature is returned when a signer produced a malformed ECDSA signature
+	ErrInvalidSignature = errors.New("webpush: signer returned an invalid ECDSA signature")
+
+	// ErrVAPIDSignerKeyMismatch is returned when the VAPID public key is not the public key of the VAPID signer
+	ErrVAPIDSignerKeyMismatch = errors.New("webpush: VAPID public key does not match the VAPID signer")
+)
+
+// ecdsaSignature is the ASN.1 structure of an ECDSA signature as returned by crypto.Signer
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// signerPublicKey returns the P-256 public key of a signer
+func signerPublicKey(signer crypto.Signer) (*ecdsa.PublicKey, error) {
+	pubKey, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok || pubKey.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedVAPIDSigner
+	}
+
+	return pubKey, nil
+}
+
+// marshalSignerPublicKey returns the uncompressed P-256 public key of a signer
+func marshalSignerPublicKey(signer crypto.Signer) ([]byte, error) {
+	pubKey, err := signerPublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return elliptic.Marshal(pubKey.Curve, pubKey.X, pubKey.Y), nil
+}
+
+// signES256 signs a JWS signing input with an ES256 crypto.Signer and returns the base64 URL encoded signature.
+// Signers return ASN.1 DER signatures while JWS requires the raw r || s form (RFC 7518 section 3.4).
+func signES256(signer crypto.Signer, signingString string) (string, error) {
+	digest := sha256.Sum256([]byte(signingString))
+
+	der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := derToJOSESignature(der, 32)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// derToJOSESignature converts an ASN.1 DER ECDSA signature into r || s, each padded to size bytes
+func derToJOSESignature(der []byte, size int) ([]byte, error) {
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+		return nil, ErrInvalidSignature
+	}
+
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.BitLen() > size*8 || sig.S.BitLen() > size*8 {
+		return nil, ErrInvalidSignature
+	}
+
+	signature := make([]byte, 2*size)
+	sig.R.FillBytes(signature[:size])
+	sig.S.FillBytes(signature[size:])
+
+	return signature, nil
+}
@@ -0,0 +1,135 @@
+package webpush
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testSigner hides the private key behind the crypto.Signer interface, like an HSM would
+type testSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *testSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *testSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
+
+func TestVAPIDWithSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	vapidAuthHeader, err := getVAPIDHeader(&vapidHeaderParams{
+		endpoint:   s.Endpoint,
+		subscriber: "test@test.com",
+		signer:     &testSigner{key: key},
+		expiration: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenString := getTokenFromAuthorizationHeader(vapidAuthHeader, t)
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	if err != nil || !token.Valid {
+		t.Fatalf("Token signed by crypto.Signer did not verify: %v", err)
+	}
+
+	// The k parameter carries the signer's public key
+	expectedKey := base64.RawURLEncoding.EncodeToString(elliptic.Marshal(key.Curve, key.X, key.Y))
+	if vapidAuthHeader[len(vapidAuthHeader)-len(expectedKey):] != expectedKey {
+		t.Fatal("Authorization header does not carry the signer public key")
+	}
+}
+
+func TestVAPIDWithUnsupportedSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = getVAPIDHeader(&vapidHeaderParams{
+		endpoint:   getStandardEncodedTestSubscription().Endpoint,
+		subscriber: "test@test.com",
+		signer:     &testSigner{key: key},
+		expiration: time.Now().Add(time.Hour),
+	})
+	if err != ErrUnsupportedVAPIDSigner {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrUnsupportedVAPIDSigner, err)
+	}
+}
+
+func TestVAPIDSignerKeyMismatch(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := func(signer *ecdsa.PrivateKey, publicKey *ecdsa.PublicKey) *vapidHeaderParams {
+		return &vapidHeaderParams{
+			endpoint:       getStandardEncodedTestSubscription().Endpoint,
+			subscriber:     "test@test.com",
+			vapidPublicKey: base64.RawURLEncoding.EncodeToString(elliptic.Marshal(publicKey.Curve, publicKey.X, publicKey.Y)),
+			signer:         &testSigner{key: signer},
+			expiration:     time.Now().Add(time.Hour),
+			now:            time.Now(),
+			cache:          newVAPIDCache(),
+		}
+	}
+
+	if _, err := getVAPIDHeader(params(key, &other.PublicKey)); err != ErrVAPIDSignerKeyMismatch {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrVAPIDSignerKeyMismatch, err)
+	}
+
+	_, err = SendNotification([]byte("Test"), getStandardEncodedTestSubscription(), &Options{
+		HTTPClient:     &testHTTPClient{},
+		Subscriber:     "test@test.com",
+		VAPIDPublicKey: base64.RawURLEncoding.EncodeToString(elliptic.Marshal(other.Curve, other.X, other.Y)),
+		VAPIDSigner:    &testSigner{key: key},
+	})
+	if err != ErrVAPIDSignerKeyMismatch {
+		t.Fatalf("Incorrect error sending, expected=%v, got=%v", ErrVAPIDSignerKeyMismatch, err)
+	}
+
+	// The matching public key signs, with a cache entry keyed by the signer
+	matching := params(key, &key.PublicKey)
+	if _, err := getVAPIDHeader(matching); err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey := canonicalVAPIDPublicKey(matching.vapidPublicKey)
+	audience, subscriber := "https://updates.push.services.mozilla.com", "mailto:test@test.com"
+	if _, ok := matching.cache.load(headerCacheKey(publicKey, audience, subscriber, "", "", ""), matching.now); ok {
+		t.Fatal("Signer header cached under the key of an empty private key")
+	}
+	signerKey := "signer:" + string(elliptic.Marshal(key.Curve, key.X, key.Y))
+	if _, ok := matching.cache.load(headerCacheKey(publicKey, audience, subscriber, signerKey, "", ""), matching.now); !ok {
+		t.Fatal("Signer header not cached under the key of the signer")
+	}
+}
+
+func TestDERToJOSESignature(t *testing.T) {
+	if _, err := derToJOSESignature([]byte("not a signature"), 32); err != ErrInvalidSignature {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidSignature, err)
+	}
+}
@@ -0,0 +1,72 @@
+package webpush
+
+import "sync"
+
+// flightGroup runs one call per key at a time, callers arriving while it runs share its result
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a call in progress or completed
+type flight struct {
+	wg     sync.WaitGroup
+	header string
+	err    error
+}
+
+// doAsync starts fn for key in the background unless a call for key is already in flight,
+// it reports whether fn was started
+func (g *flightGroup) doAsync(key string, fn func() (string, error)) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.flights[key]; ok {
+		return false
+	}
+
+	f := g.start(key)
+	go g.run(key, f, fn)
+
+	return true
+}
+
+// do runs fn for key unless a call for key is already in flight, in which case it waits for it
+func (g *flightGroup) do(key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		f.wg.Wait()
+		return f.header, f.err
+	}
+
+	f := g.start(key)
+	g.mu.Unlock()
+
+	g.run(key, f, fn)
+
+	return f.header, f.err
+}
+
+// start registers the flight of key, g.mu must be held
+func (g *flightGroup) start(key string) *flight {
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+
+	f := &flight{}
+	f.wg.Add(1)
+	g.flights[key] = f
+
+	return f
+}
+
+// run calls fn and completes the flight of key
+func (g *flightGroup) run(key string, f *flight, fn func() (string, error)) {
+	f.header, f.err = fn()
+	f.wg.Done()
+
+	g.mu.Lock()
+	delete(g.flights, key)
+	g.mu.Unlock()
+}
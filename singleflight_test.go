@@ -0,0 +1,68 @@
+package webpush
+
+import (
+	"crypto"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowJWTEncoder blocks every signing until release is closed
+type slowJWTEncoder struct {
+	encoded int32
+	release chan struct{}
+}
+
+func (e *slowJWTEncoder) Encode(claims map[string]interface{}, key crypto.Signer) (string, error) {
+	atomic.AddInt32(&e.encoded, 1)
+	<-e.release
+	return defaultJWTEncoder.Encode(claims, key)
+}
+
+func TestConcurrentMissesSignOnce(t *testing.T) {
+	encoder := &slowJWTEncoder{release: make(chan struct{})}
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithJWTEncoder(encoder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	headers := make([]string, 50)
+	for i := range headers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			header, err := client.vapidHeaderFor("https://fcm.googleapis.com")
+			if err != nil {
+				t.Error(err)
+			}
+			headers[i] = header
+		}(i)
+	}
+
+	// Let the goroutines pile up on the missing header
+	time.Sleep(20 * time.Millisecond)
+	close(encoder.release)
+	wg.Wait()
+
+	if encoded := atomic.LoadInt32(&encoder.encoded); encoded != 1 {
+		t.Fatalf("Incorrect number of signings, expected=1, got=%d", encoded)
+	}
+
+	for _, header := range headers {
+		if header != headers[0] {
+			t.Fatal("Concurrent callers got different headers")
+		}
+	}
+}
+
+// vapidHeaderFor returns the header of the primary key pair for endpoint
+func (c *Client) vapidHeaderFor(endpoint string) (string, error) {
+	keys := c.keys.active(c.now())[0]
+	return getVAPIDHeader(c.vapidHeaderParams(endpoint, &Options{
+		VAPIDPublicKey:  keys.PublicKey,
+		VAPIDPrivateKey: keys.PrivateKey,
+	}))
+}
@@ -0,0 +1,62 @@
+package webpush
+
+import (
+	"context"
+)
+
+// Span names of the operations traced with WithTracer
+const (
+	SpanSignVAPID = "webpush.sign_vapid" // Getting the VAPID Authorization header, from the cache or by signing
+	SpanEncrypt   = "webpush.encrypt"    // Encrypting the payload
+	SpanSend      = "webpush.send"       // Sending the request to the push service, including retries
+)
+
+// Span attributes set on the traced operations
+const (
+	AttributeOrigin      = "webpush.origin"            // Origin of the push service endpoint
+	AttributePayloadSize = "webpush.payload.size"      // Size of the plaintext payload, or the encrypted one when sending
+	AttributeStatusCode  = "http.response.status_code" // Status code of the push service response
+)
+
+// Tracer starts the spans of a Client, e.g. the OpenTelemetry tracer of the webpushotel module
+type Tracer interface {
+	// StartSpan starts the span name as a child of the span in ctx, returning a ctx carrying the new span
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation traced by a Tracer
+type Span interface {
+	// SetAttribute sets the attribute key, value is a string or an int
+	SetAttribute(key string, value interface{})
+
+	// End ends the span, err is the error the operation failed with
+	End(err error)
+}
+
+// WithTracer traces VAPID signing, payload encryption and the HTTP send of every notification with tracer
+func WithTracer(tracer Tracer) ClientOption {
+	return func(c *Client) error {
+		c.tracer = tracer
+		return nil
+	}
+}
+
+// noopSpan is the Span of a Client without a Tracer
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+
+func (noopSpan) End(error) {}
+
+// startSpan starts the span name with the Tracer of the Client
+func (c *Client) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return c.tracer.StartSpan(ctx, name)
+}
@@ -0,0 +1,75 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+}
+
+// recordingTracer records the ended spans
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, &recordingSpan{tracer: t, span: &recordedSpan{name: name, attributes: map[string]interface{}{}}}
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	span   *recordedSpan
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.span.attributes[key] = value
+}
+
+func (s *recordingSpan) End(err error) {
+	s.span.err = err
+	s.tracer.spans = append(s.tracer.spans, s.span)
+}
+
+func TestClientTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendErr := errors.New("connection refused")
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusGone}, sendErr
+	})
+	if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != sendErr {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", sendErr, err)
+	}
+
+	var names []string
+	for _, span := range tracer.spans {
+		names = append(names, span.name)
+	}
+	if !reflect.DeepEqual(names, []string{SpanEncrypt, SpanSignVAPID, SpanSend}) {
+		t.Fatalf("Incorrect spans, got %v", names)
+	}
+
+	if size := tracer.spans[0].attributes[AttributePayloadSize]; size != 4 {
+		t.Fatalf("Incorrect payload size, got %v", size)
+	}
+
+	send := tracer.spans[2]
+	if send.attributes[AttributeOrigin] != "https://updates.push.services.mozilla.com" || send.attributes[AttributeStatusCode] != http.StatusGone {
+		t.Fatalf("Incorrect send attributes, got %v", send.attributes)
+	}
+
+	if send.err != sendErr {
+		t.Fatalf("Incorrect span error, expected=%v, got=%v", sendErr, send.err)
+	}
+}
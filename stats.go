@@ -0,0 +1,195 @@
+package webpush
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// StatsWindow is the period covered by the origin statistics of Client.Stats
+	StatsWindow = 5 * time.Minute
+
+	// statsSamples is the number of most recent sends kept per origin
+	statsSamples = 1024
+)
+
+// Stats is a snapshot of the rolling per-origin statistics of a Client
+type Stats struct {
+	Origins map[string]OriginStats // By push service origin, e.g. https://fcm.googleapis.com
+
+	// Errors counts every failed send since the Client was created by push service and error class,
+	// e.g. Errors[PushServiceMozilla][ErrorClassGone]
+	Errors map[PushService]map[ErrorClass]uint64
+
+	RetryBudget *RetryBudgetStats // Consumption of the RetryBudget of WithRetryBudget, nil without one
+}
+
+// OriginStats are the statistics of the recent sends to one origin, within StatsWindow and
+// the last 1024 sends
+type OriginStats struct {
+	Requests  int     // Sends in the window
+	Errors    int     // Sends that failed with a transport error or a 5xx response
+	ErrorRate float64 // Errors / Requests
+
+	// Latency percentiles of the sends, including retries
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// rollingStats keeps the recent sends of every origin
+type rollingStats struct {
+	mu      sync.Mutex
+	origins map[string]*sampleRing
+	errors  map[PushService]map[ErrorClass]uint64
+}
+
+// sampleRing holds the last statsSamples sends of an origin
+type sampleRing struct {
+	samples [statsSamples]sendSample
+	next    int
+	full    bool
+}
+
+type sendSample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// failedSend reports whether a send that got resp and err failed on the push service side
+func failedSend(resp *http.Response, err error) bool {
+	return err != nil || resp == nil || resp.StatusCode >= 500
+}
+
+// abandonedSend reports whether a send of req failed with err because its caller gave up, e.g. canceled
+// its context while the request was in flight or queued for a concurrency slot. Such a send says
+// nothing of the push service, it is neither a failure nor a success.
+func abandonedSend(req *http.Request, err error) bool {
+	return err != nil && req.Context().Err() != nil
+}
+
+// record adds a send to origin at at
+func (r *rollingStats) record(origin string, at time.Time, latency time.Duration, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.origins == nil {
+		r.origins = make(map[string]*sampleRing)
+	}
+
+	ring, ok := r.origins[origin]
+	if !ok {
+		ring = &sampleRing{}
+		r.origins[origin] = ring
+	}
+
+	ring.samples[ring.next] = sendSample{at: at, latency: latency, failed: failed}
+	ring.next = (ring.next + 1) % statsSamples
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// recordError counts a send to service that failed with class
+func (r *rollingStats) recordError(service PushService, class ErrorClass) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.errors == nil {
+		r.errors = make(map[PushService]map[ErrorClass]uint64)
+	}
+
+	classes, ok := r.errors[service]
+	if !ok {
+		classes = make(map[ErrorClass]uint64)
+		r.errors[service] = classes
+	}
+	classes[class]++
+}
+
+// snapshot returns the statistics of the sends since now - StatsWindow, dropping idle origins
+func (r *rollingStats) snapshot(now time.Time) Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	since := now.Add(-StatsWindow)
+	stats := Stats{
+		Origins: make(map[string]OriginStats, len(r.origins)),
+		Errors:  make(map[PushService]map[ErrorClass]uint64, len(r.errors)),
+	}
+	for service, classes := range r.errors {
+		counts := make(map[ErrorClass]uint64, len(classes))
+		for class, count := range classes {
+			counts[class] = count
+		}
+		stats.Errors[service] = counts
+	}
+
+	for origin, ring := range r.origins {
+		samples := ring.samples[:ring.next]
+		if ring.full {
+			samples = ring.samples[:]
+		}
+
+		var latencies []time.Duration
+		var errors int
+		for _, sample := range samples {
+			if sample.at.Before(since) {
+				continue
+			}
+
+			latencies = append(latencies, sample.latency)
+			if sample.failed {
+				errors++
+			}
+		}
+
+		if len(latencies) == 0 {
+			delete(r.origins, origin)
+			continue
+		}
+
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats.Origins[origin] = OriginStats{
+			Requests:  len(latencies),
+			Errors:    errors,
+			ErrorRate: float64(errors) / float64(len(latencies)),
+			P50:       percentile(latencies, 50),
+			P90:       percentile(latencies, 90),
+			P99:       percentile(latencies, 99),
+		}
+	}
+
+	return stats
+}
+
+// percentile returns the nearest-rank p-th percentile of the sorted latencies
+func percentile(latencies []time.Duration, p int) time.Duration {
+	rank := (len(latencies)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return latencies[rank-1]
+}
+
+// Stats returns the latency percentiles and error rates of the recent sends per push service origin
+// and the failed sends by push service and error class, e.g. to adapt concurrency or for operator
+// dashboards without an external metrics stack
+func (c *Client) Stats() Stats {
+	stats := c.rolling.snapshot(c.now())
+	if c.retryBudget != nil {
+		budget := c.retryBudget.Stats()
+		stats.RetryBudget = &budget
+	}
+
+	return stats
+}
+
+// GetStats returns the rolling per-origin statistics of the package level functions
+func GetStats() Stats {
+	return defaultClient.Stats()
+}
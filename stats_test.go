@@ -0,0 +1,74 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientStats(t *testing.T) {
+	now := time.Now()
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statusCode := http.StatusCreated
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: statusCode}, nil
+	})
+	send := func() {
+		if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		send()
+	}
+	statusCode = http.StatusBadGateway
+	send()
+
+	stats := client.Stats().Origins["https://updates.push.services.mozilla.com"]
+	if stats.Requests != 4 || stats.Errors != 1 || stats.ErrorRate != 0.25 {
+		t.Fatalf("Incorrect origin stats, got %+v", stats)
+	}
+
+	if stats.P50 > stats.P90 || stats.P90 > stats.P99 || stats.P99 <= 0 {
+		t.Fatalf("Incorrect percentiles, got %+v", stats)
+	}
+
+	// Sends older than the window are dropped
+	now = now.Add(StatsWindow + time.Second)
+	if origins := client.Stats().Origins; len(origins) != 0 {
+		t.Fatalf("Expected no recent sends, got %v", origins)
+	}
+}
+
+func TestRollingStatsKeepsLastSamples(t *testing.T) {
+	now := time.Now()
+	var stats rollingStats
+	for i := 0; i < statsSamples+10; i++ {
+		stats.record("https://a.example", now, time.Duration(i)*time.Millisecond, i < 10)
+	}
+
+	origin := stats.snapshot(now).Origins["https://a.example"]
+	if origin.Requests != statsSamples || origin.Errors != 0 {
+		t.Fatalf("Expected only the last %d sends, got %+v", statsSamples, origin)
+	}
+
+	// Latencies 10ms to 1033ms remain, the 1014th of them is the p99
+	if origin.P99 != 1023*time.Millisecond {
+		t.Fatalf("Incorrect p99, got %v", origin.P99)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for p, expected := range map[int]time.Duration{50: 5, 90: 9, 99: 10, 0: 1} {
+		if got := percentile(latencies, p); got != expected {
+			t.Errorf("percentile(%d) = %v, expected %v", p, got, expected)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrSubscriptionNotFound is returned by SubscriptionStore.Get for unknown endpoints
+var ErrSubscriptionNotFound = errors.New("webpush: subscription not found")
+
+// StoredSubscription is a subscription with the tags it is stored under in a SubscriptionStore
+type StoredSubscription struct {
+	Subscription
+	Tags     []string          `json:"tags,omitempty"`     // e.g. a user ID or the topics the user opted into
+	Locale   string            `json:"locale,omitempty"`   // BCP 47 language tag of the user, e.g. de-AT
+	Metadata map[string]string `json:"metadata,omitempty"` // Application data, e.g. the user agent
+
+	// LastActive is when the user was last seen by the application, e.g. to skip dormant users (Optional)
+	LastActive *time.Time `json:"lastActive,omitempty"`
+}
+
+// HasTag reports whether s is stored under tag
+func (s *StoredSubscription) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SubscriptionStore is the seam between the bulk send and cleanup features and the storage of the
+// application. Subscriptions are identified by their endpoint. Implementations must be safe for
+// concurrent use; MemorySubscriptionStore and the webpushsql module are reference implementations.
+type SubscriptionStore interface {
+	// Save stores s, replacing the subscription with the same endpoint and its tags
+	Save(ctx context.Context, s *StoredSubscription) error
+
+	// Get returns the subscription of endpoint, ErrSubscriptionNotFound if there is none
+	Get(ctx context.Context, endpoint string) (*StoredSubscription, error)
+
+	// Delete removes the subscription of endpoint, deleting a missing subscription is not an error
+	Delete(ctx context.Context, endpoint string) error
+
+	// IterateByTag calls fn for every subscription stored under tag, or for every subscription when
+	// tag is empty, until fn returns an error, which IterateByTag returns
+	IterateByTag(ctx context.Context, tag string, fn func(*StoredSubscription) error) error
+}
+
+// MemorySubscriptionStore is an in-memory SubscriptionStore, for tests and small deployments
+type MemorySubscriptionStore struct {
+	mu            sync.RWMutex
+	subscriptions map[string]StoredSubscription
+}
+
+// NewMemorySubscriptionStore returns an empty MemorySubscriptionStore
+func NewMemorySubscriptionStore() *MemorySubscriptionStore {
+	return &MemorySubscriptionStore{subscriptions: make(map[string]StoredSubscription)}
+}
+
+// Save implements SubscriptionStore
+func (m *MemorySubscriptionStore) Save(ctx context.Context, s *StoredSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subscriptions[s.Endpoint] = copyStoredSubscription(s)
+	return nil
+}
+
+// Get implements SubscriptionStore
+func (m *MemorySubscriptionStore) Get(ctx context.Context, endpoint string) (*StoredSubscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.subscriptions[endpoint]
+	if !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	stored := copyStoredSubscription(&s)
+	return &stored, nil
+}
+
+// Delete implements SubscriptionStore
+func (m *MemorySubscriptionStore) Delete(ctx context.Context, endpoint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.subscriptions, endpoint)
+	return nil
+}
+
+// IterateByTag implements SubscriptionStore. Subscriptions are visited in endpoint order, from a
+// snapshot taken when iteration starts, so fn may call the other methods of the store.
+func (m *MemorySubscriptionStore) IterateByTag(ctx context.Context, tag string, fn func(*StoredSubscription) error) error {
+	m.mu.RLock()
+	matches := make([]StoredSubscription, 0, len(m.subscriptions))
+	for _, s := range m.subscriptions {
+		if tag == "" || s.HasTag(tag) {
+			matches = append(matches, copyStoredSubscription(&s))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Endpoint < matches[j].Endpoint })
+	for i := range matches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(&matches[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyStoredSubscription returns a copy of s not sharing its tags, metadata or times
+func copyStoredSubscription(s *StoredSubscription) StoredSubscription {
+	stored := *s
+	stored.Tags = append([]string(nil), s.Tags...)
+	if s.Metadata != nil {
+		stored.Metadata = make(map[string]string, len(s.Metadata))
+		for key, value := range s.Metadata {
+			stored.Metadata[key] = value
+		}
+	}
+	if s.ExpirationTime != nil {
+		expirationTime := *s.ExpirationTime
+		stored.ExpirationTime = &expirationTime
+	}
+	if s.LastActive != nil {
+		lastActive := *s.LastActive
+		stored.LastActive = &lastActive
+	}
+
+	return stored
+}
@@ -0,0 +1,58 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemorySubscriptionStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+
+	first := &StoredSubscription{Subscription: *getURLEncodedTestSubscription(), Tags: []string{"user:1", "news"}}
+	second := &StoredSubscription{Subscription: *getURLEncodedTestSubscription(), Tags: []string{"user:2"}}
+	second.Endpoint = "https://fcm.googleapis.com/fcm/send/second"
+	for _, s := range []*StoredSubscription{first, second} {
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Stored subscriptions don't share memory with the caller
+	first.Tags[0] = "changed"
+	got, err := store.Get(ctx, first.Endpoint)
+	if err != nil || got.Tags[0] != "user:1" || got.Keys != first.Keys {
+		t.Fatalf("Incorrect subscription, got %+v (%v)", got, err)
+	}
+
+	var endpoints []string
+	collect := func(s *StoredSubscription) error {
+		endpoints = append(endpoints, s.Endpoint)
+		return nil
+	}
+
+	if err := store.IterateByTag(ctx, "news", collect); err != nil || len(endpoints) != 1 || endpoints[0] != first.Endpoint {
+		t.Fatalf("Incorrect subscriptions tagged news, got %v (%v)", endpoints, err)
+	}
+
+	endpoints = nil
+	if err := store.IterateByTag(ctx, "", collect); err != nil || len(endpoints) != 2 || endpoints[0] != second.Endpoint {
+		t.Fatalf("Expected every subscription in endpoint order, got %v (%v)", endpoints, err)
+	}
+
+	stop := errors.New("stop")
+	if err := store.IterateByTag(ctx, "", func(*StoredSubscription) error { return stop }); err != stop {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", stop, err)
+	}
+
+	if err := store.Delete(ctx, first.Endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, first.Endpoint); err != ErrSubscriptionNotFound {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrSubscriptionNotFound, err)
+	}
+	if err := store.Delete(ctx, first.Endpoint); err != nil {
+		t.Fatalf("Deleting a missing subscription failed: %v", err)
+	}
+}
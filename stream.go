@@ -0,0 +1,72 @@
+package webpush
+
+import (
+	"context"
+	"sync"
+)
+
+// StreamJob is a notification sent by Client.Stream
+type StreamJob struct {
+	ID           string // Identifies the job in its StreamResult, chosen by the producer (Optional)
+	Message      []byte
+	Subscription *Subscription
+	Options      *Options
+}
+
+// StreamResult is the outcome of a StreamJob. The response body is already closed.
+type StreamResult struct {
+	Job    StreamJob
+	Result *SendResult
+	Err    error
+}
+
+// Stream sends the jobs written to the returned channel FanOutConcurrency at a time and streams their
+// results, in completion order, on the second channel. The jobs channel only accepts a job once a
+// sender is free and the results channel is drained, so a producer reading a database cursor or a
+// Kafka topic is slowed down to the sending rate instead of buffering everything in memory.
+//
+// Close the jobs channel when done; the results channel is closed once every job is sent. When ctx
+// ends the senders stop, so producers should select on ctx.Done() when writing jobs.
+func (c *Client) Stream(ctx context.Context) (chan<- StreamJob, <-chan StreamResult) {
+	jobs := make(chan StreamJob)
+	results := make(chan StreamResult, FanOutConcurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < FanOutConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var job StreamJob
+				var ok bool
+				select {
+				case job, ok = <-jobs:
+					if !ok {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+
+				result := StreamResult{Job: job}
+				result.Result, result.Err = c.Deliver(ctx, job.Message, job.Subscription, job.Options)
+				if result.Result != nil && result.Result.Response != nil && result.Result.Response.Body != nil {
+					result.Result.Response.Body.Close()
+				}
+
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return jobs, results
+}
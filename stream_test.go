@@ -0,0 +1,84 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	var inFlight, maxInFlight int32
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	})
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	jobs, results := client.Stream(ctx)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < 200; i++ {
+			jobs <- StreamJob{ID: strconv.Itoa(i), Message: []byte("Hello"), Subscription: getURLEncodedTestSubscription()}
+		}
+	}()
+
+	seen := make(map[string]bool)
+	for result := range results {
+		if result.Err != nil || result.Result.Response.StatusCode != http.StatusCreated {
+			t.Fatalf("Incorrect result, got %+v", result)
+		}
+		seen[result.Job.ID] = true
+	}
+	if len(seen) != 200 || maxInFlight > FanOutConcurrency {
+		t.Fatalf("Expected 200 results with at most %d sends at once, got %d and %d", FanOutConcurrency, len(seen), maxInFlight)
+	}
+}
+
+func TestStreamBackpressure(t *testing.T) {
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	})
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs, results := client.Stream(ctx)
+
+	// Without reading results, the senders fill the results channel and then stop accepting jobs
+	accepted := 0
+	for accepted < 10*FanOutConcurrency {
+		select {
+		case jobs <- StreamJob{Message: []byte("Hello"), Subscription: getURLEncodedTestSubscription()}:
+			accepted++
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if accepted > 2*FanOutConcurrency {
+		t.Fatalf("Expected backpressure after about %d jobs, accepted %d", 2*FanOutConcurrency, accepted)
+	}
+
+	cancel()
+	for range results {
+	}
+}
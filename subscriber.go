@@ -0,0 +1,70 @@
+package webpush
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrSubscriberPolicy is returned when a subscriber violates the Client SubscriberPolicy
+var ErrSubscriberPolicy = errors.New("webpush: subscriber violates the subscriber policy")
+
+// SubscriberPolicy constrains the sub claim of VAPID JWT tokens.
+// RFC 8292 recommends a contact that is actually reachable; a policy enforces it centrally
+// instead of trusting every call site. Subscribers are always strictly validated under a policy.
+type SubscriberPolicy struct {
+	AllowedDomains []string // mailto: subscribers must use one of these domains (Optional)
+	ForbidHTTPS    bool     // Reject https: subscribers, only mailto: contacts are allowed
+	Lowercase      bool     // Lowercase mailto: addresses and the host of https: subscribers
+}
+
+// apply enforces the policy on a normalized subscriber and returns the final sub claim
+func (p *SubscriberPolicy) apply(subscriber string) (string, error) {
+	if strings.HasPrefix(subscriber, "https:") {
+		if p.ForbidHTTPS {
+			return "", fmt.Errorf("%w: https subscribers are forbidden, got %q", ErrSubscriberPolicy, subscriber)
+		}
+
+		if p.Lowercase {
+			u, err := url.Parse(subscriber)
+			if err != nil {
+				return "", err
+			}
+			u.Host = strings.ToLower(u.Host)
+			subscriber = u.String()
+		}
+
+		return subscriber, nil
+	}
+
+	if p.Lowercase {
+		subscriber = strings.ToLower(subscriber)
+	}
+
+	if len(p.AllowedDomains) > 0 {
+		domain := strings.ToLower(subscriber[strings.LastIndex(subscriber, "@")+1:])
+
+		allowed := false
+		for _, allowedDomain := range p.AllowedDomains {
+			if domain == strings.ToLower(allowedDomain) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return "", fmt.Errorf("%w: domain %q is not allowed", ErrSubscriberPolicy, domain)
+		}
+	}
+
+	return subscriber, nil
+}
+
+// WithSubscriberPolicy enforces policy on the subscriber of every notification sent by the Client
+func WithSubscriberPolicy(policy SubscriberPolicy) ClientOption {
+	return func(c *Client) error {
+		c.subscriberPolicy = &policy
+		return nil
+	}
+}
@@ -0,0 +1,49 @@
+package webpush
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscriberPolicy(t *testing.T) {
+	policy := &SubscriberPolicy{
+		AllowedDomains: []string{"example.com"},
+		ForbidHTTPS:    true,
+		Lowercase:      true,
+	}
+
+	subscriber, err := policy.apply("mailto:Ops@Example.com")
+	if err != nil || subscriber != "mailto:ops@example.com" {
+		t.Fatalf("Incorrect subscriber, expected=mailto:ops@example.com, got=%s (%v)", subscriber, err)
+	}
+
+	if _, err := policy.apply("mailto:ops@gmail.com"); !errors.Is(err, ErrSubscriberPolicy) {
+		t.Fatalf("Incorrect error for a foreign domain, expected=%v, got=%v", ErrSubscriberPolicy, err)
+	}
+
+	if _, err := policy.apply("https://example.com/contact"); !errors.Is(err, ErrSubscriberPolicy) {
+		t.Fatalf("Incorrect error for an https subscriber, expected=%v, got=%v", ErrSubscriberPolicy, err)
+	}
+
+	lenient := &SubscriberPolicy{Lowercase: true}
+	if subscriber, _ := lenient.apply("https://Example.com/Contact"); subscriber != "https://example.com/Contact" {
+		t.Fatalf("Incorrect https subscriber, got %s", subscriber)
+	}
+}
+
+func TestClientSubscriberPolicyValidates(t *testing.T) {
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithSubscriber("example.com"),
+		WithSubscriberPolicy(SubscriberPolicy{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A policy implies strict validation of the subscriber
+	err = client.WarmVAPIDCache([]string{"https://fcm.googleapis.com"})
+	if !errors.Is(err, ErrInvalidSubscriber) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidSubscriber, err)
+	}
+}
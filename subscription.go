@@ -0,0 +1,206 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSubscription is returned by ParseSubscription for JSON not shaped like PushSubscription.toJSON()
+	ErrInvalidSubscription = errors.New("webpush: invalid subscription")
+
+	// ErrUnknownPushService is reported by Subscription.Validate for endpoints of an unknown host
+	ErrUnknownPushService = errors.New("webpush: endpoint host is not a known push service")
+
+	// ErrInvalidSubscriptionKey is reported by Subscription.Validate for keys of the wrong encoding or length
+	ErrInvalidSubscriptionKey = errors.New("webpush: invalid subscription key")
+
+	// ErrSubscriptionExpired is reported by Subscription.Validate for an expiration time in the past
+	ErrSubscriptionExpired = errors.New("webpush: subscription expired")
+)
+
+const (
+	// p256dhLength is the length of an uncompressed P-256 point
+	p256dhLength = 65
+
+	// authSecretLength is the length of the authentication secret of RFC 8291
+	authSecretLength = 16
+)
+
+// SubscriptionErrors are all the problems Subscription.Validate found
+type SubscriptionErrors []error
+
+func (e SubscriptionErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether any of the problems matches target
+func (e SubscriptionErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// browserSubscription is the shape of PushSubscription.toJSON(), pointers tell missing fields apart
+type browserSubscription struct {
+	Endpoint       *string  `json:"endpoint"`
+	ExpirationTime *float64 `json:"expirationTime"`
+	Keys           *struct {
+		P256dh *string `json:"p256dh"`
+		Auth   *string `json:"auth"`
+	} `json:"keys"`
+	ApplicationServerKey string `json:"applicationServerKey"`
+}
+
+// ParseSubscription parses the JSON of PushSubscription.toJSON() as sent by the browser:
+//
+//	{"endpoint": "https://...", "expirationTime": null, "keys": {"p256dh": "...", "auth": "..."}}
+//
+// Unknown fields, missing endpoint or keys and values of the wrong type are rejected with
+// ErrInvalidSubscription. Keys may use either base64 alphabet, padded or not, and are
+// returned unpadded URL-safe as browsers send them.
+func ParseSubscription(data []byte) (*Subscription, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var parsed browserSubscription
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w: unexpected data after the subscription", ErrInvalidSubscription)
+	}
+
+	if parsed.Endpoint == nil || *parsed.Endpoint == "" {
+		return nil, fmt.Errorf("%w: missing endpoint", ErrInvalidSubscription)
+	}
+
+	if endpoint, err := url.Parse(*parsed.Endpoint); err != nil || !endpoint.IsAbs() || endpoint.Host == "" {
+		return nil, fmt.Errorf("%w: endpoint is not an absolute URL", ErrInvalidSubscription)
+	}
+
+	if parsed.Keys == nil || parsed.Keys.P256dh == nil || parsed.Keys.Auth == nil {
+		return nil, fmt.Errorf("%w: missing keys.p256dh or keys.auth", ErrInvalidSubscription)
+	}
+
+	p256dh, err := normalizeSubscriptionKey("p256dh", *parsed.Keys.P256dh)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := normalizeSubscriptionKey("auth", *parsed.Keys.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscription{
+		Endpoint:             *parsed.Endpoint,
+		Keys:                 Keys{P256dh: p256dh, Auth: auth},
+		ApplicationServerKey: parsed.ApplicationServerKey,
+	}
+
+	if parsed.ExpirationTime != nil {
+		expirationTime := int64(*parsed.ExpirationTime)
+		s.ExpirationTime = &expirationTime
+	}
+
+	return s, nil
+}
+
+// normalizeSubscriptionKey returns the subscription key named name as unpadded base64 URL
+func normalizeSubscriptionKey(name, key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("%w: empty keys.%s", ErrInvalidSubscription, name)
+	}
+
+	decoded, err := decodeSubscriptionKey(key)
+	if err != nil {
+		return "", fmt.Errorf("%w: keys.%s is not base64", ErrInvalidSubscription, name)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(decoded), nil
+}
+
+// Expiration returns when the subscription expires, false when it has no expiration time
+func (s *Subscription) Expiration() (time.Time, bool) {
+	if s.ExpirationTime == nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, *s.ExpirationTime*int64(time.Millisecond)), true
+}
+
+// Validate checks that the endpoint is an absolute https URL of a known push service or of one of
+// allowedHosts, a host ("push.example.com") or a domain and its subdomains (".example.com"), that
+// keys.p256dh decodes to a P-256 point and keys.auth to 16 bytes, and that the subscription hasn't
+// expired. All problems are reported at once as SubscriptionErrors, nil when there are none.
+func (s *Subscription) Validate(allowedHosts ...string) error {
+	var problems SubscriptionErrors
+
+	if endpoint, err := url.Parse(s.Endpoint); err != nil || !endpoint.IsAbs() || endpoint.Hostname() == "" {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidEndpoint, s.Endpoint))
+	} else {
+		if !strings.EqualFold(endpoint.Scheme, "https") {
+			problems = append(problems, fmt.Errorf("%w: %q", ErrInsecureEndpoint, s.Endpoint))
+		}
+
+		if DetectPushService(s.Endpoint) == PushServiceOther && !matchesHost(endpoint.Hostname(), allowedHosts) {
+			problems = append(problems, fmt.Errorf("%w: %s", ErrUnknownPushService, endpoint.Hostname()))
+		}
+	}
+
+	if p256dh, err := decodeSubscriptionKey(s.Keys.P256dh); err != nil || len(p256dh) != p256dhLength || p256dh[0] != 4 {
+		problems = append(problems, fmt.Errorf("%w: keys.p256dh must be a %d byte uncompressed P-256 point", ErrInvalidSubscriptionKey, p256dhLength))
+	} else if x, _ := elliptic.Unmarshal(elliptic.P256(), p256dh); x == nil {
+		problems = append(problems, fmt.Errorf("%w: keys.p256dh is not a point on the P-256 curve", ErrInvalidSubscriptionKey))
+	}
+
+	if auth, err := decodeSubscriptionKey(s.Keys.Auth); err != nil || len(auth) != authSecretLength {
+		problems = append(problems, fmt.Errorf("%w: keys.auth must be %d bytes", ErrInvalidSubscriptionKey, authSecretLength))
+	}
+
+	if expiration, ok := s.Expiration(); ok && !time.Now().Before(expiration) {
+		problems = append(problems, fmt.Errorf("%w at %s", ErrSubscriptionExpired, expiration.UTC().Format(time.RFC3339)))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return problems
+}
+
+// matchesHost reports whether host is one of patterns, a host or a leading dot domain
+func matchesHost(host string, patterns []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if strings.HasPrefix(pattern, ".") {
+			if strings.HasSuffix(host, pattern) || host == pattern[1:] {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
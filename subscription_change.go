@@ -0,0 +1,134 @@
+package webpush
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// DefaultSubscriptionChangeMaxBytes is the largest request body SubscriptionChangeHandler reads by default
+const DefaultSubscriptionChangeMaxBytes = 16 << 10
+
+// SubscriptionChange is the request body of SubscriptionChangeHandler, posted by a service worker on
+// pushsubscriptionchange with the toJSON() of event.oldSubscription and of the new subscription:
+//
+//	{"oldSubscription": {"endpoint": "...", "keys": {...}}, "newSubscription": {"endpoint": "...", "keys": {...}}}
+type SubscriptionChange struct {
+	OldSubscription json.RawMessage `json:"oldSubscription"` // Optional, browsers may not provide it
+	OldEndpoint     string          `json:"oldEndpoint"`     // Instead of OldSubscription (Optional)
+	NewSubscription json.RawMessage `json:"newSubscription"`
+}
+
+// SubscriptionChangeOptions configure SubscriptionChangeHandler
+type SubscriptionChangeOptions struct {
+	AllowedHosts []string // Passed to Subscription.Validate, e.g. the hosts of private push gateways
+	MaxBytes     int64    // Largest request body, DefaultSubscriptionChangeMaxBytes when zero
+}
+
+// SubscriptionChangeHandler returns an http.Handler saving the new subscription of a POSTed
+// SubscriptionChange to store. The tags, locale and metadata of the old subscription are moved to
+// the new one, and the old one is deleted. When the old subscription has keys, they must match the
+// stored ones. Invalid requests get a 400 response, successful ones a 204.
+func SubscriptionChangeHandler(store SubscriptionStore, options SubscriptionChangeOptions) http.Handler {
+	maxBytes := options.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultSubscriptionChangeMaxBytes
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		var change SubscriptionChange
+		if err := json.Unmarshal(body, &change); err != nil {
+			http.Error(w, "invalid subscription change", http.StatusBadRequest)
+			return
+		}
+
+		subscription, err := ParseSubscription(change.NewSubscription)
+		if err == nil {
+			err = subscription.Validate(options.AllowedHosts...)
+		}
+		if err != nil {
+			http.Error(w, "invalid new subscription: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stored := &StoredSubscription{Subscription: *subscription}
+		old, err := oldSubscription(change)
+		if err != nil {
+			http.Error(w, "invalid old subscription: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if old != nil && old.Endpoint != subscription.Endpoint {
+			previous, err := store.Get(r.Context(), old.Endpoint)
+			switch {
+			case errors.Is(err, ErrSubscriptionNotFound):
+				old = nil
+			case err != nil:
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			default:
+				if old.Keys != (Keys{}) && !sameKeys(&old.Subscription, &previous.Subscription) {
+					http.Error(w, "old subscription keys don't match", http.StatusForbidden)
+					return
+				}
+
+				stored.Tags, stored.Locale, stored.Metadata, stored.LastActive = previous.Tags, previous.Locale, previous.Metadata, previous.LastActive
+			}
+		}
+
+		if err := store.Save(r.Context(), stored); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if old != nil && old.Endpoint != subscription.Endpoint {
+			if err := store.Delete(r.Context(), old.Endpoint); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// oldSubscription returns the old subscription of change, nil when it has none
+func oldSubscription(change SubscriptionChange) (*StoredSubscription, error) {
+	if len(change.OldSubscription) > 0 && string(change.OldSubscription) != "null" {
+		old := &StoredSubscription{}
+		if err := json.Unmarshal(change.OldSubscription, &old.Subscription); err != nil {
+			return nil, err
+		}
+		if old.Endpoint == "" {
+			return nil, errors.New("missing endpoint")
+		}
+
+		return old, nil
+	}
+
+	if change.OldEndpoint != "" {
+		return &StoredSubscription{Subscription: Subscription{Endpoint: change.OldEndpoint}}, nil
+	}
+
+	return nil, nil
+}
+
+// sameKeys reports whether a and b have the same keys, whatever their base64 encoding
+func sameKeys(a, b *Subscription) bool {
+	aKeys, aOK := subscriptionKeyID(a)
+	bKeys, bOK := subscriptionKeyID(b)
+	return aOK && bOK && aKeys == bKeys
+}
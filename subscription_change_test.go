@@ -0,0 +1,58 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSubscriptionChangeHandler(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	old := &StoredSubscription{Subscription: *getStandardEncodedTestSubscription(), Tags: []string{"news"}, Locale: "de"}
+	if err := store.Save(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := SubscriptionChangeHandler(store, SubscriptionChangeOptions{})
+	newSubscription := `{"endpoint":"https://fcm.googleapis.com/fcm/send/new","keys":{"p256dh":"BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk","auth":"AAAAAAAAAAAAAAAAAAAAAA"}}`
+	oldSubscription := `{"endpoint":"` + old.Endpoint + `","keys":{"p256dh":"` + old.Keys.P256dh + `","auth":"` + old.Keys.Auth + `"}}`
+	wrongKeys := `{"endpoint":"` + old.Endpoint + `","keys":{"p256dh":"` + old.Keys.P256dh + `","auth":"bbbbbbbbbbbbbbbbbbbbbb"}}`
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{name: "method", method: http.MethodGet, status: http.StatusMethodNotAllowed},
+		{name: "invalid JSON", body: `{`, status: http.StatusBadRequest},
+		{name: "invalid new subscription", body: `{"newSubscription":{"endpoint":"https://fcm.googleapis.com/fcm/send/new"}}`, status: http.StatusBadRequest},
+		{name: "wrong old keys", body: `{"oldSubscription":` + wrongKeys + `,"newSubscription":` + newSubscription + `}`, status: http.StatusForbidden},
+		{name: "change", body: `{"oldSubscription":` + oldSubscription + `,"newSubscription":` + newSubscription + `}`, status: http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		method := test.method
+		if method == "" {
+			method = http.MethodPost
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/push/subscription-change", strings.NewReader(test.body)))
+		if rec.Code != test.status {
+			t.Fatalf("%s: incorrect status, expected=%d, got=%d (%s)", test.name, test.status, rec.Code, rec.Body)
+		}
+	}
+
+	if _, err := store.Get(ctx, old.Endpoint); err != ErrSubscriptionNotFound {
+		t.Fatalf("Expected the old subscription deleted, got %v", err)
+	}
+
+	changed, err := store.Get(ctx, "https://fcm.googleapis.com/fcm/send/new")
+	if err != nil || !changed.HasTag("news") || changed.Locale != "de" || changed.Keys.Auth != "AAAAAAAAAAAAAAAAAAAAAA" {
+		t.Fatalf("Incorrect new subscription, got %+v (%v)", changed, err)
+	}
+}
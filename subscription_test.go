@@ -0,0 +1,93 @@
+package webpush
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSubscription(t *testing.T) {
+	s, err := ParseSubscription([]byte(`{
+		"endpoint": "https://fcm.googleapis.com/fcm/send/abc",
+		"expirationTime": 1767225600000,
+		"keys": {
+			"p256dh": "BNNL5ZaTfK81qhXOx23+wewhigUeFb632jN6LvRWCFH1ubQr77FE/9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk=",
+			"auth": "zqbxT6JKstKSY9JKibZLSQ"
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys are normalized to the unpadded URL-safe alphabet
+	expected := getURLEncodedTestSubscription()
+	if s.Endpoint != "https://fcm.googleapis.com/fcm/send/abc" || s.Keys != expected.Keys {
+		t.Fatalf("Incorrect subscription, got %+v", s)
+	}
+
+	if expiration, ok := s.Expiration(); !ok || !expiration.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Incorrect expiration, got %v", expiration)
+	}
+
+	s, err = ParseSubscription([]byte(`{"endpoint":"https://fcm.googleapis.com/fcm/send/abc","expirationTime":null,"keys":{"p256dh":"BNNL","auth":"zqbx"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Expiration(); ok {
+		t.Fatal("Expected no expiration")
+	}
+}
+
+func TestParseSubscriptionInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"not json":         `endpoint`,
+		"trailing data":    `{"endpoint":"https://a.example/1","keys":{"p256dh":"BNNL","auth":"zqbx"}} {}`,
+		"unknown field":    `{"endpoint":"https://a.example/1","keys":{"p256dh":"BNNL","auth":"zqbx"},"extra":1}`,
+		"missing endpoint": `{"keys":{"p256dh":"BNNL","auth":"zqbx"}}`,
+		"relative":         `{"endpoint":"/push/1","keys":{"p256dh":"BNNL","auth":"zqbx"}}`,
+		"missing keys":     `{"endpoint":"https://a.example/1"}`,
+		"missing auth":     `{"endpoint":"https://a.example/1","keys":{"p256dh":"BNNL"}}`,
+		"wrong type":       `{"endpoint":"https://a.example/1","expirationTime":"soon","keys":{"p256dh":"BNNL","auth":"zqbx"}}`,
+		"not base64":       `{"endpoint":"https://a.example/1","keys":{"p256dh":"BN*L","auth":"zqbx"}}`,
+	} {
+		if _, err := ParseSubscription([]byte(data)); !errors.Is(err, ErrInvalidSubscription) {
+			t.Errorf("%s: incorrect error, expected=%v, got=%v", name, ErrInvalidSubscription, err)
+		}
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	if err := getURLEncodedTestSubscription().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = "https://push.example.com/abc"
+	if err := s.Validate(".example.com"); err != nil {
+		t.Fatalf("Expected an allowed host, got %v", err)
+	}
+
+	// Every problem is reported
+	expired := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	s = &Subscription{
+		Endpoint:       "http://push.example.com/abc",
+		Keys:           Keys{P256dh: "BNNL", Auth: "zqbxT6JKstKSY9JKibZLSQ"},
+		ExpirationTime: &expired,
+	}
+	err := s.Validate()
+
+	var problems SubscriptionErrors
+	if !errors.As(err, &problems) || len(problems) != 4 {
+		t.Fatalf("Expected 4 problems, got %v", err)
+	}
+
+	for _, target := range []error{ErrInsecureEndpoint, ErrUnknownPushService, ErrInvalidSubscriptionKey, ErrSubscriptionExpired} {
+		if !errors.Is(err, target) {
+			t.Errorf("Expected %v in %v", target, err)
+		}
+	}
+
+	if err := (&Subscription{Endpoint: "/relative", Keys: getURLEncodedTestSubscription().Keys}).Validate(); !errors.Is(err, ErrInvalidEndpoint) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidEndpoint, err)
+	}
+}
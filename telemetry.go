@@ -0,0 +1,102 @@
+package webpush
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SigningLatencyBuckets are the upper bounds of the ES256 signing latency histogram
+var SigningLatencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// SigningStats are the VAPID header statistics of one audience
+type SigningStats struct {
+	CacheHits     uint64        // Headers served from the cache
+	Signings      uint64        // JWT tokens actually signed on a cache miss
+	SigningErrors uint64        // Failed signings
+	LatencySum    time.Duration // Total time spent signing
+
+	// Latency[i] counts the signings that took at most SigningLatencyBuckets[i],
+	// the last entry counts the slower ones
+	Latency [len(SigningLatencyBuckets) + 1]uint64
+}
+
+// audienceStats are the live counters behind SigningStats, updated atomically
+type audienceStats struct {
+	cacheHits     uint64
+	signings      uint64
+	signingErrors uint64
+	latencySum    uint64
+	latency       [len(SigningLatencyBuckets) + 1]uint64
+}
+
+// Signing stats per audience
+var signingStats sync.Map
+
+func getAudienceStats(audience string) *audienceStats {
+	if stats, ok := signingStats.Load(audience); ok {
+		return stats.(*audienceStats)
+	}
+
+	stats, _ := signingStats.LoadOrStore(audience, &audienceStats{})
+	return stats.(*audienceStats)
+}
+
+// recordCacheHit counts a VAPID header served from the cache
+func recordCacheHit(audience string) {
+	atomic.AddUint64(&getAudienceStats(audience).cacheHits, 1)
+}
+
+// recordSigning counts a JWT signing of audience that took elapsed
+func recordSigning(audience string, elapsed time.Duration, err error) {
+	stats := getAudienceStats(audience)
+	if err != nil {
+		atomic.AddUint64(&stats.signingErrors, 1)
+		return
+	}
+
+	atomic.AddUint64(&stats.signings, 1)
+	atomic.AddUint64(&stats.latencySum, uint64(elapsed))
+
+	atomic.AddUint64(&stats.latency[latencyBucket(SigningLatencyBuckets[:], elapsed)], 1)
+}
+
+// GetSigningStats returns the VAPID header cache hits and ES256 signings per audience,
+// to size signing capacity e.g. before moving to a KMS-backed signer
+func GetSigningStats() map[string]SigningStats {
+	result := make(map[string]SigningStats)
+	signingStats.Range(func(key, value interface{}) bool {
+		stats := value.(*audienceStats)
+
+		snapshot := SigningStats{
+			CacheHits:     atomic.LoadUint64(&stats.cacheHits),
+			Signings:      atomic.LoadUint64(&stats.signings),
+			SigningErrors: atomic.LoadUint64(&stats.signingErrors),
+			LatencySum:    time.Duration(atomic.LoadUint64(&stats.latencySum)),
+		}
+		for i := range stats.latency {
+			snapshot.Latency[i] = atomic.LoadUint64(&stats.latency[i])
+		}
+
+		result[key.(string)] = snapshot
+		return true
+	})
+
+	return result
+}
+
+// ResetSigningStats clears the signing stats of every audience
+func ResetSigningStats() {
+	signingStats.Range(func(key, _ interface{}) bool {
+		signingStats.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,180 @@
+package webpush
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeliveryLatencyBuckets are the upper bounds of the push service latency histogram
+var DeliveryLatencyBuckets = [...]time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// EncryptionLatencyBuckets are the upper bounds of the payload encryption latency histogram
+var EncryptionLatencyBuckets = [...]time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+}
+
+// DeliveryStats are the push service request statistics of one origin
+type DeliveryStats struct {
+	Responses  [5]uint64     // Responses[i] counts the responses with a (i+1)xx status code
+	Errors     uint64        // Sends that failed without a response
+	Retries    uint64        // Transport retries, see WithTransportRetries
+	LatencySum time.Duration // Total duration of the sends, including retries and waits for a connection
+
+	// Latency[i] counts the sends that took at most DeliveryLatencyBuckets[i],
+	// the last entry counts the slower ones
+	Latency [len(DeliveryLatencyBuckets) + 1]uint64
+}
+
+// EncryptionStats are the payload encryption statistics of the process
+type EncryptionStats struct {
+	Encryptions uint64        // Encrypted payloads
+	LatencySum  time.Duration // Total time spent encrypting
+
+	// Latency[i] counts the encryptions that took at most EncryptionLatencyBuckets[i],
+	// the last entry counts the slower ones
+	Latency [len(EncryptionLatencyBuckets) + 1]uint64
+}
+
+// originStats are the live counters behind DeliveryStats, updated atomically
+type originStats struct {
+	responses  [5]uint64
+	errors     uint64
+	retries    uint64
+	latencySum uint64
+	latency    [len(DeliveryLatencyBuckets) + 1]uint64
+}
+
+// encryptionCounters are the live counters behind EncryptionStats, updated atomically
+type encryptionCounters struct {
+	encryptions uint64
+	latencySum  uint64
+	latency     [len(EncryptionLatencyBuckets) + 1]uint64
+}
+
+var (
+	// Delivery stats per origin
+	deliveryStats sync.Map
+
+	encryptionStats encryptionCounters
+)
+
+// requestOrigin returns the lower case scheme://host of req
+func requestOrigin(req *http.Request) string {
+	return strings.ToLower(req.URL.Scheme + "://" + req.URL.Host)
+}
+
+func getOriginStats(origin string) *originStats {
+	if stats, ok := deliveryStats.Load(origin); ok {
+		return stats.(*originStats)
+	}
+
+	stats, _ := deliveryStats.LoadOrStore(origin, &originStats{})
+	return stats.(*originStats)
+}
+
+// latencyBucket returns the histogram bucket of elapsed, len(bounds) for the overflow bucket
+func latencyBucket(bounds []time.Duration, elapsed time.Duration) int {
+	for i, bound := range bounds {
+		if elapsed <= bound {
+			return i
+		}
+	}
+
+	return len(bounds)
+}
+
+// recordDelivery counts a request to origin that took elapsed and got resp or failed
+func recordDelivery(origin string, elapsed time.Duration, resp *http.Response, err error) {
+	stats := getOriginStats(origin)
+	if err != nil || resp == nil {
+		atomic.AddUint64(&stats.errors, 1)
+	} else if class := resp.StatusCode/100 - 1; class >= 0 && class < len(stats.responses) {
+		atomic.AddUint64(&stats.responses[class], 1)
+	}
+
+	atomic.AddUint64(&stats.latencySum, uint64(elapsed))
+	atomic.AddUint64(&stats.latency[latencyBucket(DeliveryLatencyBuckets[:], elapsed)], 1)
+}
+
+// recordRetry counts a transport retry of a request to origin
+func recordRetry(origin string) {
+	atomic.AddUint64(&getOriginStats(origin).retries, 1)
+}
+
+// recordEncryption counts a payload encryption that took elapsed
+func recordEncryption(elapsed time.Duration) {
+	atomic.AddUint64(&encryptionStats.encryptions, 1)
+	atomic.AddUint64(&encryptionStats.latencySum, uint64(elapsed))
+	atomic.AddUint64(&encryptionStats.latency[latencyBucket(EncryptionLatencyBuckets[:], elapsed)], 1)
+}
+
+// GetDeliveryStats returns the responses by status class, errors, retries and latencies per push service origin
+func GetDeliveryStats() map[string]DeliveryStats {
+	result := make(map[string]DeliveryStats)
+	deliveryStats.Range(func(key, value interface{}) bool {
+		stats := value.(*originStats)
+
+		snapshot := DeliveryStats{
+			Errors:     atomic.LoadUint64(&stats.errors),
+			Retries:    atomic.LoadUint64(&stats.retries),
+			LatencySum: time.Duration(atomic.LoadUint64(&stats.latencySum)),
+		}
+		for i := range stats.responses {
+			snapshot.Responses[i] = atomic.LoadUint64(&stats.responses[i])
+		}
+		for i := range stats.latency {
+			snapshot.Latency[i] = atomic.LoadUint64(&stats.latency[i])
+		}
+
+		result[key.(string)] = snapshot
+		return true
+	})
+
+	return result
+}
+
+// GetEncryptionStats returns the payload encryption latencies of the process
+func GetEncryptionStats() EncryptionStats {
+	snapshot := EncryptionStats{
+		Encryptions: atomic.LoadUint64(&encryptionStats.encryptions),
+		LatencySum:  time.Duration(atomic.LoadUint64(&encryptionStats.latencySum)),
+	}
+	for i := range encryptionStats.latency {
+		snapshot.Latency[i] = atomic.LoadUint64(&encryptionStats.latency[i])
+	}
+
+	return snapshot
+}
+
+// ResetDeliveryStats clears the delivery stats of every origin and the encryption stats
+func ResetDeliveryStats() {
+	deliveryStats.Range(func(key, _ interface{}) bool {
+		deliveryStats.Delete(key)
+		return true
+	})
+
+	atomic.StoreUint64(&encryptionStats.encryptions, 0)
+	atomic.StoreUint64(&encryptionStats.latencySum, 0)
+	for i := range encryptionStats.latency {
+		atomic.StoreUint64(&encryptionStats.latency[i], 0)
+	}
+}
@@ -0,0 +1,62 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDeliveryStats(t *testing.T) {
+	ResetDeliveryStats()
+	defer ResetDeliveryStats()
+
+	statusCode := http.StatusCreated
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		if statusCode == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: statusCode}, nil
+	})
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []int{http.StatusCreated, http.StatusCreated, http.StatusGone, 0} {
+		statusCode = code
+		_, _ = client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient})
+	}
+
+	stats := GetDeliveryStats()["https://updates.push.services.mozilla.com"]
+	if stats.Responses[1] != 2 || stats.Responses[3] != 1 || stats.Errors != 1 {
+		t.Fatalf("Incorrect responses by status class, got %v errors=%d", stats.Responses, stats.Errors)
+	}
+
+	var sends uint64
+	for _, count := range stats.Latency {
+		sends += count
+	}
+	if sends != 4 {
+		t.Fatalf("Incorrect latency histogram, got %v", stats.Latency)
+	}
+
+	if encryption := GetEncryptionStats(); encryption.Encryptions != 4 || encryption.LatencySum <= 0 {
+		t.Fatalf("Incorrect encryption stats, got %+v", encryption)
+	}
+}
+
+func TestLatencyBucket(t *testing.T) {
+	bounds := []time.Duration{time.Millisecond, time.Second}
+	for elapsed, bucket := range map[time.Duration]int{
+		time.Microsecond: 0,
+		time.Millisecond: 0,
+		time.Minute:      2,
+	} {
+		if got := latencyBucket(bounds, elapsed); got != bucket {
+			t.Errorf("latencyBucket(%v) = %d, expected %d", elapsed, got, bucket)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package webpush
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSigningStats(t *testing.T) {
+	ResetSigningStats()
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithSubscriber("test@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audience := "https://updates.push.services.mozilla.com"
+	for i := 0; i < 3; i++ {
+		if err := client.WarmVAPIDCache([]string{audience}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, ok := GetSigningStats()[audience]
+	if !ok {
+		t.Fatalf("Missing signing stats for %s", audience)
+	}
+
+	if stats.Signings != 1 || stats.CacheHits != 2 || stats.SigningErrors != 0 {
+		t.Fatalf("Incorrect stats, expected 1 signing and 2 hits, got %+v", stats)
+	}
+
+	var observed uint64
+	for _, count := range stats.Latency {
+		observed += count
+	}
+	if observed != stats.Signings {
+		t.Fatalf("Incorrect histogram count, expected=%d, got=%d", stats.Signings, observed)
+	}
+
+	ResetSigningStats()
+	if len(GetSigningStats()) != 0 {
+		t.Fatal("Stats were not reset")
+	}
+}
+
+func TestRecordSigningBuckets(t *testing.T) {
+	ResetSigningStats()
+	defer ResetSigningStats()
+
+	recordSigning("https://a.example", 3*time.Millisecond, nil)
+	recordSigning("https://a.example", 2*time.Second, nil)
+
+	stats := GetSigningStats()["https://a.example"]
+	if stats.Latency[1] != 1 || stats.Latency[len(SigningLatencyBuckets)] != 1 {
+		t.Fatalf("Incorrect buckets, got %v", stats.Latency)
+	}
+
+	if stats.LatencySum != 2003*time.Millisecond {
+		t.Fatalf("Incorrect latency sum, got %v", stats.LatencySum)
+	}
+}
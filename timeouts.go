@@ -0,0 +1,235 @@
+package webpush
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is matched by errors.Is for every TimeoutError
+var ErrTimeout = errors.New("webpush: push service request timed out")
+
+// TimeoutError is returned when a request to a push service exceeds one of its Timeouts
+type TimeoutError struct {
+	Origin string        // Push service origin, e.g. https://fcm.googleapis.com
+	Stage  string        // "dial", "tls handshake", "response header" or "attempt"
+	Budget time.Duration // The exceeded budget
+}
+
+func (e *TimeoutError) Error() string {
+	return ErrTimeout.Error() + ": " + e.Stage + " exceeded " + e.Budget.String() + " for " + e.Origin
+}
+
+// Is reports whether target is ErrTimeout
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// Timeout reports true, like the timeouts of package net
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Timeouts are the time budgets of a push service request, zero leaves a stage unlimited
+type Timeouts struct {
+	Dial           time.Duration // Establishing a TCP connection
+	TLSHandshake   time.Duration // TLS handshake of a new connection
+	ResponseHeader time.Duration // From the request being written to the response headers
+	Attempt        time.Duration // The whole request, from getting a connection to the response headers
+}
+
+// merge returns t with its zero budgets taken from defaults
+func (t Timeouts) merge(defaults Timeouts) Timeouts {
+	if t.Dial == 0 {
+		t.Dial = defaults.Dial
+	}
+
+	if t.TLSHandshake == 0 {
+		t.TLSHandshake = defaults.TLSHandshake
+	}
+
+	if t.ResponseHeader == 0 {
+		t.ResponseHeader = defaults.ResponseHeader
+	}
+
+	if t.Attempt == 0 {
+		t.Attempt = defaults.Attempt
+	}
+
+	return t
+}
+
+// timeoutBudgets holds the Timeouts of a Client, by push service origin
+type timeoutBudgets struct {
+	defaults Timeouts
+	origins  map[string]Timeouts
+}
+
+// WithTimeouts sets the time budgets of every push service request of the Client
+func WithTimeouts(timeouts Timeouts) ClientOption {
+	return func(c *Client) error {
+		c.timeoutBudgets().defaults = timeouts
+		return nil
+	}
+}
+
+// WithOriginTimeouts overrides the time budgets of requests to a push service origin,
+// e.g. a slow enterprise gateway. Zero budgets are taken from WithTimeouts.
+func WithOriginTimeouts(origin string, timeouts Timeouts) ClientOption {
+	return func(c *Client) error {
+		normalized, err := normalizeAudience(origin, true)
+		if err != nil {
+			return err
+		}
+
+		c.timeoutBudgets().origins[normalized] = timeouts
+		return nil
+	}
+}
+
+// timeoutBudgets returns the timeout budgets of the Client, creating them when needed
+func (c *Client) timeoutBudgets() *timeoutBudgets {
+	if c.timeouts == nil {
+		c.timeouts = &timeoutBudgets{origins: make(map[string]Timeouts)}
+	}
+
+	return c.timeouts
+}
+
+// wrap returns client enforcing the budgets of b
+func (b *timeoutBudgets) wrap(client HTTPClient) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		origin, err := normalizeAudience(req.URL.String(), true)
+		if err != nil {
+			return client.Do(req)
+		}
+
+		timeouts := b.origins[origin].merge(b.defaults)
+		if timeouts == (Timeouts{}) {
+			return client.Do(req)
+		}
+
+		ctx, cancel := context.WithCancel(req.Context())
+		w := &stageWatch{origin: origin, cancel: cancel, timers: make(map[string]*time.Timer)}
+		ctx = httptrace.WithClientTrace(ctx, w.clientTrace(timeouts))
+
+		w.start("attempt", timeouts.Attempt)
+		resp, err := client.Do(req.WithContext(ctx))
+		w.stopAll()
+
+		if err != nil {
+			cancel()
+
+			if expired := w.expiredError(); expired != nil {
+				return nil, expired
+			}
+
+			return nil, err
+		}
+
+		if resp.Body == nil {
+			cancel()
+			return resp, nil
+		}
+
+		// The context of the request must live until its body is read
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	})
+}
+
+// stageWatch cancels a request when one of its stages exceeds its budget
+type stageWatch struct {
+	origin string
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	timers  map[string]*time.Timer
+	expired *TimeoutError
+}
+
+// clientTrace returns the httptrace hooks timing the stages of timeouts
+func (w *stageWatch) clientTrace(timeouts Timeouts) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		ConnectStart:         func(string, string) { w.start("dial", timeouts.Dial) },
+		ConnectDone:          func(string, string, error) { w.stop("dial") },
+		TLSHandshakeStart:    func() { w.start("tls handshake", timeouts.TLSHandshake) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { w.stop("tls handshake") },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { w.start("response header", timeouts.ResponseHeader) },
+		GotFirstResponseByte: func() { w.stop("response header") },
+	}
+}
+
+// start times stage against budget unless it is already timed or budget is zero
+func (w *stageWatch) start(stage string, budget time.Duration) {
+	if budget <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.timers[stage]; ok {
+		return
+	}
+
+	w.timers[stage] = time.AfterFunc(budget, func() {
+		w.mu.Lock()
+		if w.expired == nil {
+			w.expired = &TimeoutError{Origin: w.origin, Stage: stage, Budget: budget}
+		}
+		w.mu.Unlock()
+
+		w.cancel()
+	})
+}
+
+// stop ends the timing of stage
+func (w *stageWatch) stop(stage string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if timer, ok := w.timers[stage]; ok {
+		timer.Stop()
+	}
+}
+
+// stopAll ends the timing of every stage
+func (w *stageWatch) stopAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, timer := range w.timers {
+		timer.Stop()
+	}
+}
+
+// expiredError returns the TimeoutError of the first stage that exceeded its budget
+func (w *stageWatch) expiredError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.expired == nil {
+		return nil
+	}
+
+	return w.expired
+}
+
+// cancelOnClose cancels the context of a request once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
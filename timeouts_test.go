@@ -0,0 +1,78 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+		}
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	fcm := httptest.NewServer(http.HandlerFunc(slow))
+	defer fcm.Close()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer gateway.Close()
+	defer close(release)
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTimeouts(Timeouts{ResponseHeader: 50 * time.Millisecond, Attempt: 5 * time.Second}),
+		WithOriginTimeouts(gateway.URL, Timeouts{ResponseHeader: 2 * time.Second}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(endpoint string) (*http.Response, error) {
+		s := getStandardEncodedTestSubscription()
+		s.Endpoint = endpoint + "/push/abc"
+		return client.Send(context.Background(), []byte("Test"), s, nil)
+	}
+
+	_, err = send(fcm.URL)
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected a TimeoutError, got %v", err)
+	}
+
+	if timeoutErr.Stage != "response header" || timeoutErr.Budget != 50*time.Millisecond {
+		t.Fatalf("Incorrect timeout, got %+v", timeoutErr)
+	}
+
+	// The slow gateway has a larger budget
+	resp, err := send(gateway.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Incorrect status, expected=%d, got=%d", http.StatusCreated, resp.StatusCode)
+	}
+}
+
+func TestTimeoutsMerge(t *testing.T) {
+	defaults := Timeouts{Dial: time.Second, TLSHandshake: 2 * time.Second, ResponseHeader: 3 * time.Second, Attempt: 4 * time.Second}
+	merged := Timeouts{ResponseHeader: time.Minute}.merge(defaults)
+
+	expected := Timeouts{Dial: time.Second, TLSHandshake: 2 * time.Second, ResponseHeader: time.Minute, Attempt: 4 * time.Second}
+	if merged != expected {
+		t.Fatalf("Incorrect timeouts, expected=%+v, got=%+v", expected, merged)
+	}
+}
@@ -0,0 +1,115 @@
+package webpush
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// SendResult is the outcome of a notification sent with Client.Deliver
+type SendResult struct {
+	Response *http.Response  // Response of the push service, nil when the request failed
+	Timings  *RequestTimings // Network timings of the request, nil unless the Client enables WithRequestTimings
+
+	// CorrelationID identifies the notification in events, audit records, logs and the correlation header
+	CorrelationID string
+}
+
+// RequestTimings break the latency of a push service request down into network stages.
+// Stages skipped by the request, e.g. DNS and connect on a reused connection, are zero.
+type RequestTimings struct {
+	DNSLookup        time.Duration // Resolving the push service host
+	Connect          time.Duration // Establishing the TCP connection
+	TLSHandshake     time.Duration // TLS handshake of a new connection
+	TimeToFirstByte  time.Duration // From the request being written to the first response byte
+	Total            time.Duration // From getting a connection to the first response byte
+	ReusedConnection bool          // The request went over an already established connection
+}
+
+// WithRequestTimings traces the network stages of every request, reported in the SendResult of Client.Deliver
+func WithRequestTimings() ClientOption {
+	return func(c *Client) error {
+		c.requestTimings = true
+		return nil
+	}
+}
+
+// requestTracer records the httptrace events of a request, callbacks may run on other goroutines
+type requestTracer struct {
+	mu sync.Mutex
+
+	start, dnsStart, connectStart, tlsStart, wroteRequest time.Time
+	recorded                                              RequestTimings
+}
+
+// clientTrace returns the httptrace hooks recording into t
+func (t *requestTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.record(func(now time.Time) { t.start = now })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.record(func(now time.Time) { t.dnsStart = now })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(func(now time.Time) { t.recorded.DNSLookup = now.Sub(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.record(func(now time.Time) {
+				// Keep the first attempt when dialing several addresses
+				if t.connectStart.IsZero() {
+					t.connectStart = now
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.record(func(now time.Time) {
+				if err == nil {
+					t.recorded.Connect = now.Sub(t.connectStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			t.record(func(now time.Time) { t.tlsStart = now })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(func(now time.Time) { t.recorded.TLSHandshake = now.Sub(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(func(time.Time) { t.recorded.ReusedConnection = info.Reused })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.record(func(now time.Time) { t.wroteRequest = now })
+		},
+		GotFirstResponseByte: func() {
+			t.record(func(now time.Time) {
+				if !t.wroteRequest.IsZero() {
+					t.recorded.TimeToFirstByte = now.Sub(t.wroteRequest)
+				}
+				if !t.start.IsZero() {
+					t.recorded.Total = now.Sub(t.start)
+				}
+			})
+		},
+	}
+}
+
+// record runs fn with the current time while holding t.mu
+func (t *requestTracer) record(fn func(now time.Time)) {
+	now := time.Now()
+
+	t.mu.Lock()
+	fn(now)
+	t.mu.Unlock()
+}
+
+// timings returns a copy of the timings recorded so far
+func (t *requestTracer) timings() *RequestTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := t.recorded
+	return &timings
+}
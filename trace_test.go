@@ -0,0 +1,67 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDeliverRequestTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithHTTPClient(server.Client()),
+		WithRequestTimings(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+
+	deliver := func() *RequestTimings {
+		result, err := client.Deliver(context.Background(), []byte("Test"), s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result.Response.Body.Close()
+
+		if result.Response.StatusCode != http.StatusCreated || result.Timings == nil {
+			t.Fatalf("Expected a response with timings, got %+v", result)
+		}
+
+		return result.Timings
+	}
+
+	first := deliver()
+	if first.ReusedConnection || first.Connect <= 0 || first.TLSHandshake <= 0 || first.Total < first.TimeToFirstByte {
+		t.Fatalf("Incorrect timings of a new connection, got %+v", first)
+	}
+
+	second := deliver()
+	if !second.ReusedConnection || second.Connect != 0 || second.TLSHandshake != 0 {
+		t.Fatalf("Incorrect timings of a reused connection, got %+v", second)
+	}
+}
+
+func TestClientDeliverWithoutRequestTimings(t *testing.T) {
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(&recordingHTTPClient{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.Deliver(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Response.StatusCode != http.StatusCreated || result.Timings != nil {
+		t.Fatalf("Expected a response without timings, got %+v", result)
+	}
+}
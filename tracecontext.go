@@ -0,0 +1,98 @@
+package webpush
+
+import (
+	"context"
+	"net/http"
+)
+
+// maxTraceStateLength is the longest tracestate header propagated, as recommended by W3C Trace Context
+const maxTraceStateLength = 512
+
+// TraceContext is a W3C Trace Context (https://www.w3.org/TR/trace-context/) propagated to push requests
+type TraceContext struct {
+	TraceParent string // e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	TraceState  string // Optional vendor specific trace state
+}
+
+// TraceContextFunc returns the trace context of a notification sent with ctx
+type TraceContextFunc func(ctx context.Context) TraceContext
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx carrying tc, for WithTraceContext
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context stored with ContextWithTraceContext
+func TraceContextFromContext(ctx context.Context) TraceContext {
+	tc, _ := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc
+}
+
+// WithTraceContext adds the traceparent and tracestate headers returned by extract to every push request,
+// so distributed tracing can follow the delivery through internal gateways.
+// A nil extract uses TraceContextFromContext. Malformed traceparent values are not sent.
+func WithTraceContext(extract TraceContextFunc) ClientOption {
+	return func(c *Client) error {
+		if extract == nil {
+			extract = TraceContextFromContext
+		}
+
+		c.traceContext = extract
+		return nil
+	}
+}
+
+// setTraceContextHeaders sets the trace context headers of req from its context
+func setTraceContextHeaders(req *http.Request, extract TraceContextFunc) {
+	tc := extract(req.Context())
+	if !isValidTraceParent(tc.TraceParent) {
+		return
+	}
+
+	req.Header.Set("traceparent", tc.TraceParent)
+	if tc.TraceState != "" && len(tc.TraceState) <= maxTraceStateLength {
+		req.Header.Set("tracestate", tc.TraceState)
+	}
+}
+
+// isValidTraceParent reports whether traceparent is version-format-id-parent-flags with
+// a known version and non-zero ids; versions after 00 may append fields
+func isValidTraceParent(traceparent string) bool {
+	const length = 55
+	if len(traceparent) < length || (len(traceparent) > length && traceparent[length] != '-') {
+		return false
+	}
+
+	version, traceID, parentID, flags := traceparent[0:2], traceparent[3:35], traceparent[36:52], traceparent[53:55]
+	if traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return false
+	}
+
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(traceparent) != length) {
+		return false
+	}
+
+	return isLowerHex(traceID) && !isZeroHex(traceID) && isLowerHex(parentID) && !isZeroHex(parentID) && isLowerHex(flags)
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isZeroHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '0' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,59 @@
+package webpush
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClientTraceContext(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithTraceContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(tc TraceContext) (string, string) {
+		httpClient := &recordingHTTPClient{}
+		ctx := ContextWithTraceContext(context.Background(), tc)
+		if _, err := client.Send(ctx, []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != nil {
+			t.Fatal(err)
+		}
+		return httpClient.req.Header.Get("traceparent"), httpClient.req.Header.Get("tracestate")
+	}
+
+	if parent, state := send(TraceContext{TraceParent: traceParent, TraceState: "congo=t61rcWkgMzE"}); parent != traceParent || state != "congo=t61rcWkgMzE" {
+		t.Fatalf("Incorrect trace context headers, traceparent=%q tracestate=%q", parent, state)
+	}
+
+	// Malformed trace contexts are dropped along with their tracestate
+	if parent, state := send(TraceContext{TraceParent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", TraceState: "congo=t61rcWkgMzE"}); parent != "" || state != "" {
+		t.Fatalf("Expected no trace context headers, got traceparent=%q tracestate=%q", parent, state)
+	}
+
+	// Without a trace context in ctx no headers are sent
+	httpClient := &recordingHTTPClient{}
+	if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: httpClient}); err != nil {
+		t.Fatal(err)
+	}
+	if header := httpClient.req.Header.Get("traceparent"); header != "" {
+		t.Fatalf("Expected no traceparent header, got %q", header)
+	}
+}
+
+func TestIsValidTraceParent(t *testing.T) {
+	for traceParent, valid := range map[string]bool{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        true,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future": false,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        false,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":        false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":        false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":           false,
+		"": false,
+	} {
+		if got := isValidTraceParent(traceParent); got != valid {
+			t.Errorf("isValidTraceParent(%q) = %v, expected %v", traceParent, got, valid)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package webpush
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the columns written by ExportCSV. ImportCSV accepts them in any order, along with
+// the camel case names of PushSubscription.toJSON(), and stores other columns as metadata.
+var csvColumns = []string{"endpoint", "p256dh", "auth", "expiration_time", "application_server_key", "locale", "tags"}
+
+// ImportOptions configure ImportJSONL and ImportCSV
+type ImportOptions struct {
+	// AllowedHosts are passed to Subscription.Validate, e.g. the hosts of private push gateways
+	AllowedHosts []string
+}
+
+// ImportError is the problem with one line of an import
+type ImportError struct {
+	Line int // Line of the JSONL file, or number of the CSV record counting the header, its line unless fields span lines
+	Err  error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// ImportResult reports the subscriptions saved by an import and the lines that were skipped
+type ImportResult struct {
+	Imported int
+	Errors   []*ImportError
+}
+
+// ImportJSONL saves every line of r, a StoredSubscription or PushSubscription.toJSON() object, to store.
+// Lines that don't decode or fail Subscription.Validate are skipped and reported in the ImportResult;
+// the returned error is only set when r can't be read or store fails.
+func ImportJSONL(ctx context.Context, store SubscriptionStore, r io.Reader, options ImportOptions) (*ImportResult, error) {
+	result := &ImportResult{}
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return result, err
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			s := &StoredSubscription{}
+			if decodeErr := json.Unmarshal(data, s); decodeErr != nil {
+				result.Errors = append(result.Errors, &ImportError{Line: line, Err: fmt.Errorf("%w: %v", ErrInvalidSubscription, decodeErr)})
+			} else if saveErr := importSubscription(ctx, store, s, line, options, result); saveErr != nil {
+				return result, saveErr
+			}
+		}
+
+		if err == io.EOF {
+			return result, nil
+		}
+	}
+}
+
+// ImportCSV saves every record of r, a CSV file with a header line naming its columns, to store like
+// ImportJSONL. The tags column holds space separated tags; columns without a known name are stored as metadata.
+func ImportCSV(ctx context.Context, store SubscriptionStore, r io.Reader, options ImportOptions) (*ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			result.Errors = append(result.Errors, &ImportError{Line: line, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		s, err := csvSubscription(header, record)
+		if err != nil {
+			result.Errors = append(result.Errors, &ImportError{Line: line, Err: err})
+			continue
+		}
+
+		if err := importSubscription(ctx, store, s, line, options, result); err != nil {
+			return result, err
+		}
+	}
+}
+
+// importSubscription validates and saves s, the subscription of line
+func importSubscription(ctx context.Context, store SubscriptionStore, s *StoredSubscription, line int, options ImportOptions, result *ImportResult) error {
+	if err := s.Validate(options.AllowedHosts...); err != nil {
+		result.Errors = append(result.Errors, &ImportError{Line: line, Err: err})
+		return nil
+	}
+
+	if err := store.Save(ctx, s); err != nil {
+		return err
+	}
+
+	result.Imported++
+	return nil
+}
+
+// csvSubscription returns the subscription of record, a CSV record with the columns header
+func csvSubscription(header, record []string) (*StoredSubscription, error) {
+	s := &StoredSubscription{}
+	for i, value := range record {
+		if i >= len(header) || value == "" {
+			continue
+		}
+
+		switch column := strings.TrimSpace(header[i]); column {
+		case "endpoint":
+			s.Endpoint = value
+		case "p256dh", "keys.p256dh":
+			s.Keys.P256dh = value
+		case "auth", "keys.auth":
+			s.Keys.Auth = value
+		case "expiration_time", "expirationTime":
+			expiration, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: expiration time %q", ErrInvalidSubscription, value)
+			}
+			s.ExpirationTime = &expiration
+		case "application_server_key", "applicationServerKey":
+			s.ApplicationServerKey = value
+		case "locale":
+			s.Locale = value
+		case "tags":
+			s.Tags = strings.Fields(value)
+		default:
+			if s.Metadata == nil {
+				s.Metadata = make(map[string]string)
+			}
+			s.Metadata[column] = value
+		}
+	}
+
+	return s, nil
+}
+
+// ExportJSONL writes every subscription of store to w as a line of JSON, the format read by ImportJSONL
+func ExportJSONL(ctx context.Context, store SubscriptionStore, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return store.IterateByTag(ctx, "", func(s *StoredSubscription) error {
+		return encoder.Encode(s)
+	})
+}
+
+// ExportCSV writes every subscription of store to w as CSV with a header line, the format read by
+// ImportCSV. Metadata is not exported, use ExportJSONL to keep it.
+func ExportCSV(ctx context.Context, store SubscriptionStore, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	err := store.IterateByTag(ctx, "", func(s *StoredSubscription) error {
+		var expiration string
+		if s.ExpirationTime != nil {
+			expiration = strconv.FormatInt(*s.ExpirationTime, 10)
+		}
+
+		return writer.Write([]string{
+			s.Endpoint, s.Keys.P256dh, s.Keys.Auth, expiration, s.ApplicationServerKey, s.Locale, strings.Join(s.Tags, " "),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,105 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImportJSONL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	input := `{"endpoint":"https://fcm.googleapis.com/fcm/send/a","keys":{"p256dh":"BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk","auth":"zqbxT6JKstKSY9JKibZLSQ"},"tags":["news"]}
+
+{"endpoint":
+{"endpoint":"https://fcm.googleapis.com/fcm/send/b","keys":{"p256dh":"BNNL","auth":"zqbxT6JKstKSY9JKibZLSQ"}}
+`
+
+	result, err := ImportJSONL(ctx, store, strings.NewReader(input), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Imported != 1 || len(result.Errors) != 2 ||
+		result.Errors[0].Line != 3 || !errors.Is(result.Errors[0], ErrInvalidSubscription) ||
+		result.Errors[1].Line != 4 || !errors.Is(result.Errors[1], ErrInvalidSubscriptionKey) {
+		t.Fatalf("Incorrect result, got %+v", result)
+	}
+
+	if s, err := store.Get(ctx, "https://fcm.googleapis.com/fcm/send/a"); err != nil || !s.HasTag("news") {
+		t.Fatalf("Incorrect subscription, got %+v (%v)", s, err)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	input := "endpoint,keys.p256dh,keys.auth,expirationTime,user_agent,tags\n" +
+		"https://fcm.googleapis.com/fcm/send/a,BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk,zqbxT6JKstKSY9JKibZLSQ,,Firefox,news sports\n" +
+		"https://fcm.googleapis.com/fcm/send/b,BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk,zqbxT6JKstKSY9JKibZLSQ,soon,,\n"
+
+	result, err := ImportCSV(ctx, store, strings.NewReader(input), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Imported != 1 || len(result.Errors) != 1 || result.Errors[0].Line != 3 || !errors.Is(result.Errors[0], ErrInvalidSubscription) {
+		t.Fatalf("Incorrect result, got %+v", result)
+	}
+
+	s, err := store.Get(ctx, "https://fcm.googleapis.com/fcm/send/a")
+	if err != nil || !s.HasTag("sports") || s.Metadata["user_agent"] != "Firefox" {
+		t.Fatalf("Incorrect subscription, got %+v (%v)", s, err)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySubscriptionStore()
+	expiration := int64(4102444800000)
+	stored := &StoredSubscription{Subscription: *getURLEncodedTestSubscription(), Tags: []string{"news", "user:1"}, Locale: "de"}
+	stored.ExpirationTime = &expiration
+	if err := store.Save(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	formats := []struct {
+		name  string
+		write func(context.Context, SubscriptionStore, *bytes.Buffer) error
+		read  func(context.Context, SubscriptionStore, *bytes.Buffer) (*ImportResult, error)
+	}{
+		{
+			name:  "JSONL",
+			write: func(ctx context.Context, s SubscriptionStore, b *bytes.Buffer) error { return ExportJSONL(ctx, s, b) },
+			read: func(ctx context.Context, s SubscriptionStore, b *bytes.Buffer) (*ImportResult, error) {
+				return ImportJSONL(ctx, s, b, ImportOptions{})
+			},
+		},
+		{
+			name:  "CSV",
+			write: func(ctx context.Context, s SubscriptionStore, b *bytes.Buffer) error { return ExportCSV(ctx, s, b) },
+			read: func(ctx context.Context, s SubscriptionStore, b *bytes.Buffer) (*ImportResult, error) {
+				return ImportCSV(ctx, s, b, ImportOptions{})
+			},
+		},
+	}
+
+	for _, format := range formats {
+		var buf bytes.Buffer
+		if err := format.write(ctx, store, &buf); err != nil {
+			t.Fatalf("%s: %v", format.name, err)
+		}
+
+		imported := NewMemorySubscriptionStore()
+		if result, err := format.read(ctx, imported, &buf); err != nil || result.Imported != 1 || len(result.Errors) != 0 {
+			t.Fatalf("%s: incorrect result, got %+v (%v)", format.name, result, err)
+		}
+
+		got, err := imported.Get(ctx, stored.Endpoint)
+		if err != nil || got.Keys != stored.Keys || *got.ExpirationTime != expiration || got.Locale != "de" || len(got.Tags) != 2 {
+			t.Fatalf("%s: incorrect subscription, got %+v (%v)", format.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package webpush
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithHTTPClient sends every notification of the Client with client, e.g. an *http.Client configured
+// for a corporate proxy or mTLS, or an instrumented wrapper.
+// An HTTPClient set in the Options passed to Send still takes precedence.
+func WithHTTPClient(client HTTPClient) ClientOption {
+	return func(c *Client) error {
+		c.httpClient = client
+		return nil
+	}
+}
+
+// WithTransport sends every notification of the Client through transport, leaving the rest of
+// the http.Client defaults untouched except for redirects, see WithRedirectPolicy
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *Client) error {
+		c.httpClient = &http.Client{Transport: transport, CheckRedirect: noRedirects}
+		return nil
+	}
+}
+
+// DefaultTransportOptions are tuned for a few push service origins such as FCM and Mozilla
+// receiving a high request volume: enough idle connections per origin to absorb bursts without
+// redialing, and HTTP/2 health checks so connections silently dropped by a load balancer are replaced.
+var DefaultTransportOptions = TransportOptions{
+	MaxIdleConnsPerHost:   64,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: 30 * time.Second,
+	HTTP2ReadIdleTimeout:  30 * time.Second,
+	HTTP2PingTimeout:      15 * time.Second,
+}
+
+// TransportOptions tunes the connection pool of the transport built by NewTransport.
+// Limits apply per push service origin; zero values keep the http.DefaultTransport setting.
+type TransportOptions struct {
+	MaxConnsPerHost       int           // Connections per origin, in any state (0 is unlimited)
+	MaxIdleConnsPerHost   int           // Idle connections kept per origin
+	IdleConnTimeout       time.Duration // How long an idle connection is kept
+	TLSHandshakeTimeout   time.Duration // Limit on the TLS handshake of a new connection
+	ResponseHeaderTimeout time.Duration // Limit on waiting for the push service response headers
+
+	// HTTP/2 connections are pinged after HTTP2ReadIdleTimeout without frames and closed when no
+	// ping response arrives within HTTP2PingTimeout; both require Go 1.24 and are ignored before
+	HTTP2ReadIdleTimeout time.Duration
+	HTTP2PingTimeout     time.Duration
+
+	// HTTP2MaxReceiveBufferPerStream bounds the response data buffered per stream (Go 1.24 or later)
+	HTTP2MaxReceiveBufferPerStream int
+
+	// Proxy replaces the proxy settings of the environment, nil keeps http.ProxyFromEnvironment
+	Proxy *Proxy
+
+	// TLS settings for gateways that re-terminate TLS. RootCAs replaces the system roots, start
+	// from x509.SystemCertPool to trust a gateway CA in addition to them.
+	TLSMinVersion      uint16            // e.g. tls.VersionTLS12, the crypto/tls default when zero
+	RootCAs            *x509.CertPool    // Certificate authorities trusted for push service connections
+	ClientCertificates []tls.Certificate // Presented to servers requesting a client certificate
+
+	// DNSCache resolves the hosts of new connections, nil resolves every connection
+	DNSCache *DNSCache
+
+	// DialContext opens the connections of the transport instead of a net.Dialer, e.g. DialUnix
+	// for a local sidecar. It takes precedence over DNSCache and LocalAddr.
+	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// LocalAddr is the local IP connections are made from, e.g. one of several egress IPs or the
+	// address of a network interface returned by InterfaceIP. Only remote addresses of its family are dialed.
+	LocalAddr net.IP
+}
+
+// NewTransport returns a clone of http.DefaultTransport tuned with options
+func NewTransport(options TransportOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+
+	if options.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = options.MaxConnsPerHost
+	}
+
+	if options.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+		if transport.MaxIdleConns > 0 && transport.MaxIdleConns < options.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = options.MaxIdleConnsPerHost
+		}
+	}
+
+	if options.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = options.IdleConnTimeout
+	}
+
+	if options.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = options.TLSHandshakeTimeout
+	}
+
+	if options.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = options.ResponseHeaderTimeout
+	}
+
+	if options.Proxy != nil {
+		transport.Proxy = options.Proxy.ProxyURL
+	}
+
+	if options.LocalAddr != nil || options.DNSCache != nil {
+		dialer := newDialer()
+		if options.LocalAddr != nil {
+			dialer.LocalAddr = &net.TCPAddr{IP: options.LocalAddr}
+		}
+
+		transport.DialContext = dialer.DialContext
+		if cache := options.DNSCache; cache != nil {
+			transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+				return cache.dial(ctx, dialer, network, address)
+			}
+		}
+	}
+
+	if options.DialContext != nil {
+		transport.DialContext = options.DialContext
+	}
+
+	if options.TLSMinVersion != 0 || options.RootCAs != nil || len(options.ClientCertificates) > 0 {
+		config := &tls.Config{}
+		if transport.TLSClientConfig != nil {
+			config = transport.TLSClientConfig.Clone()
+		}
+
+		if options.TLSMinVersion != 0 {
+			config.MinVersion = options.TLSMinVersion
+		}
+
+		if options.RootCAs != nil {
+			config.RootCAs = options.RootCAs
+		}
+
+		if len(options.ClientCertificates) > 0 {
+			config.Certificates = options.ClientCertificates
+		}
+
+		transport.TLSClientConfig = config
+	}
+
+	configureHTTP2(transport, options)
+
+	return transport
+}
+
+// newDialer returns a net.Dialer with the settings of http.DefaultTransport
+func newDialer() *net.Dialer {
+	return &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+}
+
+// InterfaceIP returns the first address of the network interface name, IPv4 first
+func InterfaceIP(name string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var ipv6 net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		if ipv4 := ipNet.IP.To4(); ipv4 != nil {
+			return ipv4, nil
+		}
+
+		if ipv6 == nil {
+			ipv6 = ipNet.IP
+		}
+	}
+
+	if ipv6 == nil {
+		return nil, fmt.Errorf("webpush: network interface %s has no IP address", name)
+	}
+
+	return ipv6, nil
+}
+
+// DialUnix returns a DialContext connecting every request to the Unix domain socket at path,
+// whatever its address, e.g. for an Envoy or egress proxy sidecar
+func DialUnix(path string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+}
+
+// PlaintextUpstream is an Interceptor sending https requests as plain http, for a local sidecar
+// that originates the TLS connections to the push services. The VAPID audience is still the https origin.
+func PlaintextUpstream(next HTTPClient) HTTPClient {
+	return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Scheme != "https" {
+			return next.Do(req)
+		}
+
+		plaintext := req.Clone(req.Context())
+		plaintext.URL.Scheme = "http"
+
+		return next.Do(plaintext)
+	})
+}
+
+// WithTransportOptions sends every notification of the Client through NewTransport(options)
+func WithTransportOptions(options TransportOptions) ClientOption {
+	return WithTransport(NewTransport(options))
+}
+
+// WarmConnections opens a connection to every push service origin (e.g. https://fcm.googleapis.com)
+// with a HEAD request, so the TLS and HTTP/2 handshakes of a campaign are done before its first
+// notifications instead of all at once. The response status is ignored; origins are warmed
+// concurrently and the first connection error is returned after all of them are done.
+func (c *Client) WarmConnections(ctx context.Context, origins []string) error {
+	client := c.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	errs := make([]error, len(origins))
+	var wg sync.WaitGroup
+	for i, origin := range origins {
+		normalized, err := normalizeAudience(origin, c.allowInsecure)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, origin string) {
+			defer wg.Done()
+			errs[i] = warmConnection(ctx, client, origin)
+		}(i, normalized)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// warmConnection sends a HEAD request to origin, returning the connection to the pool of client
+func warmConnection(ctx context.Context, client HTTPClient, origin string) error {
+	req, err := http.NewRequest(http.MethodHead, origin+"/", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("webpush: warming connection to %s: %w", origin, err)
+	}
+
+	// Connections are only reused once the body is drained
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,34 @@
+//go:build go1.24
+// +build go1.24
+
+package webpush
+
+import (
+	"net/http"
+)
+
+// configureHTTP2 applies the HTTP/2 settings of options to transport, keeping any it already has
+func configureHTTP2(transport *http.Transport, options TransportOptions) {
+	if options.HTTP2ReadIdleTimeout == 0 && options.HTTP2PingTimeout == 0 && options.HTTP2MaxReceiveBufferPerStream == 0 {
+		return
+	}
+
+	config := &http.HTTP2Config{}
+	if transport.HTTP2 != nil {
+		*config = *transport.HTTP2
+	}
+
+	if options.HTTP2ReadIdleTimeout > 0 {
+		config.SendPingTimeout = options.HTTP2ReadIdleTimeout
+	}
+
+	if options.HTTP2PingTimeout > 0 {
+		config.PingTimeout = options.HTTP2PingTimeout
+	}
+
+	if options.HTTP2MaxReceiveBufferPerStream > 0 {
+		config.MaxReceiveBufferPerStream = options.HTTP2MaxReceiveBufferPerStream
+	}
+
+	transport.HTTP2 = config
+}
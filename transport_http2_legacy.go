@@ -0,0 +1,11 @@
+//go:build !go1.24
+// +build !go1.24
+
+package webpush
+
+import (
+	"net/http"
+)
+
+// configureHTTP2 is a no-op, http.Transport has no HTTP/2 settings before Go 1.24
+func configureHTTP2(transport *http.Transport, options TransportOptions) {}
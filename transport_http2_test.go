@@ -0,0 +1,27 @@
+//go:build go1.24
+// +build go1.24
+
+package webpush
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewTransportConfiguresHTTP2(t *testing.T) {
+	transport := NewTransport(DefaultTransportOptions)
+
+	if transport.HTTP2 == nil {
+		t.Fatal("Expected HTTP/2 settings")
+	}
+
+	if transport.HTTP2.SendPingTimeout != DefaultTransportOptions.HTTP2ReadIdleTimeout ||
+		transport.HTTP2.PingTimeout != DefaultTransportOptions.HTTP2PingTimeout {
+		t.Fatalf("Incorrect HTTP/2 health check settings, got %+v", transport.HTTP2)
+	}
+
+	// The clone must not share the HTTP/2 settings of http.DefaultTransport
+	if defaults := http.DefaultTransport.(*http.Transport).HTTP2; defaults != nil && defaults == transport.HTTP2 {
+		t.Fatal("Expected a copy of the http.DefaultTransport HTTP/2 settings")
+	}
+}
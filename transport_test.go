@@ -0,0 +1,290 @@
+package webpush
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientWithHTTPClient(t *testing.T) {
+	httpClient := &recordingHTTPClient{}
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if httpClient.req == nil {
+		t.Fatal("Expected the request to go through the Client HTTPClient")
+	}
+
+	// The HTTPClient of the Options takes precedence
+	override := &recordingHTTPClient{}
+	httpClient.req = nil
+	if _, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), &Options{HTTPClient: override}); err != nil {
+		t.Fatal(err)
+	}
+
+	if override.req == nil || httpClient.req != nil {
+		t.Fatal("Expected the request to go through the Options HTTPClient")
+	}
+}
+
+func TestClientWithTransport(t *testing.T) {
+	var sent *http.Request
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	})
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sent == nil || resp.StatusCode != http.StatusCreated {
+		t.Fatal("Expected the request to go through the transport")
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	transport := NewTransport(TransportOptions{
+		MaxConnsPerHost:     8,
+		MaxIdleConnsPerHost: 256,
+		IdleConnTimeout:     time.Minute,
+	})
+
+	if transport.MaxConnsPerHost != 8 || transport.MaxIdleConnsPerHost != 256 || transport.IdleConnTimeout != time.Minute {
+		t.Fatalf("Incorrect pool settings, got MaxConnsPerHost=%d MaxIdleConnsPerHost=%d IdleConnTimeout=%s",
+			transport.MaxConnsPerHost, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+
+	// The total idle limit must not undercut the per origin limit
+	if transport.MaxIdleConns < 256 {
+		t.Fatalf("Incorrect MaxIdleConns, got %d", transport.MaxIdleConns)
+	}
+
+	// Unset values keep the http.DefaultTransport settings
+	defaults := http.DefaultTransport.(*http.Transport)
+	if transport.TLSHandshakeTimeout != defaults.TLSHandshakeTimeout || transport.Proxy == nil {
+		t.Fatal("Expected the http.DefaultTransport settings to be kept")
+	}
+
+	if transport == defaults {
+		t.Fatal("Expected a clone of http.DefaultTransport")
+	}
+}
+
+func TestNewTransportTLS(t *testing.T) {
+	var peerCertificates int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peerCertificates = len(r.TLS.PeerCertificates)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithTransportOptions(TransportOptions{
+			TLSMinVersion:      tls.VersionTLS12,
+			RootCAs:            roots,
+			ClientCertificates: server.TLS.Certificates,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+
+	resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated || peerCertificates != 1 {
+		t.Fatalf("Expected a mutually authenticated request, got status=%d client certificates=%d", resp.StatusCode, peerCertificates)
+	}
+
+	// Without the custom roots the server certificate is not trusted
+	untrusted, _ := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithTransportOptions(TransportOptions{}))
+	if _, err := untrusted.Send(context.Background(), []byte("Test"), s, nil); err == nil {
+		t.Fatal("Expected an unknown authority error")
+	}
+}
+
+func TestClientWarmConnections(t *testing.T) {
+	var connections, heads int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			atomic.AddInt32(&heads, 1)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client, err := NewClient(WithVAPIDKeys(getTestVAPIDKeys(t)), WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.WarmConnections(context.Background(), []string{server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// The notification reuses the warmed connection
+	if atomic.LoadInt32(&heads) != 1 || atomic.LoadInt32(&connections) != 1 {
+		t.Fatalf("Expected a single warmed connection, got heads=%d connections=%d", heads, connections)
+	}
+
+	if err := client.WarmConnections(context.Background(), []string{"http://push.example.com"}); !errors.Is(err, ErrInsecureEndpoint) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInsecureEndpoint, err)
+	}
+}
+
+func TestClientSendsThroughUnixSocketSidecar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webpush")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "sidecar.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix domain sockets unavailable: %v", err)
+	}
+
+	var received *http.Request
+	sidecar := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusCreated)
+	})}
+	go sidecar.Serve(listener)
+	defer sidecar.Close()
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithTransportOptions(TransportOptions{DialContext: DialUnix(socket)}),
+		WithInterceptors(PlaintextUpstream),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Send(context.Background(), []byte("Test"), getStandardEncodedTestSubscription(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated || received == nil {
+		t.Fatal("Expected the request to reach the sidecar")
+	}
+
+	// The sidecar sees the push service as Host over plain http
+	endpoint, _ := url.Parse(getStandardEncodedTestSubscription().Endpoint)
+	if received.Host != endpoint.Host || received.TLS != nil {
+		t.Fatalf("Incorrect sidecar request, got Host=%s TLS=%v", received.Host, received.TLS != nil)
+	}
+}
+
+func TestNewTransportLocalAddr(t *testing.T) {
+	var remoteAddr string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	// Linux routes all of 127.0.0.0/8 to the loopback interface
+	local := net.ParseIP("127.0.0.2")
+	if conn, err := (&net.Dialer{LocalAddr: &net.TCPAddr{IP: local}}).Dial("tcp", server.Listener.Addr().String()); err != nil {
+		t.Skipf("Can't bind to %s: %v", local, err)
+	} else {
+		conn.Close()
+	}
+
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithInsecureEndpoints(),
+		WithTransportOptions(TransportOptions{LocalAddr: local}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := getStandardEncodedTestSubscription()
+	s.Endpoint = server.URL + "/push/abc"
+	resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if host, _, _ := net.SplitHostPort(remoteAddr); host != local.String() {
+		t.Fatalf("Incorrect local address, expected=%s, got=%s", local, host)
+	}
+}
+
+func TestInterfaceIP(t *testing.T) {
+	ip, err := InterfaceIP("lo")
+	if err != nil {
+		t.Skipf("No loopback interface: %v", err)
+	}
+
+	if !ip.IsLoopback() {
+		t.Fatalf("Expected a loopback address, got %s", ip)
+	}
+
+	if _, err := InterfaceIP("webpush-missing0"); err == nil {
+		t.Fatal("Expected an error for a missing interface")
+	}
+}
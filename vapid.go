@@ -1,63 +1,199 @@
 package webpush
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"math/big"
+	"net"
+	"net/mail"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// Cache stats for monitoring (optional)
+// ErrVAPIDKeyMismatch is matched by errors.Is for every VAPIDKeyMismatchError
+var ErrVAPIDKeyMismatch = errors.New("webpush: subscription was created with a different VAPID public key")
+
+// VAPIDKeyMismatchError is returned when a subscription's ApplicationServerKey does not match
+// the VAPID public key used to send it. Push services would reject such a request with a 403.
+type VAPIDKeyMismatchError struct {
+	ApplicationServerKey string
+	VAPIDPublicKey       string
+}
+
+func (e *VAPIDKeyMismatchError) Error() string {
+	return ErrVAPIDKeyMismatch.Error() + ": subscription key " + e.ApplicationServerKey + ", VAPID key " + e.VAPIDPublicKey
+}
+
+// Is reports whether target is ErrVAPIDKeyMismatch
+func (e *VAPIDKeyMismatchError) Is(target error) bool {
+	return target == ErrVAPIDKeyMismatch
+}
+
+// ErrReservedClaim is returned when a ClaimsFunc tries to set a claim managed by the library
+var ErrReservedClaim = errors.New("webpush: claim is set by the library and can not be overridden")
+
+// ErrInvalidSubscriber is matched by errors.Is for every InvalidSubscriberError
+var ErrInvalidSubscriber = errors.New("webpush: subscriber must be a mailto: address or an https URL")
+
+// InvalidSubscriberError is returned in strict subscriber mode for subscribers
+// that are neither a valid mailto: address nor an https URL
+type InvalidSubscriberError struct {
+	Subscriber string
+}
+
+func (e *InvalidSubscriberError) Error() string {
+	return ErrInvalidSubscriber.Error() + ", got " + strconv.Quote(e.Subscriber)
+}
+
+// Is reports whether target is ErrInvalidSubscriber
+func (e *InvalidSubscriberError) Is(target error) bool {
+	return target == ErrInvalidSubscriber
+}
+
 var (
-	vapidCacheHits   uint64
-	vapidCacheMisses uint64
+	// ErrInsecureEndpoint is returned for endpoints that are not https URLs
+	ErrInsecureEndpoint = errors.New("webpush: endpoint must be an https URL")
+
+	// ErrInvalidEndpoint is returned for endpoints without a host
+	ErrInvalidEndpoint = errors.New("webpush: endpoint has no host")
 )
 
-// GetVAPIDCacheStats returns cache hit/miss stats for monitoring
-func GetVAPIDCacheStats() (hits, misses uint64) {
-	return atomic.LoadUint64(&vapidCacheHits), atomic.LoadUint64(&vapidCacheMisses)
+// ErrInvalidHeaderParam is returned for extra vapid Authorization header parameters
+// that are not valid auth-params or collide with t and k
+var ErrInvalidHeaderParam = errors.New("webpush: invalid vapid Authorization header parameter")
+
+// encodeHeaderParams formats params as auth-params (RFC 7235 section 2.1) sorted by name
+func encodeHeaderParams(params map[string]string) (string, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if name == "t" || name == "k" || !isToken(name) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidHeaderParam, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	encoded := make([]string, len(names))
+	for i, name := range names {
+		value := params[name]
+		if !isToken(value) {
+			value = strconv.Quote(value)
+		}
+		encoded[i] = name + "=" + value
+	}
+
+	return strings.Join(encoded, ", "), nil
 }
 
-// Cache for VAPID authorization headers (keyed by privateKey + publicKey + audience)
-var vapidHeaderCache sync.Map
+// isToken reports whether s is a non-empty HTTP token (RFC 7230 section 3.2.6)
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
 
-// Cache for parsed private keys (keyed by vapidPrivateKey)
-var privateKeyCache sync.Map
+	for _, r := range s {
+		if r > 0x7e || r <= 0x20 || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
 
-// vapidCacheEntry stores cached VAPID header with expiration
-type vapidCacheEntry struct {
-	header     string
-	expiration time.Time
+	return true
 }
 
-// cacheMargin is how long before expiration we consider cache invalid (safety margin)
-const cacheMargin = 30 * time.Minute
+var (
+	// ErrInvalidVAPIDPrivateKey is returned for private keys that are not a valid P-256 scalar
+	ErrInvalidVAPIDPrivateKey = errors.New("webpush: VAPID private key is not a valid P-256 private key")
+
+	// ErrInvalidVAPIDPublicKey is returned for public keys that are not an uncompressed P-256 point
+	ErrInvalidVAPIDPublicKey = errors.New("webpush: VAPID public key is not a valid P-256 public key")
+)
+
+// ClaimsFunc returns additional claims for the VAPID JWT token sent to audience.
+// It is called for every header lookup, so it must be cheap and deterministic:
+// the returned claims are part of the header cache key.
+type ClaimsFunc func(audience string) map[string]interface{}
+
+// isReservedClaim reports whether the claim is set from the notification options
+func isReservedClaim(name string) bool {
+	switch name {
+	case "aud", "exp", "sub", "iat":
+		return true
+	}
+	return false
+}
 
 // GenerateVAPIDKeys will create a private and public VAPID key pair
 func GenerateVAPIDKeys() (privateKey, publicKey string, err error) {
+	keys, err := generateVAPIDKeyPair(rand.Reader)
+	if err != nil {
+		return
+	}
+
+	return keys.PrivateKey, keys.PublicKey, nil
+}
+
+// generateVAPIDKeyPair creates a key pair from random and verifies the generated point
+func generateVAPIDKeyPair(random io.Reader) (VAPIDKeys, error) {
 	// Get the private key from the P256 curve
 	curve := elliptic.P256()
 
-	private, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+	private, x, y, err := elliptic.GenerateKey(curve, random)
 	if err != nil {
-		return
+		return VAPIDKeys{}, err
+	}
+
+	// Never hand out the identity, an out of range scalar or a point off the curve
+	d := new(big.Int).SetBytes(private)
+	if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 || !curve.IsOnCurve(x, y) {
+		return VAPIDKeys{}, ErrInvalidGeneratedKey
 	}
 
 	public := elliptic.Marshal(curve, x, y)
 
 	// Convert to base64
-	publicKey = base64.RawURLEncoding.EncodeToString(public)
-	privateKey = base64.RawURLEncoding.EncodeToString(private)
+	return VAPIDKeys{
+		PrivateKey: base64.RawURLEncoding.EncodeToString(private),
+		PublicKey:  base64.RawURLEncoding.EncodeToString(public),
+	}, nil
+}
+
+// parseVAPIDPrivateKey validates a decoded VAPID private key and derives its ECDSA key pair.
+// The scalar must be 32 bytes in the range [1, N-1] of P-256.
+func parseVAPIDPrivateKey(privateKey []byte) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+
+	d := new(big.Int).SetBytes(privateKey)
+	if len(privateKey) != 32 || d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, ErrInvalidVAPIDPrivateKey
+	}
+
+	return generateVAPIDHeaderKeys(privateKey), nil
+}
+
+// validateVAPIDPublicKey checks that a decoded VAPID public key is an uncompressed point on P-256.
+// The identity can't be encoded in this form, so a valid point is never the identity.
+func validateVAPIDPublicKey(publicKey []byte) error {
+	if len(publicKey) != 65 || publicKey[0] != 4 {
+		return ErrInvalidVAPIDPublicKey
+	}
+
+	if x, _ := elliptic.Unmarshal(elliptic.P256(), publicKey); x == nil {
+		return ErrInvalidVAPIDPublicKey
+	}
 
-	return
+	return nil
 }
 
 // Generates the ECDSA public and private keys for the JWT encryption
@@ -86,6 +222,31 @@ func generateVAPIDHeaderKeys(privateKey []byte) *ecdsa.PrivateKey {
 	}
 }
 
+// vapidHeaderParams are the inputs used to build a VAPID Authorization header
+type vapidHeaderParams struct {
+	endpoint         string
+	endpointURL      *url.URL // endpoint already parsed, e.g. by the request (Optional)
+	audience         string   // overrides the audience derived from endpoint
+	subscriber       string
+	vapidPublicKey   string
+	vapidPrivateKey  string
+	signer           crypto.Signer // used instead of vapidPrivateKey when set
+	expiration       time.Time
+	now              time.Time // current time of the Client clock
+	issuedAt         time.Time // iat claim, omitted when zero
+	claims           ClaimsFunc
+	strictSubscriber bool
+	subscriberPolicy *SubscriberPolicy
+	headerParams     string        // extra auth-params appended after k=
+	noCache          bool          // bypass the header and private key caches
+	allowInsecure    bool          // accept http endpoints
+	encoder          JWTEncoder    // defaults to defaultJWTEncoder
+	cacheMargin      time.Duration // re-sign cached headers expiring within the margin
+	audienceTTLs     []audienceTTL
+	cache            *vapidCache // shared cache of the package level functions when nil
+	logger           Logger      // receives the cache misses when set
+}
+
 // getVAPIDAuthorizationHeader returns a cached VAPID authorization header if available,
 // otherwise generates a new one and caches it.
 func getVAPIDAuthorizationHeader(
@@ -95,90 +256,373 @@ func getVAPIDAuthorizationHeader(
 	vapidPrivateKey string,
 	expiration time.Time,
 ) (string, error) {
+	return getVAPIDHeader(&vapidHeaderParams{
+		endpoint:        endpoint,
+		subscriber:      subscriber,
+		vapidPublicKey:  vapidPublicKey,
+		vapidPrivateKey: vapidPrivateKey,
+		expiration:      expiration,
+		now:             time.Now(),
+		cacheMargin:     DefaultVAPIDCacheMargin,
+		cache:           defaultClient.cache,
+	})
+}
+
+// resolve returns the normalized aud and sub claims and the VAPID public key of params
+func (params *vapidHeaderParams) resolve() (audience, vapidPublicKey, subscriber string, err error) {
 	// Parse endpoint to get audience
-	subURL, err := url.Parse(endpoint)
+	origin := params.endpoint
+	if params.audience != "" {
+		origin = params.audience
+	}
+
+	if params.audience == "" && params.endpointURL != nil {
+		audience, err = normalizeAudienceURL(params.endpointURL, origin, params.allowInsecure)
+	} else {
+		audience, err = normalizeAudience(origin, params.allowInsecure)
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+
+	vapidPublicKey, err = resolveVAPIDPublicKey(params.vapidPublicKey, params.signer)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	subscriber, err = normalizeSubscriber(params.subscriber, params.strictSubscriber || params.subscriberPolicy != nil)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if params.subscriberPolicy != nil {
+		subscriber, err = params.subscriberPolicy.apply(subscriber)
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+
+	return audience, vapidPublicKey, subscriber, nil
+}
+
+// getVAPIDHeader builds or loads from cache the VAPID authorization header for params
+func getVAPIDHeader(params *vapidHeaderParams) (string, error) {
+	audience, vapidPublicKey, subscriber, err := params.resolve()
 	if err != nil {
 		return "", err
 	}
 
-	audience := subURL.Scheme + "://" + subURL.Host
+	// Additional claims are part of the signed token, so they are part of the cache key too
+	var extraClaims map[string]interface{}
+	var encodedClaims []byte
+	if params.claims != nil {
+		extraClaims = params.claims(audience)
+		for name := range extraClaims {
+			if isReservedClaim(name) {
+				return "", fmt.Errorf("%w: %s", ErrReservedClaim, name)
+			}
+		}
+
+		// Map keys are sorted when encoded, the encoding is stable
+		encodedClaims, err = json.Marshal(extraClaims)
+		if err != nil {
+			return "", err
+		}
+	}
 
-	// Create cache key: privateKey + publicKey + audience
-	cacheKey := vapidPrivateKey + "|" + vapidPublicKey + "|" + audience
+	// Any base64 form of the public key shares the cache entries
+	cachedPublicKey := canonicalVAPIDPublicKey(vapidPublicKey)
 
-	// Check cache for existing valid header
-	if cached, ok := vapidHeaderCache.Load(cacheKey); ok {
-		entry := cached.(vapidCacheEntry)
-		// Return cached header if still valid (with safety margin)
-		if time.Now().Add(cacheMargin).Before(entry.expiration) {
-			// atomic.AddUint64(&vapidCacheHits, 1)
+	// Tokens of a signer are keyed by its own public key instead of the private key it hides
+	signingKey := params.vapidPrivateKey
+	if params.signer != nil {
+		signerKey, err := marshalSignerPublicKey(params.signer)
+		if err != nil {
+			return "", err
+		}
+		signingKey = "signer:" + string(signerKey)
+	}
+	cacheKey := headerCacheKey(cachedPublicKey, audience, subscriber, signingKey, string(encodedClaims), params.headerParams)
+
+	cache := params.cache
+	if cache == nil {
+		cache = defaultClient.cache
+	}
+
+	cacheTTL := audienceCacheTTL(params.audienceTTLs, audience)
+
+	// Backends without the signing time expire early enough to enforce the TTL override
+	var externalTTL time.Duration
+	if cacheTTL > 0 {
+		externalTTL = cacheTTL + params.cacheMargin
+	}
+
+	// Serve a valid cached header before anything of the signing path is allocated
+	entry, cached := cache.load(cacheKey, params.now)
+	cached = cached && !params.noCache
+
+	// Headers of audiences with a TTL override are never served past it
+	tooOld := cached && cacheTTL > 0 && !entry.signedAt.IsZero() && !params.now.Before(entry.signedAt.Add(cacheTTL))
+
+	// Return cached header if still valid (with safety margin)
+	if cached && !tooOld && params.now.Add(params.cacheMargin).Before(entry.expiration) {
+		atomic.AddUint64(&cache.stats.hits, 1)
+		recordCacheHit(audience)
+		return entry.header, nil
+	}
+
+	sign := func() (string, error) {
+		claims := map[string]interface{}{
+			"aud": audience,
+			"exp": params.expiration.Unix(),
+			"sub": subscriber,
+		}
+		if !params.issuedAt.IsZero() {
+			claims["iat"] = params.issuedAt.Unix()
+		}
+		for name, value := range extraClaims {
+			claims[name] = value
+		}
+
+		// Sign token through the external signer, or with the cached private key
+		var key crypto.Signer = params.signer
+		if key == nil {
+			privKey, err := cache.privateKey(params.vapidPrivateKey, params.noCache)
+			if err != nil {
+				return "", err
+			}
+			key = privKey
+		}
+
+		encoder := params.encoder
+		if encoder == nil {
+			encoder = defaultJWTEncoder
+		}
+
+		signingStart := time.Now()
+		jwtString, err := encoder.Encode(claims, key)
+		recordSigning(audience, time.Since(signingStart), err)
+		if err != nil {
+			return "", err
+		}
+
+		// Decode the VAPID public key
+		pubKey, err := decodeVapidKey(vapidPublicKey)
+		if err != nil {
+			return "", err
+		}
+
+		if err := validateVAPIDPublicKey(pubKey); err != nil {
+			return "", err
+		}
+
+		header := vapidAuthorization(jwtString, pubKey, params.headerParams)
+
+		// Cache the header
+		if !params.noCache {
+			cache.store(cacheKey, vapidCacheEntry{
+				header:     header,
+				expiration: params.expiration,
+				signedAt:   params.now,
+			}, params.now, externalTTL)
+		}
+
+		return header, nil
+	}
+
+	if cached {
+		// Inside the margin, keep serving the header while it is re-signed in the background
+		if !tooOld && cacheTTL == 0 && params.now.Add(minHeaderValidity).Before(entry.expiration) {
+			if params.now.Add(params.cacheMargin).Before(params.expiration) && cache.flights.doAsync(cacheKey, sign) {
+				atomic.AddUint64(&cache.stats.refreshes, 1)
+			}
+			atomic.AddUint64(&cache.stats.hits, 1)
+			recordCacheHit(audience)
 			return entry.header, nil
 		}
+
 		// Cache expired, delete it
-		vapidHeaderCache.Delete(cacheKey)
+		cache.evict(cacheKey)
 	}
 
-	// atomic.AddUint64(&vapidCacheMisses, 1)
+	// Fall back to headers signed ahead of time on another host, their age is unknown
+	if !params.noCache && cacheTTL == 0 {
+		if header, ok := cache.pregenerated.lookup(vapidPublicKey, audience, subscriber, params.now, params.cacheMargin); ok {
+			atomic.AddUint64(&cache.stats.hits, 1)
+			recordCacheHit(audience)
+			return header, nil
+		}
+	}
 
-	// Unless subscriber is an HTTPS URL, assume an e-mail address
-	if !strings.HasPrefix(subscriber, "https:") {
-		subscriber = "mailto:" + subscriber
+	atomic.AddUint64(&cache.stats.misses, 1)
+	if params.logger != nil {
+		params.logger.Debug("webpush: VAPID header cache miss", "audience", audience)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
-		"aud": audience,
-		"exp": expiration.Unix(),
-		"sub": subscriber,
-	})
+	if params.noCache {
+		return sign()
+	}
+
+	// Concurrent misses of the same header share a single signing
+	return cache.flights.do(cacheKey, sign)
+}
+
+// vapidAuthorization returns the Authorization header "vapid t=<jwt>, k=<public key>[, <params>]",
+// assembled in a pooled buffer
+func vapidAuthorization(jwt string, publicKey []byte, params string) string {
+	buf := getHeaderBuffer()
+	defer putHeaderBuffer(buf)
+
+	b := append(*buf, "vapid t="...)
+	b = append(b, jwt...)
+	b = append(b, ", k="...)
+	b = appendBase64(b, publicKey)
+	if params != "" {
+		b = append(b, ", "...)
+		b = append(b, params...)
+	}
+	*buf = b
 
-	// Get or create cached private key
-	privKey, err := getCachedPrivateKey(vapidPrivateKey)
+	return string(b)
+}
+
+// canonicalVAPIDPublicKey returns the raw URL base64 form of a VAPID public key, key itself when it is
+// already, without allocating, or when it isn't valid base64
+func canonicalVAPIDPublicKey(key string) string {
+	buf := getHeaderBuffer()
+	defer putHeaderBuffer(buf)
+
+	b := append(*buf, key...)
+	n := len(b)
+	b = append(b, make([]byte, base64.RawURLEncoding.DecodedLen(n))...)
+	_, err := base64.RawURLEncoding.Strict().Decode(b[n:], b[:n])
+	*buf = b
+	if err == nil {
+		return key
+	}
+
+	decoded, err := decodeVapidKey(key)
 	if err != nil {
-		return "", err
+		return key
 	}
 
-	// Sign token with private key
-	jwtString, err := token.SignedString(privKey)
+	return base64.RawURLEncoding.EncodeToString(decoded)
+}
+
+// normalizeAudience returns the origin of endpoint used as aud claim: lowercase scheme and host,
+// without the default port, so equivalent endpoints share cache entries.
+// Only https endpoints are accepted unless allowInsecure is set.
+func normalizeAudience(endpoint string, allowInsecure bool) (string, error) {
+	subURL, err := url.Parse(endpoint)
 	if err != nil {
 		return "", err
 	}
 
-	// Decode the VAPID public key
-	pubKey, err := decodeVapidKey(vapidPublicKey)
+	return normalizeAudienceURL(subURL, endpoint, allowInsecure)
+}
+
+// normalizeAudienceURL is normalizeAudience of the endpoint already parsed into subURL
+func normalizeAudienceURL(subURL *url.URL, endpoint string, allowInsecure bool) (string, error) {
+	scheme := strings.ToLower(subURL.Scheme)
+	if scheme != "https" && !(allowInsecure && scheme == "http") {
+		return "", fmt.Errorf("%w: %q", ErrInsecureEndpoint, endpoint)
+	}
+
+	host := strings.ToLower(subURL.Hostname())
+	if host == "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidEndpoint, endpoint)
+	}
+
+	port := subURL.Port()
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		port = ""
+	}
+
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 literal
+		host = "[" + host + "]"
+	}
+
+	return scheme + "://" + host, nil
+}
+
+// normalizeSubscriber returns the sub claim for subscriber.
+// Unless subscriber is an HTTPS URL, it is assumed to be an e-mail address and prefixed with mailto:.
+// In strict mode subscriber must be a valid mailto: address, e-mail address or https URL.
+func normalizeSubscriber(subscriber string, strict bool) (string, error) {
+	if !strict {
+		if !strings.HasPrefix(subscriber, "https:") {
+			subscriber = "mailto:" + subscriber
+		}
+		return subscriber, nil
+	}
+
+	if strings.HasPrefix(subscriber, "https:") {
+		u, err := url.Parse(subscriber)
+		if err != nil || u.Host == "" {
+			return "", &InvalidSubscriberError{Subscriber: subscriber}
+		}
+		return subscriber, nil
+	}
+
+	address := strings.TrimPrefix(subscriber, "mailto:")
+
+	// Reject display names and anything net/mail had to rewrite
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Name != "" || parsed.Address != address {
+		return "", &InvalidSubscriberError{Subscriber: subscriber}
+	}
+
+	return "mailto:" + address, nil
+}
+
+// resolveVAPIDPublicKey returns the configured public key, or derives it from the signer if not set.
+// A configured public key must be the one of the signer, push services reject the tokens otherwise.
+func resolveVAPIDPublicKey(vapidPublicKey string, signer crypto.Signer) (string, error) {
+	if signer == nil {
+		return vapidPublicKey, nil
+	}
+
+	signerKey, err := marshalSignerPublicKey(signer)
 	if err != nil {
 		return "", err
 	}
 
-	header := "vapid t=" + jwtString + ", k=" + base64.RawURLEncoding.EncodeToString(pubKey)
+	if vapidPublicKey == "" {
+		return base64.RawURLEncoding.EncodeToString(signerKey), nil
+	}
 
-	// Cache the header
-	vapidHeaderCache.Store(cacheKey, vapidCacheEntry{
-		header:     header,
-		expiration: expiration,
-	})
+	publicKey, err := decodeVapidKey(vapidPublicKey)
+	if err != nil || !bytes.Equal(publicKey, signerKey) {
+		return "", ErrVAPIDSignerKeyMismatch
+	}
 
-	return header, nil
+	return vapidPublicKey, nil
 }
 
-// getCachedPrivateKey returns a cached parsed private key or parses and caches a new one
-func getCachedPrivateKey(vapidPrivateKey string) (*ecdsa.PrivateKey, error) {
-	// Check cache
-	if cached, ok := privateKeyCache.Load(vapidPrivateKey); ok {
-		return cached.(*ecdsa.PrivateKey), nil
+// checkApplicationServerKey verifies that a subscription created with a known
+// applicationServerKey is sent with the matching VAPID public key
+func checkApplicationServerKey(s *Subscription, vapidPublicKey string) error {
+	if s.ApplicationServerKey == "" {
+		return nil
 	}
 
-	// Decode and parse the private key
-	decodedVapidPrivateKey, err := decodeVapidKey(vapidPrivateKey)
+	serverKey, err := decodeSubscriptionKey(s.ApplicationServerKey)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	privKey := generateVAPIDHeaderKeys(decodedVapidPrivateKey)
-
-	// Cache the parsed key
-	privateKeyCache.Store(vapidPrivateKey, privKey)
+	publicKey, err := decodeVapidKey(vapidPublicKey)
+	if err != nil || !bytes.Equal(serverKey, publicKey) {
+		return &VAPIDKeyMismatchError{
+			ApplicationServerKey: s.ApplicationServerKey,
+			VAPIDPublicKey:       vapidPublicKey,
+		}
+	}
 
-	return privKey, nil
+	return nil
 }
 
 // Need to decode the vapid private key in multiple base64 formats
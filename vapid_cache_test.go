@@ -1,6 +1,8 @@
 package webpush
 
 import (
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -17,8 +19,7 @@ func TestVAPIDCaching(t *testing.T) {
 	expiration := time.Now().Add(12 * time.Hour)
 
 	// Reset counters
-	vapidCacheHits = 0
-	vapidCacheMisses = 0
+	ResetVAPIDCacheStats()
 
 	// First call - should be a cache MISS
 	header1, err := getVAPIDAuthorizationHeader(endpoint, subscriber, publicKey, privateKey, expiration)
@@ -70,6 +71,10 @@ func TestVAPIDCaching(t *testing.T) {
 		t.Errorf("Expected 2 hits, 2 misses after fourth call. Got %d hits, %d misses", hits4, misses4)
 	}
 
+	if stats := GetVAPIDCacheCounters(); stats.Hits != hits4 || stats.Misses != misses4 || stats.Size < 2 {
+		t.Errorf("Incorrect cache counters, got %+v", stats)
+	}
+
 	t.Logf("✅ VAPID Caching Test Passed! Final stats: %d hits, %d misses", hits4, misses4)
 }
 
@@ -90,3 +95,441 @@ func BenchmarkVAPIDWithCaching(b *testing.B) {
 	hits, misses := GetVAPIDCacheStats()
 	b.Logf("Cache hits: %d, misses: %d", hits, misses)
 }
+
+func TestVAPIDCacheEvictionStats(t *testing.T) {
+	privateKey, publicKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := "https://fcm.googleapis.com/fcm/send/test-subscription-id"
+	expiration := time.Now().Add(30 * time.Second)
+	if _, err := getVAPIDAuthorizationHeader(endpoint, "test@example.com", publicKey, privateKey, expiration); err != nil {
+		t.Fatal(err)
+	}
+
+	before := GetVAPIDCacheCounters()
+
+	// The cached header is about to expire, so it is evicted and signed again
+	if _, err := getVAPIDAuthorizationHeader(endpoint, "test@example.com", publicKey, privateKey, expiration); err != nil {
+		t.Fatal(err)
+	}
+
+	after := GetVAPIDCacheCounters()
+	if after.Misses != before.Misses+1 || after.Evictions != before.Evictions+1 || after.Size != before.Size {
+		t.Errorf("Expected a miss and an eviction, got %+v (before %+v)", after, before)
+	}
+
+	defaultClient.cache.invalidateKeys(VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey})
+
+	if stats := GetVAPIDCacheCounters(); stats.Evictions != after.Evictions+1 || stats.Size != after.Size-1 {
+		t.Errorf("Expected an eviction, got %+v (before %+v)", stats, after)
+	}
+}
+
+func TestVAPIDCacheLRU(t *testing.T) {
+	var stats vapidCacheCounters
+	cache := newHeaderCache(2, &stats)
+
+	cache.store("a", vapidCacheEntry{header: "a"})
+	cache.store("b", vapidCacheEntry{header: "b"})
+
+	// Using a makes b the least recently used entry
+	if _, ok := cache.load("a"); !ok {
+		t.Fatal("Missing entry a")
+	}
+
+	cache.store("c", vapidCacheEntry{header: "c"})
+
+	if _, ok := cache.load("b"); ok {
+		t.Fatal("Least recently used entry was not evicted")
+	}
+
+	if keys := cache.keys(); len(keys) != 2 || keys[0] != "c" || keys[1] != "a" {
+		t.Fatalf("Incorrect cache order, got %v", keys)
+	}
+
+	cache.setMaxEntries(1)
+	if keys := cache.keys(); len(keys) != 1 || keys[0] != "c" {
+		t.Fatalf("Incorrect entries after shrinking, got %v", keys)
+	}
+
+	if snapshot := stats.snapshot(); snapshot.CapacityEvictions != 2 || snapshot.Size != 1 {
+		t.Fatalf("Incorrect counters, expected 2 capacity evictions and 1 entry, got %+v", snapshot)
+	}
+}
+
+func TestVAPIDCacheSweep(t *testing.T) {
+	cache := newHeaderCache(0, &vapidCacheCounters{})
+	now := time.Now()
+
+	cache.store("expired", vapidCacheEntry{header: "expired", expiration: now.Add(time.Minute)})
+	cache.store("valid", vapidCacheEntry{header: "valid", expiration: now.Add(time.Hour)})
+
+	cache.sweep(now)
+
+	if keys := cache.keys(); len(keys) != 1 || keys[0] != "valid" {
+		t.Fatalf("Incorrect entries after sweeping, got %v", keys)
+	}
+}
+
+func TestVAPIDCacheJanitorStop(t *testing.T) {
+	janitor := StartVAPIDCacheJanitor(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	janitor.Stop()
+	janitor.Stop()
+}
+
+func TestClientCachesAreIsolated(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	first, err := NewClient(WithVAPIDKeys(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewClient(WithVAPIDKeys(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.WarmVAPIDCache([]string{"https://fcm.googleapis.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := second.VAPIDCacheStats(); stats.Size != 0 || stats.Misses != 0 {
+		t.Fatalf("Second client shares the cache of the first, got %+v", stats)
+	}
+
+	if stats := first.VAPIDCacheStats(); stats.Size != 1 || stats.Misses != 1 {
+		t.Fatalf("Incorrect stats of the first client, got %+v", stats)
+	}
+}
+
+func TestVAPIDCacheKeyHasNoPrivateKey(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	client, err := NewClient(WithVAPIDKeys(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.WarmVAPIDCache([]string{"https://fcm.googleapis.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cacheKey := range client.cache.headers.keys() {
+		if strings.Contains(cacheKey, keys.PrivateKey) {
+			t.Fatalf("Cache key contains the private key: %s", cacheKey)
+		}
+	}
+}
+
+func TestVAPIDCacheInvalidation(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	other := getTestVAPIDKeys(t)
+	client, err := NewClient(WithVAPIDKeys(keys, other))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audiences := []string{"https://fcm.googleapis.com", "https://updates.push.services.mozilla.com"}
+	if err := client.WarmVAPIDCache(audiences); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := client.VAPIDCacheStats().Size; size != 4 {
+		t.Fatalf("Incorrect cache size, expected=4, got=%d", size)
+	}
+
+	if err := client.InvalidateAudience("https://FCM.googleapis.com:443"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cacheKey := range client.cache.headers.keys() {
+		if headerCacheKeyAudience(cacheKey) == "https://fcm.googleapis.com" {
+			t.Fatal("Header of the invalidated audience is still cached")
+		}
+	}
+
+	client.InvalidateVAPIDCacheForKey(other.PublicKey)
+	if size := client.VAPIDCacheStats().Size; size != 1 {
+		t.Fatalf("Incorrect cache size after invalidating a key, expected=1, got=%d", size)
+	}
+
+	client.ClearVAPIDCache()
+	if size := client.VAPIDCacheStats().Size; size != 0 {
+		t.Fatalf("Incorrect cache size after clearing, expected=0, got=%d", size)
+	}
+
+	if _, ok := client.cache.privateKeys.load(keys.PrivateKey); ok {
+		t.Fatal("Private key is still cached")
+	}
+}
+
+func TestVAPIDCacheRefreshesNearExpiry(t *testing.T) {
+	now := time.Now()
+	encoder := &countingJWTEncoder{}
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithJWTEncoder(encoder),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := client.vapidHeaderFor("https://fcm.googleapis.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inside the margin the old header is still served while a new one is signed
+	now = now.Add(DefaultVAPIDLifetime - DefaultVAPIDCacheMargin/2)
+	stale, err := client.vapidHeaderFor("https://fcm.googleapis.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stale != header {
+		t.Fatal("Expected the cached header while refreshing")
+	}
+
+	// Wait for the background refresh
+	refreshed := stale
+	deadline := time.Now().Add(time.Second)
+	for refreshed == header {
+		if time.Now().After(deadline) {
+			t.Fatalf("Header was not refreshed, got %+v", client.VAPIDCacheStats())
+		}
+		time.Sleep(time.Millisecond)
+
+		refreshed, err = client.vapidHeaderFor("https://fcm.googleapis.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if refreshes := client.VAPIDCacheStats().Refreshes; refreshes != 1 {
+		t.Fatalf("Incorrect number of refreshes, expected=1, got=%d", refreshes)
+	}
+
+	if encoder.encoded != 2 {
+		t.Fatalf("Incorrect number of signings, expected=2, got=%d", encoder.encoded)
+	}
+}
+
+func TestVAPIDCacheMargin(t *testing.T) {
+	now := time.Now()
+	clock := WithClock(func() time.Time { return now })
+
+	client, err := NewClient(clock, WithVAPIDLifetime(time.Hour), WithVAPIDCacheMarginRatio(0.1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if margin := client.vapidHeaderParams("https://fcm.googleapis.com", &Options{}).cacheMargin; margin != 6*time.Minute {
+		t.Fatalf("Incorrect margin, expected=6m, got=%v", margin)
+	}
+
+	client, err = NewClient(clock, WithVAPIDCacheMargin(5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if margin := client.vapidHeaderParams("https://fcm.googleapis.com", &Options{}).cacheMargin; margin != 5*time.Minute {
+		t.Fatalf("Incorrect margin, expected=5m, got=%v", margin)
+	}
+
+	for _, option := range []ClientOption{WithVAPIDCacheMargin(-time.Minute), WithVAPIDCacheMarginRatio(0), WithVAPIDCacheMarginRatio(1)} {
+		if _, err := NewClient(option); err != ErrInvalidVAPIDCacheMargin {
+			t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidVAPIDCacheMargin, err)
+		}
+	}
+}
+
+func TestPrivateKeyCacheStats(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	client, err := NewClient(WithVAPIDKeys(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.WarmVAPIDCache([]string{"https://fcm.googleapis.com", "https://updates.push.services.mozilla.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := client.VAPIDCacheStats()
+	if stats.PrivateKeyMisses != 1 || stats.PrivateKeyHits != 1 || stats.PrivateKeys != 1 {
+		t.Fatalf("Incorrect private key stats, got %+v", stats)
+	}
+
+	client.ClearVAPIDCache()
+	if stats := client.VAPIDCacheStats(); stats.PrivateKeys != 0 {
+		t.Fatalf("Incorrect private key count after clearing, got %d", stats.PrivateKeys)
+	}
+}
+
+// mapHeaderCache is a VAPIDHeaderCache standing in for a shared backend
+type mapHeaderCache struct {
+	mu      sync.Mutex
+	entries map[string]vapidCacheEntry
+}
+
+func (c *mapHeaderCache) Get(key string) (string, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	return entry.header, time.Until(entry.expiration), ok
+}
+
+func (c *mapHeaderCache) Set(key, header string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = vapidCacheEntry{header: header, expiration: time.Now().Add(ttl)}
+}
+
+func (c *mapHeaderCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+func TestSharedVAPIDHeaderCache(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	shared := &mapHeaderCache{entries: make(map[string]vapidCacheEntry)}
+
+	first, err := NewClient(WithVAPIDKeys(keys), WithVAPIDHeaderCache(shared))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoder := &countingJWTEncoder{}
+	second, err := NewClient(WithVAPIDKeys(keys), WithVAPIDHeaderCache(shared), WithJWTEncoder(encoder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := first.vapidHeaderFor("https://fcm.googleapis.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for cacheKey, entry := range shared.entries {
+		if strings.Contains(cacheKey, keys.PrivateKey) {
+			t.Fatal("Shared cache key contains the private key")
+		}
+
+		if ttl := time.Until(entry.expiration); ttl < DefaultVAPIDLifetime-time.Minute || ttl > DefaultVAPIDLifetime {
+			t.Fatalf("Incorrect TTL, got %v", ttl)
+		}
+	}
+
+	// The second sender reuses the token signed by the first one
+	shared2, err := second.vapidHeaderFor("https://fcm.googleapis.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shared2 != header || encoder.encoded != 0 {
+		t.Fatalf("Expected the shared header, got %d signings", encoder.encoded)
+	}
+
+	if size := first.VAPIDCacheStats().Size; size != 0 {
+		t.Fatalf("In-memory cache was used, got %d entries", size)
+	}
+}
+
+func TestVAPIDCacheEntries(t *testing.T) {
+	now := time.Now()
+	keys := getTestVAPIDKeys(t)
+	client, err := NewClient(WithVAPIDKeys(keys), WithSubscriber("ops@example.com"), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.WarmVAPIDCache([]string{"https://fcm.googleapis.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute)
+
+	entries := client.VAPIDCacheEntries()
+	if len(entries) != 1 {
+		t.Fatalf("Incorrect number of entries, expected=1, got=%d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Audience != "https://fcm.googleapis.com" || entry.Subscriber != "mailto:ops@example.com" {
+		t.Fatalf("Incorrect entry, got %+v", entry)
+	}
+
+	if entry.KeyFingerprint != keys.Fingerprint() || entry.Age != time.Minute {
+		t.Fatalf("Incorrect fingerprint or age, got %+v", entry)
+	}
+
+	if !entry.Expiration.Equal(now.Add(DefaultVAPIDLifetime - time.Minute)) {
+		t.Fatalf("Incorrect expiration, got %v", entry.Expiration)
+	}
+}
+
+func TestAudienceCacheTTL(t *testing.T) {
+	now := time.Now()
+	encoder := &countingJWTEncoder{}
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithJWTEncoder(encoder),
+		WithClock(func() time.Time { return now }),
+		WithAudienceCacheTTL("https://*.gateway.example", 5*time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, endpoint := range []string{"https://push.gateway.example/abc", "https://fcm.googleapis.com/fcm/send/abc"} {
+		if _, err := client.vapidHeaderFor(endpoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = now.Add(6 * time.Minute)
+	for _, endpoint := range []string{"https://push.gateway.example/abc", "https://fcm.googleapis.com/fcm/send/abc"} {
+		if _, err := client.vapidHeaderFor(endpoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the gateway header was re-signed
+	if encoder.encoded != 3 {
+		t.Fatalf("Incorrect number of signings, expected=3, got=%d", encoder.encoded)
+	}
+
+	if _, err := NewClient(WithAudienceCacheTTL("[", time.Minute)); err != ErrInvalidAudienceCacheTTL {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrInvalidAudienceCacheTTL, err)
+	}
+}
+
+func TestAudienceCacheTTLCapsExternalTTL(t *testing.T) {
+	shared := &mapHeaderCache{entries: make(map[string]vapidCacheEntry)}
+	client, err := NewClient(
+		WithVAPIDKeys(getTestVAPIDKeys(t)),
+		WithVAPIDHeaderCache(shared),
+		WithAudienceCacheTTL("https://push.gateway.example", 5*time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.vapidHeaderFor("https://push.gateway.example/abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range shared.entries {
+		if ttl := time.Until(entry.expiration); ttl > 5*time.Minute+DefaultVAPIDCacheMargin {
+			t.Fatalf("Incorrect external TTL, got %v", ttl)
+		}
+	}
+}
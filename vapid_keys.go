@@ -0,0 +1,210 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrVAPIDKeyPairMismatch is returned when a VAPID public key does not belong to the private key
+	ErrVAPIDKeyPairMismatch = errors.New("webpush: VAPID public key does not match the private key")
+
+	// ErrInvalidGeneratedKey is returned when key generation produced an unusable key,
+	// which points at a broken entropy source
+	ErrInvalidGeneratedKey = errors.New("webpush: generated VAPID key is not a valid P-256 key")
+)
+
+// VAPIDKeys is a VAPID key pair in the base64 URL encoded form produced by GenerateVAPIDKeys
+type VAPIDKeys struct {
+	PrivateKey string `json:"privateKey"`
+	PublicKey  string `json:"publicKey"`
+}
+
+// VAPIDKeyBundle is a generated VAPID key pair together with its exported forms
+type VAPIDKeyBundle struct {
+	Keys          VAPIDKeys
+	PrivateKeyPEM []byte // PKCS #8 "PRIVATE KEY" block
+	PublicKeyPEM  []byte // PKIX "PUBLIC KEY" block
+	JWK           []byte // private EC JSON Web Key
+}
+
+// GenerateVAPIDKeyBundle creates a VAPID key pair and exports it to PEM and JWK in one call.
+// Keys are generated from random, or crypto/rand when random is nil.
+func GenerateVAPIDKeyBundle(random io.Reader) (*VAPIDKeyBundle, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+
+	keys, err := generateVAPIDKeyPair(random)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyPEM, publicKeyPEM, err := keys.ExportPEM()
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, err := keys.ExportJWK()
+	if err != nil {
+		return nil, err
+	}
+
+	return &VAPIDKeyBundle{
+		Keys:          keys,
+		PrivateKeyPEM: privateKeyPEM,
+		PublicKeyPEM:  publicKeyPEM,
+		JWK:           jwk,
+	}, nil
+}
+
+// jwk is the JSON Web Key (RFC 7517) representation of a P-256 key
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+}
+
+// ExportPEM returns the private key as a PKCS #8 "PRIVATE KEY" block and the
+// public key as a PKIX "PUBLIC KEY" block
+func (k VAPIDKeys) ExportPEM() (privateKeyPEM, publicKeyPEM []byte, err error) {
+	privKey, err := k.ecdsaPrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(privKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&privKey.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})
+	publicKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	return privateKeyPEM, publicKeyPEM, nil
+}
+
+// ExportJWK returns the key pair as a private EC JSON Web Key (RFC 7518 section 6.2)
+func (k VAPIDKeys) ExportJWK() ([]byte, error) {
+	privKey, err := k.ecdsaPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(newJWK(privKey, true))
+}
+
+// ExportPublicJWK returns only the public half of the key pair as an EC JSON Web Key
+func (k VAPIDKeys) ExportPublicJWK() ([]byte, error) {
+	privKey, err := k.ecdsaPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(newJWK(privKey, false))
+}
+
+// Validate checks that the public key belongs to the private key
+func (k VAPIDKeys) Validate() error {
+	return ValidateKeyPair(k.PublicKey, k.PrivateKey)
+}
+
+// VAPIDPublicKeyFromPrivate derives the base64 URL encoded public key of a VAPID private key
+func VAPIDPublicKeyFromPrivate(privateKey string) (string, error) {
+	privKey, err := VAPIDKeys{PrivateKey: privateKey}.ecdsaPrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	public := elliptic.Marshal(privKey.Curve, privKey.X, privKey.Y)
+
+	return base64.RawURLEncoding.EncodeToString(public), nil
+}
+
+// ValidateKeyPair checks that publicKey belongs to privateKey, catching transposed or mismatched keys
+func ValidateKeyPair(publicKey, privateKey string) error {
+	derived, err := VAPIDPublicKeyFromPrivate(privateKey)
+	if err != nil {
+		return err
+	}
+
+	// Accept any of the base64 forms decodeVapidKey understands
+	decodedPublicKey, err := decodeVapidKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if err := validateVAPIDPublicKey(decodedPublicKey); err != nil {
+		return err
+	}
+
+	if base64.RawURLEncoding.EncodeToString(decodedPublicKey) != derived {
+		return ErrVAPIDKeyPairMismatch
+	}
+
+	return nil
+}
+
+// VAPIDKeyFingerprint returns a short stable identifier of a VAPID public key: the first
+// 8 bytes of its SHA-256 in hex. It is safe to use as a metrics label or log field.
+func VAPIDKeyFingerprint(publicKey string) (string, error) {
+	decoded, err := decodeVapidKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(decoded)
+
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// Fingerprint returns the VAPIDKeyFingerprint of the public key, or an empty string when it can't be decoded
+func (k VAPIDKeys) Fingerprint() string {
+	fingerprint, _ := VAPIDKeyFingerprint(k.PublicKey)
+	return fingerprint
+}
+
+// ecdsaPrivateKey decodes the private key of the pair
+func (k VAPIDKeys) ecdsaPrivateKey() (*ecdsa.PrivateKey, error) {
+	decodedVapidPrivateKey, err := decodeVapidKey(k.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseVAPIDPrivateKey(decodedVapidPrivateKey)
+}
+
+// newJWK builds the JWK form of a key, coordinates are padded to the curve size
+func newJWK(privKey *ecdsa.PrivateKey, includePrivate bool) jwk {
+	size := (privKey.Curve.Params().BitSize + 7) / 8
+	b64 := base64.RawURLEncoding
+
+	key := jwk{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   b64.EncodeToString(privKey.X.FillBytes(make([]byte, size))),
+		Y:   b64.EncodeToString(privKey.Y.FillBytes(make([]byte, size))),
+	}
+
+	if includePrivate {
+		key.D = b64.EncodeToString(privKey.D.FillBytes(make([]byte, size)))
+	}
+
+	return key
+}
@@ -0,0 +1,192 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func getTestVAPIDKeys(t testing.TB) VAPIDKeys {
+	privateKey, publicKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}
+}
+
+func TestVAPIDKeysExportPEM(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	privPEM, pubPEM, err := keys.ExportPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privBlock, _ := pem.Decode(privPEM)
+	if privBlock == nil || privBlock.Type != "PRIVATE KEY" {
+		t.Fatal("Expected a PRIVATE KEY PEM block")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(privBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privKey, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		t.Fatalf("Expected *ecdsa.PrivateKey, got %T", parsed)
+	}
+
+	decodedPrivateKey, _ := decodeVapidKey(keys.PrivateKey)
+	if privKey.D.Cmp(generateVAPIDHeaderKeys(decodedPrivateKey).D) != 0 {
+		t.Fatal("Exported private key does not match")
+	}
+
+	pubBlock, _ := pem.Decode(pubPEM)
+	if pubBlock == nil || pubBlock.Type != "PUBLIC KEY" {
+		t.Fatal("Expected a PUBLIC KEY PEM block")
+	}
+
+	parsedPub, err := x509.ParsePKIXPublicKey(pubBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !privKey.PublicKey.Equal(parsedPub) {
+		t.Fatal("Exported public key does not match the private key")
+	}
+}
+
+func TestVAPIDKeysExportJWK(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	data, err := keys.ExportJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key jwk
+	if err := json.Unmarshal(data, &key); err != nil {
+		t.Fatal(err)
+	}
+
+	if key.Kty != "EC" || key.Crv != "P-256" {
+		t.Fatalf("Incorrect key type, got kty=%s crv=%s", key.Kty, key.Crv)
+	}
+
+	if key.D != keys.PrivateKey {
+		t.Fatalf("Incorrect d, expected=%s, got=%s", keys.PrivateKey, key.D)
+	}
+
+	// The uncompressed public key is 0x04 || x || y
+	x, _ := base64.RawURLEncoding.DecodeString(key.X)
+	y, _ := base64.RawURLEncoding.DecodeString(key.Y)
+	public := append(append([]byte{4}, x...), y...)
+	if base64.RawURLEncoding.EncodeToString(public) != keys.PublicKey {
+		t.Fatal("JWK coordinates do not match the public key")
+	}
+
+	publicData, err := keys.ExportPublicJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var publicKey jwk
+	if err := json.Unmarshal(publicData, &publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if publicKey.D != "" {
+		t.Fatal("Public JWK must not contain the private key")
+	}
+}
+
+func TestValidateKeyPair(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	derived, err := VAPIDPublicKeyFromPrivate(keys.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if derived != keys.PublicKey {
+		t.Fatalf("Incorrect derived public key, expected=%s, got=%s", keys.PublicKey, derived)
+	}
+
+	if err := ValidateKeyPair(keys.PublicKey, keys.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+
+	other := getTestVAPIDKeys(t)
+	if err := ValidateKeyPair(other.PublicKey, keys.PrivateKey); err != ErrVAPIDKeyPairMismatch {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrVAPIDKeyPairMismatch, err)
+	}
+
+	// Transposed keys must not validate
+	if err := ValidateKeyPair(keys.PrivateKey, keys.PublicKey); err == nil {
+		t.Fatal("Transposed keys validated")
+	}
+
+	if _, err := NewClient(WithVAPIDKeys(VAPIDKeys{PrivateKey: keys.PrivateKey, PublicKey: other.PublicKey})); err != ErrVAPIDKeyPairMismatch {
+		t.Fatalf("Incorrect error from NewClient, expected=%v, got=%v", ErrVAPIDKeyPairMismatch, err)
+	}
+}
+
+func TestGenerateVAPIDKeyBundle(t *testing.T) {
+	bundle, err := GenerateVAPIDKeyBundle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bundle.Keys.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if block, _ := pem.Decode(bundle.PrivateKeyPEM); block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatal("Bundle is missing the private key PEM")
+	}
+
+	if block, _ := pem.Decode(bundle.PublicKeyPEM); block == nil || block.Type != "PUBLIC KEY" {
+		t.Fatal("Bundle is missing the public key PEM")
+	}
+
+	var key jwk
+	if err := json.Unmarshal(bundle.JWK, &key); err != nil || key.D != bundle.Keys.PrivateKey {
+		t.Fatal("Bundle JWK does not match the key pair")
+	}
+}
+
+func TestGenerateVAPIDKeyBundleFailsOnBrokenEntropy(t *testing.T) {
+	if _, err := GenerateVAPIDKeyBundle(strings.NewReader("short")); err == nil {
+		t.Fatal("Expected an error from an exhausted entropy source")
+	}
+}
+
+func TestVAPIDKeyFingerprint(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+
+	fingerprint, err := VAPIDKeyFingerprint(keys.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fingerprint) != 16 {
+		t.Fatalf("Incorrect fingerprint length, expected=16, got=%d", len(fingerprint))
+	}
+
+	// The padded base64 form identifies the same key
+	decoded, _ := decodeVapidKey(keys.PublicKey)
+	padded, _ := VAPIDKeyFingerprint(base64.URLEncoding.EncodeToString(decoded))
+	if padded != fingerprint || keys.Fingerprint() != fingerprint {
+		t.Fatal("Fingerprint is not stable across encodings")
+	}
+
+	if getTestVAPIDKeys(t).Fingerprint() == fingerprint {
+		t.Fatal("Different keys share a fingerprint")
+	}
+}
@@ -2,6 +2,7 @@ package webpush
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -112,3 +113,116 @@ func getTokenFromAuthorizationHeader(tokenHeader string, t *testing.T) string {
 
 	return tsplit[1][:len(tsplit[1])-1]
 }
+
+func TestVAPIDExtraClaims(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	params := &vapidHeaderParams{
+		endpoint:        getStandardEncodedTestSubscription().Endpoint,
+		subscriber:      "test@test.com",
+		vapidPublicKey:  keys.PublicKey,
+		vapidPrivateKey: keys.PrivateKey,
+		expiration:      time.Now().Add(time.Hour),
+		now:             time.Now(),
+	}
+
+	plainHeader, err := getVAPIDHeader(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params.claims = func(audience string) map[string]interface{} {
+		return map[string]interface{}{"tenant": "acme"}
+	}
+	header, err := getVAPIDHeader(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cached header without the claim must not be reused
+	if header == plainHeader {
+		t.Fatal("Header with additional claims was served from the cache of the plain header")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(getTokenFromAuthorizationHeader(header, t), claims); err != nil {
+		t.Fatal(err)
+	}
+
+	if claims["tenant"] != "acme" {
+		t.Fatalf("Incorrect tenant claim, expected=acme, got=%v", claims["tenant"])
+	}
+
+	params.claims = func(audience string) map[string]interface{} {
+		return map[string]interface{}{"aud": "https://example.com"}
+	}
+	if _, err := getVAPIDHeader(params); !errors.Is(err, ErrReservedClaim) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrReservedClaim, err)
+	}
+}
+
+func TestNormalizeSubscriber(t *testing.T) {
+	tests := []struct {
+		subscriber string
+		strict     bool
+		expected   string
+		valid      bool
+	}{
+		{"test@test.com", false, "mailto:test@test.com", true},
+		{"example.com", false, "mailto:example.com", true},
+		{"https://example.com/contact", false, "https://example.com/contact", true},
+		{"test@test.com", true, "mailto:test@test.com", true},
+		{"mailto:test@test.com", true, "mailto:test@test.com", true},
+		{"https://example.com/contact", true, "https://example.com/contact", true},
+		{"example.com", true, "", false},
+		{"Test <test@test.com>", true, "", false},
+		{"https://", true, "", false},
+		{"http://example.com", true, "", false},
+	}
+
+	for _, test := range tests {
+		subscriber, err := normalizeSubscriber(test.subscriber, test.strict)
+		if !test.valid {
+			if !errors.Is(err, ErrInvalidSubscriber) {
+				t.Fatalf("%q: expected=%v, got=%v", test.subscriber, ErrInvalidSubscriber, err)
+			}
+			continue
+		}
+
+		if err != nil || subscriber != test.expected {
+			t.Fatalf("%q: incorrect subscriber, expected=%s, got=%s (%v)", test.subscriber, test.expected, subscriber, err)
+		}
+	}
+}
+
+func TestNormalizeAudience(t *testing.T) {
+	tests := []struct {
+		endpoint      string
+		allowInsecure bool
+		expected      string
+		err           error
+	}{
+		{"https://fcm.googleapis.com/fcm/send/abc", false, "https://fcm.googleapis.com", nil},
+		{"HTTPS://FCM.GoogleAPIs.com:443/fcm/send/abc", false, "https://fcm.googleapis.com", nil},
+		{"https://push.example.com:8443/abc", false, "https://push.example.com:8443", nil},
+		{"https://[::1]:443/abc", false, "https://[::1]", nil},
+		{"http://localhost:80/abc", true, "http://localhost", nil},
+		{"http://localhost:8080/abc", true, "http://localhost:8080", nil},
+		{"http://localhost:8080/abc", false, "", ErrInsecureEndpoint},
+		{"ftp://push.example.com/abc", true, "", ErrInsecureEndpoint},
+		{"https:///abc", false, "", ErrInvalidEndpoint},
+	}
+
+	for _, test := range tests {
+		audience, err := normalizeAudience(test.endpoint, test.allowInsecure)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Fatalf("%s: incorrect error, expected=%v, got=%v", test.endpoint, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil || audience != test.expected {
+			t.Fatalf("%s: incorrect audience, expected=%s, got=%s (%v)", test.endpoint, test.expected, audience, err)
+		}
+	}
+}
@@ -3,21 +3,20 @@ package webpush
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
-
-	"golang.org/x/crypto/hkdf"
 )
 
 const MaxRecordSize uint32 = 4096
@@ -42,15 +41,21 @@ type HTTPClient interface {
 
 // Options are config and extra params needed to send a notification
 type Options struct {
-	HTTPClient      HTTPClient // Will replace with *http.Client by default if not included
-	RecordSize      uint32     // Limit the record size
-	Subscriber      string     // Sub in VAPID JWT token
-	Topic           string     // Set the Topic header to collapse a pending messages (Optional)
-	TTL             int        // Set the TTL on the endpoint POST request
-	Urgency         Urgency    // Set the Urgency header to change a message priority (Optional)
-	VAPIDPublicKey  string     // VAPID public key, passed in VAPID Authorization header
-	VAPIDPrivateKey string     // VAPID private key, used to sign VAPID JWT token
-	VapidExpiration time.Time  // optional expiration for VAPID JWT token (defaults to now + 12 hours)
+	HTTPClient      HTTPClient      // Will replace with *http.Client by default if not included
+	RecordSize      uint32          // Limit the record size
+	Subscriber      string          // Sub in VAPID JWT token
+	Topic           string          // Set the Topic header to collapse a pending messages (Optional)
+	TTL             int             // Set the TTL on the endpoint POST request
+	Urgency         Urgency         // Set the Urgency header to change a message priority (Optional)
+	ContentEncoding ContentEncoding // Payload encryption, aes128gcm by default (Optional)
+	VAPIDPublicKey  string          // VAPID public key, passed in VAPID Authorization header
+	VAPIDPrivateKey string          // VAPID private key, used to sign VAPID JWT token
+	VAPIDSigner     crypto.Signer   // Signs the VAPID JWT token instead of VAPIDPrivateKey, e.g. an HSM or KMS key (Optional)
+	TenantID        string          // Resolve the VAPID keys and subscriber through the Client VAPIDProvider (Optional)
+	Audience        string          // Override the aud in VAPID JWT token, derived from the endpoint by default (Optional)
+	VapidExpiration time.Time       // optional expiration for VAPID JWT token (defaults to now + the Client VAPID lifetime, capped at 24 hours)
+	CorrelationID   string          // Identifies the notification in SendResult, events, logs and the Client correlation header, random by default (Optional)
+	DedupeKey       string          // Identifies the notification for the Client duplicate suppression instead of its payload (Optional)
 }
 
 // Keys are the base64 encoded values from PushSubscription.getKey()
@@ -63,6 +68,12 @@ type Keys struct {
 type Subscription struct {
 	Endpoint string `json:"endpoint"`
 	Keys     Keys   `json:"keys"`
+
+	// ExpirationTime is when the subscription expires in milliseconds since the epoch, nil if it doesn't (Optional)
+	ExpirationTime *int64 `json:"expirationTime,omitempty"`
+
+	// ApplicationServerKey is the VAPID public key the subscription was created with (Optional)
+	ApplicationServerKey string `json:"applicationServerKey,omitempty"`
 }
 
 // SendNotification calls SendNotificationWithContext with default context for backwards-compatibility
@@ -74,6 +85,21 @@ func SendNotification(message []byte, s *Subscription, options *Options) (*http.
 // Message Encryption for Web Push, and VAPID protocols.
 // FOR MORE INFORMATION SEE RFC8291: https://datatracker.ietf.org/doc/rfc8291
 func SendNotificationWithContext(ctx context.Context, message []byte, s *Subscription, options *Options) (*http.Response, error) {
+	return defaultClient.Send(ctx, message, s, options)
+}
+
+// sendNotification encrypts the message and sends it with the resolved options
+func (c *Client) sendNotification(ctx context.Context, message []byte, s *Subscription, options *Options) (*SendResult, error) {
+	// Fail fast if the subscription was created with another VAPID key
+	vapidPublicKey, err := resolveVAPIDPublicKey(options.VAPIDPublicKey, options.VAPIDSigner)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkApplicationServerKey(s, vapidPublicKey); err != nil {
+		return nil, err
+	}
+
 	// Authentication secret (auth_secret)
 	authSecret, err := decodeSubscriptionKey(s.Keys.Auth)
 	if err != nil {
@@ -92,197 +118,325 @@ func SendNotificationWithContext(ctx context.Context, message []byte, s *Subscri
 		return nil, err
 	}
 
-	// Create the ecdh_secret shared key pair
-	curve := elliptic.P256()
+	// Get the record size
+	recordSize := options.RecordSize
+	if recordSize == 0 {
+		recordSize = MaxRecordSize
+	}
 
-	// Application server key pairs (single use)
-	localPrivateKey, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+	_, encryptSpan := c.startSpan(ctx, SpanEncrypt)
+	encryptSpan.SetAttribute(AttributePayloadSize, len(message))
+	encryptionStart := time.Now()
+	recordBuf, localPublicKey, err := encryptMessage(message, authSecret, dh, salt, recordSize, options.ContentEncoding)
+	encryptSpan.End(err)
 	if err != nil {
 		return nil, err
 	}
+	recordEncryption(time.Since(encryptionStart))
 
-	localPublicKey := elliptic.Marshal(curve, x, y)
+	correlation, err := correlationID(ctx, options)
+	if err != nil {
+		return nil, err
+	}
 
-	// Combine application keys with receiver's EC public key
-	sharedX, sharedY := elliptic.Unmarshal(curve, dh)
-	if sharedX == nil {
-		return nil, errors.New("Unmarshal Error: Public key is not a valid point on the curve")
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if CorrelationIDFromContext(ctx) != correlation {
+		ctx = ContextWithCorrelationID(ctx, correlation)
 	}
 
-	// Derive ECDH shared secret
-	sx, sy := curve.ScalarMult(sharedX, sharedY, localPrivateKey)
-	if !curve.IsOnCurve(sx, sy) {
-		return nil, errors.New("Encryption error: ECDH shared secret isn't on curve")
+	// POST request
+	req, err := http.NewRequestWithContext(ctx, "POST", s.Endpoint, recordBuf)
+	if err != nil {
+		return nil, err
 	}
-	mlen := curve.Params().BitSize / 8
-	sharedECDHSecret := make([]byte, mlen)
-	sx.FillBytes(sharedECDHSecret)
+	origin := requestOrigin(req)
 
-	hash := sha256.New
+	// The values of the headers share one backing array
+	headers := requestHeaders{header: req.Header, values: make([]string, 0, 8)}
+	headers.set("Content-Type", "application/octet-stream")
+	headers.set("Ttl", strconv.Itoa(options.TTL))
 
-	// ikm
-	prkInfoBuf := bytes.NewBuffer([]byte("WebPush: info\x00"))
-	prkInfoBuf.Write(dh)
-	prkInfoBuf.Write(localPublicKey)
+	// Сheck the optional headers
+	if len(options.Topic) > 0 {
+		headers.set("Topic", options.Topic)
+	}
 
-	prkHKDF := hkdf.New(hash, sharedECDHSecret, authSecret, prkInfoBuf.Bytes())
-	ikm, err := getHKDFKey(prkHKDF, 32)
-	if err != nil {
-		return nil, err
+	if isValidUrgency(options.Urgency) {
+		headers.set("Urgency", string(options.Urgency))
 	}
 
-	// Derive Content Encryption Key
-	contentEncryptionKeyInfo := []byte("Content-Encoding: aes128gcm\x00")
-	contentHKDF := hkdf.New(hash, ikm, salt, contentEncryptionKeyInfo)
-	contentEncryptionKey, err := getHKDFKey(contentHKDF, 16)
+	// Get VAPID Authorization header, span attributes are boxed so they are only set for a Tracer
+	_, signSpan := c.startSpan(ctx, SpanSignVAPID)
+	if c.tracer != nil {
+		signSpan.SetAttribute(AttributeOrigin, origin)
+	}
+	vapidParams := c.vapidHeaderParams(s.Endpoint, options)
+	vapidParams.endpointURL = req.URL
+	vapidAuthHeader, err := getVAPIDHeader(vapidParams)
+	signSpan.End(err)
 	if err != nil {
 		return nil, err
 	}
 
-	// Derive the Nonce
-	nonceInfo := []byte("Content-Encoding: nonce\x00")
-	nonceHKDF := hkdf.New(hash, ikm, salt, nonceInfo)
-	nonce, err := getHKDFKey(nonceHKDF, 12)
-	if err != nil {
-		return nil, err
+	if options.ContentEncoding == ContentEncodingAESGCM {
+		// Legacy draft encoding, with the encryption parameters and the VAPID key in headers
+		authorization, p256ecdsa := legacyVAPIDHeaders(vapidAuthHeader)
+
+		headers.set("Content-Encoding", string(ContentEncodingAESGCM))
+		headers.set("Encryption", aesgcmEncryptionHeader(salt, recordSize))
+		headers.set("Crypto-Key", aesgcmCryptoKeyHeader(localPublicKey, p256ecdsa))
+		headers.set("Authorization", authorization)
+	} else {
+		headers.set("Content-Encoding", string(ContentEncodingAES128GCM))
+		headers.set("Authorization", vapidAuthHeader)
 	}
 
-	// Cipher
-	c, err := aes.NewCipher(contentEncryptionKey)
-	if err != nil {
-		return nil, err
+	if c.correlationHeader != "" {
+		req.Header.Set(c.correlationHeader, correlation)
 	}
 
-	gcm, err := cipher.NewGCM(c)
-	if err != nil {
-		return nil, err
+	if c.traceContext != nil {
+		setTraceContextHeaders(req, c.traceContext)
 	}
 
-	// Get the record size
-	recordSize := options.RecordSize
-	if recordSize == 0 {
-		recordSize = MaxRecordSize
+	// Send the request
+	var client HTTPClient
+	if options.HTTPClient != nil {
+		client = options.HTTPClient
+	} else {
+		client = &http.Client{CheckRedirect: noRedirects}
 	}
 
-	recordLength := int(recordSize) - 16
+	if c.timeouts != nil {
+		client = c.timeouts.wrap(client)
+	}
 
-	// Encryption Content-Coding Header
-	recordBuf := bytes.NewBuffer(salt)
+	if c.retrier != nil {
+		client = c.retrier.wrap(client, c.retryBudget, c.retryScheduled)
+	}
 
-	rs := make([]byte, 4)
-	binary.BigEndian.PutUint32(rs, recordSize)
+	if c.redirects != nil {
+		client = c.redirects.wrap(client, c.allowInsecure)
+	}
 
-	recordBuf.Write(rs)
-	recordBuf.Write([]byte{byte(len(localPublicKey))})
-	recordBuf.Write(localPublicKey)
+	if c.recycler != nil {
+		client = c.recycler.wrap(client)
+	}
 
-	// Avoid data races by copying the message data
-	messageCopy := make([]byte, len(message))
-	copy(messageCopy, message)
-	dataBuf := bytes.NewBuffer(messageCopy)
-	
-	// Pad content to max record size - 16 - header
-	// Padding ending delimeter
-	dataBuf.Write([]byte("\x02"))
-	if err := pad(dataBuf, recordLength-recordBuf.Len()); err != nil {
-		return nil, err
+	if c.limiter != nil {
+		client = c.limiter.wrap(client)
 	}
 
-	// Compose the ciphertext
-	ciphertext := gcm.Seal([]byte{}, nonce, dataBuf.Bytes(), nil)
-	recordBuf.Write(ciphertext)
+	if c.breaker != nil {
+		client = c.breaker.wrap(client, c.now, c.circuitOpened)
+	}
+	client = c.intercept(client)
 
-	// POST request
-	req, err := http.NewRequest("POST", s.Endpoint, recordBuf)
-	if err != nil {
-		return nil, err
+	// The request is copied once with the context of the send
+	traceCtx := httptrace.WithClientTrace(ctx, c.transportStats.clientTrace())
+
+	var tracer *requestTracer
+	if c.requestTimings {
+		tracer = &requestTracer{}
+		traceCtx = httptrace.WithClientTrace(traceCtx, tracer.clientTrace())
 	}
 
-	if ctx != nil {
-		req = req.WithContext(ctx)
+	sendCtx, sendSpan := c.startSpan(traceCtx, SpanSend)
+	if c.tracer != nil {
+		sendSpan.SetAttribute(AttributeOrigin, origin)
+		sendSpan.SetAttribute(AttributePayloadSize, recordBuf.Len())
 	}
+	req = req.WithContext(sendCtx)
 
-	req.Header.Set("Content-Encoding", "aes128gcm")
-	req.Header.Set("Content-Type", "application/octet-stream")
-	req.Header.Set("TTL", strconv.Itoa(options.TTL))
+	events := c.emit()
+	events.SendStarted(sendCtx, SendStartedEvent{Origin: origin, CorrelationID: correlation, PayloadSize: recordBuf.Len()})
 
-	// Сheck the optional headers
-	if len(options.Topic) > 0 {
-		req.Header.Set("Topic", options.Topic)
+	if c.retryBudget != nil {
+		c.retryBudget.Request()
 	}
 
-	if isValidUrgency(options.Urgency) {
-		req.Header.Set("Urgency", string(options.Urgency))
+	atomic.AddInt64(&c.transportStats.inFlight, 1)
+	sendStart := time.Now()
+	resp, err := client.Do(req)
+	atomic.AddInt64(&c.transportStats.inFlight, -1)
+	elapsed := time.Since(sendStart)
+	recordDelivery(origin, elapsed, resp, err)
+	c.rolling.record(origin, c.now(), elapsed, failedSend(resp, err) && !abandonedSend(req, err))
+	if class := ClassifySend(resp, err); class != "" {
+		c.rolling.recordError(DetectPushService(s.Endpoint), class)
 	}
 
-	expiration := options.VapidExpiration
-	if expiration.IsZero() {
-		expiration = time.Now().Add(time.Hour * 12)
+	completed := SendCompletedEvent{Origin: origin, CorrelationID: correlation, Duration: elapsed, Err: err}
+	if resp != nil {
+		completed.StatusCode = resp.StatusCode
+		sendSpan.SetAttribute(AttributeStatusCode, resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			c.log().Warn("webpush: push service is throttling", "host", req.URL.Host, "retry_after", resp.Header.Get("Retry-After"),
+				"correlation_id", correlation)
+		}
+	}
+	sendSpan.End(err)
+	events.SendCompleted(sendCtx, completed)
+
+	if err == nil && resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+		events.SubscriptionGone(sendCtx, SubscriptionGoneEvent{
+			Origin:        origin,
+			CorrelationID: correlation,
+			StatusCode:    resp.StatusCode,
+			Subscription:  s,
+		})
+
+		if c.pruner != nil {
+			c.pruner.prune(sendCtx, s, req.URL.Host, c.log(), correlation)
+		}
 	}
 
-	// Get VAPID Authorization header
-	vapidAuthHeader, err := getVAPIDAuthorizationHeader(
-		s.Endpoint,
-		options.Subscriber,
-		options.VAPIDPublicKey,
-		options.VAPIDPrivateKey,
-		expiration,
-	)
-	if err != nil {
-		return nil, err
+	if c.audit != nil {
+		record := AuditRecord{
+			Time:          c.now(),
+			EndpointHash:  EndpointHash(s.Endpoint),
+			CorrelationID: correlation,
+			StatusCode:    completed.StatusCode,
+			TTL:           options.TTL,
+			TenantID:      options.TenantID,
+			Err:           err,
+		}
+		if isValidUrgency(options.Urgency) {
+			record.Urgency = options.Urgency
+		}
+		if resp != nil {
+			record.MessageID = resp.Header.Get("Location")
+		}
+		c.audit.Audit(sendCtx, record)
 	}
 
-	req.Header.Set("Authorization", vapidAuthHeader)
+	if resp != nil && resp.Body != nil {
+		resp.Body = &drainingBody{ReadCloser: resp.Body}
+	}
 
-	// Send the request
-	var client HTTPClient
-	if options.HTTPClient != nil {
-		client = options.HTTPClient
-	} else {
-		client = &http.Client{}
+	result := &SendResult{Response: resp, CorrelationID: correlation}
+	if tracer != nil {
+		result.Timings = tracer.timings()
 	}
 
-	return client.Do(req)
+	return result, err
 }
 
-// decodeSubscriptionKey decodes a base64 subscription key.
-// if necessary, add "=" padding to the key for URL decode
-func decodeSubscriptionKey(key string) ([]byte, error) {
-	// "=" padding
-	buf := bytes.NewBufferString(key)
-	if rem := len(key) % 4; rem != 0 {
-		buf.WriteString(strings.Repeat("=", 4-rem))
+// requestHeaders sets the headers of a notification request, the values of every header sharing
+// one backing array instead of a slice each
+type requestHeaders struct {
+	header http.Header
+	values []string
+}
+
+// set sets the header of the canonical key to value
+func (h *requestHeaders) set(key, value string) {
+	h.values = append(h.values, value)
+	n := len(h.values)
+	h.header[key] = h.values[n-1 : n : n]
+}
+
+// encryptMessage encrypts message for the subscription keys authSecret and dh with a new
+// single use key pair, returning the encrypted records and the public key of the pair
+func encryptMessage(message, authSecret, dh, salt []byte, recordSize uint32, encoding ContentEncoding) (*bytes.Buffer, []byte, error) {
+	localPublicKey, sharedECDHSecret, err := ephemeralECDH(dh)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	bytes, err := base64.StdEncoding.DecodeString(buf.String())
-	if err == nil {
-		return bytes, nil
+	var record []byte
+	if encoding == ContentEncodingAESGCM {
+		record, err = encryptAESGCM(message, authSecret, dh, salt, localPublicKey, sharedECDHSecret, recordSize)
+	} else {
+		record, err = encryptAES128GCM(message, authSecret, dh, salt, localPublicKey, sharedECDHSecret, recordSize)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return base64.URLEncoding.DecodeString(buf.String())
+	return bytes.NewBuffer(record), localPublicKey, nil
 }
 
-// Returns a key of length "length" given an hkdf function
-func getHKDFKey(hkdf io.Reader, length int) ([]byte, error) {
-	key := make([]byte, length)
-	n, err := io.ReadFull(hkdf, key)
-	if n != len(key) || err != nil {
-		return key, err
+// Key derivation info of aes128gcm (RFC 8291)
+var (
+	aes128gcmIKMInfo   = []byte("WebPush: info\x00")
+	aes128gcmKeyInfo   = []byte("Content-Encoding: aes128gcm\x00")
+	aes128gcmNonceInfo = []byte("Content-Encoding: nonce\x00")
+)
+
+// encryptAES128GCM encrypts message as a single aes128gcm record (RFC 8188) with the key derivation of RFC 8291.
+// The record is allocated once at its final size and the padded plaintext is sealed in place.
+func encryptAES128GCM(message, authSecret, dh, salt, localPublicKey, sharedECDHSecret []byte, recordSize uint32) ([]byte, error) {
+	// The derived keys share one allocation
+	derived := make([]byte, 32+16+12)
+	ikm, contentEncryptionKey, nonce := derived[:32], derived[32:48], derived[48:]
+
+	// ikm
+	buf := getHeaderBuffer()
+	ikmInfo := append((*buf)[:0], aes128gcmIKMInfo...)
+	ikmInfo = append(ikmInfo, dh...)
+	ikmInfo = append(ikmInfo, localPublicKey...)
+	err := newHKDF(authSecret, sharedECDHSecret).expand(ikm, ikmInfo)
+	*buf = ikmInfo
+	putHeaderBuffer(buf)
+	if err != nil {
+		return nil, err
 	}
 
-	return key, nil
-}
+	// Derive the Content Encryption Key and the Nonce
+	keys := newHKDF(salt, ikm)
+	if err := keys.expand(contentEncryptionKey, aes128gcmKeyInfo); err != nil {
+		return nil, err
+	}
+	if err := keys.expand(nonce, aes128gcmNonceInfo); err != nil {
+		return nil, err
+	}
 
-func pad(payload *bytes.Buffer, maxPadLen int) error {
-	payloadLen := payload.Len()
-	if payloadLen > maxPadLen {
-		return ErrMaxPadExceeded
+	// Cipher
+	block, err := aes.NewCipher(contentEncryptionKey)
+	if err != nil {
+		return nil, err
 	}
 
-	padLen := maxPadLen - payloadLen
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encryption Content-Coding Header, then the data padded to max record size - 16 - header
+	headerLength := len(salt) + 4 + 1 + len(localPublicKey)
+	dataLength := int(recordSize) - 16 - headerLength
+	if len(message)+1 > dataLength {
+		return nil, ErrMaxPadExceeded
+	}
 
-	padding := make([]byte, padLen)
-	payload.Write(padding)
+	record := make([]byte, headerLength+dataLength+gcm.Overhead())
+	n := copy(record, salt)
+	binary.BigEndian.PutUint32(record[n:], recordSize)
+	record[n+4] = byte(len(localPublicKey))
+	copy(record[n+5:], localPublicKey)
+
+	// Copying the message avoids data races, the padding ending delimiter is followed by zeros
+	data := record[headerLength : headerLength+dataLength]
+	copy(data, message)
+	data[len(message)] = 0x02
+
+	// Compose the ciphertext
+	gcm.Seal(data[:0], nonce, data, nil)
+
+	return record, nil
+}
+
+// decodeSubscriptionKey decodes a base64 subscription key, standard or URL encoded with or without "=" padding.
+// The encoding is told by its alphabet, so a key is decoded once.
+func decodeSubscriptionKey(key string) ([]byte, error) {
+	key = strings.TrimRight(key, "=")
+
+	if strings.ContainsAny(key, "-_") {
+		return base64.RawURLEncoding.DecodeString(key)
+	}
 
-	return nil
+	return base64.RawStdEncoding.DecodeString(key)
 }
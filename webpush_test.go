@@ -1,6 +1,11 @@
 package webpush
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
@@ -33,6 +38,7 @@ func getStandardEncodedTestSubscription() *Subscription {
 }
 
 func TestSendNotificationToURLEncodedSubscription(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
 	resp, err := SendNotification([]byte("Test"), getURLEncodedTestSubscription(), &Options{
 		HTTPClient:      &testHTTPClient{},
 		RecordSize:      3070,
@@ -40,8 +46,8 @@ func TestSendNotificationToURLEncodedSubscription(t *testing.T) {
 		Topic:           "test_topic",
 		TTL:             0,
 		Urgency:         "low",
-		VAPIDPublicKey:  "test-public",
-		VAPIDPrivateKey: "test-private",
+		VAPIDPublicKey:  keys.PublicKey,
+		VAPIDPrivateKey: keys.PrivateKey,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -57,13 +63,15 @@ func TestSendNotificationToURLEncodedSubscription(t *testing.T) {
 }
 
 func TestSendNotificationToStandardEncodedSubscription(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
 	resp, err := SendNotification([]byte("Test"), getStandardEncodedTestSubscription(), &Options{
 		HTTPClient:      &testHTTPClient{},
 		Subscriber:      "<EMAIL@EXAMPLE.COM>",
 		Topic:           "test_topic",
 		TTL:             0,
 		Urgency:         "low",
-		VAPIDPrivateKey: "testKey",
+		VAPIDPublicKey:  keys.PublicKey,
+		VAPIDPrivateKey: keys.PrivateKey,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -91,3 +99,98 @@ func TestSendTooLargeNotification(t *testing.T) {
 		t.Fatalf("Error is nil, expected=%s", ErrMaxPadExceeded)
 	}
 }
+
+func TestSendNotificationChecksApplicationServerKey(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	s := getStandardEncodedTestSubscription()
+
+	// Browsers expose the key in standard base64 with padding
+	decoded, _ := decodeVapidKey(keys.PublicKey)
+	s.ApplicationServerKey = base64.StdEncoding.EncodeToString(decoded)
+
+	options := &Options{
+		HTTPClient:      &testHTTPClient{},
+		Subscriber:      "<EMAIL@EXAMPLE.COM>",
+		VAPIDPublicKey:  keys.PublicKey,
+		VAPIDPrivateKey: keys.PrivateKey,
+	}
+	if _, err := SendNotification([]byte("Test"), s, options); err != nil {
+		t.Fatal(err)
+	}
+
+	options.VAPIDPublicKey, options.VAPIDPrivateKey = getTestVAPIDKeys(t).PublicKey, keys.PrivateKey
+	_, err := SendNotification([]byte("Test"), s, options)
+
+	var mismatch *VAPIDKeyMismatchError
+	if !errors.As(err, &mismatch) || mismatch.ApplicationServerKey != s.ApplicationServerKey {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrVAPIDKeyMismatch, err)
+	}
+}
+
+func TestSendNotificationWithCorruptedVAPIDKeys(t *testing.T) {
+	keys := getTestVAPIDKeys(t)
+	tests := []struct {
+		publicKey  string
+		privateKey string
+		expected   error
+	}{
+		{"test-public", "test-private", ErrInvalidVAPIDPrivateKey},
+		{keys.PublicKey, "testKey", ErrInvalidVAPIDPrivateKey},
+		// N of P-256, just out of the scalar range
+		{keys.PublicKey, "_____wAAAAD__________7zm-q2nF56E87nKwvxjJVE", ErrInvalidVAPIDPrivateKey},
+		{"", keys.PrivateKey, ErrInvalidVAPIDPublicKey},
+		{keys.PublicKey[:len(keys.PublicKey)-4] + "AAAA", keys.PrivateKey, ErrInvalidVAPIDPublicKey},
+	}
+
+	for _, test := range tests {
+		_, err := SendNotification([]byte("Test"), getStandardEncodedTestSubscription(), &Options{
+			HTTPClient:      &testHTTPClient{},
+			Subscriber:      "<EMAIL@EXAMPLE.COM>",
+			VAPIDPublicKey:  test.publicKey,
+			VAPIDPrivateKey: test.privateKey,
+		})
+		if err != test.expected {
+			t.Fatalf("Incorrect error for %q/%q, expected=%v, got=%v", test.publicKey, test.privateKey, test.expected, err)
+		}
+	}
+}
+
+// benchmarkSend measures Client.Send in process, the request body read like a transport would
+func benchmarkSend(b *testing.B, encoding ContentEncoding, options ...ClientOption) {
+	httpClient := HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		io.Copy(ioutil.Discard, req.Body)
+		req.Body.Close()
+		return &http.Response{StatusCode: http.StatusCreated, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	client, err := NewClient(append([]ClientOption{WithVAPIDKeys(getTestVAPIDKeys(b)), WithSubscriber("benchmark@example.com"),
+		WithHTTPClient(httpClient)}, options...)...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	message := []byte(strings.Repeat("x", 128))
+	subscription := getURLEncodedTestSubscription()
+	send := func() {
+		resp, err := client.Send(ctx, message, subscription, &Options{TTL: 60, ContentEncoding: encoding})
+		if err != nil {
+			b.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// Warm the VAPID cache
+	send()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		send()
+	}
+}
+
+func BenchmarkSendNotification(b *testing.B) {
+	b.Run("aes128gcm", func(b *testing.B) { benchmarkSend(b, ContentEncodingAES128GCM) })
+	b.Run("aesgcm", func(b *testing.B) { benchmarkSend(b, ContentEncodingAESGCM) })
+	b.Run("aes128gcm-nocache", func(b *testing.B) { benchmarkSend(b, ContentEncodingAES128GCM, WithoutVAPIDCache()) })
+}
@@ -0,0 +1,234 @@
+// Package webpushconformance checks Web Push implementations against RFC 8291 (message encryption),
+// RFC 8188 (the aes128gcm content coding) and RFC 8292 (VAPID). It is usable as a library by code
+// embedding webpush or porting it to another language:
+//
+//	if err := webpushconformance.CheckClient(ctx, client); err != nil {
+//		t.Fatal(err)
+//	}
+package webpushconformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrNonConformant is matched by errors.Is for every conformance failure
+var ErrNonConformant = errors.New("webpushconformance: not conformant")
+
+// Vector is an encryption test vector of RFC 8291
+type Vector struct {
+	Plaintext        []byte
+	UAPrivateKey     []byte // Private key of the user agent, the browser
+	UAPublicKey      []byte // Uncompressed public key of the user agent, keys.p256dh of the subscription
+	AuthSecret       []byte // keys.auth of the subscription
+	SenderPrivateKey []byte // Ephemeral private key of the application server
+	SenderPublicKey  []byte // Ephemeral public key of the application server, the key id of the message
+	Salt             []byte
+	RecordSize       uint32
+	Message          []byte // The encrypted aes128gcm message, without padding
+}
+
+// RFC8291Example is the example of RFC 8291 section 5
+var RFC8291Example = Vector{
+	Plaintext:        []byte("When I grow up, I want to be a watermelon"),
+	UAPrivateKey:     decode("q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"),
+	UAPublicKey:      decode("BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"),
+	AuthSecret:       decode("BTBZMqHH6r4Tts7J_aSIgg"),
+	SenderPrivateKey: decode("yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"),
+	SenderPublicKey:  decode("BP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A8"),
+	Salt:             decode("DGv6ra1nlYgDCS1FRnbzlw"),
+	RecordSize:       4096,
+	Message:          decode("DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"),
+}
+
+func decode(s string) []byte {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+// nonConformant returns an error matching ErrNonConformant
+func nonConformant(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrNonConformant}, args...)...)
+}
+
+// Encrypter is the aes128gcm encryption of an implementation under test, taking the ephemeral key and
+// salt that implementations normally generate for every message
+type Encrypter func(plaintext, uaPublicKey, authSecret, senderPrivateKey, salt []byte, recordSize uint32) ([]byte, error)
+
+// CheckEncrypter encrypts RFC8291Example with encrypt. The message must match the example; padding
+// is allowed, the padded message must then decrypt to the plaintext and share the example's ciphertext
+// up to the padding.
+func CheckEncrypter(encrypt Encrypter) error {
+	v := RFC8291Example
+	message, err := encrypt(v.Plaintext, v.UAPublicKey, v.AuthSecret, v.SenderPrivateKey, v.Salt, v.RecordSize)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(message, v.Message) {
+		return nil
+	}
+
+	// Padding only changes the ciphertext after the plaintext and its delimiter, and the tag
+	prefix := len(v.Message) - 16
+	if len(message) < len(v.Message) || !bytes.Equal(message[:prefix], v.Message[:prefix]) {
+		return nonConformant("message differs from the RFC 8291 example")
+	}
+
+	return CheckMessage(message, v.Plaintext)
+}
+
+// Subscription returns a subscription of the user agent of RFC8291Example at endpoint, whose
+// messages CheckMessage decrypts
+func Subscription(endpoint string) *webpush.Subscription {
+	return &webpush.Subscription{
+		Endpoint: endpoint,
+		Keys: webpush.Keys{
+			P256dh: base64.RawURLEncoding.EncodeToString(RFC8291Example.UAPublicKey),
+			Auth:   base64.RawURLEncoding.EncodeToString(RFC8291Example.AuthSecret),
+		},
+	}
+}
+
+// CheckMessage decrypts message, encrypted for a Subscription, and compares it to plaintext
+func CheckMessage(message, plaintext []byte) error {
+	v := RFC8291Example
+	decrypted, err := Decrypt(message, v.UAPrivateKey, v.UAPublicKey, v.AuthSecret)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(decrypted, plaintext) {
+		return nonConformant("message decrypts to %q instead of %q", decrypted, plaintext)
+	}
+
+	return nil
+}
+
+// Decrypt returns the plaintext of message, an aes128gcm message of RFC 8188 encrypted as in RFC 8291
+// for the user agent with the given keys
+func Decrypt(message, uaPrivateKey, uaPublicKey, authSecret []byte) ([]byte, error) {
+	if len(message) < 21 {
+		return nil, nonConformant("truncated aes128gcm header")
+	}
+
+	salt, recordSize, idLength := message[:16], binary.BigEndian.Uint32(message[16:20]), int(message[20])
+	if len(message) < 21+idLength || recordSize < 18 {
+		return nil, nonConformant("truncated aes128gcm header")
+	}
+
+	senderKey, records := message[21:21+idLength], message[21+idLength:]
+	x, y := elliptic.Unmarshal(elliptic.P256(), senderKey)
+	if x == nil {
+		return nil, nonConformant("key id is not an uncompressed P-256 point")
+	}
+
+	sharedX, _ := elliptic.P256().ScalarMult(x, y, uaPrivateKey)
+	secret := make([]byte, 32)
+	shared := sharedX.Bytes()
+	copy(secret[len(secret)-len(shared):], shared)
+
+	info := append(append([]byte("WebPush: info\x00"), uaPublicKey...), senderKey...)
+	ikm, cek, nonce := make([]byte, 32), make([]byte, 16), make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, authSecret, info), ikm); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	var plaintext []byte
+	for seq := uint64(0); len(records) > 0; seq++ {
+		record := records
+		if len(record) > int(recordSize) {
+			record = records[:recordSize]
+		}
+		records = records[len(record):]
+
+		// The nonce of a record is the base nonce XOR its sequence number
+		recordNonce := append([]byte(nil), nonce...)
+		for i := 0; i < 8; i++ {
+			recordNonce[11-i] ^= byte(seq >> (8 * i))
+		}
+
+		data, err := gcm.Open(nil, recordNonce, record, nil)
+		if err != nil {
+			return nil, nonConformant("record %d doesn't decrypt: %v", seq, err)
+		}
+
+		// Strip the padding, ending with the delimiter 2 in the last record and 1 in the others
+		end := len(data) - 1
+		for end >= 0 && data[end] == 0 {
+			end--
+		}
+		delimiter := byte(1)
+		if len(records) == 0 {
+			delimiter = 2
+		}
+		if end < 0 || data[end] != delimiter {
+			return nil, nonConformant("record %d has an invalid padding delimiter", seq)
+		}
+
+		plaintext = append(plaintext, data[:end]...)
+	}
+
+	return plaintext, nil
+}
+
+// conformanceEndpoint is the endpoint CheckClient sends to, never reached
+const conformanceEndpoint = "https://push.example.net/push/JzLQ3raZJfFBR0aqvOMsLrt54w4rJUsV"
+
+// CheckClient sends the plaintext of RFC8291Example with client, which needs a VAPID key pair and
+// subscriber, and checks the request: its aes128gcm message, TTL header and VAPID Authorization header.
+// The request is captured, it doesn't leave the process.
+func CheckClient(ctx context.Context, client *webpush.Client) error {
+	var captured *http.Request
+	var body []byte
+	capture := webpush.HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		captured = req
+
+		return &http.Response{StatusCode: http.StatusCreated, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(nil)), Request: req}, nil
+	})
+
+	resp, err := client.Send(ctx, RFC8291Example.Plaintext, Subscription(conformanceEndpoint), &webpush.Options{HTTPClient: capture, TTL: 30})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return CheckRequest(captured, body, RFC8291Example.Plaintext)
+}
@@ -0,0 +1,97 @@
+package webpushconformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/elliptic"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestRFC8291Example(t *testing.T) {
+	v := RFC8291Example
+	x, y := elliptic.P256().ScalarBaseMult(v.UAPrivateKey)
+	if !bytes.Equal(elliptic.Marshal(elliptic.P256(), x, y), v.UAPublicKey) {
+		t.Fatal("The user agent private key doesn't derive its public key")
+	}
+
+	if err := CheckMessage(v.Message, v.Plaintext); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckEncrypter(t *testing.T) {
+	example := func([]byte, []byte, []byte, []byte, []byte, uint32) ([]byte, error) {
+		return RFC8291Example.Message, nil
+	}
+	if err := CheckEncrypter(example); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := func([]byte, []byte, []byte, []byte, []byte, uint32) ([]byte, error) {
+		message := append([]byte(nil), RFC8291Example.Message...)
+		message[len(message)-20] ^= 1
+		return message, nil
+	}
+	if err := CheckEncrypter(corrupted); !errors.Is(err, ErrNonConformant) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrNonConformant, err)
+	}
+}
+
+func newClient(t *testing.T) *webpush.Client {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := webpush.NewClient(
+		webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		webpush.WithSubscriber("ops@example.com"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestCheckClient(t *testing.T) {
+	if err := CheckClient(context.Background(), newClient(t)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckVAPIDHeader(t *testing.T) {
+	endpoint := "https://push.example.net/push/1"
+	var authorization string
+	capture := webpush.HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		authorization = req.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusCreated, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+	resp, err := newClient(t).Send(context.Background(), []byte("Hello"), Subscription(endpoint), &webpush.Options{HTTPClient: capture, TTL: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := CheckVAPIDHeader(authorization, endpoint, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, check := range map[string]func() error{
+		"audience":   func() error { return CheckVAPIDHeader(authorization, "https://other.example.net/push/1", time.Now()) },
+		"expiration": func() error { return CheckVAPIDHeader(authorization, endpoint, time.Now().Add(-13*time.Hour)) },
+		"expired":    func() error { return CheckVAPIDHeader(authorization, endpoint, time.Now().Add(13*time.Hour)) },
+		"scheme":     func() error { return CheckVAPIDHeader("WebPush "+authorization, endpoint, time.Now()) },
+	} {
+		if err := check(); !errors.Is(err, ErrNonConformant) {
+			t.Errorf("%s: incorrect error, expected=%v, got=%v", name, ErrNonConformant, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package webpushconformance
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckRequest checks req, a push message request to a Subscription with body, as RFC 8030, 8291 and
+// 8292 require: a POST with a TTL and Content-Encoding: aes128gcm, a body decrypting to plaintext and
+// a valid VAPID Authorization header
+func CheckRequest(req *http.Request, body, plaintext []byte) error {
+	if req.Method != http.MethodPost {
+		return nonConformant("method is %s instead of POST", req.Method)
+	}
+
+	if ttl, err := strconv.Atoi(req.Header.Get("TTL")); err != nil || ttl < 0 {
+		return nonConformant("TTL header %q is not a number of seconds", req.Header.Get("TTL"))
+	}
+
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "aes128gcm" {
+		return nonConformant("Content-Encoding is %q instead of aes128gcm", encoding)
+	}
+
+	if err := CheckMessage(body, plaintext); err != nil {
+		return err
+	}
+
+	return CheckVAPIDHeader(req.Header.Get("Authorization"), req.URL.String(), time.Now())
+}
+
+// CheckVAPIDHeader checks the Authorization header of a request to endpoint as of now against RFC 8292:
+// the vapid scheme with the t and k parameters, an ES256 JWT signed by k, an aud claim of the origin of
+// endpoint, an exp claim within the next 24 hours and a mailto: or https: sub claim
+func CheckVAPIDHeader(authorization, endpoint string, now time.Time) error {
+	if !strings.HasPrefix(authorization, "vapid ") {
+		return nonConformant("Authorization header doesn't use the vapid scheme")
+	}
+
+	params := make(map[string]string)
+	for _, param := range strings.Split(strings.TrimPrefix(authorization, "vapid "), ",") {
+		if i := strings.Index(param, "="); i > 0 {
+			params[strings.TrimSpace(param[:i])] = strings.TrimSpace(param[i+1:])
+		}
+	}
+
+	publicKey, err := base64.RawURLEncoding.DecodeString(params["k"])
+	if err != nil {
+		return nonConformant("k parameter is not base64url without padding")
+	}
+
+	x, y := elliptic.Unmarshal(elliptic.P256(), publicKey)
+	if x == nil {
+		return nonConformant("k parameter is not an uncompressed P-256 point")
+	}
+
+	parts := strings.Split(params["t"], ".")
+	if len(parts) != 3 {
+		return nonConformant("t parameter is not a JWS compact serialization")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "ES256" {
+		return nonConformant("JWT alg is %q instead of ES256", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return nonConformant("JWT signature is not a 64 byte ES256 signature")
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	if !ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		return nonConformant("JWT signature doesn't verify with the k parameter")
+	}
+
+	var claims struct {
+		Aud string      `json:"aud"`
+		Exp json.Number `json:"exp"`
+		Sub string      `json:"sub"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nonConformant("JWT claims are not a JSON object")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if origin := u.Scheme + "://" + u.Host; claims.Aud != origin {
+		return nonConformant("aud claim %q is not the origin %q", claims.Aud, origin)
+	}
+
+	exp, err := claims.Exp.Int64()
+	if err != nil {
+		return nonConformant("exp claim %q is not a NumericDate", claims.Exp)
+	}
+	if expiration := time.Unix(exp, 0); !expiration.After(now) || expiration.After(now.Add(24*time.Hour)) {
+		return nonConformant("exp claim %s is not within the next 24 hours", expiration.UTC().Format(time.RFC3339))
+	}
+
+	if !strings.HasPrefix(claims.Sub, "mailto:") && !strings.HasPrefix(claims.Sub, "https:") {
+		return nonConformant("sub claim %q is not a mailto: or https: URI", claims.Sub)
+	}
+
+	return nil
+}
+
+// decodeSegment decodes the base64url JSON segment of a JWT into v
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,118 @@
+// Command webpushd is a push worker: it leases send jobs from a Redis stream, sends them and publishes
+// their results to a Redis channel, until SIGINT or SIGTERM drains the jobs in flight. Retries wait in
+// the stream's delayed set and jobs that failed for good are added to the -dead-letter stream, so
+// neither is lost by a restart. With -dedupe-window, a job delivered to the same subscription within
+// the window, e.g. by a replayed stream, is dropped.
+//
+//	webpushd -redis redis://localhost:6379 -jobs webpush:jobs -results webpush:results \
+//		-dead-letter webpush:dead -keys keys.json -subscriber ops@example.com -concurrency 64
+//
+// Producers add jobs with XADD webpush:jobs '*' body '{"id": "42", "subscription": {...}, "payload": "Hello", "ttl": 3600}'.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushd"
+	"github.com/redis/go-redis/v9"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "webpushd:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("webpushd", flag.ContinueOnError)
+	redisURL := flags.String("redis", "redis://localhost:6379", "URL of the Redis server")
+	jobs := flags.String("jobs", "webpush:jobs", "Redis stream of the jobs")
+	results := flags.String("results", "webpush:results", "Redis channel of the results")
+	deadLetter := flags.String("dead-letter", "", "Redis stream of the jobs that failed for good, dropped when empty")
+	group := flags.String("group", webpushd.DefaultRedisGroup, "Redis consumer group of the workers")
+	visibility := flags.Duration("visibility", webpushd.DefaultVisibility, "lease of a job before another worker may take it over")
+	keysFile := flags.String("keys", "", "JSON file with the VAPID privateKey and publicKey")
+	subscriber := flags.String("subscriber", "", "sub claim of the VAPID JWT tokens")
+	concurrency := flags.Int("concurrency", webpushd.DefaultConcurrency, "jobs sent at once")
+	retries := flags.Int("retries", webpushd.DefaultRetries, "resends of a throttled or failed job")
+	retryBudget := flags.Float64("retry-budget", 0, "ratio of the recent sends that may be retries, e.g. 0.1, unlimited when 0")
+	dedupeWindow := flags.Duration("dedupe-window", 0, "suppresses a job delivered to the same subscription within the window, e.g. 24h, none when 0")
+	dedupeKeys := flags.String("dedupe-keys", "webpush:sent:", "prefix of the Redis keys of the delivered jobs of -dedupe-window")
+	allowedHosts := flags.String("allowed-hosts", "", "comma separated hosts of private push services")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *keysFile == "" {
+		return errors.New("-keys is required")
+	}
+
+	data, err := ioutil.ReadFile(*keysFile)
+	if err != nil {
+		return err
+	}
+
+	var keys webpush.VAPIDKeys
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("%s: %w", *keysFile, err)
+	}
+
+	redisOptions, err := redis.ParseURL(*redisURL)
+	if err != nil {
+		return err
+	}
+	rdb := redis.NewClient(redisOptions)
+	defer rdb.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	clientOptions := []webpush.ClientOption{webpush.WithVAPIDKeys(keys), webpush.WithSubscriber(*subscriber),
+		webpush.WithTransportRetries(2), webpush.WithLogger(logger)}
+
+	var budget *webpush.RetryBudget
+	if *retryBudget > 0 {
+		if budget, err = webpush.NewRetryBudget(*retryBudget, 10, 0); err != nil {
+			return err
+		}
+		clientOptions = append(clientOptions, webpush.WithRetryBudget(budget))
+	}
+
+	if *dedupeWindow > 0 {
+		tracker := webpushd.NewRedisDuplicateTracker(rdb, *dedupeKeys)
+		clientOptions = append(clientOptions, webpush.WithDuplicateSuppression(tracker, *dedupeWindow))
+	}
+
+	client, err := webpush.NewClient(clientOptions...)
+	if err != nil {
+		return err
+	}
+
+	options := webpushd.Options{Concurrency: *concurrency, Retries: *retries, RetryBudget: budget, Logger: logger}
+	if *retries == 0 {
+		options.Retries = -1
+	}
+	if *allowedHosts != "" {
+		options.AllowedHosts = strings.Split(*allowedHosts, ",")
+	}
+	if *deadLetter != "" {
+		options.DeadLetter = webpushd.NewRedisQueue(rdb, *deadLetter, webpushd.RedisOptions{Group: *group})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("webpushd: consuming jobs", "jobs", *jobs, "results", *results, "concurrency", *concurrency)
+	queue := webpushd.NewRedisQueue(rdb, *jobs, webpushd.RedisOptions{Group: *group, Visibility: *visibility})
+	return webpushd.NewDaemon(client, queue, webpushd.NewRedisPublisher(rdb, *results), options).Run(ctx)
+}
@@ -0,0 +1,20 @@
+module github.com/SherClockHolmes/webpush-go/webpushd
+
+go 1.25.0
+
+require (
+	github.com/SherClockHolmes/webpush-go v0.0.0
+	github.com/alicebob/miniredis/v2 v2.39.0
+	github.com/redis/go-redis/v9 v9.22.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	go.uber.org/atomic v1.11.0 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+)
+
+replace github.com/SherClockHolmes/webpush-go => ../
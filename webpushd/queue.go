@@ -0,0 +1,36 @@
+package webpushd
+
+import (
+	"context"
+	"time"
+)
+
+// Message is a job leased from a Queue
+type Message struct {
+	ID       string // Identifies the message in its Queue
+	Receipt  string // Opaque handle acknowledging this lease, set by the Queue
+	Body     []byte // The encoded Job
+	Attempts int    // Leases of the message ended by Nack, plus this one
+}
+
+// Queue is a persistent queue of jobs, so scheduled sends and retries survive restarts of the
+// Daemon. A leased message is invisible to other consumers until it is acknowledged with Ack,
+// returned with Nack, or its lease expires because its consumer died, after which it is leased again.
+type Queue interface {
+	// Enqueue adds a message, leasable once delay has elapsed
+	Enqueue(ctx context.Context, body []byte, delay time.Duration) error
+
+	// Lease blocks until a message is available or ctx is done
+	Lease(ctx context.Context) (*Message, error)
+
+	// Ack removes a leased message from the queue
+	Ack(ctx context.Context, m *Message) error
+
+	// Nack returns a leased message to the queue, leasable again once delay has elapsed
+	Nack(ctx context.Context, m *Message, delay time.Duration) error
+}
+
+// Publisher receives the Result of every job
+type Publisher interface {
+	Publish(ctx context.Context, result *Result) error
+}
@@ -0,0 +1,324 @@
+package webpushd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultRedisGroup is the consumer group of a RedisQueue by default
+	DefaultRedisGroup = "webpushd"
+
+	// DefaultVisibility is the lease of a message by default, after which a message that wasn't
+	// acknowledged or returned, e.g. because its worker crashed, is leased again
+	DefaultVisibility = 5 * time.Minute
+)
+
+// redisPollTimeout bounds an XREADGROUP so Lease notices a done ctx on connections ignoring it and
+// moves delayed messages to the stream when they are due
+const redisPollTimeout = time.Second
+
+// redisPromote moves the due messages of the sorted set KEYS[2] to the stream KEYS[1]. A member is
+// "<attempts> <nonce> <body>", scored with the Unix time in milliseconds it is due at.
+var redisPromote = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, 100)
+for _, member in ipairs(due) do
+	local a = string.find(member, ' ', 1, true)
+	local b = string.find(member, ' ', a + 1, true)
+	redis.call('XADD', KEYS[1], '*', 'attempts', string.sub(member, 1, a - 1), 'body', string.sub(member, b + 1))
+	redis.call('ZREM', KEYS[2], member)
+end
+return #due
+`)
+
+// RedisOptions configure a RedisQueue
+type RedisOptions struct {
+	Group      string        // Consumer group of the workers, DefaultRedisGroup when empty
+	Consumer   string        // Name of this worker in the group, the host name and process ID when empty
+	Visibility time.Duration // Lease of a message, DefaultVisibility when zero
+}
+
+// RedisQueue is a Queue of a Redis stream, read by a consumer group so every message is leased by
+// one worker. Delayed messages wait in the sorted set "<stream>:delayed" until they are due; with
+// Redis Cluster, put the stream name in a hash tag, e.g. "{webpush:jobs}", so both keys share a slot.
+// Producers enqueue jobs with Enqueue or XADD <stream> * body '{"id": "42", ...}'.
+//
+// A message whose lease expires is leased again without counting an attempt.
+type RedisQueue struct {
+	client  redis.UniversalClient
+	stream  string
+	delayed string
+	options RedisOptions
+	now     func() time.Time
+
+	mu    sync.Mutex
+	ready bool // The consumer group exists
+}
+
+// NewRedisQueue returns a RedisQueue of stream
+func NewRedisQueue(client redis.UniversalClient, stream string, options RedisOptions) *RedisQueue {
+	if options.Group == "" {
+		options.Group = DefaultRedisGroup
+	}
+
+	if options.Consumer == "" {
+		hostname, _ := os.Hostname()
+		options.Consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	if options.Visibility <= 0 {
+		options.Visibility = DefaultVisibility
+	}
+
+	return &RedisQueue{client: client, stream: stream, delayed: stream + ":delayed", options: options, now: time.Now}
+}
+
+// Enqueue implements Queue
+func (q *RedisQueue) Enqueue(ctx context.Context, body []byte, delay time.Duration) error {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	return q.add(ctx, q.client, 0, hex.EncodeToString(nonce), body, delay)
+}
+
+// add appends a message to the stream, or to the delayed set when delay is positive
+func (q *RedisQueue) add(ctx context.Context, c redis.Cmdable, attempts int, nonce string, body []byte, delay time.Duration) error {
+	if delay <= 0 {
+		return c.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.stream,
+			Values: []interface{}{"attempts", attempts, "body", body},
+		}).Err()
+	}
+
+	return c.ZAdd(ctx, q.delayed, redis.Z{
+		Score:  float64(q.now().Add(delay).UnixMilli()),
+		Member: fmt.Sprintf("%d %s %s", attempts, nonce, body),
+	}).Err()
+}
+
+// Lease implements Queue
+func (q *RedisQueue) Lease(ctx context.Context) (*Message, error) {
+	if err := q.createGroup(ctx); err != nil {
+		return nil, q.err(ctx, err)
+	}
+
+	for {
+		keys := []string{q.stream, q.delayed}
+		if err := redisPromote.Run(ctx, q.client, keys, q.now().UnixMilli()).Err(); err != nil {
+			return nil, q.err(ctx, err)
+		}
+
+		// Messages of crashed workers first
+		claimed, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   q.stream,
+			Group:    q.options.Group,
+			Consumer: q.options.Consumer,
+			MinIdle:  q.options.Visibility,
+			Start:    "0-0",
+			Count:    1,
+		}).Result()
+		if err != nil {
+			return nil, q.err(ctx, err)
+		}
+		if len(claimed) > 0 {
+			return q.message(claimed[0]), nil
+		}
+
+		streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    q.options.Group,
+			Consumer: q.options.Consumer,
+			Streams:  []string{q.stream, ">"},
+			Count:    1,
+			Block:    redisPollTimeout,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, q.err(ctx, err)
+		}
+		if len(streams) > 0 && len(streams[0].Messages) > 0 {
+			return q.message(streams[0].Messages[0]), nil
+		}
+	}
+}
+
+// Ack implements Queue
+func (q *RedisQueue) Ack(ctx context.Context, m *Message) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.stream, q.options.Group, m.ID)
+		pipe.XDel(ctx, q.stream, m.ID)
+		return nil
+	})
+
+	return err
+}
+
+// Nack implements Queue
+func (q *RedisQueue) Nack(ctx context.Context, m *Message, delay time.Duration) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if err := q.add(ctx, pipe, m.Attempts, m.ID, m.Body, delay); err != nil {
+			return err
+		}
+		pipe.XAck(ctx, q.stream, q.options.Group, m.ID)
+		pipe.XDel(ctx, q.stream, m.ID)
+		return nil
+	})
+
+	return err
+}
+
+// createGroup creates the consumer group and the stream unless they exist
+func (q *RedisQueue) createGroup(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.ready {
+		return nil
+	}
+
+	err := q.client.XGroupCreateMkStream(ctx, q.stream, q.options.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	q.ready = true
+
+	return nil
+}
+
+// message returns the Message of a stream entry, whose attempts don't count this lease yet
+func (q *RedisQueue) message(entry redis.XMessage) *Message {
+	m := &Message{ID: entry.ID, Receipt: entry.ID, Attempts: 1}
+	if body, ok := entry.Values["body"].(string); ok {
+		m.Body = []byte(body)
+	}
+	if attempts, ok := entry.Values["attempts"].(string); ok {
+		if n, err := strconv.Atoi(attempts); err == nil && n > 0 {
+			m.Attempts = n + 1
+		}
+	}
+
+	return m
+}
+
+// err returns the error of ctx when it is done, err otherwise
+func (q *RedisQueue) err(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// RedisPublisher is a Publisher of a Redis channel
+type RedisPublisher struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// NewRedisPublisher returns a RedisPublisher of channel
+func NewRedisPublisher(client redis.UniversalClient, channel string) *RedisPublisher {
+	return &RedisPublisher{client: client, channel: channel}
+}
+
+// Publish implements Publisher
+func (p *RedisPublisher) Publish(ctx context.Context, result *Result) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return p.client.Publish(ctx, p.channel, encoded).Err()
+}
+
+// redisAdvanceRun sets the field ARGV[1] of the hash KEYS[1] to ARGV[3] if it is ARGV[2], or missing
+// when ARGV[2] is empty
+var redisAdvanceRun = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if (current == false and ARGV[2] == '') or current == ARGV[2] then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// RedisScheduleStore is a ScheduleStore of a Redis hash, shared by the Schedulers of several processes
+type RedisScheduleStore struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// NewRedisScheduleStore returns a RedisScheduleStore of the hash key
+func NewRedisScheduleStore(client redis.UniversalClient, key string) *RedisScheduleStore {
+	return &RedisScheduleStore{client: client, key: key}
+}
+
+// LastRun implements ScheduleStore
+func (s *RedisScheduleStore) LastRun(ctx context.Context, name string) (time.Time, bool, error) {
+	value, err := s.client.HGet(ctx, s.key, name).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("webpushd: last run of %s: %w", name, err)
+	}
+
+	return time.Unix(0, nanos), true, nil
+}
+
+// AdvanceRun implements ScheduleStore
+func (s *RedisScheduleStore) AdvanceRun(ctx context.Context, name string, from, to time.Time) (bool, error) {
+	var expected string
+	if !from.IsZero() {
+		expected = strconv.FormatInt(from.UnixNano(), 10)
+	}
+
+	advanced, err := redisAdvanceRun.Run(ctx, s.client, []string{s.key}, name, expected, strconv.FormatInt(to.UnixNano(), 10)).Int()
+	return advanced == 1, err
+}
+
+// RedisDuplicateTracker is a webpush.DuplicateTracker of Redis keys expiring with the suppression window,
+// so the Clients of several processes don't deliver the same notification twice
+type RedisDuplicateTracker struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisDuplicateTracker returns a RedisDuplicateTracker of the keys starting with prefix, e.g. "webpush:sent:"
+func NewRedisDuplicateTracker(client redis.UniversalClient, prefix string) *RedisDuplicateTracker {
+	return &RedisDuplicateTracker{client: client, prefix: prefix}
+}
+
+// Remember implements webpush.DuplicateTracker
+func (t *RedisDuplicateTracker) Remember(ctx context.Context, key string, now, expires time.Time) (bool, error) {
+	ttl := expires.Sub(now)
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+
+	added, err := t.client.SetNX(ctx, t.prefix+key, 1, ttl).Result()
+	return !added, err
+}
+
+// Forget implements webpush.DuplicateTracker
+func (t *RedisDuplicateTracker) Forget(ctx context.Context, key string) error {
+	return t.client.Del(ctx, t.prefix+key).Err()
+}
@@ -0,0 +1,168 @@
+package webpushd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSchedule is returned for a schedule expression that doesn't parse
+var ErrInvalidSchedule = errors.New("webpushd: invalid schedule")
+
+// scheduleSearchYears bounds the search for the next time of a schedule, e.g. of "0 0 30 2 *"
+const scheduleSearchYears = 5
+
+// scheduleDescriptors are the shorthands of common schedules
+var scheduleDescriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// schedule is a parsed cron expression: the minutes, hours, days of the month, months and days of
+// the week it matches, as bit sets
+type schedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool // The field is *, so only the other day field restricts days
+}
+
+// parseSchedule parses a cron expression of five fields, minute hour day-of-month month day-of-week,
+// each *, a value, a range a-b or a list of them, optionally with a /step, or a descriptor such as @daily.
+// Days of the week run from 0, Sunday, to 6, with 7 also Sunday; when both day fields are restricted
+// a day matching either matches, as in cron.
+func parseSchedule(expression string) (*schedule, error) {
+	if descriptor, ok := scheduleDescriptors[strings.TrimSpace(expression)]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q must have 5 fields", ErrInvalidSchedule, expression)
+	}
+
+	s := &schedule{}
+	var err error
+	for _, field := range []struct {
+		bits     *uint64
+		min, max int
+		any      *bool
+	}{
+		{&s.minute, 0, 59, nil},
+		{&s.hour, 0, 23, nil},
+		{&s.dom, 1, 31, &s.anyDom},
+		{&s.month, 1, 12, nil},
+		{&s.dow, 0, 7, &s.anyDow},
+	} {
+		value := fields[0]
+		fields = fields[1:]
+		if *field.bits, err = parseScheduleField(value, field.min, field.max); err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSchedule, expression, err)
+		}
+		if field.any != nil {
+			*field.any = value == "*" || strings.HasPrefix(value, "*/")
+		}
+	}
+
+	// 7 is Sunday too
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+// parseScheduleField returns the bit set of the values of field within min and max
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step, part = n, part[:i]
+		}
+
+		low, high := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid range %q", part)
+				}
+			} else if step > 1 {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of %d-%d", part, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// next returns the first time of s after t, in the location of t, or the zero time if there is none
+// within scheduleSearchYears
+func (s *schedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(scheduleSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t matches the day fields of s
+func (s *schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dow
+	case s.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
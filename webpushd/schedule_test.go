@@ -0,0 +1,57 @@
+package webpushd
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04 Mon", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	for _, test := range []struct {
+		expression string
+		after      string
+		next       string
+	}{
+		{"* * * * *", "2026-10-14 09:30 Wed", "2026-10-14 09:31 Wed"},
+		{"0 9 * * 1-5", "2026-10-16 09:00 Fri", "2026-10-19 09:00 Mon"},
+		{"*/15 * * * *", "2026-10-14 09:31 Wed", "2026-10-14 09:45 Wed"},
+		{"30 8,18 * * *", "2026-10-14 09:00 Wed", "2026-10-14 18:30 Wed"},
+		{"@daily", "2026-12-31 12:00 Thu", "2027-01-01 00:00 Fri"},
+		{"@weekly", "2026-10-14 09:00 Wed", "2026-10-18 00:00 Sun"},
+		{"0 0 * * 7", "2026-10-14 09:00 Wed", "2026-10-18 00:00 Sun"},
+		{"0 0 29 2 *", "2026-03-01 00:00 Sun", "2028-02-29 00:00 Tue"},
+		// Either day field matches when both are restricted
+		{"0 12 1 * 5", "2026-10-14 09:00 Wed", "2026-10-16 12:00 Fri"},
+	} {
+		s, err := parseSchedule(test.expression)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expression, err)
+		}
+
+		if next := s.next(at(test.after)); !next.Equal(at(test.next)) {
+			t.Fatalf("%s after %s: expected %s, got %s", test.expression, test.after, test.next, next.Format("2006-01-02 15:04 Mon"))
+		}
+	}
+
+	never, err := parseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := never.next(at("2026-10-14 09:00 Wed")); !next.IsZero() {
+		t.Fatalf("Expected no time for February 30, got %s", next)
+	}
+
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseSchedule(expression); !errors.Is(err, ErrInvalidSchedule) {
+			t.Fatalf("%q: expected ErrInvalidSchedule, got %v", expression, err)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package webpushd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+const (
+	// DefaultScheduleInterval is how often a Scheduler checks for due broadcasts by default
+	DefaultScheduleInterval = 30 * time.Second
+
+	// DefaultScheduleGrace is how late a run may start and still count as on time by default
+	DefaultScheduleGrace = 5 * time.Minute
+
+	// maxCatchUpRuns bounds the missed runs MissedRunsAll catches up with in one check
+	maxCatchUpRuns = 100
+
+	// enqueuedWindow is how long the IDs of the enqueued jobs are remembered, to skip them when a run is retried
+	enqueuedWindow = 24 * time.Hour
+)
+
+var (
+	// ErrDuplicateBroadcast is returned by Scheduler.Register for a name already registered
+	ErrDuplicateBroadcast = errors.New("webpushd: broadcast already registered")
+
+	// ErrInvalidBroadcast is returned by Scheduler.Register for a broadcast without a name or segment
+	ErrInvalidBroadcast = errors.New("webpushd: broadcast needs a name and a segment")
+)
+
+// MissedRuns is what a Scheduler does about the runs of a broadcast that passed while no Scheduler
+// was running, or that are later than the grace period
+type MissedRuns int
+
+const (
+	// MissedRunsSkip drops the missed runs, the broadcast runs again at its next time
+	MissedRunsSkip MissedRuns = iota
+
+	// MissedRunsOnce runs the broadcast once for all the missed runs, e.g. for a digest
+	MissedRunsOnce
+
+	// MissedRunsAll runs the broadcast for every missed run, the last 100 at most
+	MissedRunsAll
+)
+
+// Broadcast is a recurring notification to a segment
+type Broadcast struct {
+	Name     string           // Identifies the broadcast in the ScheduleStore and the IDs of its jobs
+	Schedule string           // Cron expression of five fields, e.g. "0 9 * * 1-5", or @hourly, @daily, @weekly, @monthly
+	Location *time.Location   // Of the times of Schedule, time.UTC when nil
+	Segment  *webpush.Segment // Subscriptions the broadcast is sent to
+	Payload  string           // text/template of the payload, executed with a BroadcastData
+	TTL      int              // Of the notifications
+	Urgency  webpush.Urgency  // Of the notifications (Optional)
+	Topic    string           // Of the notifications (Optional)
+	Missed   MissedRuns       // What to do about missed runs, MissedRunsSkip when zero
+	Funcs    template.FuncMap // Functions of the Payload template (Optional)
+}
+
+// BroadcastData is the data of the Payload template of a run of a Broadcast
+type BroadcastData struct {
+	Subscription *webpush.StoredSubscription
+	Time         time.Time // Scheduled time of the run
+	Since        time.Time // Scheduled time of the previous run, e.g. the start of a digest
+}
+
+// ScheduleStore keeps the time of the last run of every broadcast, so missed runs are noticed across
+// restarts and several Schedulers sharing a store run each broadcast once
+type ScheduleStore interface {
+	// LastRun returns the scheduled time of the last run of name, false when it never ran
+	LastRun(ctx context.Context, name string) (time.Time, bool, error)
+
+	// AdvanceRun sets the last run of name to to if it is still from, the zero time when it never ran,
+	// and reports whether it was, so only one of several Schedulers runs it
+	AdvanceRun(ctx context.Context, name string, from, to time.Time) (bool, error)
+}
+
+// ScheduleOptions configure a Scheduler
+type ScheduleOptions struct {
+	Interval time.Duration  // How often broadcasts are checked, DefaultScheduleInterval when zero
+	Grace    time.Duration  // How late a run still counts as on time, DefaultScheduleGrace when zero
+	Logger   webpush.Logger // Receives the failures of runs, nothing is logged when nil
+
+	// Enqueued remembers the IDs of the jobs enqueued in the last day, so the retry of a failed run
+	// skips them, a webpush.MemoryDuplicateTracker when nil. Share one between the Schedulers of a
+	// store, e.g. a RedisDuplicateTracker, so a run retried by another Scheduler skips them too.
+	Enqueued webpush.DuplicateTracker
+}
+
+// Scheduler enqueues the jobs of recurring broadcasts into a Queue at the times of their schedules,
+// for a Daemon to send. Every run is claimed in the ScheduleStore before its jobs are enqueued, so a
+// run is enqueued by one of several Schedulers. A run failing part way, e.g. on a queue error, is
+// released and retried by the next check, skipping the jobs in Enqueued; a Scheduler stopped while
+// enqueuing doesn't finish its run. Jobs are identified by the broadcast, the time of the run and
+// the subscription.
+type Scheduler struct {
+	queue   Queue
+	store   ScheduleStore
+	options ScheduleOptions
+	now     func() time.Time
+
+	mu         sync.Mutex
+	broadcasts []*scheduledBroadcast
+}
+
+// scheduledBroadcast is a registered Broadcast with its parsed schedule and template
+type scheduledBroadcast struct {
+	Broadcast
+	schedule *schedule
+	payload  *template.Template
+}
+
+// NewScheduler returns a Scheduler enqueuing into queue and keeping the last runs in store
+func NewScheduler(queue Queue, store ScheduleStore, options ScheduleOptions) *Scheduler {
+	if options.Interval <= 0 {
+		options.Interval = DefaultScheduleInterval
+	}
+
+	if options.Grace <= 0 {
+		options.Grace = DefaultScheduleGrace
+	}
+
+	if options.Enqueued == nil {
+		options.Enqueued = webpush.NewMemoryDuplicateTracker()
+	}
+
+	return &Scheduler{queue: queue, store: store, options: options, now: time.Now}
+}
+
+// Register adds a broadcast, running from its next scheduled time unless the ScheduleStore knows its last run
+func (s *Scheduler) Register(b Broadcast) error {
+	if b.Name == "" || b.Segment == nil {
+		return ErrInvalidBroadcast
+	}
+
+	parsed, err := parseSchedule(b.Schedule)
+	if err != nil {
+		return err
+	}
+
+	payload, err := template.New(b.Name).Funcs(b.Funcs).Parse(b.Payload)
+	if err != nil {
+		return err
+	}
+
+	if b.Location == nil {
+		b.Location = time.UTC
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, registered := range s.broadcasts {
+		if registered.Name == b.Name {
+			return fmt.Errorf("%w: %s", ErrDuplicateBroadcast, b.Name)
+		}
+	}
+	s.broadcasts = append(s.broadcasts, &scheduledBroadcast{Broadcast: b, schedule: parsed, payload: payload})
+
+	return nil
+}
+
+// Run checks the broadcasts every interval until ctx is done, returning nil then
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs the due broadcasts
+func (s *Scheduler) check(ctx context.Context) {
+	s.mu.Lock()
+	broadcasts := append([]*scheduledBroadcast(nil), s.broadcasts...)
+	s.mu.Unlock()
+
+	for _, b := range broadcasts {
+		if err := s.checkBroadcast(ctx, b, s.now().In(b.Location)); err != nil && ctx.Err() == nil && s.options.Logger != nil {
+			s.options.Logger.Error("webpushd: scheduled broadcast failed", "broadcast", b.Name, "error", err)
+		}
+	}
+}
+
+// checkBroadcast claims and enqueues the runs of b due at now, according to its MissedRuns
+func (s *Scheduler) checkBroadcast(ctx context.Context, b *scheduledBroadcast, now time.Time) error {
+	last, ok, err := s.store.LastRun(ctx, b.Name)
+	if err != nil {
+		return err
+	}
+
+	// A new broadcast starts now
+	if !ok {
+		_, err := s.store.AdvanceRun(ctx, b.Name, time.Time{}, now)
+		return err
+	}
+
+	var due []time.Time
+	for t := b.schedule.next(last.In(b.Location)); !t.IsZero() && !t.After(now); t = b.schedule.next(t) {
+		due = append(due, t)
+		if len(due) > maxCatchUpRuns {
+			due = due[1:]
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	latest := due[len(due)-1]
+	runs := due
+	switch b.Missed {
+	case MissedRunsSkip:
+		runs = nil
+		if now.Sub(latest) <= s.options.Grace {
+			runs = due[len(due)-1:]
+		}
+	case MissedRunsOnce:
+		runs = due[len(due)-1:]
+	}
+
+	claimed, err := s.store.AdvanceRun(ctx, b.Name, last, latest)
+	if err != nil || !claimed {
+		return err
+	}
+
+	since := last
+	for _, run := range runs {
+		if err := s.enqueue(ctx, b, run, since); err != nil {
+			// Release the run to the last one enqueued completely, the next check retries it
+			if _, releaseErr := s.store.AdvanceRun(context.WithoutCancel(ctx), b.Name, latest, since); releaseErr != nil {
+				return errors.Join(err, fmt.Errorf("webpushd: releasing the run of %s: %w", b.Name, releaseErr))
+			}
+			return err
+		}
+		since = run
+	}
+
+	return nil
+}
+
+// enqueue adds a job per subscription of the segment of b for the run at t, skipping the jobs already enqueued
+func (s *Scheduler) enqueue(ctx context.Context, b *scheduledBroadcast, t, since time.Time) error {
+	run := strconv.FormatInt(t.Unix(), 10)
+	return b.Segment.Iterate(ctx, func(subscription *webpush.StoredSubscription) error {
+		var payload strings.Builder
+		if err := b.payload.Execute(&payload, BroadcastData{Subscription: subscription, Time: t, Since: since}); err != nil {
+			return err
+		}
+
+		// As the DedupeKey, the ID lets Daemons suppressing duplicates drop the jobs enqueued twice by
+		// Schedulers not sharing Enqueued
+		id := b.Name + ":" + run + ":" + webpush.EndpointHash(subscription.Endpoint)
+		encoded, err := json.Marshal(Job{
+			ID:           id,
+			Subscription: subscription.Subscription,
+			Payload:      payload.String(),
+			TTL:          b.TTL,
+			Urgency:      b.Urgency,
+			Topic:        b.Topic,
+			DedupeKey:    id,
+		})
+		if err != nil {
+			return err
+		}
+
+		now := s.now()
+		enqueued, err := s.options.Enqueued.Remember(ctx, id, now, now.Add(enqueuedWindow))
+		if err != nil || enqueued {
+			return err
+		}
+
+		if err := s.queue.Enqueue(ctx, encoded, 0); err != nil {
+			s.options.Enqueued.Forget(ctx, id)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// MemoryScheduleStore is an in-memory ScheduleStore, for tests and a single Scheduler that may miss
+// the runs due while it is restarted
+type MemoryScheduleStore struct {
+	mu   sync.Mutex
+	runs map[string]time.Time
+}
+
+// NewMemoryScheduleStore returns an empty MemoryScheduleStore
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{runs: make(map[string]time.Time)}
+}
+
+// LastRun implements ScheduleStore
+func (m *MemoryScheduleStore) LastRun(_ context.Context, name string) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last, ok := m.runs[name]
+	return last, ok, nil
+}
+
+// AdvanceRun implements ScheduleStore
+func (m *MemoryScheduleStore) AdvanceRun(_ context.Context, name string, from, to time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.runs[name].Equal(from) {
+		return false, nil
+	}
+
+	m.runs[name] = to
+	return true, nil
+}
@@ -0,0 +1,217 @@
+package webpushd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	subscriptions := webpush.NewMemorySubscriptionStore()
+	for _, locale := range []string{"en", "de"} {
+		s := &webpush.StoredSubscription{Locale: locale}
+		s.Endpoint = "https://fcm.googleapis.com/fcm/send/" + locale
+		if err := subscriptions.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	for _, test := range []struct {
+		missed  MissedRuns
+		elapsed time.Duration
+		runs    int
+	}{
+		{MissedRunsSkip, 30 * time.Minute, 1},             // On time
+		{MissedRunsSkip, 3*time.Hour + 40*time.Minute, 0}, // The runs at 10:00, 11:00, 12:00 and 13:00 were missed
+		{MissedRunsOnce, 3*time.Hour + 40*time.Minute, 1},
+		{MissedRunsAll, 3*time.Hour + 40*time.Minute, 4},
+	} {
+		queue := &memoryQueue{}
+		store := NewMemoryScheduleStore()
+		now := start
+		scheduler := NewScheduler(queue, store, ScheduleOptions{})
+		scheduler.now = func() time.Time { return now }
+
+		err := scheduler.Register(Broadcast{
+			Name:     "digest",
+			Schedule: "@hourly",
+			Segment:  webpush.NewSegment(subscriptions),
+			Payload:  `{{.Subscription.Locale}} since {{.Since.Format "15:04"}}`,
+			TTL:      3600,
+			Missed:   test.missed,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := scheduler.Register(Broadcast{Name: "digest", Schedule: "@daily", Segment: webpush.NewSegment(subscriptions)}); !errors.Is(err, ErrDuplicateBroadcast) {
+			t.Fatalf("Expected ErrDuplicateBroadcast, got %v", err)
+		}
+
+		// The first check starts the broadcast
+		scheduler.check(ctx)
+		if len(queue.enqueued) != 0 {
+			t.Fatalf("Expected no run before the first scheduled time, got %d jobs", len(queue.enqueued))
+		}
+
+		now = start.Add(test.elapsed)
+		scheduler.check(ctx)
+		if len(queue.enqueued) != 2*test.runs {
+			t.Fatalf("%v after %s: expected %d runs, got %d jobs", test.missed, test.elapsed, test.runs, len(queue.enqueued))
+		}
+
+		// Another Scheduler of the store doesn't run them again
+		other := NewScheduler(queue, store, ScheduleOptions{})
+		other.now = scheduler.now
+		if err := other.Register(Broadcast{Name: "digest", Schedule: "@hourly", Segment: webpush.NewSegment(subscriptions), Missed: test.missed}); err != nil {
+			t.Fatal(err)
+		}
+		other.check(ctx)
+		if len(queue.enqueued) != 2*test.runs {
+			t.Fatalf("Expected the runs to be claimed once, got %d jobs", len(queue.enqueued))
+		}
+
+		if test.runs == 0 {
+			continue
+		}
+
+		var job Job
+		if err := json.Unmarshal(queue.enqueued[0], &job); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(job.ID, "digest:") || job.TTL != 3600 || job.Payload != job.Subscription.Endpoint[len(job.Subscription.Endpoint)-2:]+" since 09:30" {
+			t.Fatalf("Incorrect job, got %+v", job)
+		}
+	}
+}
+
+// failingQueue is a memoryQueue failing every Enqueue after accepting n of them
+type failingQueue struct {
+	memoryQueue
+	n int
+}
+
+func (q *failingQueue) Enqueue(ctx context.Context, body []byte, delay time.Duration) error {
+	if len(q.enqueued) >= q.n {
+		return errors.New("queue unavailable")
+	}
+	return q.memoryQueue.Enqueue(ctx, body, delay)
+}
+
+func TestSchedulerRetriesFailedRuns(t *testing.T) {
+	ctx := context.Background()
+	subscriptions := webpush.NewMemorySubscriptionStore()
+	for _, locale := range []string{"en", "de", "fr"} {
+		s := &webpush.StoredSubscription{Locale: locale}
+		s.Endpoint = "https://fcm.googleapis.com/fcm/send/" + locale
+		if err := subscriptions.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	now := start
+	queue := &failingQueue{n: 1}
+	store := NewMemoryScheduleStore()
+	scheduler := NewScheduler(queue, store, ScheduleOptions{})
+	scheduler.now = func() time.Time { return now }
+	if err := scheduler.Register(Broadcast{Name: "digest", Schedule: "@hourly", Segment: webpush.NewSegment(subscriptions), Payload: "Digest"}); err != nil {
+		t.Fatal(err)
+	}
+	scheduler.check(ctx)
+
+	// The run failing part way is released
+	now = start.Add(31 * time.Minute)
+	b := scheduler.broadcasts[0]
+	if err := scheduler.checkBroadcast(ctx, b, now); err == nil || len(queue.enqueued) != 1 {
+		t.Fatalf("Expected the run to fail after 1 job, got %d jobs (%v)", len(queue.enqueued), err)
+	}
+	if last, _, _ := store.LastRun(ctx, "digest"); !last.Equal(start) {
+		t.Fatalf("Expected the failed run to be released, got the last run at %s", last)
+	}
+
+	// The next check enqueues the rest of the run only
+	queue.n = 10
+	if err := scheduler.checkBroadcast(ctx, b, now); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool)
+	for _, body := range queue.enqueued {
+		var job Job
+		if err := json.Unmarshal(body, &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.DedupeKey != job.ID {
+			t.Fatalf("Expected the job ID as the dedupe key, got %+v", job)
+		}
+		ids[job.ID] = true
+	}
+	if len(queue.enqueued) != 3 || len(ids) != 3 {
+		t.Fatalf("Expected 3 distinct jobs, got %d jobs and %d IDs", len(queue.enqueued), len(ids))
+	}
+	if last, _, _ := store.LastRun(ctx, "digest"); !last.Equal(start.Add(30 * time.Minute)) {
+		t.Fatalf("Expected the run to be claimed, got the last run at %s", last)
+	}
+
+	if err := scheduler.checkBroadcast(ctx, b, now); err != nil || len(queue.enqueued) != 3 {
+		t.Fatalf("Expected the claimed run not to run again, got %d jobs (%v)", len(queue.enqueued), err)
+	}
+
+	// Another Scheduler of the store that read the last run before it was claimed doesn't enqueue it
+	other := NewScheduler(queue, &staleScheduleStore{ScheduleStore: store, last: start}, ScheduleOptions{})
+	other.now = scheduler.now
+	if err := other.Register(Broadcast{Name: "digest", Schedule: "@hourly", Segment: webpush.NewSegment(subscriptions), Payload: "Digest"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.checkBroadcast(ctx, other.broadcasts[0], now); err != nil || len(queue.enqueued) != 3 {
+		t.Fatalf("Expected the run to be enqueued once, got %d jobs (%v)", len(queue.enqueued), err)
+	}
+}
+
+// staleScheduleStore returns a last run read before another Scheduler advanced it
+type staleScheduleStore struct {
+	ScheduleStore
+	last time.Time
+}
+
+func (s *staleScheduleStore) LastRun(context.Context, string) (time.Time, bool, error) {
+	return s.last, true, nil
+}
+
+func TestRedisScheduleStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+
+	ctx := context.Background()
+	store := NewRedisScheduleStore(rdb, "schedules")
+	if _, ok, err := store.LastRun(ctx, "digest"); err != nil || ok {
+		t.Fatalf("Expected no last run, got %v (%v)", ok, err)
+	}
+
+	first := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	if advanced, err := store.AdvanceRun(ctx, "digest", time.Time{}, first); err != nil || !advanced {
+		t.Fatalf("Expected to start the broadcast, got %v (%v)", advanced, err)
+	}
+	if advanced, err := store.AdvanceRun(ctx, "digest", time.Time{}, second); err != nil || advanced {
+		t.Fatalf("Expected a started broadcast not to start again, got %v (%v)", advanced, err)
+	}
+	if advanced, err := store.AdvanceRun(ctx, "digest", first, second); err != nil || !advanced {
+		t.Fatalf("Expected to advance the broadcast, got %v (%v)", advanced, err)
+	}
+
+	last, ok, err := store.LastRun(ctx, "digest")
+	if err != nil || !ok || !last.Equal(second) {
+		t.Fatalf("Expected the last run at %s, got %s (%v)", second, last, err)
+	}
+}
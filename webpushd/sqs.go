@@ -0,0 +1,214 @@
+package webpushd
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// sqsMaxDelay is the longest delay of an SQS message, longer delays are served in several hops
+	sqsMaxDelay = 15 * time.Minute
+
+	// sqsMaxVisibility is the longest visibility timeout of an SQS message
+	sqsMaxVisibility = 12 * time.Hour
+
+	// sqsWait is the long polling wait of a ReceiveMessage call
+	sqsWait = 20
+
+	// sqsAttempts and sqsNotBefore are the message attributes carrying the attempts of a message
+	// and the Unix time in milliseconds it is due at
+	sqsAttempts  = "attempts"
+	sqsNotBefore = "notBefore"
+)
+
+// SQSMessage is a message received from Amazon SQS
+type SQSMessage struct {
+	MessageID     string
+	ReceiptHandle string
+	Body          string
+	Attributes    map[string]string // String message attributes
+}
+
+// SQSAPI is the subset of Amazon SQS used by SQSQueue.
+// It is satisfied by a thin wrapper around the AWS SDK, e.g. with aws-sdk-go-v2:
+//
+//	type sqsAPI struct{ client *sqs.Client }
+//
+//	func (a sqsAPI) SendMessage(ctx context.Context, queueURL, body string, delaySeconds int32, attributes map[string]string) error {
+//		values := map[string]types.MessageAttributeValue{}
+//		for name, value := range attributes {
+//			values[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+//		}
+//		_, err := a.client.SendMessage(ctx, &sqs.SendMessageInput{
+//			QueueUrl:          aws.String(queueURL),
+//			MessageBody:       aws.String(body),
+//			DelaySeconds:      delaySeconds,
+//			MessageAttributes: values,
+//		})
+//		return err
+//	}
+//
+//	func (a sqsAPI) ReceiveMessages(ctx context.Context, queueURL string, waitSeconds, visibilityTimeout int32) ([]webpushd.SQSMessage, error) {
+//		out, err := a.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+//			QueueUrl:              aws.String(queueURL),
+//			MaxNumberOfMessages:   10,
+//			WaitTimeSeconds:       waitSeconds,
+//			VisibilityTimeout:     visibilityTimeout,
+//			MessageAttributeNames: []string{"All"},
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		messages := make([]webpushd.SQSMessage, len(out.Messages))
+//		for i, m := range out.Messages {
+//			attributes := map[string]string{}
+//			for name, value := range m.MessageAttributes {
+//				attributes[name] = aws.ToString(value.StringValue)
+//			}
+//			messages[i] = webpushd.SQSMessage{MessageID: aws.ToString(m.MessageId),
+//				ReceiptHandle: aws.ToString(m.ReceiptHandle), Body: aws.ToString(m.Body), Attributes: attributes}
+//		}
+//		return messages, nil
+//	}
+//
+//	func (a sqsAPI) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+//		_, err := a.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: aws.String(queueURL), ReceiptHandle: aws.String(receiptHandle)})
+//		return err
+//	}
+//
+//	func (a sqsAPI) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, visibilityTimeout int32) error {
+//		_, err := a.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+//			QueueUrl: aws.String(queueURL), ReceiptHandle: aws.String(receiptHandle), VisibilityTimeout: visibilityTimeout})
+//		return err
+//	}
+type SQSAPI interface {
+	// SendMessage sends a message with string attributes, invisible for delaySeconds
+	SendMessage(ctx context.Context, queueURL, body string, delaySeconds int32, attributes map[string]string) error
+	// ReceiveMessages long polls for up to waitSeconds and leases the received messages for visibilityTimeout seconds
+	ReceiveMessages(ctx context.Context, queueURL string, waitSeconds, visibilityTimeout int32) ([]SQSMessage, error)
+	// DeleteMessage deletes a received message
+	DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error
+	// ChangeMessageVisibility makes a received message invisible for visibilityTimeout more seconds
+	ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, visibilityTimeout int32) error
+}
+
+// SQSQueue is a Queue of an Amazon SQS queue. The attempts of a message are kept in its attributes,
+// delays beyond the 15 minutes of SQS are served by extending the visibility of the message when it
+// is received early. Configure a redrive policy to dead-letter messages leased too often, e.g. because
+// their workers crashed: a message whose lease expires is leased again without counting an attempt.
+type SQSQueue struct {
+	api        SQSAPI
+	queueURL   string
+	visibility time.Duration
+	now        func() time.Time
+
+	mu       sync.Mutex
+	received []SQSMessage // Received but not leased yet
+}
+
+// NewSQSQueue returns an SQSQueue of queueURL leasing messages for visibility, DefaultVisibility when zero
+func NewSQSQueue(api SQSAPI, queueURL string, visibility time.Duration) *SQSQueue {
+	if visibility <= 0 {
+		visibility = DefaultVisibility
+	}
+	if visibility > sqsMaxVisibility {
+		visibility = sqsMaxVisibility
+	}
+
+	return &SQSQueue{api: api, queueURL: queueURL, visibility: visibility, now: time.Now}
+}
+
+// Enqueue implements Queue
+func (q *SQSQueue) Enqueue(ctx context.Context, body []byte, delay time.Duration) error {
+	return q.send(ctx, string(body), 0, delay)
+}
+
+// send sends a message of attempts, due after delay
+func (q *SQSQueue) send(ctx context.Context, body string, attempts int, delay time.Duration) error {
+	attributes := map[string]string{sqsAttempts: strconv.Itoa(attempts)}
+	if delay > sqsMaxDelay {
+		attributes[sqsNotBefore] = strconv.FormatInt(q.now().Add(delay).UnixMilli(), 10)
+		delay = sqsMaxDelay
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	return q.api.SendMessage(ctx, q.queueURL, body, int32(delay/time.Second), attributes)
+}
+
+// Lease implements Queue
+func (q *SQSQueue) Lease(ctx context.Context) (*Message, error) {
+	for {
+		m, err := q.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Received before it is due, keep it invisible until then
+		if notBefore, err := strconv.ParseInt(m.Attributes[sqsNotBefore], 10, 64); err == nil {
+			if wait := time.UnixMilli(notBefore).Sub(q.now()); wait > 0 {
+				if wait > sqsMaxVisibility {
+					wait = sqsMaxVisibility
+				}
+				if err := q.api.ChangeMessageVisibility(ctx, q.queueURL, m.ReceiptHandle, int32((wait+time.Second-1)/time.Second)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		attempts, _ := strconv.Atoi(m.Attributes[sqsAttempts])
+		if attempts < 0 {
+			attempts = 0
+		}
+
+		return &Message{ID: m.MessageID, Receipt: m.ReceiptHandle, Body: []byte(m.Body), Attempts: attempts + 1}, nil
+	}
+}
+
+// next returns a received message, receiving more when none is left
+func (q *SQSQueue) next(ctx context.Context) (SQSMessage, error) {
+	for {
+		q.mu.Lock()
+		if len(q.received) > 0 {
+			m := q.received[0]
+			q.received = q.received[1:]
+			q.mu.Unlock()
+			return m, nil
+		}
+		q.mu.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			return SQSMessage{}, err
+		}
+
+		received, err := q.api.ReceiveMessages(ctx, q.queueURL, sqsWait, int32(q.visibility/time.Second))
+		if err != nil {
+			if ctx.Err() != nil {
+				return SQSMessage{}, ctx.Err()
+			}
+			return SQSMessage{}, err
+		}
+
+		q.mu.Lock()
+		q.received = append(q.received, received...)
+		q.mu.Unlock()
+	}
+}
+
+// Ack implements Queue
+func (q *SQSQueue) Ack(ctx context.Context, m *Message) error {
+	return q.api.DeleteMessage(ctx, q.queueURL, m.Receipt)
+}
+
+// Nack implements Queue by sending a copy of the message and deleting the leased one
+func (q *SQSQueue) Nack(ctx context.Context, m *Message, delay time.Duration) error {
+	if err := q.send(ctx, string(m.Body), m.Attempts, delay); err != nil {
+		return err
+	}
+
+	return q.api.DeleteMessage(ctx, q.queueURL, m.Receipt)
+}
@@ -0,0 +1,108 @@
+package webpushd
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSQS is an SQSAPI keeping messages in memory, ignoring delays and visibility
+type fakeSQS struct {
+	mu         sync.Mutex
+	next       int
+	messages   []SQSMessage
+	delays     []int32
+	deleted    []string
+	visibility map[string]int32
+}
+
+func (f *fakeSQS) SendMessage(_ context.Context, _, body string, delaySeconds int32, attributes map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	id := strconv.Itoa(f.next)
+	f.messages = append(f.messages, SQSMessage{MessageID: id, ReceiptHandle: "receipt-" + id, Body: body, Attributes: attributes})
+	f.delays = append(f.delays, delaySeconds)
+	return nil
+}
+
+func (f *fakeSQS) ReceiveMessages(ctx context.Context, _ string, _, _ int32) ([]SQSMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.messages) == 0 {
+		return nil, ctx.Err()
+	}
+	received := f.messages
+	f.messages = nil
+	return received, nil
+}
+
+func (f *fakeSQS) DeleteMessage(_ context.Context, _, receiptHandle string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, receiptHandle)
+	return nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibility(_ context.Context, _, receiptHandle string, visibilityTimeout int32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.visibility == nil {
+		f.visibility = map[string]int32{}
+	}
+	f.visibility[receiptHandle] = visibilityTimeout
+	return nil
+}
+
+func TestSQSQueue(t *testing.T) {
+	api := &fakeSQS{}
+	now := time.Now()
+	queue := NewSQSQueue(api, "https://sqs.example.com/jobs", 0)
+	queue.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := queue.Enqueue(ctx, []byte("later"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Enqueue(ctx, []byte("now"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if api.delays[0] != 900 || api.delays[1] != 0 {
+		t.Fatalf("Expected the delay to be capped at 15 minutes, got %v", api.delays)
+	}
+
+	// The message due later is received early and kept invisible
+	m, err := queue.Lease(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Body) != "now" || m.Attempts != 1 || m.Receipt != "receipt-2" {
+		t.Fatalf("Expected the message due now, got %+v", m)
+	}
+	if api.visibility["receipt-1"] != 3600 {
+		t.Fatalf("Expected the early message to stay invisible for an hour, got %v", api.visibility)
+	}
+
+	if err := queue.Nack(ctx, m, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.deleted) != 1 || api.deleted[0] != "receipt-2" || api.delays[2] != 60 {
+		t.Fatalf("Expected the message to be resent with a delay, got deletes %v and delays %v", api.deleted, api.delays)
+	}
+
+	m, err = queue.Lease(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Body) != "now" || m.Attempts != 2 {
+		t.Fatalf("Expected the returned message to count its attempt, got %+v", m)
+	}
+	if err := queue.Ack(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.deleted) != 2 || api.deleted[1] != "receipt-3" {
+		t.Fatalf("Expected the message to be deleted, got %v", api.deleted)
+	}
+}
@@ -0,0 +1,246 @@
+// Package webpushd runs a webpush.Client as a push worker: a Daemon consumes send jobs from a Queue,
+// sends them with a pool of workers, retries throttled and failed sends and publishes a Result for
+// every job to a Publisher. Jobs are leased and acknowledged once settled, so scheduled sends,
+// retries and dead letters survive restarts: RedisQueue keeps them in a Redis stream and SQSQueue in
+// an Amazon SQS queue. A Scheduler enqueues the jobs of recurring broadcasts, e.g. daily digests, at
+// the times of their cron schedules. The webpushd command runs a Daemon of a RedisQueue as a standalone
+// deployable worker.
+//
+// It is a separate module so the root package doesn't depend on a Redis client.
+package webpushd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+const (
+	// DefaultConcurrency is the number of workers of a Daemon by default
+	DefaultConcurrency = 32
+
+	// DefaultRetries is the number of times a Daemon resends a throttled or failed job by default
+	DefaultRetries = 3
+
+	// DefaultBackoff is the wait before the first resend by default, doubling for each further one
+	DefaultBackoff = time.Second
+
+	// MaxBackoff bounds the wait before a resend, including a Retry-After of the push service
+	MaxBackoff = 5 * time.Minute
+)
+
+// Job is a notification to send, in the JSON consumed from a Queue:
+//
+//	{"id": "42", "subscription": {"endpoint": "...", "keys": {...}}, "payload": "Hello", "ttl": 3600}
+type Job struct {
+	ID           string               `json:"id"` // Identifies the job in its Result, chosen by the producer
+	Subscription webpush.Subscription `json:"subscription"`
+	Payload      string               `json:"payload"`
+	TTL          int                  `json:"ttl"`
+	Urgency      webpush.Urgency      `json:"urgency,omitempty"`
+	Topic        string               `json:"topic,omitempty"`
+	DedupeKey    string               `json:"dedupeKey,omitempty"` // Of the duplicate suppression of the Client, the payload by default
+}
+
+// Result reports the outcome of a Job, in the JSON published to a Queue
+type Result struct {
+	ID            string             `json:"id"`
+	StatusCode    int                `json:"statusCode,omitempty"` // Of the last response of the push service
+	Class         webpush.ErrorClass `json:"class,omitempty"`      // Empty when the job was delivered
+	Gone          bool               `json:"gone,omitempty"`       // The subscription should be deleted
+	Attempts      int                `json:"attempts"`
+	CorrelationID string             `json:"correlationId,omitempty"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// Options configure a Daemon
+type Options struct {
+	Concurrency  int                  // Workers sending jobs at once, DefaultConcurrency when zero
+	Retries      int                  // Resends of a throttled or failed job, DefaultRetries when zero, none when negative
+	Backoff      time.Duration        // Delay of the first resend, DefaultBackoff when zero
+	AllowedHosts []string             // Passed to Subscription.Validate, e.g. the hosts of private push gateways
+	DeadLetter   Queue                // Receives the jobs that failed for good, e.g. to inspect them later (Optional)
+	RetryBudget  *webpush.RetryBudget // Caps the resends, shared with the Client by WithRetryBudget so its sends count (Optional)
+	Logger       webpush.Logger       // Receives the queue failures of jobs, nothing is logged when nil
+}
+
+// Daemon sends the jobs of a Queue with a Client
+type Daemon struct {
+	client  *webpush.Client
+	queue   Queue
+	results Publisher
+	options Options
+}
+
+// NewDaemon returns a Daemon sending the jobs of queue with client and publishing their results to results
+func NewDaemon(client *webpush.Client, queue Queue, results Publisher, options Options) *Daemon {
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+
+	if options.Retries == 0 {
+		options.Retries = DefaultRetries
+	} else if options.Retries < 0 {
+		options.Retries = 0
+	}
+
+	if options.Backoff <= 0 {
+		options.Backoff = DefaultBackoff
+	}
+
+	return &Daemon{client: client, queue: queue, results: results, options: options}
+}
+
+// Run leases and sends jobs until ctx is done or the Queue fails. The jobs in flight when ctx ends
+// are finished, acknowledged and published before Run returns; it returns nil when stopped by ctx.
+func (d *Daemon) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var failure error
+	for i := 0; i < d.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				m, err := d.queue.Lease(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						once.Do(func() { failure = err })
+						cancel()
+					}
+					return
+				}
+
+				d.handle(context.WithoutCancel(ctx), m)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return failure
+}
+
+// handle sends the job of m and settles m: after a retryable failure it is returned to the queue with
+// a backoff, otherwise its result is published and it is acknowledged
+func (d *Daemon) handle(ctx context.Context, m *Message) {
+	result, retry := d.process(ctx, m)
+	result.Attempts = m.Attempts
+
+	if retry > 0 && m.Attempts <= d.options.Retries && d.withinBudget(result) {
+		if err := d.queue.Nack(ctx, m, retry); err != nil {
+			d.logError("webpushd: returning a job to the queue failed", result, err)
+		}
+		return
+	}
+
+	if err := d.results.Publish(ctx, result); err != nil {
+		d.logError("webpushd: publishing a result failed", result, err)
+	}
+
+	// Duplicates were delivered before
+	if result.Class != "" && !result.Gone && result.Class != webpush.ErrorClassDuplicate && d.options.DeadLetter != nil {
+		if err := d.options.DeadLetter.Enqueue(ctx, m.Body, 0); err != nil {
+			d.logError("webpushd: dead-lettering a job failed", result, err)
+			return
+		}
+	}
+
+	if err := d.queue.Ack(ctx, m); err != nil {
+		d.logError("webpushd: acknowledging a job failed", result, err)
+	}
+}
+
+// withinBudget reports whether the RetryBudget, if any, allows resending the job of result
+func (d *Daemon) withinBudget(result *Result) bool {
+	if d.options.RetryBudget == nil || d.options.RetryBudget.Retry() {
+		return true
+	}
+
+	if d.options.Logger != nil {
+		d.options.Logger.Warn("webpushd: retry budget exhausted, giving up on the job", "id", result.ID, "class", string(result.Class))
+	}
+	return false
+}
+
+func (d *Daemon) logError(msg string, result *Result, err error) {
+	if d.options.Logger != nil {
+		d.options.Logger.Error(msg, "id", result.ID, "class", string(result.Class), "error", err)
+	}
+}
+
+// process sends the job of m once, returning its result and the delay before a resend, zero when the
+// failure isn't retryable
+func (d *Daemon) process(ctx context.Context, m *Message) (*Result, time.Duration) {
+	var job Job
+	if err := json.Unmarshal(m.Body, &job); err != nil {
+		return &Result{Class: webpush.ErrorClassClient, Error: "invalid job: " + err.Error()}, 0
+	}
+
+	result := &Result{ID: job.ID}
+	if err := job.Subscription.Validate(d.options.AllowedHosts...); err != nil {
+		result.Class, result.Error = webpush.ErrorClassClient, "invalid subscription: "+err.Error()
+		return result, 0
+	}
+
+	sent, err := d.client.Deliver(ctx, []byte(job.Payload), &job.Subscription, &webpush.Options{
+		TTL:       job.TTL,
+		Urgency:   job.Urgency,
+		Topic:     job.Topic,
+		DedupeKey: job.DedupeKey,
+	})
+
+	var resp *http.Response
+	if sent != nil {
+		resp = sent.Response
+		result.CorrelationID = sent.CorrelationID
+	}
+	if resp != nil {
+		resp.Body.Close()
+		result.StatusCode = resp.StatusCode
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Class = webpush.ClassifySend(resp, err)
+	result.Gone = result.Class == webpush.ErrorClassGone
+
+	if !retryable(result.Class, err) {
+		return result, 0
+	}
+
+	// The backoff doubles with every attempt, a Retry-After of the push service takes precedence
+	wait := d.options.Backoff << uint(m.Attempts-1)
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+	}
+	if wait <= 0 || wait > MaxBackoff {
+		wait = MaxBackoff
+	}
+
+	return result, wait
+}
+
+// retryable reports whether a send of class failing with err may succeed when resent
+func retryable(class webpush.ErrorClass, err error) bool {
+	if errors.Is(err, webpush.ErrFrequencyCapped) || errors.Is(err, webpush.ErrMaxPadExceeded) {
+		return false
+	}
+
+	switch class {
+	case webpush.ErrorClassThrottled, webpush.ErrorClassServer, webpush.ErrorClassTimeout, webpush.ErrorClassTransport, webpush.ErrorClassCircuitOpen:
+		return true
+	}
+
+	return false
+}
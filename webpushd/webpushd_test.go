@@ -0,0 +1,348 @@
+package webpushd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushtest"
+)
+
+// memoryQueue is a Queue and Publisher recording how the messages are settled
+type memoryQueue struct {
+	mu       sync.Mutex
+	enqueued [][]byte
+	acked    []*Message
+	nacked   []time.Duration
+	results  []*Result
+}
+
+func (q *memoryQueue) Enqueue(_ context.Context, body []byte, _ time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.enqueued = append(q.enqueued, body)
+	return nil
+}
+
+func (q *memoryQueue) Lease(ctx context.Context) (*Message, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (q *memoryQueue) Ack(_ context.Context, m *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked = append(q.acked, m)
+	return nil
+}
+
+func (q *memoryQueue) Nack(_ context.Context, _ *Message, delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nacked = append(q.nacked, delay)
+	return nil
+}
+
+func (q *memoryQueue) Publish(_ context.Context, result *Result) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.results = append(q.results, result)
+	return nil
+}
+
+func newTestClient(t *testing.T, push *webpushtest.Server) *webpush.Client {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		webpush.WithSubscriber("ops@example.com"), webpush.WithHTTPClient(push.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestDaemon(t *testing.T) {
+	push := webpushtest.NewServer()
+	defer push.Close()
+	client := newTestClient(t, push)
+
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := rdb.Subscribe(ctx, "results")
+	defer results.Close()
+	if _, err := results.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	now := time.Now()
+	queue := NewRedisQueue(rdb, "jobs", RedisOptions{})
+	queue.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	u, _ := url.Parse(push.URL)
+	daemon := NewDaemon(client, queue, NewRedisPublisher(rdb, "results"), Options{Concurrency: 4, AllowedHosts: []string{u.Hostname()}})
+
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+
+	// The first job is throttled once, the second is gone
+	push.Script(webpushtest.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"7"}}})
+	subscription := push.NewSubscription()
+	gone := push.NewSubscription()
+	push.Unsubscribe(gone)
+
+	for i, job := range []interface{}{
+		Job{ID: "0", Subscription: *subscription, Payload: "Hello", TTL: 60},
+		Job{ID: "1", Subscription: *gone, Payload: "Hello", TTL: 60},
+		"not a job",
+	} {
+		encoded, _ := json.Marshal(job)
+		if err := queue.Enqueue(ctx, encoded, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 0 {
+			// The throttled job waits for Retry-After in the delayed set
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				scores, err := rdb.ZRangeWithScores(ctx, "jobs:delayed", 0, -1).Result()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(scores) == 1 {
+					if due := time.UnixMilli(int64(scores[0].Score)); !due.Equal(now.Add(7 * time.Second).Truncate(time.Millisecond)) {
+						t.Fatalf("Expected to wait for Retry-After, due at %v", due)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Throttled job wasn't delayed")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			mu.Lock()
+			now = now.Add(7 * time.Second)
+			mu.Unlock()
+		}
+
+		// Keep the jobs in order to match the script
+		message, err := results.ReceiveMessage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result Result
+		if err := json.Unmarshal([]byte(message.Payload), &result); err != nil {
+			t.Fatal(err)
+		}
+
+		switch i {
+		case 0:
+			if result.ID != "0" || result.StatusCode != http.StatusCreated || result.Class != "" || result.Attempts != 2 {
+				t.Fatalf("Expected a delivery after a retry, got %+v", result)
+			}
+		case 1:
+			if result.ID != "1" || !result.Gone || result.Attempts != 1 {
+				t.Fatalf("Expected a gone subscription, got %+v", result)
+			}
+		case 2:
+			if result.Class != webpush.ErrorClassClient || result.Error == "" {
+				t.Fatalf("Expected an invalid job, got %+v", result)
+			}
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// Every job was acknowledged
+	if pending, err := rdb.XLen(context.Background(), "jobs").Result(); err != nil || pending != 0 {
+		t.Fatalf("Expected an empty stream, got %d (%v)", pending, err)
+	}
+}
+
+func TestDaemonRetries(t *testing.T) {
+	push := webpushtest.NewServer()
+	defer push.Close()
+	client := newTestClient(t, push)
+
+	u, _ := url.Parse(push.URL)
+	queue, deadLetter := &memoryQueue{}, &memoryQueue{}
+	daemon := NewDaemon(client, queue, queue, Options{Retries: 2, Backoff: time.Second, AllowedHosts: []string{u.Hostname()}, DeadLetter: deadLetter})
+
+	push.Script(webpushtest.Status(http.StatusServiceUnavailable), webpushtest.Status(http.StatusServiceUnavailable),
+		webpushtest.Status(http.StatusServiceUnavailable))
+	encoded, _ := json.Marshal(Job{ID: "1", Subscription: *push.NewSubscription(), Payload: "Hello", TTL: 60})
+	for attempts := 1; attempts <= 3; attempts++ {
+		daemon.handle(context.Background(), &Message{ID: "1", Body: encoded, Attempts: attempts})
+	}
+
+	if len(queue.nacked) != 2 || queue.nacked[0] != time.Second || queue.nacked[1] != 2*time.Second {
+		t.Fatalf("Expected exponential backoff, got %v", queue.nacked)
+	}
+	if len(queue.results) != 1 || queue.results[0].Class != webpush.ErrorClassServer || queue.results[0].Attempts != 3 {
+		t.Fatalf("Expected to give up after 2 retries, got %+v", queue.results)
+	}
+	if len(queue.acked) != 1 || len(deadLetter.enqueued) != 1 || string(deadLetter.enqueued[0]) != string(encoded) {
+		t.Fatalf("Expected the job to be dead-lettered, got %d acks and %d dead letters", len(queue.acked), len(deadLetter.enqueued))
+	}
+}
+
+func TestRedisQueue(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	queue := NewRedisQueue(rdb, "jobs", RedisOptions{Consumer: "a", Visibility: time.Minute})
+	queue.now = func() time.Time { return now }
+
+	if err := queue.Enqueue(ctx, []byte("later"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.Enqueue(ctx, []byte("now"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := queue.Lease(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Body) != "now" || m.Attempts != 1 {
+		t.Fatalf("Expected the message due now, got %q after %d attempts", m.Body, m.Attempts)
+	}
+
+	// The lease of a crashed worker expires
+	server.SetTime(time.Now().Add(2 * time.Minute))
+	other := NewRedisQueue(rdb, "jobs", RedisOptions{Consumer: "b", Visibility: time.Minute})
+	other.now = queue.now
+	reclaimed, err := other.Lease(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed.ID != m.ID || reclaimed.Attempts != 1 {
+		t.Fatalf("Expected the expired lease to be reclaimed, got %+v", reclaimed)
+	}
+
+	if err := other.Nack(ctx, reclaimed, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both messages are due
+	now = now.Add(time.Hour)
+	leased := map[string]int{}
+	for i := 0; i < 2; i++ {
+		m, err := queue.Lease(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leased[string(m.Body)] = m.Attempts
+		if err := queue.Ack(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if leased["now"] != 2 || leased["later"] != 1 {
+		t.Fatalf("Expected the returned message to count its attempt, got %v", leased)
+	}
+
+	if n, err := rdb.XLen(ctx, "jobs").Result(); err != nil || n != 0 {
+		t.Fatalf("Expected an empty stream, got %d (%v)", n, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := queue.Lease(canceled); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDaemonRetryBudget(t *testing.T) {
+	push := webpushtest.NewServer()
+	defer push.Close()
+
+	budget, err := webpush.NewRetryBudget(0, 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(push.URL)
+	queue := &memoryQueue{}
+	daemon := NewDaemon(newTestClient(t, push), queue, queue, Options{AllowedHosts: []string{u.Hostname()}, RetryBudget: budget, DeadLetter: queue})
+
+	push.Script(webpushtest.Status(http.StatusServiceUnavailable))
+	encoded, _ := json.Marshal(Job{ID: "1", Subscription: *push.NewSubscription(), Payload: "Hello", TTL: 60})
+	daemon.handle(context.Background(), &Message{ID: "1", Body: encoded, Attempts: 1})
+
+	if len(queue.nacked) != 0 || len(queue.results) != 1 || len(queue.enqueued) != 1 || budget.Stats().Denied != 1 {
+		t.Fatalf("Expected the spent budget to fail the job, got %d resends and %d results", len(queue.nacked), len(queue.results))
+	}
+}
+
+func TestDaemonDuplicates(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+
+	push := webpushtest.NewServer()
+	defer push.Close()
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := NewRedisDuplicateTracker(rdb, "sent:")
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		webpush.WithSubscriber("ops@example.com"), webpush.WithHTTPClient(push.Client()),
+		webpush.WithDuplicateSuppression(tracker, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(push.URL)
+	queue, deadLetter := &memoryQueue{}, &memoryQueue{}
+	daemon := NewDaemon(client, queue, queue, Options{AllowedHosts: []string{u.Hostname()}, DeadLetter: deadLetter})
+
+	// The failed first attempt doesn't suppress the retry, the replayed job is suppressed
+	push.Script(webpushtest.Status(http.StatusServiceUnavailable))
+	encoded, _ := json.Marshal(Job{ID: "1", Subscription: *push.NewSubscription(), Payload: "Hello", TTL: 60, DedupeKey: "campaign-1"})
+	for attempts := 1; attempts <= 3; attempts++ {
+		daemon.handle(context.Background(), &Message{ID: "1", Body: encoded, Attempts: attempts})
+	}
+
+	if len(queue.nacked) != 1 || len(queue.results) != 2 || queue.results[0].Class != "" ||
+		queue.results[1].Class != webpush.ErrorClassDuplicate {
+		t.Fatalf("Expected a delivery and a suppressed duplicate, got %d resends and %+v", len(queue.nacked), queue.results)
+	}
+	if len(queue.acked) != 2 || len(deadLetter.enqueued) != 0 {
+		t.Fatalf("Expected the duplicate to be acknowledged, got %d acks and %d dead letters", len(queue.acked), len(deadLetter.enqueued))
+	}
+
+	server.FastForward(time.Hour)
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Fatalf("Expected the suppression window to expire, got %v", keys)
+	}
+}
@@ -0,0 +1,38 @@
+// Package webpushexpvar publishes the webpush VAPID cache, transport and delivery statistics through expvar.
+// It lives in its own package because importing expvar registers /debug/vars on
+// http.DefaultServeMux, which is left to the application to opt into.
+package webpushexpvar
+
+import (
+	"expvar"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// Publish exposes the shared VAPID header cache counters, the transport counters and sends in flight
+// of the package level functions, the per-audience signing stats, the cumulative send outcomes per
+// origin and the encryption stats as the expvar variable name, e.g. "webpush".
+// Like expvar.Publish it panics if name is already registered.
+func Publish(name string) {
+	publish(name, webpush.GetVAPIDCacheCounters, webpush.GetTransportStats, webpush.GetStats)
+}
+
+// PublishClient is Publish for the cache, transport and rolling stats of client
+func PublishClient(name string, client *webpush.Client) {
+	publish(name, client.VAPIDCacheStats, client.TransportStats, client.Stats)
+}
+
+func publish(name string, cacheStats func() webpush.VAPIDCacheStats, transportStats func() webpush.TransportStats, stats func() webpush.Stats) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		rolling := stats()
+		return map[string]interface{}{
+			"cache":      cacheStats(),
+			"signing":    webpush.GetSigningStats(),
+			"transport":  transportStats(),
+			"delivery":   webpush.GetDeliveryStats(),
+			"encryption": webpush.GetEncryptionStats(),
+			"origins":    rolling.Origins,
+			"errors":     rolling.Errors,
+		}
+	}))
+}
@@ -0,0 +1,43 @@
+package webpushexpvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	Publish("webpush_test")
+
+	v := expvar.Get("webpush_test")
+	if v == nil {
+		t.Fatal("Variable was not published")
+	}
+
+	var stats struct {
+		Cache struct {
+			Hits   uint64
+			Misses uint64
+		} `json:"cache"`
+		Transport struct {
+			InFlight int64
+		} `json:"transport"`
+		Delivery map[string]struct {
+			Responses [5]uint64
+		} `json:"delivery"`
+	}
+	if err := json.Unmarshal([]byte(v.String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(v.String()), &sections); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"transport", "delivery", "encryption", "origins"} {
+		if _, ok := sections[key]; !ok {
+			t.Fatalf("Missing %q, got %s", key, v.String())
+		}
+	}
+}
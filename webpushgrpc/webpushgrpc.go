@@ -0,0 +1,189 @@
+// Package webpushgrpc serves the WebPush gRPC service of webpushpb/webpush.proto with a webpush.Client,
+// so backends in other languages can send through the pipeline of this library:
+//
+//	server := grpc.NewServer()
+//	webpushpb.RegisterWebPushServer(server, webpushgrpc.NewServer(client, webpushgrpc.Options{}))
+//
+// It is a separate module so the root package doesn't depend on gRPC.
+package webpushgrpc
+
+//go:generate protoc -I webpushpb --go_out=webpushpb --go_opt=paths=source_relative --go-grpc_out=webpushpb --go-grpc_opt=paths=source_relative webpush.proto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushgrpc/webpushpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultConcurrency is the number of notifications of a SendBatch stream sent at once by default
+const DefaultConcurrency = 32
+
+// Options configure a Server
+type Options struct {
+	AllowedHosts []string // Passed to Subscription.Validate, e.g. the hosts of private push gateways
+	Concurrency  int      // Notifications of a SendBatch stream sent at once, DefaultConcurrency when zero
+}
+
+// Server implements webpushpb.WebPushServer
+type Server struct {
+	webpushpb.UnimplementedWebPushServer
+
+	client  *webpush.Client
+	options Options
+}
+
+// NewServer returns a Server sending through client
+func NewServer(client *webpush.Client, options Options) *Server {
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+
+	return &Server{client: client, options: options}
+}
+
+// Send implements webpushpb.WebPushServer
+func (s *Server) Send(ctx context.Context, req *webpushpb.SendRequest) (*webpushpb.SendResponse, error) {
+	return s.send(ctx, req)
+}
+
+// SendBatch implements webpushpb.WebPushServer
+func (s *Server) SendBatch(stream webpushpb.WebPush_SendBatchServer) error {
+	ctx := stream.Context()
+	semaphore := make(chan struct{}, s.options.Concurrency)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var streamErr error
+	respond := func(resp *webpushpb.SendResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if streamErr == nil {
+			streamErr = stream.Send(resp)
+		}
+	}
+	// Once a response couldn't be sent, the results of the next notifications can't reach the client
+	// either, so they aren't sent
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return streamErr
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		if err := failed(); err != nil {
+			<-semaphore
+			wg.Wait()
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if failed() != nil {
+				return
+			}
+
+			resp, err := s.send(ctx, req)
+			if err != nil {
+				resp = &webpushpb.SendResponse{Id: req.GetId(), Error: status.Convert(err).Message()}
+			}
+			respond(resp)
+		}()
+	}
+
+	wg.Wait()
+	return streamErr
+}
+
+// GenerateKeys implements webpushpb.WebPushServer
+func (s *Server) GenerateKeys(context.Context, *webpushpb.GenerateKeysRequest) (*webpushpb.GenerateKeysResponse, error) {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &webpushpb.GenerateKeysResponse{PrivateKey: privateKey, PublicKey: publicKey}, nil
+}
+
+// send sends req, failing with the gRPC status of the problem
+func (s *Server) send(ctx context.Context, req *webpushpb.SendRequest) (*webpushpb.SendResponse, error) {
+	subscription := &webpush.Subscription{
+		Endpoint: req.GetSubscription().GetEndpoint(),
+		Keys:     webpush.Keys{P256dh: req.GetSubscription().GetP256Dh(), Auth: req.GetSubscription().GetAuth()},
+	}
+	if err := subscription.Validate(s.options.AllowedHosts...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid subscription: "+err.Error())
+	}
+
+	if req.GetTtl() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must not be negative")
+	}
+
+	urgency := webpush.Urgency(req.GetUrgency())
+	switch urgency {
+	case "", webpush.UrgencyVeryLow, webpush.UrgencyLow, webpush.UrgencyNormal, webpush.UrgencyHigh:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "urgency must be very-low, low, normal or high")
+	}
+
+	result, err := s.client.Deliver(ctx, req.GetPayload(), subscription, &webpush.Options{
+		TTL:     int(req.GetTtl()),
+		Urgency: urgency,
+		Topic:   req.GetTopic(),
+	})
+	if result != nil && result.Response != nil {
+		result.Response.Body.Close()
+	}
+
+	switch {
+	case errors.Is(err, webpush.ErrMaxPadExceeded):
+		return nil, status.Error(codes.InvalidArgument, "payload too large")
+	case errors.Is(err, webpush.ErrFrequencyCapped):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return nil, status.FromContextError(err).Err()
+	case err != nil:
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	resp := result.Response
+	response := &webpushpb.SendResponse{
+		Id:            req.GetId(),
+		StatusCode:    int32(resp.StatusCode),
+		Gone:          resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone,
+		CorrelationId: result.CorrelationID,
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		response.RetryAfterSeconds = int32(seconds)
+	}
+
+	return response, nil
+}
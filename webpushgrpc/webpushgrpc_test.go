@@ -0,0 +1,158 @@
+package webpushgrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushgrpc/webpushpb"
+	"github.com/SherClockHolmes/webpush-go/webpushtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	push := webpushtest.NewServer()
+	defer push.Close()
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		webpush.WithSubscriber("ops@example.com"), webpush.WithHTTPClient(push.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(push.URL)
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	webpushpb.RegisterWebPushServer(server, NewServer(client, Options{AllowedHosts: []string{u.Hostname()}}))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rpc := webpushpb.NewWebPushClient(conn)
+	ctx := context.Background()
+
+	subscription := func() *webpushpb.Subscription {
+		s := push.NewSubscription()
+		return &webpushpb.Subscription{Endpoint: s.Endpoint, P256Dh: s.Keys.P256dh, Auth: s.Keys.Auth}
+	}
+
+	resp, err := rpc.Send(ctx, &webpushpb.SendRequest{Id: "1", Subscription: subscription(), Payload: []byte("Hello"), Ttl: 60, Urgency: "high"})
+	if err != nil || resp.GetId() != "1" || resp.GetStatusCode() != http.StatusCreated {
+		t.Fatalf("Incorrect response, got %v (%v)", resp, err)
+	}
+	if notifications := push.Notifications(); len(notifications) != 1 || string(notifications[0].Payload) != "Hello" || notifications[0].Urgency != "high" {
+		t.Fatalf("Incorrect notification, got %+v", notifications)
+	}
+
+	_, err = rpc.Send(ctx, &webpushpb.SendRequest{Subscription: &webpushpb.Subscription{Endpoint: "https://internal.example.com/"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", codes.InvalidArgument, err)
+	}
+
+	stream, err := rpc.SendBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		req := &webpushpb.SendRequest{Id: strconv.Itoa(i), Subscription: subscription(), Payload: []byte("Batch"), Ttl: 60}
+		if i == 3 {
+			req.Urgency = "urgent"
+		}
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(map[string]*webpushpb.SendResponse)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		responses[resp.GetId()] = resp
+	}
+	if len(responses) != 10 || responses["3"].GetError() == "" || responses["4"].GetStatusCode() != http.StatusCreated {
+		t.Fatalf("Incorrect batch responses, got %v", responses)
+	}
+
+	keys, err := rpc.GenerateKeys(ctx, &webpushpb.GenerateKeysRequest{})
+	if err != nil || keys.GetPrivateKey() == "" || keys.GetPublicKey() == "" {
+		t.Fatalf("Incorrect keys, got %v (%v)", keys, err)
+	}
+}
+
+// brokenStream is a SendBatch stream whose responses can't be sent
+type brokenStream struct {
+	grpc.ServerStream
+	requests []*webpushpb.SendRequest
+}
+
+func (s *brokenStream) Context() context.Context { return context.Background() }
+
+func (s *brokenStream) Send(*webpushpb.SendResponse) error { return io.ErrClosedPipe }
+
+func (s *brokenStream) Recv() (*webpushpb.SendRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
+
+func TestSendBatchStopsAfterStreamError(t *testing.T) {
+	push := webpushtest.NewServer()
+	defer push.Close()
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		webpush.WithSubscriber("ops@example.com"), webpush.WithHTTPClient(push.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(push.URL)
+	stream := &brokenStream{}
+	for i := 0; i < 10; i++ {
+		s := push.NewSubscription()
+		stream.requests = append(stream.requests, &webpushpb.SendRequest{Id: strconv.Itoa(i), Payload: []byte("Batch"), Ttl: 60,
+			Subscription: &webpushpb.Subscription{Endpoint: s.Endpoint, P256Dh: s.Keys.P256dh, Auth: s.Keys.Auth}})
+	}
+
+	server := NewServer(client, Options{AllowedHosts: []string{u.Hostname()}, Concurrency: 1})
+	if err := server.SendBatch(stream); err != io.ErrClosedPipe {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", io.ErrClosedPipe, err)
+	}
+
+	// The notifications after the failed response are never sent
+	if notifications := push.Notifications(); len(notifications) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(notifications))
+	}
+}
@@ -0,0 +1,443 @@
+// The WebPush service sends web push notifications through a webpush-go Client, for backends not written in Go.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: webpush.proto
+
+package webpushpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Subscription is the PushSubscription of a browser.
+type Subscription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Endpoint      string                 `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	P256Dh        string                 `protobuf:"bytes,2,opt,name=p256dh,proto3" json:"p256dh,omitempty"`
+	Auth          string                 `protobuf:"bytes,3,opt,name=auth,proto3" json:"auth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subscription) Reset() {
+	*x = Subscription{}
+	mi := &file_webpush_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subscription) ProtoMessage() {}
+
+func (x *Subscription) ProtoReflect() protoreflect.Message {
+	mi := &file_webpush_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subscription.ProtoReflect.Descriptor instead.
+func (*Subscription) Descriptor() ([]byte, []int) {
+	return file_webpush_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Subscription) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Subscription) GetP256Dh() string {
+	if x != nil {
+		return x.P256Dh
+	}
+	return ""
+}
+
+func (x *Subscription) GetAuth() string {
+	if x != nil {
+		return x.Auth
+	}
+	return ""
+}
+
+type SendRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the request in its response, chosen by the caller.
+	Id           string        `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Subscription *Subscription `protobuf:"bytes,2,opt,name=subscription,proto3" json:"subscription,omitempty"`
+	Payload      []byte        `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Ttl          int32         `protobuf:"varint,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// very-low, low, normal or high, normal when empty.
+	Urgency       string `protobuf:"bytes,5,opt,name=urgency,proto3" json:"urgency,omitempty"`
+	Topic         string `protobuf:"bytes,6,opt,name=topic,proto3" json:"topic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendRequest) Reset() {
+	*x = SendRequest{}
+	mi := &file_webpush_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendRequest) ProtoMessage() {}
+
+func (x *SendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_webpush_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendRequest.ProtoReflect.Descriptor instead.
+func (*SendRequest) Descriptor() ([]byte, []int) {
+	return file_webpush_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SendRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SendRequest) GetSubscription() *Subscription {
+	if x != nil {
+		return x.Subscription
+	}
+	return nil
+}
+
+func (x *SendRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *SendRequest) GetTtl() int32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *SendRequest) GetUrgency() string {
+	if x != nil {
+		return x.Urgency
+	}
+	return ""
+}
+
+func (x *SendRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+type SendResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Status of the push service, 0 when the request failed before getting a response.
+	StatusCode int32 `protobuf:"varint,2,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	// The subscription expired or was unsubscribed and should be deleted.
+	Gone bool `protobuf:"varint,3,opt,name=gone,proto3" json:"gone,omitempty"`
+	// Seconds to wait before resending, from the Retry-After header of the push service.
+	RetryAfterSeconds int32  `protobuf:"varint,4,opt,name=retry_after_seconds,json=retryAfterSeconds,proto3" json:"retry_after_seconds,omitempty"`
+	CorrelationId     string `protobuf:"bytes,5,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	// Why the notification wasn't sent, only set by SendBatch.
+	Error         string `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendResponse) Reset() {
+	*x = SendResponse{}
+	mi := &file_webpush_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendResponse) ProtoMessage() {}
+
+func (x *SendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_webpush_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendResponse.ProtoReflect.Descriptor instead.
+func (*SendResponse) Descriptor() ([]byte, []int) {
+	return file_webpush_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SendResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SendResponse) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *SendResponse) GetGone() bool {
+	if x != nil {
+		return x.Gone
+	}
+	return false
+}
+
+func (x *SendResponse) GetRetryAfterSeconds() int32 {
+	if x != nil {
+		return x.RetryAfterSeconds
+	}
+	return 0
+}
+
+func (x *SendResponse) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *SendResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GenerateKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateKeysRequest) Reset() {
+	*x = GenerateKeysRequest{}
+	mi := &file_webpush_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateKeysRequest) ProtoMessage() {}
+
+func (x *GenerateKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_webpush_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateKeysRequest.ProtoReflect.Descriptor instead.
+func (*GenerateKeysRequest) Descriptor() ([]byte, []int) {
+	return file_webpush_proto_rawDescGZIP(), []int{3}
+}
+
+type GenerateKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrivateKey    string                 `protobuf:"bytes,1,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateKeysResponse) Reset() {
+	*x = GenerateKeysResponse{}
+	mi := &file_webpush_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateKeysResponse) ProtoMessage() {}
+
+func (x *GenerateKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_webpush_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateKeysResponse.ProtoReflect.Descriptor instead.
+func (*GenerateKeysResponse) Descriptor() ([]byte, []int) {
+	return file_webpush_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GenerateKeysResponse) GetPrivateKey() string {
+	if x != nil {
+		return x.PrivateKey
+	}
+	return ""
+}
+
+func (x *GenerateKeysResponse) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+var File_webpush_proto protoreflect.FileDescriptor
+
+const file_webpush_proto_rawDesc = "" +
+	"\n" +
+	"\rwebpush.proto\x12\n" +
+	"webpush.v1\"V\n" +
+	"\fSubscription\x12\x1a\n" +
+	"\bendpoint\x18\x01 \x01(\tR\bendpoint\x12\x16\n" +
+	"\x06p256dh\x18\x02 \x01(\tR\x06p256dh\x12\x12\n" +
+	"\x04auth\x18\x03 \x01(\tR\x04auth\"\xb7\x01\n" +
+	"\vSendRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12<\n" +
+	"\fsubscription\x18\x02 \x01(\v2\x18.webpush.v1.SubscriptionR\fsubscription\x12\x18\n" +
+	"\apayload\x18\x03 \x01(\fR\apayload\x12\x10\n" +
+	"\x03ttl\x18\x04 \x01(\x05R\x03ttl\x12\x18\n" +
+	"\aurgency\x18\x05 \x01(\tR\aurgency\x12\x14\n" +
+	"\x05topic\x18\x06 \x01(\tR\x05topic\"\xc0\x01\n" +
+	"\fSendResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vstatus_code\x18\x02 \x01(\x05R\n" +
+	"statusCode\x12\x12\n" +
+	"\x04gone\x18\x03 \x01(\bR\x04gone\x12.\n" +
+	"\x13retry_after_seconds\x18\x04 \x01(\x05R\x11retryAfterSeconds\x12%\n" +
+	"\x0ecorrelation_id\x18\x05 \x01(\tR\rcorrelationId\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"\x15\n" +
+	"\x13GenerateKeysRequest\"V\n" +
+	"\x14GenerateKeysResponse\x12\x1f\n" +
+	"\vprivate_key\x18\x01 \x01(\tR\n" +
+	"privateKey\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey2\xdb\x01\n" +
+	"\aWebPush\x129\n" +
+	"\x04Send\x12\x17.webpush.v1.SendRequest\x1a\x18.webpush.v1.SendResponse\x12B\n" +
+	"\tSendBatch\x12\x17.webpush.v1.SendRequest\x1a\x18.webpush.v1.SendResponse(\x010\x01\x12Q\n" +
+	"\fGenerateKeys\x12\x1f.webpush.v1.GenerateKeysRequest\x1a .webpush.v1.GenerateKeysResponseBf\n" +
+	"%com.github.sherclockholmes.webpush.v1P\x01Z;github.com/SherClockHolmes/webpush-go/webpushgrpc/webpushpbb\x06proto3"
+
+var (
+	file_webpush_proto_rawDescOnce sync.Once
+	file_webpush_proto_rawDescData []byte
+)
+
+func file_webpush_proto_rawDescGZIP() []byte {
+	file_webpush_proto_rawDescOnce.Do(func() {
+		file_webpush_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_webpush_proto_rawDesc), len(file_webpush_proto_rawDesc)))
+	})
+	return file_webpush_proto_rawDescData
+}
+
+var file_webpush_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_webpush_proto_goTypes = []any{
+	(*Subscription)(nil),         // 0: webpush.v1.Subscription
+	(*SendRequest)(nil),          // 1: webpush.v1.SendRequest
+	(*SendResponse)(nil),         // 2: webpush.v1.SendResponse
+	(*GenerateKeysRequest)(nil),  // 3: webpush.v1.GenerateKeysRequest
+	(*GenerateKeysResponse)(nil), // 4: webpush.v1.GenerateKeysResponse
+}
+var file_webpush_proto_depIdxs = []int32{
+	0, // 0: webpush.v1.SendRequest.subscription:type_name -> webpush.v1.Subscription
+	1, // 1: webpush.v1.WebPush.Send:input_type -> webpush.v1.SendRequest
+	1, // 2: webpush.v1.WebPush.SendBatch:input_type -> webpush.v1.SendRequest
+	3, // 3: webpush.v1.WebPush.GenerateKeys:input_type -> webpush.v1.GenerateKeysRequest
+	2, // 4: webpush.v1.WebPush.Send:output_type -> webpush.v1.SendResponse
+	2, // 5: webpush.v1.WebPush.SendBatch:output_type -> webpush.v1.SendResponse
+	4, // 6: webpush.v1.WebPush.GenerateKeys:output_type -> webpush.v1.GenerateKeysResponse
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_webpush_proto_init() }
+func file_webpush_proto_init() {
+	if File_webpush_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_webpush_proto_rawDesc), len(file_webpush_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_webpush_proto_goTypes,
+		DependencyIndexes: file_webpush_proto_depIdxs,
+		MessageInfos:      file_webpush_proto_msgTypes,
+	}.Build()
+	File_webpush_proto = out.File
+	file_webpush_proto_goTypes = nil
+	file_webpush_proto_depIdxs = nil
+}
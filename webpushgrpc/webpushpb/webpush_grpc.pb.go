@@ -0,0 +1,206 @@
+// The WebPush service sends web push notifications through a webpush-go Client, for backends not written in Go.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: webpush.proto
+
+package webpushpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WebPush_Send_FullMethodName         = "/webpush.v1.WebPush/Send"
+	WebPush_SendBatch_FullMethodName    = "/webpush.v1.WebPush/SendBatch"
+	WebPush_GenerateKeys_FullMethodName = "/webpush.v1.WebPush/GenerateKeys"
+)
+
+// WebPushClient is the client API for WebPush service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WebPushClient interface {
+	// Send sends one notification. Invalid subscriptions fail with INVALID_ARGUMENT, frequency capped sends
+	// with RESOURCE_EXHAUSTED and unreachable push services with UNAVAILABLE; push service rejections are
+	// reported in the response.
+	Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error)
+	// SendBatch sends a stream of notifications concurrently and streams back a response for each, in
+	// completion order and matched by id. Failures are reported in SendResponse.error.
+	SendBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendRequest, SendResponse], error)
+	// GenerateKeys returns a new VAPID key pair.
+	GenerateKeys(ctx context.Context, in *GenerateKeysRequest, opts ...grpc.CallOption) (*GenerateKeysResponse, error)
+}
+
+type webPushClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWebPushClient(cc grpc.ClientConnInterface) WebPushClient {
+	return &webPushClient{cc}
+}
+
+func (c *webPushClient) Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendResponse)
+	err := c.cc.Invoke(ctx, WebPush_Send_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *webPushClient) SendBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendRequest, SendResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WebPush_ServiceDesc.Streams[0], WebPush_SendBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SendRequest, SendResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WebPush_SendBatchClient = grpc.BidiStreamingClient[SendRequest, SendResponse]
+
+func (c *webPushClient) GenerateKeys(ctx context.Context, in *GenerateKeysRequest, opts ...grpc.CallOption) (*GenerateKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateKeysResponse)
+	err := c.cc.Invoke(ctx, WebPush_GenerateKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WebPushServer is the server API for WebPush service.
+// All implementations must embed UnimplementedWebPushServer
+// for forward compatibility.
+type WebPushServer interface {
+	// Send sends one notification. Invalid subscriptions fail with INVALID_ARGUMENT, frequency capped sends
+	// with RESOURCE_EXHAUSTED and unreachable push services with UNAVAILABLE; push service rejections are
+	// reported in the response.
+	Send(context.Context, *SendRequest) (*SendResponse, error)
+	// SendBatch sends a stream of notifications concurrently and streams back a response for each, in
+	// completion order and matched by id. Failures are reported in SendResponse.error.
+	SendBatch(grpc.BidiStreamingServer[SendRequest, SendResponse]) error
+	// GenerateKeys returns a new VAPID key pair.
+	GenerateKeys(context.Context, *GenerateKeysRequest) (*GenerateKeysResponse, error)
+	mustEmbedUnimplementedWebPushServer()
+}
+
+// UnimplementedWebPushServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWebPushServer struct{}
+
+func (UnimplementedWebPushServer) Send(context.Context, *SendRequest) (*SendResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Send not implemented")
+}
+func (UnimplementedWebPushServer) SendBatch(grpc.BidiStreamingServer[SendRequest, SendResponse]) error {
+	return status.Error(codes.Unimplemented, "method SendBatch not implemented")
+}
+func (UnimplementedWebPushServer) GenerateKeys(context.Context, *GenerateKeysRequest) (*GenerateKeysResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GenerateKeys not implemented")
+}
+func (UnimplementedWebPushServer) mustEmbedUnimplementedWebPushServer() {}
+func (UnimplementedWebPushServer) testEmbeddedByValue()                 {}
+
+// UnsafeWebPushServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WebPushServer will
+// result in compilation errors.
+type UnsafeWebPushServer interface {
+	mustEmbedUnimplementedWebPushServer()
+}
+
+func RegisterWebPushServer(s grpc.ServiceRegistrar, srv WebPushServer) {
+	// If the following call panics, it indicates UnimplementedWebPushServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WebPush_ServiceDesc, srv)
+}
+
+func _WebPush_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebPushServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WebPush_Send_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebPushServer).Send(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebPush_SendBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WebPushServer).SendBatch(&grpc.GenericServerStream[SendRequest, SendResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WebPush_SendBatchServer = grpc.BidiStreamingServer[SendRequest, SendResponse]
+
+func _WebPush_GenerateKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebPushServer).GenerateKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WebPush_GenerateKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebPushServer).GenerateKeys(ctx, req.(*GenerateKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WebPush_ServiceDesc is the grpc.ServiceDesc for WebPush service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WebPush_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "webpush.v1.WebPush",
+	HandlerType: (*WebPushServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Send",
+			Handler:    _WebPush_Send_Handler,
+		},
+		{
+			MethodName: "GenerateKeys",
+			Handler:    _WebPush_GenerateKeys_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SendBatch",
+			Handler:       _WebPush_SendBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "webpush.proto",
+}
@@ -0,0 +1,19 @@
+module github.com/SherClockHolmes/webpush-go/webpushhttp3
+
+go 1.26.0
+
+require (
+	github.com/SherClockHolmes/webpush-go v0.0.0
+	github.com/quic-go/quic-go v0.63.0
+)
+
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
+	github.com/quic-go/qpack v0.6.0 // indirect
+	golang.org/x/crypto v0.54.0 // indirect
+	golang.org/x/net v0.56.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+)
+
+replace github.com/SherClockHolmes/webpush-go => ../
@@ -0,0 +1,238 @@
+// Package webpushhttp3 is an experimental HTTP/3 (QUIC) transport for webpush.
+// Requests go over HTTP/2 until a push service origin advertises HTTP/3 with an Alt-Svc header,
+// then over HTTP/3, falling back to HTTP/2 when a QUIC connection can't be set up. Requests failing
+// after a connection was set up aren't resent, they may have reached the push service.
+// It is a separate module so the root package doesn't depend on quic-go.
+package webpushhttp3
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// BrokenOriginBackoff is how long an origin is sent HTTP/2 requests after an HTTP/3 request to it failed
+const BrokenOriginBackoff = 5 * time.Minute
+
+// defaultAltSvcMaxAge is the lifetime of an Alt-Svc alternative without ma parameter (RFC 7838)
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// Transport is an http.RoundTripper using HTTP/3 for origins advertising it
+type Transport struct {
+	fallback *http.Transport
+	h3       *http3.Transport
+	now      func() time.Time
+
+	mu      sync.Mutex
+	origins map[string]*origin
+}
+
+// origin is what is known about the HTTP/3 support of an origin
+type origin struct {
+	h3Until     time.Time // HTTP/3 is advertised until
+	brokenUntil time.Time // HTTP/3 failed, don't try before
+}
+
+// NewTransport returns a Transport falling back to webpush.NewTransport(options).
+// The TLS settings of options apply to HTTP/3 connections too.
+func NewTransport(options webpush.TransportOptions) *Transport {
+	fallback := webpush.NewTransport(options)
+
+	h3 := &http3.Transport{Dial: dialQUIC}
+	if fallback.TLSClientConfig != nil {
+		h3.TLSClientConfig = fallback.TLSClientConfig.Clone()
+	}
+
+	return &Transport{
+		fallback: fallback,
+		h3:       h3,
+		now:      time.Now,
+		origins:  make(map[string]*origin),
+	}
+}
+
+// WithHTTP3 sends the notifications of a webpush Client through NewTransport(options)
+func WithHTTP3(options webpush.TransportOptions) webpush.ClientOption {
+	return webpush.WithTransport(NewTransport(options))
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := strings.ToLower(req.URL.Host)
+
+	if req.URL.Scheme == "https" && t.useHTTP3(key) {
+		resp, err := t.h3.RoundTrip(req)
+		if err == nil {
+			t.observe(key, resp)
+			return resp, nil
+		}
+
+		// A canceled request says nothing of the origin
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		// Only requests that never left are resent, when no QUIC connection could be set up
+		var dialErr *dialError
+		if !errors.As(err, &dialErr) {
+			t.markBroken(key)
+			return nil, err
+		}
+
+		// A shared dial may have failed with the context of a request canceled before
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			t.markBroken(key)
+		}
+
+		// The body was consumed by the attempt, it can only be retried when it can be read again
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, err
+			}
+
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+
+	resp, err := t.fallback.RoundTrip(req)
+	if err == nil {
+		t.observe(key, resp)
+	}
+
+	return resp, err
+}
+
+// dialError is the failure to set up a QUIC connection, before any request was sent on it
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *dialError) Unwrap() error {
+	return e.err
+}
+
+// dialQUIC dials a QUIC connection with a complete handshake, so no request goes out as 0-RTT data
+// before the handshake fails
+func dialQUIC(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error) {
+	conn, err := quic.DialAddr(ctx, addr, tlsConfig, config)
+	if err != nil {
+		return nil, &dialError{err: err}
+	}
+
+	return conn, nil
+}
+
+// CloseIdleConnections closes the idle HTTP/2 and HTTP/3 connections
+func (t *Transport) CloseIdleConnections() {
+	t.fallback.CloseIdleConnections()
+	t.h3.CloseIdleConnections()
+}
+
+// Close closes every connection of the Transport
+func (t *Transport) Close() error {
+	t.fallback.CloseIdleConnections()
+	return t.h3.Close()
+}
+
+// useHTTP3 reports whether requests to the origin go over HTTP/3
+func (t *Transport) useHTTP3(key string) bool {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	o, ok := t.origins[key]
+	return ok && now.Before(o.h3Until) && !now.Before(o.brokenUntil)
+}
+
+// markBroken sends the requests to the origin over HTTP/2 for BrokenOriginBackoff
+func (t *Transport) markBroken(key string) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if o, ok := t.origins[key]; ok {
+		o.brokenUntil = now.Add(BrokenOriginBackoff)
+	}
+}
+
+// observe records the HTTP/3 alternative advertised by a response of the origin
+func (t *Transport) observe(key string, resp *http.Response) {
+	altSvc := resp.Header.Get("Alt-Svc")
+	if altSvc == "" {
+		return
+	}
+
+	maxAge, ok := http3Alternative(altSvc, resp.Request.URL.Port())
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	o, known := t.origins[key]
+	if !known {
+		o = &origin{}
+		t.origins[key] = o
+	}
+
+	if ok {
+		o.h3Until = now.Add(maxAge)
+	} else {
+		o.h3Until = time.Time{}
+	}
+}
+
+// http3Alternative parses an Alt-Svc header (RFC 7838) for an h3 alternative on the same host and port
+// as the origin, returning how long it is valid. Alternatives on other hosts or ports are not used.
+func http3Alternative(altSvc, port string) (time.Duration, bool) {
+	if port == "" {
+		port = "443"
+	}
+
+	for _, alternative := range strings.Split(altSvc, ",") {
+		params := strings.Split(alternative, ";")
+
+		protocol := strings.SplitN(strings.TrimSpace(params[0]), "=", 2)
+		if len(protocol) != 2 || protocol[0] != "h3" || strings.Trim(protocol[1], `"`) != ":"+port {
+			continue
+		}
+
+		maxAge := defaultAltSvcMaxAge
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || kv[0] != "ma" {
+				continue
+			}
+
+			if seconds, err := strconv.Atoi(strings.Trim(kv[1], `"`)); err == nil {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+
+		return maxAge, maxAge > 0
+	}
+
+	return 0, false
+}
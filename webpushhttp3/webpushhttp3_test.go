@@ -0,0 +1,238 @@
+package webpushhttp3
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestHTTP3Alternative(t *testing.T) {
+	tests := []struct {
+		altSvc string
+		port   string
+		maxAge time.Duration
+		ok     bool
+	}{
+		{`h3=":443"; ma=3600`, "", time.Hour, true},
+		{`h3-29=":443", h3=":443"`, "443", defaultAltSvcMaxAge, true},
+		{`h3="alt.example.com:443"`, "", 0, false},
+		{`h3=":8443"`, "443", 0, false},
+		{`h2=":443"`, "", 0, false},
+		{`clear`, "", 0, false},
+	}
+
+	for _, test := range tests {
+		maxAge, ok := http3Alternative(test.altSvc, test.port)
+		if ok != test.ok || maxAge != test.maxAge {
+			t.Fatalf("%s: expected=(%s, %v), got=(%s, %v)", test.altSvc, test.maxAge, test.ok, maxAge, ok)
+		}
+	}
+}
+
+func TestTransportUpgradesAndFallsBack(t *testing.T) {
+	var mu sync.Mutex
+	var protocols []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		protocols = append(protocols, r.Proto)
+		mu.Unlock()
+
+		w.Header().Set("Alt-Svc", `h3=":`+portOf(r.Host)+`"; ma=60`)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	// HTTP/3 on the UDP port of the same number
+	udp, err := net.ListenPacket("udp", server.Listener.Addr().String())
+	if err != nil {
+		t.Skipf("UDP port unavailable: %v", err)
+	}
+
+	h3Server := &http3.Server{Handler: handler, TLSConfig: http3.ConfigureTLSConfig(server.TLS.Clone())}
+	go h3Server.Serve(udp)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	transport := NewTransport(webpush.TransportOptions{RootCAs: roots})
+	transport.h3.QUICConfig = &quic.Config{HandshakeIdleTimeout: 500 * time.Millisecond}
+	defer transport.Close()
+
+	keys, err := webpush.GenerateVAPIDKeyBundle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(keys.Keys), webpush.WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &webpush.Subscription{
+		Endpoint: server.URL + "/push/abc",
+		Keys: webpush.Keys{
+			Auth:   "zqbxT6JKstKSY9JKibZLSQ",
+			P256dh: "BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk",
+		},
+	}
+
+	send := func() {
+		resp, err := client.Send(context.Background(), []byte("Test"), s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	send()
+	send()
+
+	// Without HTTP/3 the new connection can't be set up and the request falls back to HTTP/2
+	h3Server.Close()
+	udp.Close()
+	transport.h3.CloseIdleConnections()
+	send()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := []string{"HTTP/2.0", "HTTP/3.0", "HTTP/2.0"}
+	if len(protocols) != len(expected) {
+		t.Fatalf("Incorrect requests, expected=%v, got=%v", expected, protocols)
+	}
+
+	for i := range expected {
+		if protocols[i] != expected[i] {
+			t.Fatalf("Incorrect protocols, expected=%v, got=%v", expected, protocols)
+		}
+	}
+}
+
+func TestTransportDoesNotResendAfterSending(t *testing.T) {
+	var mu sync.Mutex
+	var protocols []string
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		protocols = append(protocols, r.Proto)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.Header().Set("Alt-Svc", `h3=":`+portOf(r.Host)+`"; ma=60`)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	udp, err := net.ListenPacket("udp", server.Listener.Addr().String())
+	if err != nil {
+		t.Skipf("UDP port unavailable: %v", err)
+	}
+	defer udp.Close()
+
+	// The HTTP/3 server resets the stream once it read the notification
+	h3Server := &http3.Server{TLSConfig: http3.ConfigureTLSConfig(server.TLS.Clone()), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		io.Copy(io.Discard, r.Body)
+		panic(http.ErrAbortHandler)
+	})}
+	go h3Server.Serve(udp)
+	defer h3Server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	transport := NewTransport(webpush.TransportOptions{RootCAs: roots})
+	transport.h3.QUICConfig = &quic.Config{HandshakeIdleTimeout: 500 * time.Millisecond}
+	defer transport.Close()
+
+	keys, err := webpush.GenerateVAPIDKeyBundle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(keys.Keys), webpush.WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &webpush.Subscription{
+		Endpoint: server.URL + "/push/abc",
+		Keys: webpush.Keys{
+			Auth:   "zqbxT6JKstKSY9JKibZLSQ",
+			P256dh: "BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk",
+		},
+	}
+	send := func(ctx context.Context) error {
+		resp, err := client.Send(ctx, []byte("Test"), s, nil)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := send(context.Background()); err == nil {
+		t.Fatalf("Expected the reset stream to fail the send, got %v", protocols)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := []string{"HTTP/2.0", "HTTP/3.0"}
+	if len(protocols) != len(expected) || protocols[0] != expected[0] || protocols[1] != expected[1] {
+		t.Fatalf("Expected no HTTP/2 resend, expected=%v, got=%v", expected, protocols)
+	}
+}
+
+func TestTransportCanceledRequest(t *testing.T) {
+	transport := NewTransport(webpush.TransportOptions{})
+	defer transport.Close()
+	transport.origins["127.0.0.1:9"] = &origin{h3Until: time.Now().Add(time.Hour)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://127.0.0.1:9/push/abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The error is the context's, and the origin stays on HTTP/3
+	if _, err := transport.RoundTrip(req); err != context.Canceled {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", context.Canceled, err)
+	}
+	if !transport.useHTTP3("127.0.0.1:9") {
+		t.Fatal("Expected the canceled request to keep the origin on HTTP/3")
+	}
+}
+
+// portOf returns the port of a host:port
+func portOf(hostPort string) string {
+	_, port, _ := net.SplitHostPort(hostPort)
+	if _, err := strconv.Atoi(port); err != nil {
+		return "443"
+	}
+
+	return port
+}
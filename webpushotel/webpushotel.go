@@ -0,0 +1,69 @@
+// Package webpushotel traces webpush notifications with OpenTelemetry: VAPID signing, payload
+// encryption and the HTTP send are recorded as spans of the tracer of a TracerProvider.
+// It is a separate module so the root package doesn't depend on OpenTelemetry.
+package webpushotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// instrumentationName is the name of the tracer of the spans
+const instrumentationName = "github.com/SherClockHolmes/webpush-go/webpushotel"
+
+// WithTracerProvider traces the notifications of the Client with a tracer of provider
+func WithTracerProvider(provider trace.TracerProvider) webpush.ClientOption {
+	return webpush.WithTracer(NewTracer(provider))
+}
+
+// Tracer adapts an OpenTelemetry tracer to webpush.Tracer
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a Tracer starting spans with a tracer of provider
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// StartSpan implements webpush.Tracer
+func (t *Tracer) StartSpan(ctx context.Context, name string) (context.Context, webpush.Span) {
+	kind := trace.SpanKindInternal
+	if name == webpush.SpanSend {
+		kind = trace.SpanKindClient
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, &otelSpan{span: span}
+}
+
+// otelSpan adapts an OpenTelemetry span to webpush.Span
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttribute(key string, value interface{}) {
+	switch value := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, value))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, value))
+		if key == webpush.AttributeStatusCode && value >= 400 {
+			s.span.SetStatus(codes.Error, "")
+		}
+	}
+}
+
+func (s *otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
@@ -0,0 +1,74 @@
+package webpushotel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := webpush.NewClient(
+		webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		WithTracerProvider(provider),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &webpush.Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/abc"}
+	s.Keys.Auth = "zqbxT6JKstKSY9JKibZLSQ"
+	s.Keys.P256dh = "BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk"
+	httpClient := webpush.HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusGone}, nil
+	})
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "campaign")
+	if _, err := client.Send(ctx, []byte("Test"), s, &webpush.Options{HTTPClient: httpClient}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	for _, name := range []string{webpush.SpanSignVAPID, webpush.SpanEncrypt, webpush.SpanSend} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("Missing the %s span, got %v", name, spans)
+		}
+
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("The %s span is not a child of the caller's span", name)
+		}
+	}
+
+	send := spans[webpush.SpanSend]
+	attributes := make(map[string]interface{})
+	for _, kv := range send.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+
+	if attributes[webpush.AttributeOrigin] != "https://fcm.googleapis.com" || attributes[webpush.AttributeStatusCode] != int64(http.StatusGone) {
+		t.Fatalf("Incorrect send attributes, got %v", attributes)
+	}
+
+	if send.Status().Code != codes.Error {
+		t.Fatalf("Expected an error status for a 410 response, got %v", send.Status())
+	}
+}
@@ -0,0 +1,160 @@
+// Package webpushprom exports the webpush VAPID cache, signing, encryption, delivery and connection statistics
+// as a Prometheus Collector.
+// It is a separate module so the root package doesn't depend on the Prometheus client.
+package webpushprom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(
+		"webpush_vapid_cache_hits_total", "VAPID Authorization headers served from the cache.", nil, nil)
+	cacheMissesDesc = prometheus.NewDesc(
+		"webpush_vapid_cache_misses_total", "VAPID Authorization headers that had to be signed.", nil, nil)
+	cacheEvictionsDesc = prometheus.NewDesc(
+		"webpush_vapid_cache_evictions_total", "VAPID header cache entries evicted, by reason.", []string{"reason"}, nil)
+	cacheRefreshesDesc = prometheus.NewDesc(
+		"webpush_vapid_cache_refreshes_total", "VAPID headers re-signed in the background before expiring.", nil, nil)
+	cacheEntriesDesc = prometheus.NewDesc(
+		"webpush_vapid_cache_entries", "VAPID Authorization headers currently cached.", nil, nil)
+
+	privateKeyHitsDesc = prometheus.NewDesc(
+		"webpush_private_key_cache_hits_total", "VAPID private keys served already parsed.", nil, nil)
+	privateKeyMissesDesc = prometheus.NewDesc(
+		"webpush_private_key_cache_misses_total", "VAPID private keys that had to be parsed.", nil, nil)
+	privateKeyEntriesDesc = prometheus.NewDesc(
+		"webpush_private_key_cache_entries", "Parsed VAPID private keys currently cached.", nil, nil)
+	privateKeyEvictionsDesc = prometheus.NewDesc(
+		"webpush_private_key_cache_evictions_total", "Parsed VAPID private keys evicted to stay within the maximum entries.", nil, nil)
+
+	requestsDesc = prometheus.NewDesc(
+		"webpush_requests_total", "Push service requests that got a connection.", nil, nil)
+	reusedConnectionsDesc = prometheus.NewDesc(
+		"webpush_connections_reused_total", "Push service requests sent over an already established connection.", nil, nil)
+
+	signingsDesc = prometheus.NewDesc(
+		"webpush_vapid_signing_duration_seconds", "Latency of ES256 VAPID JWT signings.", []string{"audience"}, nil)
+	signingErrorsDesc = prometheus.NewDesc(
+		"webpush_vapid_signing_errors_total", "Failed ES256 VAPID JWT signings.", []string{"audience"}, nil)
+
+	responsesDesc = prometheus.NewDesc(
+		"webpush_responses_total", "Push service responses, by origin and status class.", []string{"origin", "class"}, nil)
+	sendErrorsDesc = prometheus.NewDesc(
+		"webpush_send_errors_total", "Sends that failed without a push service response.", []string{"origin"}, nil)
+	retriesDesc = prometheus.NewDesc(
+		"webpush_transport_retries_total", "Requests retried after failing before reaching the push service.", []string{"origin"}, nil)
+	sendsDesc = prometheus.NewDesc(
+		"webpush_send_duration_seconds", "Latency of push service requests, including retries.", []string{"origin"}, nil)
+	encryptionsDesc = prometheus.NewDesc(
+		"webpush_encryption_duration_seconds", "Latency of payload encryptions.", nil, nil)
+)
+
+// Collector collects the statistics of a webpush Client
+type Collector struct {
+	stats     func() webpush.VAPIDCacheStats
+	transport func() webpush.TransportStats
+}
+
+// NewCollector returns a Collector for the caches of client, or of the package level functions when client is nil.
+// Signing, encryption and delivery statistics are process wide.
+func NewCollector(client *webpush.Client) *Collector {
+	if client == nil {
+		return &Collector{stats: webpush.GetVAPIDCacheCounters, transport: webpush.GetTransportStats}
+	}
+
+	return &Collector{stats: client.VAPIDCacheStats, transport: client.TransportStats}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheRefreshesDesc
+	ch <- cacheEntriesDesc
+	ch <- privateKeyHitsDesc
+	ch <- privateKeyMissesDesc
+	ch <- privateKeyEntriesDesc
+	ch <- privateKeyEvictionsDesc
+	ch <- requestsDesc
+	ch <- reusedConnectionsDesc
+	ch <- signingsDesc
+	ch <- signingErrorsDesc
+	ch <- responsesDesc
+	ch <- sendErrorsDesc
+	ch <- retriesDesc
+	ch <- sendsDesc
+	ch <- encryptionsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue,
+		float64(stats.Evictions-stats.CapacityEvictions), "expired")
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue,
+		float64(stats.CapacityEvictions), "capacity")
+	ch <- prometheus.MustNewConstMetric(cacheRefreshesDesc, prometheus.CounterValue, float64(stats.Refreshes))
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Size))
+
+	ch <- prometheus.MustNewConstMetric(privateKeyHitsDesc, prometheus.CounterValue, float64(stats.PrivateKeyHits))
+	ch <- prometheus.MustNewConstMetric(privateKeyMissesDesc, prometheus.CounterValue, float64(stats.PrivateKeyMisses))
+	ch <- prometheus.MustNewConstMetric(privateKeyEntriesDesc, prometheus.GaugeValue, float64(stats.PrivateKeys))
+	ch <- prometheus.MustNewConstMetric(privateKeyEvictionsDesc, prometheus.CounterValue, float64(stats.PrivateKeyEvictions))
+
+	transport := c.transport()
+	ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(transport.Requests))
+	ch <- prometheus.MustNewConstMetric(reusedConnectionsDesc, prometheus.CounterValue, float64(transport.ReusedConnections))
+
+	for audience, signing := range webpush.GetSigningStats() {
+		buckets := cumulativeBuckets(webpush.SigningLatencyBuckets[:], signing.Latency[:])
+		ch <- prometheus.MustNewConstHistogram(signingsDesc, signing.Signings, signing.LatencySum.Seconds(), buckets, audience)
+		ch <- prometheus.MustNewConstMetric(signingErrorsDesc, prometheus.CounterValue, float64(signing.SigningErrors), audience)
+	}
+
+	for origin, delivery := range webpush.GetDeliveryStats() {
+		var sends uint64
+		for class, count := range delivery.Responses {
+			sends += count
+			ch <- prometheus.MustNewConstMetric(responsesDesc, prometheus.CounterValue, float64(count), origin, strconv.Itoa(class+1)+"xx")
+		}
+		sends += delivery.Errors
+
+		ch <- prometheus.MustNewConstMetric(sendErrorsDesc, prometheus.CounterValue, float64(delivery.Errors), origin)
+		ch <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(delivery.Retries), origin)
+
+		buckets := cumulativeBuckets(webpush.DeliveryLatencyBuckets[:], delivery.Latency[:])
+		ch <- prometheus.MustNewConstHistogram(sendsDesc, sends, delivery.LatencySum.Seconds(), buckets, origin)
+	}
+
+	encryption := webpush.GetEncryptionStats()
+	buckets := cumulativeBuckets(webpush.EncryptionLatencyBuckets[:], encryption.Latency[:])
+	ch <- prometheus.MustNewConstHistogram(encryptionsDesc, encryption.Encryptions, encryption.LatencySum.Seconds(), buckets)
+}
+
+// Register registers a Collector for client, or for the package level functions when client is nil, with registerer
+func Register(registerer prometheus.Registerer, client *webpush.Client) error {
+	return registerer.Register(NewCollector(client))
+}
+
+// cumulativeBuckets converts the webpush latency histogram counts to Prometheus buckets.
+// Prometheus buckets are cumulative, the overflow bucket is the +Inf count.
+func cumulativeBuckets(bounds []time.Duration, counts []uint64) map[float64]uint64 {
+	buckets := make(map[float64]uint64, len(bounds))
+	var count uint64
+	for i, bound := range bounds {
+		count += counts[i]
+		buckets[bound.Seconds()] = count
+	}
+
+	return buckets
+}
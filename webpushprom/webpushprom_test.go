@@ -0,0 +1,88 @@
+package webpushprom
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestCollector(t *testing.T) {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.WarmVAPIDCache([]string{"https://fcm.googleapis.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &webpush.Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/abc"}
+	s.Keys.Auth = "zqbxT6JKstKSY9JKibZLSQ"
+	s.Keys.P256dh = "BNNL5ZaTfK81qhXOx23-wewhigUeFb632jN6LvRWCFH1ubQr77FE_9qV1FuojuRmHP42zmf34rXgW80OvUVDgTk"
+	httpClient := webpush.HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusCreated}, nil
+	})
+	if _, err := client.Send(context.Background(), []byte("Test"), s, &webpush.Options{HTTPClient: httpClient}); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := Register(registry, client); err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetCounter() != nil:
+				values[family.GetName()] += metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				values[family.GetName()] += metric.GetGauge().GetValue()
+			case metric.GetHistogram() != nil:
+				values[family.GetName()] += float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	// The send reuses the warmed header
+	if values["webpush_vapid_cache_hits_total"] != 2 || values["webpush_vapid_cache_misses_total"] != 1 {
+		t.Fatalf("Incorrect cache counters, got %v", values)
+	}
+
+	if values["webpush_vapid_cache_entries"] != 1 || values["webpush_private_key_cache_entries"] != 1 {
+		t.Fatalf("Incorrect cache sizes, got %v", values)
+	}
+
+	if _, ok := values["webpush_connections_reused_total"]; !ok {
+		t.Fatalf("Missing the connection reuse counter, got %v", values)
+	}
+
+	if values["webpush_responses_total"] != 1 || values["webpush_send_duration_seconds"] != 1 {
+		t.Fatalf("Incorrect delivery stats, got %v", values)
+	}
+
+	if values["webpush_encryption_duration_seconds"] < 1 {
+		t.Fatalf("Missing the encryption latency histogram, got %v", values)
+	}
+
+	if values["webpush_vapid_signing_duration_seconds"] < 1 {
+		t.Fatalf("Missing the signing latency histogram, got %v", values)
+	}
+}
@@ -0,0 +1,212 @@
+// Package webpushrelay is an http.Handler relaying notifications through a webpush.Client, so services
+// not written in Go can send web push through one relay holding the VAPID keys:
+//
+//	POST /send
+//	Authorization: Bearer <Options.Token>
+//
+//	{"subscription": {"endpoint": "...", "keys": {...}}, "payload": {"title": "Hello"}, "ttl": 3600, "urgency": "high"}
+//
+// The relay answers 200 with a Response once the push service answered, whatever its status,
+// 400 for invalid requests, 413 for payloads too large to encrypt and 502 when the push service couldn't be reached.
+package webpushrelay
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+const (
+	// DefaultMaxBytes is the largest request body read by default
+	DefaultMaxBytes = 64 << 10
+
+	// DefaultTimeout bounds a send by default, including the request to the push service
+	DefaultTimeout = 30 * time.Second
+)
+
+// Options configure a relay
+type Options struct {
+	Token        string        // Required as "Authorization: Bearer <Token>" when not empty
+	AllowedHosts []string      // Passed to Subscription.Validate, e.g. the hosts of private push gateways
+	MaxBytes     int64         // Largest request body, DefaultMaxBytes when zero
+	Timeout      time.Duration // Bounds a send, DefaultTimeout when zero
+}
+
+// Request is the body of POST /send
+type Request struct {
+	Subscription json.RawMessage `json:"subscription"` // PushSubscription.toJSON() of the browser
+	Payload      json.RawMessage `json:"payload"`      // A JSON string is sent as its text, other JSON values as they are
+	TTL          int             `json:"ttl"`
+	Urgency      webpush.Urgency `json:"urgency"` // Optional
+	Topic        string          `json:"topic"`   // Optional
+}
+
+// Response is the body of a 200 response of POST /send
+type Response struct {
+	StatusCode    int    `json:"statusCode"`           // Of the push service
+	Gone          bool   `json:"gone"`                 // The subscription expired or was unsubscribed, delete it
+	RetryAfter    int    `json:"retryAfter,omitempty"` // Seconds to wait before resending, from the Retry-After header
+	CorrelationID string `json:"correlationId"`
+}
+
+// errorResponse is the body of a failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns the http.Handler of a relay sending through client
+func NewHandler(client *webpush.Client, options Options) http.Handler {
+	if options.MaxBytes <= 0 {
+		options.MaxBytes = DefaultMaxBytes
+	}
+
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	relay := &relay{client: client, options: options}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/send", relay.send)
+	return mux
+}
+
+type relay struct {
+	client  *webpush.Client
+	options Options
+}
+
+func (r *relay) send(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	if r.options.Token != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+r.options.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, r.options.MaxBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+
+	var request Request
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	subscription, err := webpush.ParseSubscription(request.Subscription)
+	if err == nil {
+		err = subscription.Validate(r.options.AllowedHosts...)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid subscription: "+err.Error())
+		return
+	}
+
+	payload, err := decodePayload(request.Payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+
+	if request.TTL < 0 {
+		writeError(w, http.StatusBadRequest, "ttl must not be negative")
+		return
+	}
+
+	switch request.Urgency {
+	case "", webpush.UrgencyVeryLow, webpush.UrgencyLow, webpush.UrgencyNormal, webpush.UrgencyHigh:
+	default:
+		writeError(w, http.StatusBadRequest, "urgency must be very-low, low, normal or high")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), r.options.Timeout)
+	defer cancel()
+
+	result, err := r.client.Deliver(ctx, payload, subscription, &webpush.Options{
+		TTL:     request.TTL,
+		Urgency: request.Urgency,
+		Topic:   request.Topic,
+	})
+	if result != nil && result.Response != nil {
+		result.Response.Body.Close()
+	}
+
+	var capped *webpush.FrequencyCapError
+	switch {
+	case errors.Is(err, webpush.ErrMaxPadExceeded):
+		writeError(w, http.StatusRequestEntityTooLarge, "payload too large")
+		return
+	case errors.As(err, &capped):
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(time.Until(capped.RetryAt))))
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	resp := result.Response
+	response := Response{
+		StatusCode:    resp.StatusCode,
+		Gone:          resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone,
+		CorrelationID: result.CorrelationID,
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		response.RetryAfter = seconds
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// decodePayload returns the notification payload of a Request
+func decodePayload(raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	if raw[0] != '"' {
+		return raw, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return nil, err
+	}
+
+	return []byte(text), nil
+}
+
+// retryAfterSeconds rounds wait up to whole seconds, at least 1
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
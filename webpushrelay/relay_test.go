@@ -0,0 +1,81 @@
+package webpushrelay
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushtest"
+)
+
+func TestHandler(t *testing.T) {
+	server := webpushtest.NewServer()
+	defer server.Close()
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+		webpush.WithSubscriber("ops@example.com"), webpush.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(server.URL)
+	handler := NewHandler(client, Options{Token: "secret", AllowedHosts: []string{u.Hostname()}})
+
+	subscription, err := json.Marshal(server.NewSubscription())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/send", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "secret", `{"subscription": `+string(subscription)+`, "payload": {"title": "Hello"}, "ttl": 60, "urgency": "high"}`)
+	var response Response
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || w.Code != http.StatusOK || response.StatusCode != http.StatusCreated {
+		t.Fatalf("Incorrect response, got %d %+v (%v)", w.Code, response, err)
+	}
+
+	notifications := server.Notifications()
+	if len(notifications) != 1 || string(notifications[0].Payload) != `{"title": "Hello"}` || notifications[0].TTL != 60 ||
+		notifications[0].Urgency != "high" {
+		t.Fatalf("Incorrect notification, got %+v", notifications)
+	}
+
+	server.Script(webpushtest.Status(http.StatusGone))
+	w = send(http.MethodPost, "secret", `{"subscription": `+string(subscription)+`, "payload": "Bye"}`)
+	response = Response{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || !response.Gone {
+		t.Fatalf("Expected a gone subscription, got %d %+v (%v)", w.Code, response, err)
+	}
+
+	for _, test := range []struct {
+		name, method, token, body string
+		status                    int
+	}{
+		{"method", http.MethodGet, "secret", "", http.StatusMethodNotAllowed},
+		{"token", http.MethodPost, "wrong", "{}", http.StatusUnauthorized},
+		{"json", http.MethodPost, "secret", "{", http.StatusBadRequest},
+		{"host", http.MethodPost, "secret", `{"subscription": {"endpoint": "https://internal.example.com/", "keys": {"p256dh": "a", "auth": "b"}}}`, http.StatusBadRequest},
+		{"urgency", http.MethodPost, "secret", `{"subscription": ` + string(subscription) + `, "urgency": "urgent"}`, http.StatusBadRequest},
+		{"payload", http.MethodPost, "secret", `{"subscription": ` + string(subscription) + `, "payload": "` + strings.Repeat("a", 5000) + `"}`, http.StatusRequestEntityTooLarge},
+	} {
+		if w := send(test.method, test.token, test.body); w.Code != test.status {
+			t.Errorf("%s: incorrect status, expected=%d, got=%d %s", test.name, test.status, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package webpushsql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// DefaultResultBatch is the number of results a ResultSink inserts per transaction by default
+const DefaultResultBatch = 500
+
+// ResultOptions configure a ResultSink
+type ResultOptions struct {
+	Table       string      // Results table, webpush_results by default
+	Campaign    string      // Stored with every result, e.g. to tell the results of several bulk sends apart
+	Batch       int         // Results inserted per transaction, DefaultResultBatch when zero
+	Placeholder Placeholder // QuestionPlaceholder by default
+}
+
+// ResultSink is a webpush.ResultSink inserting a row per notification, in batches so a campaign of
+// millions of subscriptions doesn't commit a transaction per result
+type ResultSink struct {
+	db      *sql.DB
+	store   *Store // Rewrites the placeholders of the queries
+	options ResultOptions
+
+	mu      sync.Mutex
+	pending []webpush.ResultRecord
+}
+
+// NewResultSink returns a ResultSink in db, call Migrate to create its table
+func NewResultSink(db *sql.DB, options ResultOptions) *ResultSink {
+	if options.Table == "" {
+		options.Table = "webpush_results"
+	}
+
+	if options.Batch <= 0 {
+		options.Batch = DefaultResultBatch
+	}
+
+	if options.Placeholder == nil {
+		options.Placeholder = QuestionPlaceholder
+	}
+
+	return &ResultSink{db: db, store: &Store{placeholder: options.Placeholder}, options: options}
+}
+
+// Migrate creates the table of the ResultSink unless it exists
+func (s *ResultSink) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+s.options.Table+" (campaign VARCHAR(255) NOT NULL, "+
+		"id CHAR(64) NOT NULL, endpoint TEXT NOT NULL, status_code INTEGER NOT NULL, class VARCHAR(32) NOT NULL, "+
+		"correlation_id VARCHAR(255) NOT NULL, error TEXT NOT NULL, created_at BIGINT NOT NULL)")
+
+	return err
+}
+
+// Write implements webpush.ResultSink, inserting the pending results once a batch is full
+func (s *ResultSink) Write(ctx context.Context, result *webpush.FanOutResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, webpush.NewResultRecord(result))
+	if len(s.pending) < s.options.Batch {
+		return nil
+	}
+
+	return s.insert(ctx)
+}
+
+// Flush implements webpush.ResultSink, inserting the pending results
+func (s *ResultSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insert(ctx)
+}
+
+// insert inserts the pending results in a transaction
+func (s *ResultSink) insert(ctx context.Context) error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.PrepareContext(ctx, s.store.query("INSERT INTO "+s.options.Table+
+		" (campaign, id, endpoint, status_code, class, correlation_id, error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"))
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	now := time.Now().Unix()
+	for _, record := range s.pending {
+		_, err := statement.ExecContext(ctx, s.options.Campaign, webpush.EndpointHash(record.Endpoint), record.Endpoint,
+			record.StatusCode, string(record.Class), record.CorrelationID, record.Error, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.pending = s.pending[:0]
+
+	return nil
+}
@@ -0,0 +1,215 @@
+// Package webpushsql is a database/sql implementation of webpush.SubscriptionStore and webpush.ResultSink.
+// It is a separate module so the root package and its tests don't depend on a database driver.
+package webpushsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// Placeholder returns the bind parameter n, starting at 1, of a SQL dialect
+type Placeholder func(n int) string
+
+// QuestionPlaceholder is the ? placeholder of MySQL and SQLite
+func QuestionPlaceholder(int) string { return "?" }
+
+// DollarPlaceholder is the $1 placeholder of PostgreSQL
+func DollarPlaceholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// Options configure a Store
+type Options struct {
+	Table       string      // Subscriptions table, webpush_subscriptions by default; tags go to Table + "_tags"
+	Placeholder Placeholder // QuestionPlaceholder by default
+}
+
+// Store is a webpush.SubscriptionStore in two tables: the subscriptions, keyed by the
+// webpush.EndpointHash of their endpoint, and their tags
+type Store struct {
+	db          *sql.DB
+	table       string
+	tags        string
+	placeholder Placeholder
+}
+
+// NewStore returns a Store in db, call Migrate to create its tables
+func NewStore(db *sql.DB, options Options) *Store {
+	if options.Table == "" {
+		options.Table = "webpush_subscriptions"
+	}
+
+	if options.Placeholder == nil {
+		options.Placeholder = QuestionPlaceholder
+	}
+
+	return &Store{db: db, table: options.Table, tags: options.Table + "_tags", placeholder: options.Placeholder}
+}
+
+// Migrate creates the tables of the Store unless they exist
+func (s *Store) Migrate(ctx context.Context) error {
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS " + s.table + " (id CHAR(64) PRIMARY KEY, subscription TEXT NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS " + s.tags + " (id CHAR(64) NOT NULL, tag VARCHAR(255) NOT NULL, PRIMARY KEY (id, tag))",
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// query replaces the ? parameters of statement with the placeholders of the Store
+func (s *Store) query(statement string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range statement {
+		if r == '?' {
+			n++
+			b.WriteString(s.placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Save implements webpush.SubscriptionStore
+func (s *Store) Save(ctx context.Context, subscription *webpush.StoredSubscription) error {
+	encoded, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+	id := webpush.EndpointHash(subscription.Endpoint)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exec := func(query string, args ...interface{}) error {
+		_, err := tx.ExecContext(ctx, s.query(query), args...)
+		return err
+	}
+
+	if err := exec("DELETE FROM "+s.tags+" WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	if err := exec("DELETE FROM "+s.table+" WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	if err := exec("INSERT INTO "+s.table+" (id, subscription) VALUES (?, ?)", id, string(encoded)); err != nil {
+		return err
+	}
+
+	for _, tag := range uniqueTags(subscription.Tags) {
+		if err := exec("INSERT INTO "+s.tags+" (id, tag) VALUES (?, ?)", id, tag); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get implements webpush.SubscriptionStore
+func (s *Store) Get(ctx context.Context, endpoint string) (*webpush.StoredSubscription, error) {
+	var encoded string
+	row := s.db.QueryRowContext(ctx, s.query("SELECT subscription FROM "+s.table+" WHERE id = ?"), webpush.EndpointHash(endpoint))
+	if err := row.Scan(&encoded); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webpush.ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+
+	return decode(encoded)
+}
+
+// Delete implements webpush.SubscriptionStore
+func (s *Store) Delete(ctx context.Context, endpoint string) error {
+	id := webpush.EndpointHash(endpoint)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{s.tags, s.table} {
+		if _, err := tx.ExecContext(ctx, s.query("DELETE FROM "+table+" WHERE id = ?"), id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// IterateByTag implements webpush.SubscriptionStore, visiting subscriptions in the order of their id.
+// The rows are read while fn runs, so fn must not write to the Store on databases allowing a single
+// connection, e.g. SQLite in memory.
+func (s *Store) IterateByTag(ctx context.Context, tag string, fn func(*webpush.StoredSubscription) error) error {
+	var rows *sql.Rows
+	var err error
+	if tag == "" {
+		rows, err = s.db.QueryContext(ctx, "SELECT subscription FROM "+s.table+" ORDER BY id")
+	} else {
+		rows, err = s.db.QueryContext(ctx, s.query("SELECT s.subscription FROM "+s.table+" s JOIN "+s.tags+
+			" t ON t.id = s.id WHERE t.tag = ? ORDER BY s.id"), tag)
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return err
+		}
+
+		subscription, err := decode(encoded)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(subscription); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// decode returns the subscription stored as encoded
+func decode(encoded string) (*webpush.StoredSubscription, error) {
+	var subscription webpush.StoredSubscription
+	if err := json.Unmarshal([]byte(encoded), &subscription); err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+// uniqueTags returns tags without duplicates, which would violate the primary key of the tags table
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	unique := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,143 @@
+package webpushsql
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	store := NewStore(db, Options{})
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expiration := int64(1767225600000)
+	first := &webpush.StoredSubscription{
+		Subscription: webpush.Subscription{
+			Endpoint:       "https://fcm.googleapis.com/fcm/send/first",
+			Keys:           webpush.Keys{P256dh: "BNNL", Auth: "zqbx"},
+			ExpirationTime: &expiration,
+		},
+		Tags:     []string{"user:1", "news", "news"},
+		Locale:   "de-AT",
+		Metadata: map[string]string{"user_agent": "Firefox"},
+	}
+	second := &webpush.StoredSubscription{
+		Subscription: webpush.Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/second"},
+		Tags:         []string{"user:2"},
+	}
+	for _, s := range []*webpush.StoredSubscription{first, second, first} {
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := store.Get(ctx, first.Endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Keys != first.Keys || *got.ExpirationTime != expiration || !got.HasTag("news") ||
+		got.Locale != "de-AT" || got.Metadata["user_agent"] != "Firefox" {
+		t.Fatalf("Incorrect subscription, got %+v", got)
+	}
+
+	var endpoints []string
+	collect := func(s *webpush.StoredSubscription) error {
+		endpoints = append(endpoints, s.Endpoint)
+		return nil
+	}
+
+	if err := store.IterateByTag(ctx, "news", collect); err != nil || len(endpoints) != 1 || endpoints[0] != first.Endpoint {
+		t.Fatalf("Incorrect subscriptions tagged news, got %v (%v)", endpoints, err)
+	}
+
+	endpoints = nil
+	if err := store.IterateByTag(ctx, "", collect); err != nil || len(endpoints) != 2 {
+		t.Fatalf("Expected every subscription, got %v (%v)", endpoints, err)
+	}
+
+	if err := store.Delete(ctx, first.Endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, first.Endpoint); err != webpush.ErrSubscriptionNotFound {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", webpush.ErrSubscriptionNotFound, err)
+	}
+
+	endpoints = nil
+	if err := store.IterateByTag(ctx, "news", collect); err != nil || len(endpoints) != 0 {
+		t.Fatalf("Expected the tags to be deleted, got %v (%v)", endpoints, err)
+	}
+}
+
+func TestDollarPlaceholder(t *testing.T) {
+	store := NewStore(nil, Options{Placeholder: DollarPlaceholder})
+	if query := store.query("INSERT INTO t (a, b) VALUES (?, ?)"); query != "INSERT INTO t (a, b) VALUES ($1, $2)" {
+		t.Fatalf("Incorrect query, got %s", query)
+	}
+}
+
+func TestResultSink(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	sink := NewResultSink(db, ResultOptions{Campaign: "digest", Batch: 2})
+	if err := sink.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, status := range []int{http.StatusCreated, http.StatusGone, http.StatusCreated} {
+		result := &webpush.FanOutResult{
+			Subscription: &webpush.StoredSubscription{Subscription: webpush.Subscription{Endpoint: "https://fcm.googleapis.com/fcm/send/" + strconv.Itoa(i)}},
+			Result:       &webpush.SendResult{Response: &http.Response{StatusCode: status}},
+		}
+		if err := sink.Write(ctx, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := func() int {
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webpush_results WHERE campaign = 'digest'").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("Expected a full batch to be inserted, got %d rows", n)
+	}
+
+	if err := sink.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 3 {
+		t.Fatalf("Expected every result after Flush, got %d rows", n)
+	}
+
+	var class string
+	if err := db.QueryRowContext(ctx, "SELECT class FROM webpush_results WHERE endpoint LIKE '%/1'").Scan(&class); err != nil {
+		t.Fatal(err)
+	}
+	if class != string(webpush.ErrorClassGone) {
+		t.Fatalf("Incorrect class, got %q", class)
+	}
+}
@@ -0,0 +1,42 @@
+package webpushtest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// SendFunc sends a notification to subscription through httpClient, e.g. by passing it as the
+// HTTPClient of the Options of the sending pipeline under test
+type SendFunc func(ctx context.Context, subscription *webpush.Subscription, httpClient webpush.HTTPClient) error
+
+// AssertPayload checks a sending pipeline end to end: it starts a Server, calls send with a new
+// subscription of it, and fails t unless exactly one valid notification with the plaintext want arrived.
+// The notification is returned for further checks, e.g. of its TTL or urgency.
+func AssertPayload(t testing.TB, send SendFunc, want []byte) Notification {
+	t.Helper()
+
+	server := NewServer()
+	defer server.Close()
+
+	if err := send(context.Background(), server.NewSubscription(), server.Client()); err != nil {
+		t.Fatalf("webpushtest: sending failed: %v", err)
+	}
+
+	for _, err := range server.Rejected() {
+		t.Errorf("webpushtest: push service rejected a request: %v", err)
+	}
+
+	notifications := server.Notifications()
+	if len(notifications) != 1 {
+		t.Fatalf("webpushtest: expected 1 notification, got %d", len(notifications))
+	}
+
+	if got := notifications[0].Payload; !bytes.Equal(got, want) {
+		t.Fatalf("webpushtest: incorrect payload, expected=%q, got=%q", want, got)
+	}
+
+	return notifications[0]
+}
@@ -0,0 +1,34 @@
+package webpushtest
+
+import (
+	"context"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestAssertPayload(t *testing.T) {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notification := AssertPayload(t, func(ctx context.Context, s *webpush.Subscription, httpClient webpush.HTTPClient) error {
+		resp, err := webpush.SendNotificationWithContext(ctx, []byte(`{"title":"Hello"}`), s, &webpush.Options{
+			HTTPClient:      httpClient,
+			Subscriber:      "ops@example.com",
+			VAPIDPublicKey:  publicKey,
+			VAPIDPrivateKey: privateKey,
+			TTL:             30,
+		})
+		if err != nil {
+			return err
+		}
+
+		return resp.Body.Close()
+	}, []byte(`{"title":"Hello"}`))
+
+	if notification.TTL != 30 {
+		t.Fatalf("Incorrect TTL, got %d", notification.TTL)
+	}
+}
@@ -0,0 +1,153 @@
+package webpushtest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushconformance"
+)
+
+// DefaultBenchmarkDuration is how long each configuration is measured by default
+const DefaultBenchmarkDuration = time.Second
+
+// BenchmarkOptions configure RunBenchmark
+type BenchmarkOptions struct {
+	PayloadSize int           // Bytes of the payload, 128 when zero
+	Duration    time.Duration // Time spent sending with each configuration, DefaultBenchmarkDuration when zero
+}
+
+// BenchmarkConfig is a configuration compared by RunBenchmark
+type BenchmarkConfig struct {
+	Name     string                  `json:"name"`
+	Encoding webpush.ContentEncoding `json:"encoding"`
+	Cache    bool                    `json:"cache"` // VAPID header cache enabled
+}
+
+// BenchmarkResult is the measurement of a BenchmarkConfig
+type BenchmarkResult struct {
+	BenchmarkConfig
+	Sends       int     `json:"sends"`
+	NsPerOp     float64 `json:"nsPerOp"`
+	AllocsPerOp float64 `json:"allocsPerOp"`
+	BytesPerOp  float64 `json:"bytesPerOp"`
+}
+
+// BenchmarkReport is the outcome of RunBenchmark, JSON encodable
+type BenchmarkReport struct {
+	GoVersion   string            `json:"goVersion"`
+	GOOS        string            `json:"goos"`
+	GOARCH      string            `json:"goarch"`
+	NumCPU      int               `json:"numCPU"`
+	PayloadSize int               `json:"payloadSize"`
+	Results     []BenchmarkResult `json:"results"`
+}
+
+// benchmarkConfigs are the configurations compared by RunBenchmark
+var benchmarkConfigs = []BenchmarkConfig{
+	{Name: "aes128gcm", Encoding: webpush.ContentEncodingAES128GCM, Cache: true},
+	{Name: "aes128gcm-nocache", Encoding: webpush.ContentEncodingAES128GCM},
+	{Name: "aesgcm", Encoding: webpush.ContentEncodingAESGCM, Cache: true},
+	{Name: "aesgcm-nocache", Encoding: webpush.ContentEncodingAESGCM},
+}
+
+// RunBenchmark measures the cost of a send on this machine with each content encoding, with and
+// without the VAPID header cache, to guide tuning. Requests are answered in process without network
+// access, so only the library's work is measured: encryption, signing and building the request.
+func RunBenchmark(ctx context.Context, options BenchmarkOptions) (*BenchmarkReport, error) {
+	if options.PayloadSize <= 0 {
+		options.PayloadSize = 128
+	}
+
+	if options.Duration <= 0 {
+		options.Duration = DefaultBenchmarkDuration
+	}
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return nil, err
+	}
+	keys := webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}
+
+	report := &BenchmarkReport{
+		GoVersion:   runtime.Version(),
+		GOOS:        runtime.GOOS,
+		GOARCH:      runtime.GOARCH,
+		NumCPU:      runtime.NumCPU(),
+		PayloadSize: options.PayloadSize,
+	}
+
+	subscription := webpushconformance.Subscription("https://fcm.googleapis.com/fcm/send/benchmark")
+	payload := []byte(strings.Repeat("x", options.PayloadSize))
+	httpClient := webpush.HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		return &http.Response{StatusCode: http.StatusCreated, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+
+	for _, config := range benchmarkConfigs {
+		clientOptions := []webpush.ClientOption{webpush.WithVAPIDKeys(keys), webpush.WithSubscriber("benchmark@example.com"), webpush.WithHTTPClient(httpClient)}
+		if !config.Cache {
+			clientOptions = append(clientOptions, webpush.WithoutVAPIDCache())
+		}
+
+		client, err := webpush.NewClient(clientOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		send := func() error {
+			resp, err := client.Send(ctx, payload, subscription, &webpush.Options{TTL: 60, ContentEncoding: config.Encoding})
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}
+
+		// Warm the cache and the connection-independent state of the client
+		if err := send(); err != nil {
+			return nil, err
+		}
+
+		result, err := measure(ctx, options.Duration, send)
+		if err != nil {
+			return nil, err
+		}
+		result.BenchmarkConfig = config
+		report.Results = append(report.Results, *result)
+	}
+
+	return report, nil
+}
+
+// measure calls send for duration, reporting its time and allocations per call
+func measure(ctx context.Context, duration time.Duration, send func() error) (*BenchmarkResult, error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	sends := 0
+	for time.Since(start) < duration {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := send(); err != nil {
+			return nil, err
+		}
+		sends++
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return &BenchmarkResult{
+		Sends:       sends,
+		NsPerOp:     float64(elapsed.Nanoseconds()) / float64(sends),
+		AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(sends),
+		BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / float64(sends),
+	}, nil
+}
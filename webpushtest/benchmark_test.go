@@ -0,0 +1,23 @@
+package webpushtest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	report, err := RunBenchmark(context.Background(), BenchmarkOptions{PayloadSize: 64, Duration: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Results) != len(benchmarkConfigs) || report.PayloadSize != 64 {
+		t.Fatalf("Incorrect report, got %+v", report)
+	}
+	for _, result := range report.Results {
+		if result.Sends == 0 || result.NsPerOp <= 0 || result.AllocsPerOp <= 0 {
+			t.Fatalf("Incorrect result, got %+v", result)
+		}
+	}
+}
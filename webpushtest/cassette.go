@@ -0,0 +1,170 @@
+package webpushtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in cassettes
+const redacted = "REDACTED"
+
+// redactedHeaders are the request headers carrying credentials or key material
+var redactedHeaders = []string{"Authorization", "Crypto-Key", "Encryption"}
+
+// ErrCassetteMismatch is returned when a replayed request doesn't match the next recorded one
+var ErrCassetteMismatch = errors.New("webpushtest: request doesn't match the cassette")
+
+// Interaction is a request and response recorded in a cassette. The endpoint path, which identifies
+// the subscription, the request body and the credential headers are not recorded.
+type Interaction struct {
+	Method        string      `json:"method"`
+	Origin        string      `json:"origin"`
+	RequestHeader http.Header `json:"requestHeader"`
+	StatusCode    int         `json:"statusCode"`
+	Header        http.Header `json:"header"`
+	Body          string      `json:"body"`
+}
+
+// Cassette records the interactions of a push service for replay in tests without network access,
+// so error handling can be tested against the real responses of a provider:
+//
+//	cassette, err := webpushtest.LoadCassette("testdata/fcm.json") // or NewCassette to record
+//	client, err := webpush.NewClient(webpush.WithTransport(cassette.Replay()))
+type Cassette struct {
+	mu           sync.Mutex
+	Interactions []Interaction `json:"interactions"`
+	next         int
+}
+
+// NewCassette returns an empty Cassette for recording
+func NewCassette() *Cassette {
+	return &Cassette{}
+}
+
+// LoadCassette reads a Cassette saved with Save
+func LoadCassette(name string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := &Cassette{}
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, err
+	}
+
+	return cassette, nil
+}
+
+// Save writes the recorded interactions to the file name
+func (c *Cassette) Save(name string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// Record returns a RoundTripper sending requests through next, http.DefaultTransport when nil,
+// and appending the interactions to c
+func (c *Cassette) Record(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		header := resp.Header.Clone()
+		header.Del("Set-Cookie")
+
+		c.mu.Lock()
+		c.Interactions = append(c.Interactions, Interaction{
+			Method:        req.Method,
+			Origin:        strings.ToLower(req.URL.Scheme + "://" + req.URL.Host),
+			RequestHeader: redactHeader(req.Header),
+			StatusCode:    resp.StatusCode,
+			Header:        header,
+			Body:          string(body),
+		})
+		c.mu.Unlock()
+
+		return resp, nil
+	})
+}
+
+// Replay returns a RoundTripper answering requests with the interactions of c in order, without
+// network access. A request of another method or origin than the next interaction, or beyond the
+// last one, fails with ErrCassetteMismatch.
+func (c *Cassette) Replay() http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		origin := strings.ToLower(req.URL.Scheme + "://" + req.URL.Host)
+		if c.next >= len(c.Interactions) {
+			return nil, fmt.Errorf("%w: no interaction left for %s %s", ErrCassetteMismatch, req.Method, origin)
+		}
+
+		interaction := c.Interactions[c.next]
+		if interaction.Method != req.Method || interaction.Origin != origin {
+			return nil, fmt.Errorf("%w: expected %s %s, got %s %s", ErrCassetteMismatch, interaction.Method,
+				interaction.Origin, req.Method, origin)
+		}
+		c.next++
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+			StatusCode:    interaction.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Header.Clone(),
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.Body)),
+			ContentLength: int64(len(interaction.Body)),
+			Request:       req,
+		}, nil
+	})
+}
+
+// redactHeader returns a copy of header with the credentials and key material redacted
+func redactHeader(header http.Header) http.Header {
+	copied := header.Clone()
+	for _, name := range redactedHeaders {
+		if copied.Get(name) != "" {
+			copied.Set(name, redacted)
+		}
+	}
+
+	return copied
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,82 @@
+package webpushtest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestCassette(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	subscription := server.NewSubscription()
+	server.Script(Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}, Body: `{"reason":"throttled"}`})
+
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}
+
+	send := func(transport http.RoundTripper) (*http.Response, error) {
+		client, err := webpush.NewClient(webpush.WithVAPIDKeys(keys), webpush.WithSubscriber("ops@example.com"), webpush.WithTransport(transport))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return client.Send(context.Background(), []byte("Hello"), subscription, &webpush.Options{TTL: 60})
+	}
+
+	recording := NewCassette()
+	transport := recording.Record(server.Client().Transport)
+	for i := 0; i < 2; i++ {
+		resp, err := send(transport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	name := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recording.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "/push/") || strings.Contains(string(data), "vapid t=") {
+		t.Fatalf("Cassette leaks the endpoint or the VAPID JWT: %s", data)
+	}
+
+	cassette, err := LoadCassette(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := cassette.Replay()
+	resp, err := send(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "30" || string(body) != `{"reason":"throttled"}` {
+		t.Fatalf("Incorrect replayed response, got %d %v %s", resp.StatusCode, resp.Header, body)
+	}
+
+	if resp, err := send(replay); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Incorrect replayed response, got %v (%v)", resp, err)
+	}
+
+	if _, err := send(replay); !errors.Is(err, ErrCassetteMismatch) {
+		t.Fatalf("Expected ErrCassetteMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package webpushtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrConnectionDropped is returned by a FaultTransport for a dropped connection. The request was sent,
+// so like a real connection reset the notification may have been delivered.
+var ErrConnectionDropped = errors.New("webpushtest: injected connection drop")
+
+// defaultFaultStatuses are the statuses of injected error responses when Faults.Statuses is empty
+var defaultFaultStatuses = []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable}
+
+// Faults configure a FaultTransport. Rates are probabilities between 0 and 1, drawn for every request.
+type Faults struct {
+	Latency time.Duration // Added before every request
+	Jitter  time.Duration // Random extra latency up to Jitter
+
+	ErrorRate   float64 // Starts a burst of error responses, which never reach the push service
+	BurstLength int     // Requests answered with an error once a burst starts, 1 by default
+	Statuses    []int   // Statuses of the error responses, picked at random; 429, 500 and 503 by default
+	RetryAfter  string  // Retry-After header of the error responses, none when empty
+
+	DropRate     float64 // Sends the request, then fails it with ErrConnectionDropped instead of returning the response
+	TruncateRate float64 // Cuts the response body in half, reading it then fails with io.ErrUnexpectedEOF
+
+	Seed int64 // Seeds the random draws so a failing run can be reproduced
+}
+
+// FaultStats count the faults a FaultTransport injected
+type FaultStats struct {
+	Requests    int
+	Errors      int
+	Drops       int
+	Truncations int
+}
+
+// FaultTransport is an http.RoundTripper injecting Faults into the requests it sends,
+// to check retry and circuit breaker settings before a real incident:
+//
+//	faults := webpushtest.NewFaultTransport(server.Client().Transport, webpushtest.Faults{ErrorRate: 0.2, BurstLength: 5})
+//	client, err := webpush.NewClient(webpush.WithTransport(faults), webpush.WithCircuitBreaker(5, time.Minute))
+type FaultTransport struct {
+	next   http.RoundTripper
+	faults Faults
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	burst int
+	stats FaultStats
+}
+
+// NewFaultTransport returns a FaultTransport sending requests through next, http.DefaultTransport when nil
+func NewFaultTransport(next http.RoundTripper, faults Faults) *FaultTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if faults.BurstLength < 1 {
+		faults.BurstLength = 1
+	}
+
+	if len(faults.Statuses) == 0 {
+		faults.Statuses = defaultFaultStatuses
+	}
+
+	return &FaultTransport{next: next, faults: faults, rand: rand.New(rand.NewSource(faults.Seed))}
+}
+
+// Stats returns the faults injected so far
+func (t *FaultTransport) Stats() FaultStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stats
+}
+
+// fault is the fault drawn for a request
+type fault int
+
+const (
+	noFault fault = iota
+	errorFault
+	dropFault
+	truncateFault
+)
+
+// draw picks the latency and fault of the next request
+func (t *FaultTransport) draw() (time.Duration, fault, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats.Requests++
+	latency := t.faults.Latency
+	if t.faults.Jitter > 0 {
+		latency += time.Duration(t.rand.Int63n(int64(t.faults.Jitter)))
+	}
+
+	if t.burst == 0 && t.faults.ErrorRate > 0 && t.rand.Float64() < t.faults.ErrorRate {
+		t.burst = t.faults.BurstLength
+	}
+	if t.burst > 0 {
+		t.burst--
+		t.stats.Errors++
+		return latency, errorFault, t.faults.Statuses[t.rand.Intn(len(t.faults.Statuses))]
+	}
+
+	if t.faults.DropRate > 0 && t.rand.Float64() < t.faults.DropRate {
+		t.stats.Drops++
+		return latency, dropFault, 0
+	}
+
+	if t.faults.TruncateRate > 0 && t.rand.Float64() < t.faults.TruncateRate {
+		t.stats.Truncations++
+		return latency, truncateFault, 0
+	}
+
+	return latency, noFault, 0
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	latency, fault, status := t.draw()
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, req.Context().Err()
+		}
+	}
+
+	if fault == errorFault {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+
+		header := http.Header{}
+		if t.faults.RetryAfter != "" {
+			header.Set("Retry-After", t.faults.RetryAfter)
+		}
+
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode: status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fault {
+	case dropFault:
+		resp.Body.Close()
+		return nil, ErrConnectionDropped
+	case truncateFault:
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = &truncatedBody{Reader: strings.NewReader(string(body[:len(body)/2]))}
+	}
+
+	return resp, nil
+}
+
+// truncatedBody fails with io.ErrUnexpectedEOF at its end, like a body cut by a closed connection
+type truncatedBody struct {
+	*strings.Reader
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+func (b *truncatedBody) Close() error { return nil }
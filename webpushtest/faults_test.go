@@ -0,0 +1,105 @@
+package webpushtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestFaultTransport(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	subscription := server.NewSubscription()
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(ctx context.Context, faults Faults) (*FaultTransport, *http.Response, error) {
+		transport := NewFaultTransport(server.Client().Transport, faults)
+		client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}),
+			webpush.WithSubscriber("ops@example.com"), webpush.WithTransport(transport))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.Send(ctx, []byte("Hello"), subscription, &webpush.Options{TTL: 60})
+		return transport, resp, err
+	}
+
+	transport, resp, err := send(context.Background(), Faults{ErrorRate: 1, Statuses: []int{http.StatusServiceUnavailable}, RetryAfter: "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "10" || transport.Stats() != (FaultStats{Requests: 1, Errors: 1}) {
+		t.Fatalf("Expected an injected 503, got %d %v %+v", resp.StatusCode, resp.Header, transport.Stats())
+	}
+	if len(server.Notifications()) != 0 {
+		t.Fatal("An injected error reached the push service")
+	}
+
+	if _, _, err := send(context.Background(), Faults{DropRate: 1}); !errors.Is(err, ErrConnectionDropped) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", ErrConnectionDropped, err)
+	}
+	if len(server.Notifications()) != 1 {
+		t.Fatal("Expected the dropped request to reach the push service")
+	}
+
+	server.Script(Response{StatusCode: http.StatusCreated, Body: "created"})
+	_, resp, err = send(context.Background(), Faults{TruncateRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != io.ErrUnexpectedEOF || string(body) != "cre" {
+		t.Fatalf("Expected a truncated body, got %q (%v)", body, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := send(ctx, Faults{Latency: time.Minute}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Incorrect error, expected=%v, got=%v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestFaultTransportBurst(t *testing.T) {
+	transport := NewFaultTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody, Request: req}, nil
+	}), Faults{ErrorRate: 0.1, BurstLength: 3, Seed: 1})
+
+	var statuses []int
+	for i := 0; i < 200; i++ {
+		req, _ := http.NewRequest(http.MethodPost, "https://push.example.net/push/1", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, resp.StatusCode)
+	}
+
+	// Errors come in back to back bursts of 3, the last one may be cut by the end of the loop
+	run, failed := 0, 0
+	for i, status := range statuses {
+		if status != http.StatusCreated {
+			run++
+			failed++
+			continue
+		}
+		if run%3 != 0 {
+			t.Fatalf("Error burst of %d ending at %d", run, i)
+		}
+		run = 0
+	}
+	if stats := transport.Stats(); failed == 0 || stats.Errors != failed || stats.Requests != len(statuses) {
+		t.Fatalf("Incorrect stats, got %+v", stats)
+	}
+}
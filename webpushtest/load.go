@@ -0,0 +1,127 @@
+package webpushtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// LoadOptions configure RunLoad
+type LoadOptions struct {
+	Subscriptions int     // Number of synthetic subscriptions, each sent one notification
+	QPS           float64 // Target notifications per second, unlimited when zero
+	Payload       []byte  // Payload of the notifications, "load test" when nil
+}
+
+// LoadReport is the outcome of RunLoad
+type LoadReport struct {
+	Notifications int           // Notifications sent
+	Failed        int           // Notifications that failed or weren't answered with 201
+	Duration      time.Duration // Time to send every notification
+	Throughput    float64       // Notifications per second
+	AllocsPerSend float64       // Heap allocations per notification, including those of the in-process push service
+	BytesPerSend  float64       // Heap bytes allocated per notification, including those of the in-process push service
+	P50           time.Duration // Latency percentiles of the push service requests
+	P90           time.Duration
+	P99           time.Duration
+	Max           time.Duration
+}
+
+func (r *LoadReport) String() string {
+	return fmt.Sprintf("%d notifications (%d failed) in %s: %.0f/s, %.0f allocs and %.0f B per send, latency p50 %s p90 %s p99 %s max %s",
+		r.Notifications, r.Failed, r.Duration.Round(time.Millisecond), r.Throughput, r.AllocsPerSend, r.BytesPerSend,
+		r.P50, r.P90, r.P99, r.Max)
+}
+
+// RunLoad fabricates options.Subscriptions subscriptions of a new Server and sends each of them a
+// notification through the fan-out pipeline of client, paced at options.QPS, reporting throughput,
+// allocations and latency so performance regressions are measurable
+func RunLoad(ctx context.Context, client *webpush.Client, options LoadOptions) (*LoadReport, error) {
+	server := NewServer()
+	defer server.Close()
+
+	store := webpush.NewMemorySubscriptionStore()
+	for i := 0; i < options.Subscriptions; i++ {
+		s := &webpush.StoredSubscription{Subscription: *server.NewSubscription()}
+		if err := store.Save(ctx, s); err != nil {
+			return nil, err
+		}
+	}
+
+	payload := options.Payload
+	if payload == nil {
+		payload = []byte("load test")
+	}
+
+	var mu sync.Mutex
+	latencies := make([]time.Duration, 0, options.Subscriptions)
+	serverClient := server.Client()
+	httpClient := webpush.HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := serverClient.Do(req)
+		elapsed := time.Since(start)
+
+		mu.Lock()
+		latencies = append(latencies, elapsed)
+		mu.Unlock()
+
+		return resp, err
+	})
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	sent := 0
+	results, err := client.SendToSegment(ctx, webpush.NewSegment(store), func(*webpush.StoredSubscription) ([]byte, *webpush.Options, error) {
+		if options.QPS > 0 {
+			time.Sleep(time.Until(start.Add(time.Duration(float64(sent) / options.QPS * float64(time.Second)))))
+		}
+		sent++
+
+		return payload, &webpush.Options{HTTPClient: httpClient, TTL: 60}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	report := &LoadReport{Notifications: len(results), Duration: duration}
+	for _, result := range results {
+		if result.Err != nil || result.Result.Response.StatusCode != http.StatusCreated {
+			report.Failed++
+		}
+	}
+
+	if len(results) > 0 {
+		report.Throughput = float64(len(results)) / duration.Seconds()
+		report.AllocsPerSend = float64(after.Mallocs-before.Mallocs) / float64(len(results))
+		report.BytesPerSend = float64(after.TotalAlloc-before.TotalAlloc) / float64(len(results))
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if len(latencies) > 0 {
+		report.P50, report.P90, report.P99 = rank(latencies, 50), rank(latencies, 90), rank(latencies, 99)
+		report.Max = latencies[len(latencies)-1]
+	}
+
+	return report, nil
+}
+
+// rank returns the nearest-rank percentile p of sorted
+func rank(sorted []time.Duration, p int) time.Duration {
+	i := (p*len(sorted)+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
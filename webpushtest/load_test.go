@@ -0,0 +1,32 @@
+package webpushtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func TestRunLoad(t *testing.T) {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := webpush.NewClient(webpush.WithVAPIDKeys(webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := RunLoad(context.Background(), client, LoadOptions{Subscriptions: 20, QPS: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 20 notifications at 200 per second take at least the 95ms until the last one is due
+	if report.Notifications != 20 || report.Failed != 0 || report.Duration < 95*time.Millisecond ||
+		report.AllocsPerSend == 0 || report.P50 == 0 || report.P99 < report.P50 || report.Max < report.P99 {
+		t.Fatalf("Incorrect report, got %s", report)
+	}
+}
@@ -0,0 +1,374 @@
+// Package webpushtest provides an in-process push service for testing code that sends notifications
+// with webpush, without reaching FCM, Mozilla or the other real push services.
+//
+// The Server checks requests like a push service would: it validates the VAPID JWT and decrypts the
+// aes128gcm payload with the keys of the subscriptions it created, and can be scripted to answer
+// with a sequence of statuses such as 201, 429 and 410.
+//
+//	server := webpushtest.NewServer()
+//	defer server.Close()
+//
+//	subscription := server.NewSubscription()
+//	client, err := webpush.NewClient(webpush.WithVAPIDKeys(keys), webpush.WithHTTPClient(server.Client()))
+//	...
+//	server.Script(webpushtest.Status(http.StatusTooManyRequests), webpushtest.Status(http.StatusCreated))
+package webpushtest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/SherClockHolmes/webpush-go/webpushconformance"
+)
+
+// maxPayload is the largest request body accepted, the 4096 byte record size of RFC 8291
+const maxPayload = 4096
+
+// Response is a scripted response of the Server
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// Status returns a Response with statusCode and its status text as body
+func Status(statusCode int) Response {
+	return Response{StatusCode: statusCode, Body: http.StatusText(statusCode)}
+}
+
+// Notification is a notification accepted by the Server, with its payload decrypted
+type Notification struct {
+	Subscription   *webpush.Subscription
+	Payload        []byte
+	TTL            int
+	Urgency        string
+	Topic          string
+	Audience       string // aud claim of the VAPID JWT
+	Subscriber     string // sub claim of the VAPID JWT
+	VAPIDPublicKey string // k parameter of the Authorization header
+	Header         http.Header
+	Response       Response // The response sent for the notification
+}
+
+// subscription is a subscription created by the Server, with its user agent keys
+type subscription struct {
+	public     *webpush.Subscription
+	privateKey []byte
+	publicKey  []byte
+	authSecret []byte
+	gone       bool
+}
+
+// Server is an in-process push service running on an httptest TLS server
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	now           func() time.Time
+	subscriptions map[string]*subscription
+	script        []Response
+	notifications []Notification
+	rejected      []error
+	nextID        int
+}
+
+// NewServer starts a Server, which must be closed with Close. Send to it with an HTTPClient
+// trusting its certificate, such as the *http.Client returned by Client.
+func NewServer() *Server {
+	s := &Server{now: time.Now, subscriptions: make(map[string]*subscription)}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+
+	// Connections cut by Close log TLS handshake errors
+	s.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	s.StartTLS()
+	return s
+}
+
+// NewSubscription returns a new subscription of the Server
+func (s *Server) NewSubscription() *webpush.Subscription {
+	privateKey, x, y, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	authSecret := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, authSecret); err != nil {
+		panic(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	path := "/push/" + strconv.Itoa(s.nextID)
+	publicKey := elliptic.Marshal(elliptic.P256(), x, y)
+	public := &webpush.Subscription{
+		Endpoint: s.URL + path,
+		Keys: webpush.Keys{
+			P256dh: base64.RawURLEncoding.EncodeToString(publicKey),
+			Auth:   base64.RawURLEncoding.EncodeToString(authSecret),
+		},
+	}
+	s.subscriptions[path] = &subscription{public: public, privateKey: privateKey, publicKey: publicKey, authSecret: authSecret}
+
+	return public
+}
+
+// Unsubscribe makes the Server answer every notification to sub with 410 Gone, like a push service
+// after the user revoked the permission
+func (s *Server) Unsubscribe(sub *webpush.Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, known := range s.subscriptions {
+		if known.public.Endpoint == sub.Endpoint {
+			known.gone = true
+		}
+	}
+}
+
+// Script queues responses for the next valid notifications, in order. Once the queue is empty,
+// notifications are answered with 201 Created.
+func (s *Server) Script(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.script = append(s.script, responses...)
+}
+
+// Notifications returns the valid notifications received so far, whatever their scripted response
+func (s *Server) Notifications() []Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Notification(nil), s.notifications...)
+}
+
+// Rejected returns why the invalid requests received so far were rejected
+func (s *Server) Rejected() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]error(nil), s.rejected...)
+}
+
+// Reset forgets the received notifications, rejected requests and scripted responses
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.script, s.notifications, s.rejected = nil, nil, nil
+}
+
+// requestError is an invalid request, answered with status
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func reject(status int, format string, args ...interface{}) *requestError {
+	return &requestError{status: status, err: fmt.Errorf("webpushtest: "+format, args...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	notification, err := s.receive(r)
+
+	s.mu.Lock()
+	if err != nil {
+		s.rejected = append(s.rejected, err)
+		s.mu.Unlock()
+		http.Error(w, err.Error(), err.status)
+		return
+	}
+
+	var response Response
+	switch {
+	case notification.Subscription == nil:
+		response = Status(http.StatusGone)
+	case len(s.script) > 0:
+		response, s.script = s.script[0], s.script[1:]
+	default:
+		response = Response{StatusCode: http.StatusCreated}
+	}
+
+	header := w.Header()
+	for key, values := range response.Header {
+		header[key] = values
+	}
+	if response.StatusCode == http.StatusCreated && header.Get("Location") == "" {
+		header.Set("Location", s.URL+"/message/"+strconv.Itoa(len(s.notifications)+1))
+	}
+
+	if notification.Subscription != nil {
+		notification.Response = response
+		s.notifications = append(s.notifications, *notification)
+	}
+	s.mu.Unlock()
+
+	w.WriteHeader(response.StatusCode)
+	io.WriteString(w, response.Body)
+}
+
+// receive validates r, returning its notification; the Subscription of the notification is nil
+// when the subscription is gone
+func (s *Server) receive(r *http.Request) (*Notification, *requestError) {
+	if r.Method != http.MethodPost {
+		return nil, reject(http.StatusMethodNotAllowed, "method %s is not POST", r.Method)
+	}
+
+	s.mu.Lock()
+	sub, ok := s.subscriptions[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		return nil, reject(http.StatusNotFound, "unknown subscription %s", r.URL.Path)
+	}
+
+	notification := &Notification{Subscription: sub.public, Header: r.Header.Clone(), Urgency: r.Header.Get("Urgency"), Topic: r.Header.Get("Topic")}
+
+	ttl, err := strconv.Atoi(r.Header.Get("TTL"))
+	if err != nil || ttl < 0 {
+		return nil, reject(http.StatusBadRequest, "invalid TTL header %q", r.Header.Get("TTL"))
+	}
+	notification.TTL = ttl
+
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "aes128gcm" {
+		return nil, reject(http.StatusUnsupportedMediaType, "unsupported Content-Encoding %q", encoding)
+	}
+
+	if err := s.checkVAPID(r, notification); err != nil {
+		return nil, err
+	}
+
+	if sub.gone {
+		notification.Subscription = nil
+		return notification, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayload+1))
+	if err != nil {
+		return nil, reject(http.StatusBadRequest, "reading the body: %v", err)
+	}
+	if len(body) > maxPayload {
+		return nil, reject(http.StatusRequestEntityTooLarge, "payload larger than %d bytes", maxPayload)
+	}
+
+	payload, err := decrypt(sub, body)
+	if err != nil {
+		return nil, reject(http.StatusBadRequest, "decrypting the payload: %v", err)
+	}
+	notification.Payload = payload
+
+	return notification, nil
+}
+
+// checkVAPID validates the vapid Authorization header of r
+func (s *Server) checkVAPID(r *http.Request, notification *Notification) *requestError {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "vapid ") {
+		return reject(http.StatusUnauthorized, "missing vapid Authorization header")
+	}
+
+	var token string
+	for _, param := range strings.Split(strings.TrimPrefix(authorization, "vapid "), ",") {
+		param = strings.TrimSpace(param)
+		switch {
+		case strings.HasPrefix(param, "t="):
+			token = strings.TrimPrefix(param, "t=")
+		case strings.HasPrefix(param, "k="):
+			notification.VAPIDPublicKey = strings.TrimPrefix(param, "k=")
+		}
+	}
+
+	publicKey, err := base64.RawURLEncoding.DecodeString(notification.VAPIDPublicKey)
+	if err != nil {
+		return reject(http.StatusUnauthorized, "invalid VAPID public key")
+	}
+
+	x, y := elliptic.Unmarshal(elliptic.P256(), publicKey)
+	if x == nil {
+		return reject(http.StatusUnauthorized, "VAPID public key is not a P-256 point")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return reject(http.StatusUnauthorized, "VAPID JWT is not a JWS")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	if err != nil || len(signature) != 64 ||
+		!ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		return reject(http.StatusForbidden, "invalid VAPID JWT signature")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	var claims struct {
+		Aud string      `json:"aud"`
+		Exp json.Number `json:"exp"`
+		Sub string      `json:"sub"`
+	}
+	if decodeSegment(parts[0], &header) != nil || header.Alg != "ES256" {
+		return reject(http.StatusUnauthorized, "VAPID JWT is not ES256")
+	}
+	if decodeSegment(parts[1], &claims) != nil {
+		return reject(http.StatusUnauthorized, "invalid VAPID JWT claims")
+	}
+
+	if claims.Aud != s.URL {
+		return reject(http.StatusForbidden, "VAPID JWT aud %q is not %q", claims.Aud, s.URL)
+	}
+
+	exp, err := claims.Exp.Int64()
+	now := s.now()
+	if err != nil || !time.Unix(exp, 0).After(now) || time.Unix(exp, 0).After(now.Add(webpush.MaxVAPIDLifetime)) {
+		return reject(http.StatusForbidden, "VAPID JWT exp %s is not within 24 hours", claims.Exp)
+	}
+
+	if claims.Sub == "" {
+		return reject(http.StatusUnauthorized, "VAPID JWT has no sub claim")
+	}
+
+	notification.Audience, notification.Subscriber = claims.Aud, claims.Sub
+	return nil
+}
+
+// decodeSegment decodes the base64url JSON segment of a JWT into v
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// decrypt returns the plaintext of body, an aes128gcm message of RFC 8188 encrypted for sub as in RFC 8291
+func decrypt(sub *subscription, body []byte) ([]byte, error) {
+	return webpushconformance.Decrypt(body, sub.privateKey, sub.publicKey, sub.authSecret)
+}
@@ -0,0 +1,112 @@
+package webpushtest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+func newTestClient(t *testing.T, server *Server) (*webpush.Client, webpush.VAPIDKeys) {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := webpush.VAPIDKeys{PrivateKey: privateKey, PublicKey: publicKey}
+	client, err := webpush.NewClient(
+		webpush.WithVAPIDKeys(keys),
+		webpush.WithSubscriber("ops@example.com"),
+		webpush.WithHTTPClient(server.Client()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, keys
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client, keys := newTestClient(t, server)
+	subscription := server.NewSubscription()
+	server.Script(Status(http.StatusTooManyRequests), Response{StatusCode: http.StatusBadRequest, Body: "bad"})
+
+	var statuses []int
+	for i := 0; i < 3; i++ {
+		resp, err := client.Send(context.Background(), []byte("Hello"), subscription, &webpush.Options{TTL: 60, Urgency: webpush.UrgencyHigh})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+
+	if statuses[0] != http.StatusTooManyRequests || statuses[1] != http.StatusBadRequest || statuses[2] != http.StatusCreated {
+		t.Fatalf("Incorrect statuses, got %v", statuses)
+	}
+
+	notifications := server.Notifications()
+	if len(notifications) != 3 {
+		t.Fatalf("Incorrect notifications, got %d", len(notifications))
+	}
+
+	last := notifications[2]
+	if string(last.Payload) != "Hello" || last.TTL != 60 || last.Urgency != "high" || last.Subscriber != "mailto:ops@example.com" ||
+		last.VAPIDPublicKey != keys.PublicKey || last.Audience != server.URL || last.Subscription != subscription {
+		t.Fatalf("Incorrect notification, got %+v", last)
+	}
+
+	server.Unsubscribe(subscription)
+	resp, err := client.Send(context.Background(), []byte("Hello"), subscription, &webpush.Options{TTL: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Fatalf("Expected 410 for an unsubscribed subscription, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client, _ := newTestClient(t, server)
+	subscription := server.NewSubscription()
+
+	// A subscription of another user agent can't be decrypted
+	other := server.NewSubscription()
+	forged := *subscription
+	forged.Keys = other.Keys
+
+	send := func(s *webpush.Subscription, options *webpush.Options) int {
+		resp, err := client.Send(context.Background(), []byte("Hello"), s, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := send(&forged, &webpush.Options{TTL: 60}); status != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an undecryptable payload, got %d", status)
+	}
+
+	if status := send(subscription, &webpush.Options{TTL: 60, ContentEncoding: webpush.ContentEncodingAESGCM}); status != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected 415 for aesgcm, got %d", status)
+	}
+
+	if status := send(subscription, &webpush.Options{TTL: 60, Audience: "https://fcm.googleapis.com"}); status != http.StatusForbidden {
+		t.Fatalf("Expected 403 for another audience, got %d", status)
+	}
+
+	rejected := server.Rejected()
+	if len(rejected) != 3 || !strings.Contains(rejected[2].Error(), "aud") || len(server.Notifications()) != 0 {
+		t.Fatalf("Incorrect rejections, got %v", rejected)
+	}
+}